@@ -9,13 +9,28 @@ import (
 
 const rememberMeAuthTokenDurationSeconds int64 = 30 * 24 * 60 * 60
 
+// resourceTypeValues lists every supported resources.type value.
+var resourceTypeValues = []string{"rss", "watchlist", "quickadd", "sitemap", "pagewatch", "newsletter", "json"}
+
 func registerCollections(app core.App) {
 	ensureResourcesCollection(app)
 	ensureEntriesCollection(app)
 	ensurePreferencesCollection(app)
+	ensurePreferenceRulesCollection(app)
 	ensureSettingsCollection(app)
 	ensureDailyNewsSettingsCollection(app)
 	ensureDailyDigestsCollection(app)
+	ensureUserSettingsCollection(app)
+	ensureChatSessionsCollection(app)
+	ensureChatMessagesCollection(app)
+	ensureSiteRulesCollection(app)
+	ensureArchivesCollection(app)
+	ensureImportJobsCollection(app)
+	ensureRescoreJobsCollection(app)
+	ensureTagsCollection(app)
+	ensureRelevanceModelsCollection(app)
+	ensureInteractionsCollection(app)
+	ensurePromptTemplatesCollection(app)
 	ensureDailyNewsDefaultSettings(app)
 	ensureSuperuserAuthTokenDuration(app)
 	migrateCollections(app)
@@ -60,7 +75,7 @@ func ensureResourcesCollection(app core.App) {
 	collection.Fields.Add(&core.SelectField{
 		Name:      "type",
 		Required:  true,
-		Values:    []string{"rss", "watchlist", "quickadd"},
+		Values:    append([]string(nil), resourceTypeValues...),
 		MaxSelect: 1,
 	})
 	collection.Fields.Add(&core.TextField{
@@ -240,6 +255,265 @@ func ensureSettingsCollection(app core.App) {
 	}
 }
 
+// ensureSiteRulesCollection creates the per-domain extraction rules
+// collection. rules holds an ftr-site-config style rule file.
+func ensureSiteRulesCollection(app core.App) {
+	if _, err := app.FindCollectionByNameOrId("site_rules"); err == nil {
+		return
+	}
+
+	collection := core.NewBaseCollection("site_rules")
+	collection.Fields.Add(&core.AutodateField{Name: "created", OnCreate: true})
+	collection.Fields.Add(&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true})
+	collection.Fields.Add(&core.TextField{Name: "domain", Required: true, Max: 253})
+	collection.Fields.Add(&core.TextField{Name: "rules", Max: 50000})
+	collection.Fields.Add(&core.TextField{Name: "imported_from", Max: 1000})
+	collection.Indexes = append(collection.Indexes,
+		"CREATE UNIQUE INDEX idx_site_rules_domain ON site_rules (domain)",
+	)
+
+	collection.ListRule = types.Pointer("@request.auth.id != ''")
+	collection.ViewRule = types.Pointer("@request.auth.id != ''")
+	collection.CreateRule = types.Pointer("@request.auth.id != ''")
+	collection.UpdateRule = types.Pointer("@request.auth.id != ''")
+	collection.DeleteRule = types.Pointer("@request.auth.id != ''")
+
+	if err := app.Save(collection); err != nil {
+		log.Printf("Failed to create site_rules collection: %v", err)
+	}
+}
+
+// ensureArchivesCollection creates the collection holding self-contained
+// HTML snapshots of starred and bookmarked entries, deduplicated by hash.
+func ensureArchivesCollection(app core.App) {
+	if _, err := app.FindCollectionByNameOrId("archives"); err == nil {
+		return
+	}
+
+	collection := core.NewBaseCollection("archives")
+	collection.Fields.Add(&core.AutodateField{Name: "created", OnCreate: true})
+	collection.Fields.Add(&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true})
+	collection.Fields.Add(&core.TextField{Name: "hash", Required: true, Max: 64})
+	collection.Fields.Add(&core.URLField{Name: "url"})
+	collection.Fields.Add(&core.FileField{Name: "file", MaxSelect: 1, MaxSize: 50 << 20, Protected: true})
+	collection.Fields.Add(&core.NumberField{Name: "size"})
+	collection.Fields.Add(&core.SelectField{Name: "captured_with", Values: []string{"http", "browser"}, MaxSelect: 1})
+	collection.Indexes = append(collection.Indexes,
+		"CREATE UNIQUE INDEX idx_archives_hash ON archives (hash)",
+	)
+
+	collection.ListRule = types.Pointer("@request.auth.id != ''")
+	collection.ViewRule = types.Pointer("@request.auth.id != ''")
+	collection.CreateRule = nil
+	collection.UpdateRule = nil
+	collection.DeleteRule = types.Pointer("@request.auth.id != ''")
+
+	if err := app.Save(collection); err != nil {
+		log.Printf("Failed to create archives collection: %v", err)
+	}
+}
+
+// ensureImportJobsCollection creates the collection tracking batch Quick Add
+// and bookmark import jobs: the queued links with their results, and
+// progress counters.
+func ensureImportJobsCollection(app core.App) {
+	if _, err := app.FindCollectionByNameOrId("import_jobs"); err == nil {
+		return
+	}
+
+	collection := core.NewBaseCollection("import_jobs")
+	collection.Fields.Add(&core.AutodateField{Name: "created", OnCreate: true})
+	collection.Fields.Add(&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true})
+	collection.Fields.Add(&core.SelectField{Name: "source", Required: true, Values: []string{"text", "pocket", "instapaper", "raindrop", "netscape"}, MaxSelect: 1})
+	collection.Fields.Add(&core.SelectField{Name: "status", Required: true, Values: []string{"pending", "running", "done", "failed"}, MaxSelect: 1})
+	collection.Fields.Add(&core.JSONField{Name: "items", MaxSize: 20 << 20})
+	collection.Fields.Add(&core.NumberField{Name: "total"})
+	collection.Fields.Add(&core.NumberField{Name: "processed"})
+	collection.Fields.Add(&core.NumberField{Name: "added"})
+	collection.Fields.Add(&core.NumberField{Name: "skipped"})
+	collection.Fields.Add(&core.NumberField{Name: "failed"})
+	collection.Fields.Add(&core.TextField{Name: "error"})
+	collection.Fields.Add(&core.DateField{Name: "finished_at"})
+
+	collection.ListRule = types.Pointer("@request.auth.id != ''")
+	collection.ViewRule = types.Pointer("@request.auth.id != ''")
+	collection.CreateRule = nil
+	collection.UpdateRule = nil
+	collection.DeleteRule = types.Pointer("@request.auth.id != ''")
+
+	if err := app.Save(collection); err != nil {
+		log.Printf("Failed to create import_jobs collection: %v", err)
+	}
+}
+
+// ensureRescoreJobsCollection creates the collection tracking jobs that score
+// recent unread entries again after the preference profile changed, with
+// each entry's old and new score.
+func ensureRescoreJobsCollection(app core.App) {
+	if _, err := app.FindCollectionByNameOrId("rescore_jobs"); err == nil {
+		return
+	}
+
+	collection := core.NewBaseCollection("rescore_jobs")
+	collection.Fields.Add(&core.AutodateField{Name: "created", OnCreate: true})
+	collection.Fields.Add(&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true})
+	collection.Fields.Add(&core.SelectField{Name: "trigger", Required: true, Values: []string{"profile_change", "manual"}, MaxSelect: 1})
+	collection.Fields.Add(&core.SelectField{Name: "status", Required: true, Values: []string{"pending", "running", "done", "failed"}, MaxSelect: 1})
+	collection.Fields.Add(&core.NumberField{Name: "days"})
+	collection.Fields.Add(&core.NumberField{Name: "max_entries"})
+	collection.Fields.Add(&core.NumberField{Name: "max_tokens"})
+	collection.Fields.Add(&core.NumberField{Name: "profile_version"})
+	collection.Fields.Add(&core.JSONField{Name: "items", MaxSize: 20 << 20})
+	collection.Fields.Add(&core.NumberField{Name: "total"})
+	collection.Fields.Add(&core.NumberField{Name: "processed"})
+	collection.Fields.Add(&core.NumberField{Name: "changed"})
+	collection.Fields.Add(&core.NumberField{Name: "unchanged"})
+	collection.Fields.Add(&core.NumberField{Name: "failed"})
+	collection.Fields.Add(&core.NumberField{Name: "skipped"})
+	collection.Fields.Add(&core.NumberField{Name: "estimated_tokens"})
+	collection.Fields.Add(&core.TextField{Name: "error"})
+	collection.Fields.Add(&core.DateField{Name: "finished_at"})
+
+	collection.ListRule = types.Pointer("@request.auth.id != ''")
+	collection.ViewRule = types.Pointer("@request.auth.id != ''")
+	collection.CreateRule = nil
+	collection.UpdateRule = nil
+	collection.DeleteRule = types.Pointer("@request.auth.id != ''")
+
+	if err := app.Save(collection); err != nil {
+		log.Printf("Failed to create rescore_jobs collection: %v", err)
+	}
+}
+
+func ensureTagsCollection(app core.App) {
+	if _, err := app.FindCollectionByNameOrId("tags"); err == nil {
+		return
+	}
+
+	collection := core.NewBaseCollection("tags")
+	collection.Fields.Add(&core.AutodateField{Name: "created", OnCreate: true})
+	collection.Fields.Add(&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true})
+	collection.Fields.Add(&core.TextField{Name: "name", Required: true, Max: 50})
+	collection.Fields.Add(&core.JSONField{Name: "synonyms", MaxSize: 5000})
+
+	collection.ListRule = types.Pointer("@request.auth.id != ''")
+	collection.ViewRule = types.Pointer("@request.auth.id != ''")
+	collection.CreateRule = types.Pointer("@request.auth.id != ''")
+	collection.UpdateRule = types.Pointer("@request.auth.id != ''")
+	collection.DeleteRule = types.Pointer("@request.auth.id != ''")
+	collection.Indexes = append(collection.Indexes, "CREATE UNIQUE INDEX idx_tags_name ON tags (name)")
+
+	if err := app.Save(collection); err != nil {
+		log.Printf("Failed to create tags collection: %v", err)
+	}
+}
+
+func ensureRelevanceModelsCollection(app core.App) {
+	if _, err := app.FindCollectionByNameOrId("relevance_models"); err == nil {
+		return
+	}
+
+	collection := core.NewBaseCollection("relevance_models")
+	collection.Fields.Add(&core.AutodateField{Name: "created", OnCreate: true})
+	collection.Fields.Add(&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true})
+	collection.Fields.Add(&core.DateField{Name: "trained_at", Required: true})
+	collection.Fields.Add(&core.NumberField{Name: "samples"})
+	collection.Fields.Add(&core.NumberField{Name: "holdout"})
+	collection.Fields.Add(&core.NumberField{Name: "accuracy"})
+	collection.Fields.Add(&core.NumberField{Name: "mae"})
+	collection.Fields.Add(&core.JSONField{Name: "model", MaxSize: 20 << 20})
+
+	collection.ListRule = types.Pointer("@request.auth.id != ''")
+	collection.ViewRule = types.Pointer("@request.auth.id != ''")
+	collection.CreateRule = nil
+	collection.UpdateRule = nil
+	collection.DeleteRule = nil
+
+	if err := app.Save(collection); err != nil {
+		log.Printf("Failed to create relevance_models collection: %v", err)
+	}
+}
+
+// ensureInteractionsCollection creates the log of reading interactions
+// (opened, read time, bookmarked, ...) used as implicit preference signals.
+func ensureInteractionsCollection(app core.App) {
+	if _, err := app.FindCollectionByNameOrId("interactions"); err == nil {
+		return
+	}
+
+	collection := core.NewBaseCollection("interactions")
+	collection.Fields.Add(&core.AutodateField{Name: "created", OnCreate: true})
+	collection.Fields.Add(&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true})
+	collection.Fields.Add(&core.RelationField{Name: "entry", CollectionId: getCollectionId(app, "entries"), Required: true, MaxSelect: 1, CascadeDelete: true})
+	collection.Fields.Add(&core.SelectField{Name: "type", Required: true, Values: []string{"opened", "read", "bookmarked", "chatted", "link_summarized", "dismissed"}, MaxSelect: 1})
+	collection.Fields.Add(&core.NumberField{Name: "duration_seconds", Min: floatPtr(0)})
+	collection.Indexes = append(collection.Indexes,
+		"CREATE INDEX idx_interactions_entry ON interactions (entry)",
+		"CREATE INDEX idx_interactions_created ON interactions (created)",
+	)
+
+	collection.ListRule = types.Pointer("@request.auth.id != ''")
+	collection.ViewRule = types.Pointer("@request.auth.id != ''")
+	collection.CreateRule = nil
+	collection.UpdateRule = nil
+	collection.DeleteRule = types.Pointer("@request.auth.id != ''")
+
+	if err := app.Save(collection); err != nil {
+		log.Printf("Failed to create interactions collection: %v", err)
+	}
+}
+
+// ensurePromptTemplatesCollection creates the collection holding custom
+// versions of the built-in prompt templates.
+func ensurePromptTemplatesCollection(app core.App) {
+	if _, err := app.FindCollectionByNameOrId("prompt_templates"); err == nil {
+		return
+	}
+
+	collection := core.NewBaseCollection("prompt_templates")
+	collection.Fields.Add(&core.AutodateField{Name: "created", OnCreate: true})
+	collection.Fields.Add(&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true})
+	collection.Fields.Add(&core.TextField{Name: "name", Required: true, Max: 50})
+	collection.Fields.Add(&core.NumberField{Name: "version", Required: true, Min: floatPtr(2)})
+	collection.Fields.Add(&core.TextField{Name: "template", Required: true, Max: 20000})
+	collection.Fields.Add(&core.TextField{Name: "notes", Max: 500})
+	collection.Fields.Add(&core.BoolField{Name: "active"})
+	collection.Indexes = append(collection.Indexes,
+		"CREATE UNIQUE INDEX idx_prompt_templates_name_version ON prompt_templates (name, version)",
+	)
+
+	collection.ListRule = types.Pointer("@request.auth.id != ''")
+	collection.ViewRule = types.Pointer("@request.auth.id != ''")
+	collection.CreateRule = nil
+	collection.UpdateRule = nil
+	collection.DeleteRule = nil
+
+	if err := app.Save(collection); err != nil {
+		log.Printf("Failed to create prompt_templates collection: %v", err)
+	}
+}
+
+func ensurePreferenceRulesCollection(app core.App) {
+	if _, err := app.FindCollectionByNameOrId("preference_rules"); err == nil {
+		return
+	}
+
+	collection := core.NewBaseCollection("preference_rules")
+	collection.Fields.Add(&core.AutodateField{Name: "created", OnCreate: true})
+	collection.Fields.Add(&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true})
+	collection.Fields.Add(&core.TextField{Name: "rule", Required: true, Max: 500})
+
+	collection.ListRule = types.Pointer("@request.auth.id != ''")
+	collection.ViewRule = types.Pointer("@request.auth.id != ''")
+	collection.CreateRule = types.Pointer("@request.auth.id != ''")
+	collection.UpdateRule = types.Pointer("@request.auth.id != ''")
+	collection.DeleteRule = types.Pointer("@request.auth.id != ''")
+
+	if err := app.Save(collection); err != nil {
+		log.Printf("Failed to create preference_rules collection: %v", err)
+	}
+}
+
 func ensureDailyNewsSettingsCollection(app core.App) {
 	if collection, err := app.FindCollectionByNameOrId("daily_news_settings"); err == nil {
 		collection.ListRule = types.Pointer("user = @request.auth.id")
@@ -261,6 +535,7 @@ func ensureDailyNewsSettingsCollection(app core.App) {
 	collection.Fields.Add(&core.TextField{Name: "generation_time", Required: true, Max: 5})
 	collection.Fields.Add(&core.TextField{Name: "timezone", Required: true, Max: 100})
 	collection.Fields.Add(&core.TextField{Name: "extra_instructions", Max: 8000})
+	collection.Fields.Add(&core.TextField{Name: "rank_by", Max: 20})
 	collection.ListRule = types.Pointer("user = @request.auth.id")
 	collection.ViewRule = types.Pointer("user = @request.auth.id")
 	collection.CreateRule = nil
@@ -273,6 +548,81 @@ func ensureDailyNewsSettingsCollection(app core.App) {
 	}
 }
 
+func ensureUserSettingsCollection(app core.App) {
+	if _, err := app.FindCollectionByNameOrId("user_settings"); err == nil {
+		return
+	}
+
+	collection := core.NewBaseCollection("user_settings")
+	collection.Fields.Add(&core.AutodateField{Name: "created", OnCreate: true})
+	collection.Fields.Add(&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true})
+	collection.Fields.Add(&core.RelationField{Name: "user", CollectionId: getCollectionId(app, core.CollectionNameSuperusers), Required: true, MaxSelect: 1})
+	collection.Fields.Add(&core.TextField{Name: "summary_language", Max: 10})
+	collection.Fields.Add(&core.BoolField{Name: "translate_articles"})
+	collection.ListRule = types.Pointer("user = @request.auth.id")
+	collection.ViewRule = types.Pointer("user = @request.auth.id")
+	collection.CreateRule = nil
+	collection.UpdateRule = nil
+	collection.DeleteRule = nil
+	collection.Indexes = append(collection.Indexes, "CREATE UNIQUE INDEX idx_user_settings_user ON user_settings (user)")
+
+	if err := app.Save(collection); err != nil {
+		log.Printf("Failed to create user_settings collection: %v", err)
+	}
+}
+
+// ensureChatSessionsCollection creates the collection holding a user's
+// saved conversations about an entry.
+func ensureChatSessionsCollection(app core.App) {
+	if _, err := app.FindCollectionByNameOrId("chat_sessions"); err == nil {
+		return
+	}
+
+	collection := core.NewBaseCollection("chat_sessions")
+	collection.Fields.Add(&core.AutodateField{Name: "created", OnCreate: true})
+	collection.Fields.Add(&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true})
+	collection.Fields.Add(&core.RelationField{Name: "user", CollectionId: getCollectionId(app, core.CollectionNameSuperusers), Required: true, MaxSelect: 1})
+	collection.Fields.Add(&core.RelationField{Name: "entry", CollectionId: getCollectionId(app, "entries"), Required: true, MaxSelect: 1, CascadeDelete: true})
+	collection.Fields.Add(&core.TextField{Name: "title", Max: 200})
+	collection.ListRule = types.Pointer("user = @request.auth.id")
+	collection.ViewRule = types.Pointer("user = @request.auth.id")
+	collection.CreateRule = nil
+	collection.UpdateRule = nil
+	collection.DeleteRule = nil
+	collection.Indexes = append(collection.Indexes, "CREATE INDEX idx_chat_sessions_user_entry ON chat_sessions (user, entry)")
+
+	if err := app.Save(collection); err != nil {
+		log.Printf("Failed to create chat_sessions collection: %v", err)
+	}
+}
+
+// ensureChatMessagesCollection creates the collection holding the messages
+// of a chat session, in the order of their position.
+func ensureChatMessagesCollection(app core.App) {
+	if _, err := app.FindCollectionByNameOrId("chat_messages"); err == nil {
+		return
+	}
+
+	collection := core.NewBaseCollection("chat_messages")
+	collection.Fields.Add(&core.AutodateField{Name: "created", OnCreate: true})
+	collection.Fields.Add(&core.AutodateField{Name: "updated", OnCreate: true, OnUpdate: true})
+	collection.Fields.Add(&core.RelationField{Name: "session", CollectionId: getCollectionId(app, "chat_sessions"), Required: true, MaxSelect: 1, CascadeDelete: true})
+	collection.Fields.Add(&core.NumberField{Name: "position", OnlyInt: true})
+	collection.Fields.Add(&core.SelectField{Name: "role", Required: true, Values: []string{"user", "assistant"}, MaxSelect: 1})
+	collection.Fields.Add(&core.EditorField{Name: "content"})
+	collection.Fields.Add(&core.BoolField{Name: "partial"})
+	collection.ListRule = types.Pointer("session.user = @request.auth.id")
+	collection.ViewRule = types.Pointer("session.user = @request.auth.id")
+	collection.CreateRule = nil
+	collection.UpdateRule = nil
+	collection.DeleteRule = nil
+	collection.Indexes = append(collection.Indexes, "CREATE UNIQUE INDEX idx_chat_messages_session ON chat_messages (session, position)")
+
+	if err := app.Save(collection); err != nil {
+		log.Printf("Failed to create chat_messages collection: %v", err)
+	}
+}
+
 func ensureDailyDigestsCollection(app core.App) {
 	if collection, err := app.FindCollectionByNameOrId("daily_digests"); err == nil {
 		collection.ListRule = types.Pointer("user = @request.auth.id")
@@ -389,6 +739,57 @@ func migrateCollections(app core.App) {
 	addFieldIfMissing(app, "entries", &core.JSONField{Name: "takeaways", MaxSize: 5000})
 	addFieldIfMissing(app, "resources", &core.SelectField{Name: "fragment_mode", Values: []string{"auto", "separated"}, MaxSelect: 1})
 	addFieldIfMissing(app, "resources", &core.TextField{Name: "fragment_separator"})
+	addFieldIfMissing(app, "resources", &core.TextField{Name: "url_pattern"})
+	addFieldIfMissing(app, "resources", &core.EditorField{Name: "page_snapshot"})
+	addFieldIfMissing(app, "resources", &core.TextField{Name: "page_snapshot_hash"})
+	addFieldIfMissing(app, "resources", &core.TextField{Name: "newsletter_senders"})
+	addFieldIfMissing(app, "resources", &core.TextField{Name: "json_items_path"})
+	addFieldIfMissing(app, "resources", &core.JSONField{Name: "json_mapping", MaxSize: 5000})
+	addFieldIfMissing(app, "resources", &core.JSONField{Name: "json_headers", MaxSize: 5000})
+	addFieldIfMissing(app, "entries", &core.EditorField{Name: "change_diff"})
+	addFieldIfMissing(app, "entries", &core.JSONField{Name: "media", MaxSize: 5000})
+	addFieldIfMissing(app, "entries", &core.EditorField{Name: "content_html"})
+	addFieldIfMissing(app, "entries", &core.EditorField{Name: "content_markdown"})
+	addFieldIfMissing(app, "entries", &core.TextField{Name: "byline"})
+	addFieldIfMissing(app, "entries", &core.TextField{Name: "site_name"})
+	addFieldIfMissing(app, "entries", &core.TextField{Name: "excerpt"})
+	addFieldIfMissing(app, "entries", &core.TextField{Name: "lead_image"})
+	addFieldIfMissing(app, "entries", &core.TextField{Name: "language"})
+	addFieldIfMissing(app, "entries", &core.NumberField{Name: "word_count"})
+	addFieldIfMissing(app, "entries", &core.RelationField{Name: "archive", CollectionId: getCollectionId(app, "archives"), MaxSelect: 1})
+	addFieldIfMissing(app, "entries", &core.DateField{Name: "archived_at"})
+	addFieldIfMissing(app, "entries", &core.JSONField{Name: "pdf_pages", MaxSize: 20 << 20})
+	addFieldIfMissing(app, "entries", &core.FileField{Name: "source_file", MaxSelect: 1, MaxSize: 50 << 20, Protected: true})
+	addFieldIfMissing(app, "entries", &core.TextField{Name: "source_format"})
+	addFieldIfMissing(app, "entries", &core.JSONField{Name: "tags", MaxSize: 5000})
+	addFieldIfMissing(app, "entries", &core.TextField{Name: "score_reason", Max: 1000})
+	addFieldIfMissing(app, "entries", &core.JSONField{Name: "score_influences", MaxSize: 5000})
+	addFieldIfMissing(app, "entries", &core.NumberField{Name: "score_relevance", Min: floatPtr(0), Max: floatPtr(5)})
+	addFieldIfMissing(app, "entries", &core.NumberField{Name: "score_depth", Min: floatPtr(0), Max: floatPtr(5)})
+	addFieldIfMissing(app, "entries", &core.NumberField{Name: "score_novelty", Min: floatPtr(0), Max: floatPtr(5)})
+	addFieldIfMissing(app, "entries", &core.NumberField{Name: "score_credibility", Min: floatPtr(0), Max: floatPtr(5)})
+	addFieldIfMissing(app, "daily_news_settings", &core.TextField{Name: "rank_by", Max: 20})
+	addFieldIfMissing(app, "entries", &core.NumberField{Name: "local_stars", Min: floatPtr(0), Max: floatPtr(5)})
+	addFieldIfMissing(app, "entries", &core.NumberField{Name: "local_confidence", Min: floatPtr(0), Max: floatPtr(1)})
+	addFieldIfMissing(app, "entries", &core.TextField{Name: "score_source", Max: 20})
+	addFieldIfMissing(app, "preferences", &core.NumberField{Name: "version"})
+	addFieldIfMissing(app, "preferences", &core.TextField{Name: "model"})
+	addFieldIfMissing(app, "preferences", &core.TextField{Name: "source", Max: 20})
+	addFieldIfMissing(app, "preferences", &core.TextField{Name: "restored_from"})
+	addFieldIfMissing(app, "preferences", &core.JSONField{Name: "source_corrections", MaxSize: 200000})
+	addFieldIfMissing(app, "resources", &core.SelectField{Name: "summary_length", Values: []string{"short", "medium", "long"}, MaxSelect: 1})
+	addFieldIfMissing(app, "resources", &core.SelectField{Name: "summary_format", Values: []string{"sentences", "bullets", "tldr", "key_numbers"}, MaxSelect: 1})
+	addFieldIfMissing(app, "resources", &core.TextField{Name: "summary_language", Max: 50})
+	addFieldIfMissing(app, "resources", &core.TextField{Name: "summary_instructions", Max: 2000})
+	addFieldIfMissing(app, "tags", &core.SelectField{Name: "summary_length", Values: []string{"short", "medium", "long"}, MaxSelect: 1})
+	addFieldIfMissing(app, "tags", &core.SelectField{Name: "summary_format", Values: []string{"sentences", "bullets", "tldr", "key_numbers"}, MaxSelect: 1})
+	addFieldIfMissing(app, "tags", &core.TextField{Name: "summary_language", Max: 50})
+	addFieldIfMissing(app, "tags", &core.TextField{Name: "summary_instructions", Max: 2000})
+	addFieldIfMissing(app, "entries", &core.TextField{Name: "detected_language", Max: 10})
+	addFieldIfMissing(app, "entries", &core.JSONField{Name: "translations", MaxSize: 5 << 20})
+	addFieldIfMissing(app, "entries", &core.DateField{Name: "unarchivable_since"})
+	addFieldIfMissing(app, "preferences", &core.JSONField{Name: "implicit_signals", MaxSize: 50000})
+	addFieldIfMissing(app, "resources", &core.DateField{Name: "sitemap_since"})
 	migrateResourceTypeValues(app)
 }
 
@@ -406,7 +807,8 @@ func addFieldIfMissing(app core.App, collectionName string, field core.Field) {
 	}
 }
 
-// migrateResourceTypeValues ensures the resources "type" select field includes "quickadd".
+// migrateResourceTypeValues ensures the resources "type" select field includes
+// every value in resourceTypeValues.
 func migrateResourceTypeValues(app core.App) {
 	col, err := app.FindCollectionByNameOrId("resources")
 	if err != nil {
@@ -420,14 +822,22 @@ func migrateResourceTypeValues(app core.App) {
 	if !ok {
 		return
 	}
+	existing := make(map[string]bool, len(sf.Values))
 	for _, v := range sf.Values {
-		if v == "quickadd" {
-			return // already present
+		existing[v] = true
+	}
+	changed := false
+	for _, v := range resourceTypeValues {
+		if !existing[v] {
+			sf.Values = append(sf.Values, v)
+			changed = true
 		}
 	}
-	sf.Values = append(sf.Values, "quickadd")
+	if !changed {
+		return // already present
+	}
 	if err := app.Save(col); err != nil {
-		log.Printf("Failed to add quickadd to resource type values: %v", err)
+		log.Printf("Failed to add resource type values: %v", err)
 	}
 }
 
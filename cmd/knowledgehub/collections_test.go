@@ -5,6 +5,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/jgordijn/knowledgehub/internal/testutil"
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/tools/types"
 
@@ -248,3 +250,98 @@ func TestEnsureSuperuserAuthTokenDuration_PreservesLongerDuration(t *testing.T)
 		t.Fatalf("superuser auth token duration = %d, want %d", got, customDuration)
 	}
 }
+
+func TestMigrateResourceTypeValues_AddsMissingTypes(t *testing.T) {
+	app, cleanup := newTestApp(t)
+	defer cleanup()
+	registerCollections(app)
+
+	resources, err := app.FindCollectionByNameOrId("resources")
+	if err != nil {
+		t.Fatalf("resources collection not found: %v", err)
+	}
+	typeField := resources.Fields.GetByName("type").(*core.SelectField)
+	typeField.Values = []string{"rss", "watchlist"}
+	if err := app.Save(resources); err != nil {
+		t.Fatalf("failed to save legacy type values: %v", err)
+	}
+
+	migrateResourceTypeValues(app)
+
+	resources, _ = app.FindCollectionByNameOrId("resources")
+	got := resources.Fields.GetByName("type").(*core.SelectField).Values
+	for _, want := range resourceTypeValues {
+		found := false
+		for _, v := range got {
+			if v == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("type values %v missing %q", got, want)
+		}
+	}
+	assertFieldExists(t, resources, "url_pattern")
+}
+
+func TestRegisterCollections_DeletesInteractionsWithEntry(t *testing.T) {
+	app, cleanup := newTestApp(t)
+	defer cleanup()
+	registerCollections(app)
+
+	resource := testutil.CreateResource(t, app, "Blog", "https://example.com/feed.xml", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, resource.Id, "Post", "https://example.com/a", "post-1")
+	other := testutil.CreateEntry(t, app, resource.Id, "Other", "https://example.com/b", "post-2")
+	for _, id := range []string{entry.Id, other.Id} {
+		if _, err := ai.RecordInteraction(app, id, ai.InteractionOpened, 0); err != nil {
+			t.Fatalf("failed to record interaction: %v", err)
+		}
+	}
+
+	if err := app.Delete(entry); err != nil {
+		t.Fatalf("failed to delete entry: %v", err)
+	}
+
+	remaining, err := app.FindRecordsByFilter("interactions", "", "", 0, 0)
+	if err != nil {
+		t.Fatalf("failed to list interactions: %v", err)
+	}
+	if len(remaining) != 1 || remaining[0].GetString("entry") != other.Id {
+		t.Fatalf("expected only the other entry's interaction to remain, got %d", len(remaining))
+	}
+}
+
+func TestRegisterCollections_DeletesChatSessionsWithEntry(t *testing.T) {
+	app, cleanup := newTestApp(t)
+	defer cleanup()
+	registerCollections(app)
+
+	user := testutil.CreateSuperuser(t, app, "reader@example.com")
+	resource := testutil.CreateResource(t, app, "Blog", "https://example.com/feed.xml", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, resource.Id, "Post", "https://example.com/a", "post-1")
+	other := testutil.CreateEntry(t, app, resource.Id, "Other", "https://example.com/b", "post-2")
+	sessions, err := app.FindCollectionByNameOrId("chat_sessions")
+	if err != nil {
+		t.Fatalf("chat_sessions collection not found: %v", err)
+	}
+	for _, id := range []string{entry.Id, other.Id} {
+		session := core.NewRecord(sessions)
+		session.Set("user", user.Id)
+		session.Set("entry", id)
+		if err := app.Save(session); err != nil {
+			t.Fatalf("failed to create chat session: %v", err)
+		}
+	}
+
+	if err := app.Delete(entry); err != nil {
+		t.Fatalf("failed to delete entry: %v", err)
+	}
+
+	remaining, err := app.FindRecordsByFilter("chat_sessions", "", "", 0, 0)
+	if err != nil {
+		t.Fatalf("failed to list chat sessions: %v", err)
+	}
+	if len(remaining) != 1 || remaining[0].GetString("entry") != other.Id {
+		t.Fatalf("expected only the other entry's session to remain, got %d", len(remaining))
+	}
+}
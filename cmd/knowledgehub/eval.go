@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/spf13/cobra"
+)
+
+// newEvalCommand returns the "eval" subcommand, which replays the entries
+// the user rated against a prompt version and model and reports how well the
+// resulting stars match.
+func newEvalCommand(app core.App) *cobra.Command {
+	var opts ai.EvalOptions
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "eval",
+		Short: "Evaluate a prompt version against the entries you rated",
+		Long: "Replays the most recent entries with user_stars against a prompt version and model, " +
+			"without changing them, and reports the mean absolute error, agreement and estimated cost.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			registerCollections(app)
+			return runEval(app, opts, asJSON, cmd.OutOrStdout())
+		},
+	}
+	cmd.Flags().StringVar(&opts.Prompt, "prompt", ai.PromptScoreOnly, "prompt to evaluate: summary, score_only or score_only_batch")
+	cmd.Flags().IntVar(&opts.Version, "version", 0, "prompt version (0 = active, 1 = built-in)")
+	cmd.Flags().StringVar(&opts.Model, "model", "", "model to use (default: the configured model)")
+	cmd.Flags().IntVar(&opts.Limit, "limit", ai.DefaultEvalLimit, "number of rated entries to replay")
+	cmd.Flags().IntVar(&opts.BatchSize, "batch-size", ai.DefaultEvalBatchSize, "entries per call for score_only_batch")
+	cmd.Flags().Float64Var(&opts.InputPricePerMillion, "input-price", 0, "input price in dollars per million tokens")
+	cmd.Flags().Float64Var(&opts.OutputPricePerMillion, "output-price", 0, "output price in dollars per million tokens")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the report as JSON")
+	return cmd
+}
+
+func runEval(app core.App, opts ai.EvalOptions, asJSON bool, out io.Writer) error {
+	report, err := ai.EvaluatePrompt(app, opts)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	fmt.Fprintf(out, "Prompt %s v%d with %s\n", report.Prompt, report.Version, report.Model)
+	fmt.Fprintf(out, "Entries: %d (scored %d, failed %d)\n", report.Entries, report.Scored, report.Failed)
+	fmt.Fprintf(out, "MAE: %.3f  Agreement: %.1f%%  Within one star: %.1f%%\n", report.MAE, report.Agreement*100, report.WithinOne*100)
+	fmt.Fprintf(out, "Estimated tokens: %d in, %d out  Estimated cost: $%.4f\n\n", report.PromptTokens, report.CompletionTokens, report.EstimatedCost)
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "USER\tAI\tTITLE")
+	for _, item := range report.Items {
+		stars := fmt.Sprint(item.Stars)
+		if item.Error != "" {
+			stars = "error"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", item.UserStars, stars, item.Title)
+	}
+	return w.Flush()
+}
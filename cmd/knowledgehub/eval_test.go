@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+)
+
+func TestEvalCommand(t *testing.T) {
+	app, cleanup := newHooksTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	testutil.CreateEntryWithStars(t, app, res.Id, "Raft consensus", "https://example.com/raft", 4, 4)
+
+	restore := ai.SetCompleteFunc(func(_, _ string, _ []ai.Message) (string, error) {
+		return `{"summary":"","stars":3}`, nil
+	})
+	defer restore()
+
+	var out bytes.Buffer
+	cmd := newEvalCommand(app)
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--model", "test/model", "--limit", "5"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("eval: %v", err)
+	}
+	for _, want := range []string{"Prompt score_only v1 with test/model", "Entries: 1 (scored 1, failed 0)", "MAE: 1.000", "Raft consensus"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	cmd = newEvalCommand(app)
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--json", "--prompt", "summary"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("eval --json: %v", err)
+	}
+	var report ai.EvalReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil || report.Prompt != "summary" || report.Scored != 1 {
+		t.Errorf("unexpected JSON report: %+v %v", report, err)
+	}
+
+	cmd = newEvalCommand(app)
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"--version", "5"})
+	if err := cmd.Execute(); err == nil {
+		t.Error("expected an unknown version to fail")
+	}
+}
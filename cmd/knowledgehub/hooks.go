@@ -2,7 +2,10 @@ package main
 
 import (
 	"log"
+	"sync"
+	"time"
 
+	"github.com/jgordijn/knowledgehub/internal/engine"
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/core"
 )
@@ -26,6 +29,47 @@ func registerHooks(app *pocketbase.PocketBase) {
 		return e.Next()
 	})
 
+	// Keep the in-memory site rule registry in sync with the collection.
+	reloadSiteRules := func(e *core.RecordEvent) error {
+		if err := e.Next(); err != nil {
+			return err
+		}
+		if err := engine.LoadSiteRules(e.App); err != nil {
+			log.Printf("Warning: could not reload site rules: %v", err)
+		}
+		return nil
+	}
+	app.OnRecordAfterCreateSuccess("site_rules").BindFunc(reloadSiteRules)
+	app.OnRecordAfterUpdateSuccess("site_rules").BindFunc(reloadSiteRules)
+	app.OnRecordAfterDeleteSuccess("site_rules").BindFunc(reloadSiteRules)
+
+	// Record when an archived entry loses its star or bookmark, which starts
+	// its snapshot's retention period.
+	app.OnRecordUpdate("entries").BindFunc(func(e *core.RecordEvent) error {
+		engine.TrackArchiveRetention(e.Record, time.Now())
+		return e.Next()
+	})
+
+	// Snapshot entries when they get starred or bookmarked (if archiving is enabled).
+	app.OnRecordAfterUpdateSuccess("entries").BindFunc(func(e *core.RecordEvent) error {
+		if e.Record.GetString("archive") == "" && engine.IsArchivable(e.Record) && engine.ArchivingEnabled(e.App) {
+			go archiveEntry(e.App, e.Record.Id)
+		}
+		return e.Next()
+	})
+
+	// When the preference profile changes, score recent unread entries again.
+	app.OnRecordAfterCreateSuccess("preferences").BindFunc(func(e *core.RecordEvent) error {
+		go func() {
+			if job, err := engine.QueueProfileRescore(e.App); err != nil {
+				log.Printf("Warning: could not queue rescoring: %v", err)
+			} else if job != nil {
+				log.Printf("Queued rescoring of %d entries after profile change", job.GetInt("total"))
+			}
+		}()
+		return e.Next()
+	})
+
 	// On resource delete, cascade delete associated entries.
 	app.OnRecordDelete("resources").BindFunc(func(e *core.RecordEvent) error {
 		deleteAllResourceEntries(e.App, e.Record.Id)
@@ -33,6 +77,36 @@ func registerHooks(app *pocketbase.PocketBase) {
 	})
 }
 
+var (
+	archivingMu sync.Mutex
+	archiving   = map[string]bool{}
+)
+
+// archiveEntry snapshots an entry unless a snapshot of it is already being
+// taken; every update of a starred entry fires the hook until it is done.
+func archiveEntry(app core.App, entryID string) {
+	archivingMu.Lock()
+	if archiving[entryID] {
+		archivingMu.Unlock()
+		return
+	}
+	archiving[entryID] = true
+	archivingMu.Unlock()
+	defer func() {
+		archivingMu.Lock()
+		delete(archiving, entryID)
+		archivingMu.Unlock()
+	}()
+
+	entry, err := app.FindRecordById("entries", entryID)
+	if err != nil {
+		return
+	}
+	if _, err := engine.ArchiveEntry(app, entry, engine.DefaultHTTPClient); err != nil {
+		log.Printf("Warning: could not archive entry %s: %v", entryID, err)
+	}
+}
+
 func fragmentConfigChanged(oldRecord, newRecord *core.Record) bool {
 	return oldRecord.GetBool("fragment_feed") != newRecord.GetBool("fragment_feed") ||
 		oldRecord.GetString("fragment_mode") != newRecord.GetString("fragment_mode") ||
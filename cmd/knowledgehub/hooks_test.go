@@ -3,9 +3,12 @@ package main
 import (
 	"os"
 	"testing"
+	"time"
 
+	"github.com/jgordijn/knowledgehub/internal/engine"
 	"github.com/jgordijn/knowledgehub/internal/testutil"
 	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
 )
 
 func newHooksTestApp(t *testing.T) (*pocketbase.PocketBase, func()) {
@@ -74,3 +77,72 @@ func TestRegisterHooks_ClearsFragmentStateOnFragmentConfigChange(t *testing.T) {
 		t.Fatalf("expected normal entry to be preserved: %v", err)
 	}
 }
+
+func TestRegisterHooks_TracksArchiveRetention(t *testing.T) {
+	app, cleanup := newHooksTestApp(t)
+	defer cleanup()
+
+	col, err := app.FindCollectionByNameOrId("archives")
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := core.NewRecord(col)
+	archive.Set("hash", "abc")
+	if err := app.Save(archive); err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+	resource := testutil.CreateResource(t, app, "Blog", "https://example.com/feed.xml", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntryWithStars(t, app, resource.Id, "Post", "https://example.com/a", 3, 5)
+	entry.Set("archive", archive.Id)
+	if err := app.Save(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	entry.Set("user_stars", 0)
+	if err := app.Save(entry); err != nil {
+		t.Fatal(err)
+	}
+	saved, _ := app.FindRecordById("entries", entry.Id)
+	if saved.GetDateTime("unarchivable_since").IsZero() {
+		t.Fatal("expected unstarring an archived entry to start its retention period")
+	}
+
+	saved.Set("bookmarked", true)
+	if err := app.Save(saved); err != nil {
+		t.Fatal(err)
+	}
+	saved, _ = app.FindRecordById("entries", entry.Id)
+	if !saved.GetDateTime("unarchivable_since").IsZero() {
+		t.Error("expected bookmarking to stop the retention period")
+	}
+}
+
+func TestRegisterHooks_QueuesRescoreOnProfileChange(t *testing.T) {
+	app, cleanup := newHooksTestApp(t)
+	defer cleanup()
+
+	resource := testutil.CreateResource(t, app, "Blog", "https://example.com/feed.xml", "rss", "healthy", 0, true)
+	testutil.CreateEntryWithStars(t, app, resource.Id, "Post", "https://example.com/a", 3, 0)
+
+	collection, err := app.FindCollectionByNameOrId("preferences")
+	if err != nil {
+		t.Fatalf("failed to find preferences collection: %v", err)
+	}
+	profile := core.NewRecord(collection)
+	profile.Set("profile_text", "Likes Go.")
+	if err := app.Save(profile); err != nil {
+		t.Fatalf("failed to save profile: %v", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		jobs, _ := app.FindRecordsByFilter("rescore_jobs", "status = 'done' || status = 'failed'", "", 0, 0)
+		if len(jobs) == 1 {
+			if jobs[0].GetString("trigger") != engine.RescoreTriggerProfile || jobs[0].GetInt("total") != 1 {
+				t.Fatalf("unexpected job: trigger=%s total=%d", jobs[0].GetString("trigger"), jobs[0].GetInt("total"))
+			}
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("expected a rescore job after the profile changed")
+}
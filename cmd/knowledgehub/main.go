@@ -38,13 +38,27 @@ func main() {
 	// Register collections on first run
 	app.OnServe().BindFunc(func(se *core.ServeEvent) error {
 		registerCollections(se.App)
+		loadSiteRules(se.App)
 
 		// Register custom routes
 		routes.RegisterChatRoute(se)
+		routes.RegisterChatSessionRoutes(se)
 		routes.RegisterTriggerRoutes(se)
 		routes.RegisterLinkSummaryRoute(se)
 		routes.RegisterQuickAddRoutes(se)
 		routes.RegisterDailyNewsRoutes(se)
+		routes.RegisterArchiveRoutes(se)
+		routes.RegisterUploadRoutes(se)
+		routes.RegisterImportRoutes(se)
+		routes.RegisterTagRoutes(se)
+		routes.RegisterScoringRoutes(se)
+		routes.RegisterRelevanceRoutes(se)
+		routes.RegisterPreferenceRoutes(se)
+		routes.RegisterInteractionRoutes(se)
+		routes.RegisterRescoreRoutes(se)
+		routes.RegisterOnboardingRoutes(se)
+		routes.RegisterPromptRoutes(se)
+		routes.RegisterLanguageRoutes(se)
 		registerSetupRoutes(se)
 
 		// Health check endpoint
@@ -71,14 +85,52 @@ func main() {
 	// Register hooks
 	registerHooks(app)
 
+	// Offline prompt evaluation: knowledgehub eval
+	app.RootCmd.AddCommand(newEvalCommand(app))
+
 	// Start the scheduler
 	app.OnServe().BindFunc(func(se *core.ServeEvent) error {
 		scheduler := engine.NewScheduler(se.App)
 		go scheduler.Start()
+		if n := engine.ResumeImportJobs(se.App, engine.DefaultHTTPClient); n > 0 {
+			log.Printf("Resumed %d import jobs", n)
+		}
+		if n := engine.ResumeRescoreJobs(se.App); n > 0 {
+			log.Printf("Resumed %d rescore jobs", n)
+		}
 		return se.Next()
 	})
 
+	// Start the newsletter SMTP listener when configured
+	if smtpAddr := os.Getenv("KH_SMTP_ADDR"); smtpAddr != "" {
+		app.OnServe().BindFunc(func(se *core.ServeEvent) error {
+			smtpServer := engine.NewSMTPServer(se.App)
+			go func() {
+				if err := smtpServer.ListenAndServe(smtpAddr); err != nil {
+					log.Printf("SMTP: listener stopped: %v", err)
+				}
+			}()
+			return se.Next()
+		})
+	}
+
 	if err := app.Start(); err != nil {
 		log.Fatal(err)
 	}
 }
+
+// loadSiteRules imports rule files from KH_SITE_RULES_DIR (when set) into the
+// site_rules collection and loads the rules used for content extraction.
+func loadSiteRules(app core.App) {
+	if dir := os.Getenv("KH_SITE_RULES_DIR"); dir != "" {
+		count, err := engine.ImportSiteRules(app, dir)
+		if err != nil {
+			log.Printf("Failed to import site rules from %s: %v", dir, err)
+		} else {
+			log.Printf("Imported %d site rules from %s", count, dir)
+		}
+	}
+	if err := engine.LoadSiteRules(app); err != nil {
+		log.Printf("Failed to load site rules: %v", err)
+	}
+}
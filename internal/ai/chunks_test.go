@@ -0,0 +1,151 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+)
+
+func TestTruncateUTF8(t *testing.T) {
+	if got := TruncateUTF8("short", 10); got != "short" {
+		t.Errorf("expected short input unchanged, got %q", got)
+	}
+	got := TruncateUTF8("aéé", 2) // é is two bytes; byte 2 is inside the first é
+	if got != "a..." || !utf8.ValidString(got) {
+		t.Errorf("expected cut before the split rune, got %q", got)
+	}
+	if got := TruncateUTF8(strings.Repeat("€", 3000), 8000); !utf8.ValidString(got) {
+		t.Error("expected valid UTF-8 after truncation")
+	}
+}
+
+func TestChunkMarkdown(t *testing.T) {
+	md := "Intro paragraph.\n\n## Methods\n\n" + strings.Repeat("Method detail. ", 100) +
+		"\n\n## Results\n\n" + strings.Repeat("Result détail. ", 100)
+
+	chunks := ChunkMarkdown(md, 100) // about 400 characters per chunk
+	if len(chunks) < 4 {
+		t.Fatalf("expected the long sections to be split, got %d chunks", len(chunks))
+	}
+	if chunks[0].Heading != "" || !strings.HasPrefix(chunks[0].Text, "Intro paragraph.\n\n## Methods") {
+		t.Errorf("expected the short intro to share a chunk with the next heading, got %+v", chunks[0])
+	}
+	var sawResults bool
+	for i, c := range chunks {
+		if c.Index != i || len(c.Text) > 400 || !utf8.ValidString(c.Text) {
+			t.Errorf("chunk %d invalid: index %d, %d bytes", i, c.Index, len(c.Text))
+		}
+		if strings.HasPrefix(c.Text, "## Results") {
+			sawResults = true
+			if c.Heading != "Results" || c.Label() != fmt.Sprintf("Part %d: Results", i+1) {
+				t.Errorf("expected the Results heading to start a labelled chunk, got %+v", c)
+			}
+		}
+	}
+	if !sawResults {
+		t.Error("expected a chunk to start at the Results heading")
+	}
+	if last := chunks[len(chunks)-1]; last.Heading != "Results" {
+		t.Errorf("expected continuation chunks to keep their section heading, got %q", last.Heading)
+	}
+}
+
+func TestMarkdownHeading(t *testing.T) {
+	tests := map[string]string{"# Title": "Title", "### Deep ###": "Deep", "#hashtag": "", "Plain": "", "####### Seven": ""}
+	for in, want := range tests {
+		got, ok := markdownHeading(in)
+		if got != want || ok != (want != "") {
+			t.Errorf("markdownHeading(%q) = %q, %v", in, got, ok)
+		}
+	}
+}
+
+func TestSelectChunkContext(t *testing.T) {
+	short := "A short article."
+	if got := SelectChunkContext(short, "anything", 8000); got != short {
+		t.Errorf("expected short content unchanged, got %q", got)
+	}
+
+	var sections []string
+	for i := 0; i < 8; i++ {
+		sections = append(sections, fmt.Sprintf("## Part %d\n\n%s", i, strings.Repeat("filler words here. ", 60)))
+	}
+	sections[5] = "## Pricing\n\nThe subscription costs twelve euros monthly."
+	got := SelectChunkContext(strings.Join(sections, "\n\n"), "How much does the subscription cost?", 2000)
+	if !strings.Contains(got, "Pricing]\n## Pricing\n\nThe subscription costs") || len(got) > 2000 {
+		t.Errorf("expected the relevant section within budget, got %d bytes:\n%.300s", len(got), got)
+	}
+}
+
+func TestSummarizeAndScore_LongArticleMapReduce(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Long essay", "https://example.com/essay", "essay")
+	var sections []string
+	for i := 1; i <= 4; i++ {
+		sections = append(sections, fmt.Sprintf("## Chapter %d\n\n%s", i, strings.Repeat(fmt.Sprintf("Chapter %d argument. ", i), 400)))
+	}
+	entry.Set("raw_content", strings.Join(sections, "\n\n"))
+
+	var parts int
+	var final string
+	restore := SetCompleteFunc(func(_, _ string, msgs []Message) (string, error) {
+		prompt := msgs[len(msgs)-1].Content
+		if strings.HasPrefix(prompt, "Summarize part ") {
+			parts++
+			return fmt.Sprintf("Gist of part %d.", parts), nil
+		}
+		final = prompt
+		return `{"summary":"Whole essay.","stars":4,"takeaways":["One","Two"]}`, nil
+	})
+	defer restore()
+
+	if err := SummarizeAndScore(app, entry); err != nil {
+		t.Fatalf("SummarizeAndScore: %v", err)
+	}
+	if parts < 4 {
+		t.Errorf("expected every chapter to be summarized, got %d part calls", parts)
+	}
+	if !strings.Contains(final, "summarized in") || !strings.Contains(final, "[Part 1: Chapter 1]\nGist of part 1.") ||
+		!strings.Contains(final, fmt.Sprintf("Gist of part %d.", parts)) {
+		t.Errorf("expected the reduce prompt to hold every part summary:\n%.1500s", final)
+	}
+	if entry.GetString("summary") != "Whole essay." {
+		t.Errorf("summary = %q", entry.GetString("summary"))
+	}
+}
+
+func TestSummarizeAndScore_LongArticleMapFailure(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Long essay", "https://example.com/essay", "essay")
+	entry.Set("raw_content", strings.Repeat("A long paragraph of text. ", 2000))
+
+	restore := SetCompleteFunc(func(_, _ string, _ []Message) (string, error) {
+		return "", errors.New("rate limited")
+	})
+	defer restore()
+
+	if err := SummarizeAndScore(app, entry); err == nil || !strings.Contains(err.Error(), "rate limited") {
+		t.Errorf("expected the map failure to be returned, got %v", err)
+	}
+}
+
+func TestChunkForSummary_CapsChunks(t *testing.T) {
+	if chunks, _ := chunkForSummary("Short."); chunks != nil {
+		t.Error("expected no chunking for short content")
+	}
+	huge := strings.Repeat("Paragraph of filler text that goes on.\n\n", 8000)
+	chunks, truncated := chunkForSummary(huge)
+	if len(chunks) != MaxSummaryChunks || !truncated {
+		t.Errorf("expected %d chunks and truncation, got %d %v", MaxSummaryChunks, len(chunks), truncated)
+	}
+}
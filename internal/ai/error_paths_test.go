@@ -18,13 +18,7 @@ func TestCheckAndRegeneratePreferences_DBError(t *testing.T) {
 	testutil.CreateSetting(t, app, "openrouter_model", "test-model")
 
 	// Delete entries collection to make countCorrectionsSinceLastProfile fail
-	col, err := app.FindCollectionByNameOrId("entries")
-	if err != nil {
-		t.Fatalf("finding entries collection: %v", err)
-	}
-	if err := app.Delete(col); err != nil {
-		t.Fatalf("deleting entries collection: %v", err)
-	}
+	testutil.DeleteEntriesCollection(t, app)
 
 	// Should not panic, should log and return
 	CheckAndRegeneratePreferences(app)
@@ -65,15 +59,9 @@ func TestCountCorrectionsSinceLastProfile_EntriesError(t *testing.T) {
 	testutil.CreatePreference(t, app, "test profile", "2024-01-01T00:00:00Z")
 
 	// Delete entries collection
-	entriesCol, err := app.FindCollectionByNameOrId("entries")
-	if err != nil {
-		t.Fatalf("finding entries collection: %v", err)
-	}
-	if err := app.Delete(entriesCol); err != nil {
-		t.Fatalf("deleting entries collection: %v", err)
-	}
+	testutil.DeleteEntriesCollection(t, app)
 
-	_, err = countCorrectionsSinceLastProfile(app)
+	_, err := countCorrectionsSinceLastProfile(app)
 	if err == nil {
 		t.Error("expected error when entries collection is missing")
 	}
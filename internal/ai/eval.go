@@ -0,0 +1,214 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// DefaultEvalLimit is how many labeled entries an evaluation replays when no
+// limit is given.
+const DefaultEvalLimit = 50
+
+// DefaultEvalBatchSize is how many entries a score_only_batch evaluation
+// scores per call when no batch size is given.
+const DefaultEvalBatchSize = 8
+
+// EvalOptions selects what an offline prompt evaluation replays.
+type EvalOptions struct {
+	// Prompt is summary, score_only or score_only_batch; empty means
+	// score_only.
+	Prompt string
+	// Version 0 evaluates the active version.
+	Version int
+	// Model empty uses the configured model.
+	Model string
+	Limit int
+	// BatchSize is how many entries share a score_only_batch call; 0 means
+	// DefaultEvalBatchSize.
+	BatchSize int
+	// Prices in dollars per million tokens, used for the cost estimate.
+	InputPricePerMillion  float64
+	OutputPricePerMillion float64
+}
+
+// EvalItem is the outcome for one labeled entry.
+type EvalItem struct {
+	EntryID   string `json:"entry_id"`
+	Title     string `json:"title"`
+	UserStars int    `json:"user_stars"`
+	Stars     int    `json:"stars,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// EvalReport summarizes how a prompt version and model agree with the
+// user's own ratings.
+type EvalReport struct {
+	Prompt  string `json:"prompt"`
+	Version int    `json:"version"`
+	Model   string `json:"model"`
+	Entries int    `json:"entries"`
+	Scored  int    `json:"scored"`
+	Failed  int    `json:"failed"`
+	// MAE is the mean absolute star difference; Agreement the share of exact
+	// matches and WithinOne the share at most one star off.
+	MAE              float64    `json:"mae"`
+	Agreement        float64    `json:"agreement"`
+	WithinOne        float64    `json:"within_one"`
+	PromptTokens     int        `json:"prompt_tokens"`
+	CompletionTokens int        `json:"completion_tokens"`
+	EstimatedCost    float64    `json:"estimated_cost"`
+	Items            []EvalItem `json:"items"`
+}
+
+// ErrNoLabeledEntries is returned when no entry has a user rating.
+var ErrNoLabeledEntries = errors.New("no entries with user_stars to evaluate")
+
+// EvaluatePrompt replays the most recent user-rated entries against a prompt
+// version and model, and compares the stars with the user's. Entries are not
+// changed. Rating corrections are left out of the prompt, since they are
+// built from the same labels being evaluated.
+func EvaluatePrompt(app core.App, opts EvalOptions) (EvalReport, error) {
+	if opts.Prompt == "" {
+		opts.Prompt = PromptScoreOnly
+	}
+	if opts.Prompt != PromptSummary && opts.Prompt != PromptScoreOnly && opts.Prompt != PromptScoreOnlyBatch {
+		return EvalReport{}, fmt.Errorf("prompt %q cannot be evaluated; use %s, %s or %s",
+			opts.Prompt, PromptSummary, PromptScoreOnly, PromptScoreOnlyBatch)
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = DefaultEvalLimit
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultEvalBatchSize
+	}
+	if opts.BatchSize > MaxScoreBatchSize {
+		opts.BatchSize = MaxScoreBatchSize
+	}
+	tmpl, err := LoadPrompt(app, opts.Prompt, opts.Version)
+	if err != nil {
+		return EvalReport{}, fmt.Errorf("prompt %s version %d: %w", opts.Prompt, opts.Version, err)
+	}
+	if _, err := tmpl.Render(promptSampleData[opts.Prompt]); err != nil {
+		return EvalReport{}, err
+	}
+	apiKey, err := GetAPIKey(app)
+	if err != nil {
+		return EvalReport{}, fmt.Errorf("no API key configured: %w", err)
+	}
+	if opts.Model == "" {
+		opts.Model = GetModel(app)
+	}
+
+	entries, err := app.FindRecordsByFilter("entries", "user_stars > 0 && processing_status = 'done'", "-created", opts.Limit, 0)
+	if err != nil {
+		return EvalReport{}, err
+	}
+	if len(entries) == 0 {
+		return EvalReport{}, ErrNoLabeledEntries
+	}
+
+	report := EvalReport{Prompt: tmpl.Name, Version: tmpl.Version, Model: opts.Model, Entries: len(entries), Items: []EvalItem{}}
+	profile := loadPreferenceProfile(app)
+	taxonomy := LoadTagTaxonomy(app)
+	results := make([]SummaryResult, len(entries))
+	errs := make([]error, len(entries))
+	if tmpl.Name == PromptScoreOnlyBatch {
+		for start := 0; start < len(entries); start += opts.BatchSize {
+			end := min(start+opts.BatchSize, len(entries))
+			chunk := entries[start:end]
+			prompt := renderScoreBatchPrompt(tmpl, chunk, profile, "") +
+				tagPromptInstruction(taxonomy.Names()) + scoreReasonInstruction(profile, "") + dimensionScoresInstruction()
+			report.PromptTokens += estimateTokens(scoreBatchSystemPrompt) + estimateTokens(prompt)
+
+			batch, response, err := requestScoreBatch(apiKey, opts.Model, prompt)
+			report.CompletionTokens += estimateTokens(response)
+			for i, entry := range chunk {
+				if err != nil {
+					errs[start+i] = err
+				} else if result, ok := batch[entry.Id]; ok {
+					results[start+i] = result
+				} else {
+					errs[start+i] = errors.New("missing from the batch response")
+				}
+			}
+		}
+	} else {
+		for i, entry := range entries {
+			title := entry.GetString("title")
+			content := entryArticleContent(entry)
+			if content == "" {
+				content = title
+			}
+			var prompt, system string
+			if tmpl.Name == PromptSummary {
+				prompt = renderSummaryPrompt(tmpl, title, content, profile, "")
+				system = "You are a helpful assistant that summarizes articles and rates their relevance. Always respond with valid JSON."
+			} else {
+				prompt = renderScoreOnlyPrompt(tmpl, title, content, profile, "")
+				system = "You are a helpful assistant that rates article relevance. Always respond with valid JSON."
+			}
+			prompt += tagPromptInstruction(taxonomy.Names()) + scoreReasonInstruction(profile, "") + dimensionScoresInstruction()
+			report.PromptTokens += estimateTokens(system) + estimateTokens(prompt)
+
+			response, err := callComplete(apiKey, opts.Model, []Message{
+				{Role: "system", Content: system},
+				{Role: "user", Content: prompt},
+			})
+			if err == nil {
+				report.CompletionTokens += estimateTokens(response)
+				results[i], err = parseSummaryResult(response)
+			}
+			errs[i] = err
+		}
+	}
+
+	absErr, agree, withinOne := 0, 0, 0
+	for i, entry := range entries {
+		item := EvalItem{EntryID: entry.Id, Title: entry.GetString("title"), UserStars: entry.GetInt("user_stars")}
+		if errs[i] != nil {
+			item.Error = errs[i].Error()
+			report.Failed++
+		} else {
+			// Combine dimension scores the way processing does, on a copy so
+			// the entry keeps its stored scores.
+			scored := entry.Clone()
+			applyDimensionScores(app, scored, results[i])
+			item.Stars = scored.GetInt("ai_stars")
+
+			report.Scored++
+			diff := int(math.Abs(float64(item.Stars - item.UserStars)))
+			absErr += diff
+			if diff == 0 {
+				agree++
+			}
+			if diff <= 1 {
+				withinOne++
+			}
+		}
+		report.Items = append(report.Items, item)
+	}
+
+	if report.Scored > 0 {
+		n := float64(report.Scored)
+		report.MAE = roundTo(float64(absErr)/n, 3)
+		report.Agreement = roundTo(float64(agree)/n, 3)
+		report.WithinOne = roundTo(float64(withinOne)/n, 3)
+	}
+	report.EstimatedCost = roundTo(
+		float64(report.PromptTokens)*opts.InputPricePerMillion/1e6+float64(report.CompletionTokens)*opts.OutputPricePerMillion/1e6, 6)
+	return report, nil
+}
+
+// estimateTokens approximates a token count at about four characters per
+// token, like EstimateScoreTokens.
+func estimateTokens(s string) int {
+	return (len(s) + charsPerToken - 1) / charsPerToken
+}
+
+func roundTo(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
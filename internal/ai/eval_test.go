@@ -0,0 +1,136 @@
+package ai
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+)
+
+func TestEvaluatePrompt(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	// AI stars equal user stars so no preference regeneration is triggered.
+	testutil.CreateEntryWithStars(t, app, res.Id, "Exact", "https://example.com/exact", 4, 4)
+	testutil.CreateEntryWithStars(t, app, res.Id, "Close", "https://example.com/close", 3, 3)
+	testutil.CreateEntryWithStars(t, app, res.Id, "Broken", "https://example.com/broken", 2, 2)
+	testutil.CreateEntry(t, app, res.Id, "Unrated", "https://example.com/unrated", "unrated")
+
+	var models []string
+	restore := SetCompleteFunc(func(_, model string, msgs []Message) (string, error) {
+		models = append(models, model)
+		prompt := msgs[len(msgs)-1].Content
+		switch {
+		case strings.Contains(prompt, "Exact"):
+			return `{"summary":"","stars":4}`, nil
+		case strings.Contains(prompt, "Close"):
+			return `{"summary":"","stars":4}`, nil
+		default:
+			return "not json", nil
+		}
+	})
+	defer restore()
+
+	report, err := EvaluatePrompt(app, EvalOptions{Model: "test/model", InputPricePerMillion: 1, OutputPricePerMillion: 2})
+	if err != nil {
+		t.Fatalf("EvaluatePrompt: %v", err)
+	}
+	if report.Prompt != PromptScoreOnly || report.Version != BuiltinPromptVersion || report.Model != "test/model" {
+		t.Errorf("unexpected report header: %+v", report)
+	}
+	if report.Entries != 3 || report.Scored != 2 || report.Failed != 1 {
+		t.Errorf("expected 3 entries, 2 scored and 1 failed, got %+v", report)
+	}
+	if report.MAE != 0.5 || report.Agreement != 0.5 || report.WithinOne != 1 {
+		t.Errorf("unexpected metrics: MAE %v agreement %v within one %v", report.MAE, report.Agreement, report.WithinOne)
+	}
+	if report.PromptTokens == 0 || report.CompletionTokens == 0 || report.EstimatedCost <= 0 {
+		t.Errorf("expected token and cost estimates, got %+v", report)
+	}
+	if len(models) != 3 || models[0] != "test/model" {
+		t.Errorf("expected every call to use the chosen model, got %v", models)
+	}
+
+	// The evaluation must not touch the entries.
+	entries, _ := app.FindRecordsByFilter("entries", "title = 'Close'", "", 1, 0)
+	if len(entries) != 1 || entries[0].GetInt("ai_stars") != 3 {
+		t.Errorf("expected stored ai_stars to stay 3")
+	}
+}
+
+func TestEvaluatePrompt_CustomVersion(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	testutil.CreateEntryWithStars(t, app, res.Id, "Post", "https://example.com/post", 3, 3)
+	custom, err := SavePromptVersion(app, PromptScoreOnly, "EXPERIMENT {{.Title}}", "")
+	if err != nil {
+		t.Fatalf("SavePromptVersion: %v", err)
+	}
+
+	var prompt string
+	restore := SetCompleteFunc(func(_, _ string, msgs []Message) (string, error) {
+		prompt = msgs[len(msgs)-1].Content
+		return `{"summary":"","stars":3}`, nil
+	})
+	defer restore()
+
+	report, err := EvaluatePrompt(app, EvalOptions{Version: custom.Version})
+	if err != nil {
+		t.Fatalf("EvaluatePrompt: %v", err)
+	}
+	if !strings.HasPrefix(prompt, "EXPERIMENT Post") || report.Version != custom.Version || report.Agreement != 1 {
+		t.Errorf("expected the custom version to be evaluated, got v%d, prompt:\n%s", report.Version, prompt)
+	}
+}
+
+func TestEvaluatePrompt_BatchPrompt(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	exact := testutil.CreateEntryWithStars(t, app, res.Id, "Exact", "https://example.com/exact", 4, 4)
+	off := testutil.CreateEntryWithStars(t, app, res.Id, "Off", "https://example.com/off", 2, 2)
+	testutil.CreateEntryWithStars(t, app, res.Id, "Missing", "https://example.com/missing", 3, 3)
+
+	calls := 0
+	restore := SetCompleteFunc(func(_, _ string, _ []Message) (string, error) {
+		calls++
+		return `{"results": [{"id": "` + exact.Id + `", "stars": 4}, {"id": "` + off.Id + `", "stars": 5}]}`, nil
+	})
+	defer restore()
+
+	report, err := EvaluatePrompt(app, EvalOptions{Prompt: PromptScoreOnlyBatch, BatchSize: 5})
+	if err != nil {
+		t.Fatalf("EvaluatePrompt: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected the entries to share one call, got %d", calls)
+	}
+	if report.Prompt != PromptScoreOnlyBatch || report.Scored != 2 || report.Failed != 1 || report.Agreement != 0.5 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if report.PromptTokens == 0 || report.CompletionTokens == 0 {
+		t.Errorf("expected token estimates, got %+v", report)
+	}
+}
+
+func TestEvaluatePrompt_Errors(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+
+	if _, err := EvaluatePrompt(app, EvalOptions{Prompt: PromptDailyNews}); err == nil {
+		t.Error("expected daily_news to be rejected")
+	}
+	if _, err := EvaluatePrompt(app, EvalOptions{Version: 7}); !errors.Is(err, ErrPromptNotFound) {
+		t.Errorf("expected ErrPromptNotFound, got %v", err)
+	}
+	if _, err := EvaluatePrompt(app, EvalOptions{}); !errors.Is(err, ErrNoLabeledEntries) {
+		t.Errorf("expected ErrNoLabeledEntries, got %v", err)
+	}
+}
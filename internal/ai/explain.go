@@ -0,0 +1,52 @@
+package ai
+
+import (
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Limits on the stored explanation of a rating.
+const (
+	maxScoreReasonLength = 500
+	maxInfluences        = 5
+	maxInfluenceLength   = 200
+)
+
+// scoreReasonInstruction asks the model to explain its rating and, when the
+// prompt carries a profile or corrections, to name the parts that mattered.
+func scoreReasonInstruction(profile, corrections string) string {
+	var sb strings.Builder
+	sb.WriteString("\n\nAlso include \"score_reason\": one short sentence explaining why you chose this star rating.")
+	if profile != "" || corrections != "" {
+		sb.WriteString(" Include \"influences\": an array of up to 5 short quotes or paraphrases of the parts of the user's interest profile or recent rating corrections that influenced the rating (an empty array if none applied).")
+	}
+	return sb.String()
+}
+
+// applyScoreExplanation stores the model's reasoning for a rating on the
+// entry, replacing any explanation of an earlier rating.
+func applyScoreExplanation(entry *core.Record, result SummaryResult) {
+	entry.Set("score_reason", TruncateUTF8(strings.TrimSpace(result.ScoreReason), maxScoreReasonLength-3))
+
+	var influences []string
+	for _, influence := range result.Influences {
+		if influence = strings.TrimSpace(influence); influence == "" {
+			continue
+		}
+		influences = append(influences, TruncateUTF8(influence, maxInfluenceLength-3))
+		if len(influences) == maxInfluences {
+			break
+		}
+	}
+	if influences == nil {
+		influences = []string{}
+	}
+	entry.Set("score_influences", influences)
+}
+
+// EntryScoreInfluences returns the profile parts and corrections the AI said
+// influenced an entry's rating.
+func EntryScoreInfluences(entry *core.Record) []string {
+	return jsonStrings(entry, "score_influences")
+}
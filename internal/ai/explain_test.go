@@ -0,0 +1,120 @@
+package ai
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestScoreReasonInstruction(t *testing.T) {
+	plain := scoreReasonInstruction("", "")
+	if !strings.Contains(plain, `"score_reason"`) || strings.Contains(plain, `"influences"`) {
+		t.Errorf("without profile or corrections only a reason is asked for: %q", plain)
+	}
+	if got := scoreReasonInstruction("Likes Go", ""); !strings.Contains(got, `"influences"`) {
+		t.Errorf("expected influences with a profile: %q", got)
+	}
+	if got := scoreReasonInstruction("", "- \"x\": AI rated 2, user rated 5"); !strings.Contains(got, `"influences"`) {
+		t.Errorf("expected influences with corrections: %q", got)
+	}
+}
+
+func TestSummarizeAndScore_StoresScoreExplanation(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	testutil.CreatePreference(t, app, "Values deep dives into distributed systems. Dislikes product announcements.", "2026-01-01T00:00:00Z")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Launch week recap", "https://example.com/launch", "launch")
+
+	var prompt string
+	restore := SetCompleteFunc(func(_, _ string, msgs []Message) (string, error) {
+		prompt = msgs[len(msgs)-1].Content
+		return `{"summary":"Product launches.","stars":2,
+			"score_reason":"Mostly product announcements, which the profile says the user dislikes.",
+			"influences":["Dislikes product announcements", "  ", "Values deep dives"]}`, nil
+	})
+	defer restore()
+
+	if err := SummarizeAndScore(app, entry); err != nil {
+		t.Fatalf("SummarizeAndScore: %v", err)
+	}
+	if !strings.Contains(prompt, `"score_reason"`) || !strings.Contains(prompt, `"influences"`) {
+		t.Errorf("expected explanation instructions in prompt:\n%s", prompt)
+	}
+
+	saved, _ := app.FindRecordById("entries", entry.Id)
+	if got := saved.GetString("score_reason"); got != "Mostly product announcements, which the profile says the user dislikes." {
+		t.Errorf("score_reason = %q", got)
+	}
+	if got := EntryScoreInfluences(saved); !reflect.DeepEqual(got, []string{"Dislikes product announcements", "Values deep dives"}) {
+		t.Errorf("influences = %v", got)
+	}
+}
+
+func TestScoreOnly_ReplacesOldExplanation(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	res := testutil.CreateResource(t, app, "Links", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Note", "https://example.com/note", "note")
+	entry.Set("score_reason", "Old reason")
+	entry.Set("score_influences", []string{"old"})
+	app.Save(entry)
+
+	restore := SetCompleteFunc(func(_, _ string, _ []Message) (string, error) {
+		return `{"summary":"","stars":3}`, nil
+	})
+	defer restore()
+
+	if err := ScoreOnly(app, entry); err != nil {
+		t.Fatalf("ScoreOnly: %v", err)
+	}
+	saved, _ := app.FindRecordById("entries", entry.Id)
+	if saved.GetString("score_reason") != "" || len(EntryScoreInfluences(saved)) != 0 {
+		t.Errorf("expected old explanation cleared, got %q %v", saved.GetString("score_reason"), EntryScoreInfluences(saved))
+	}
+}
+
+func TestApplyScoreExplanation_Limits(t *testing.T) {
+	col := core.NewBaseCollection("entries")
+	col.Fields.Add(&core.TextField{Name: "score_reason"})
+	col.Fields.Add(&core.JSONField{Name: "score_influences"})
+	entry := core.NewRecord(col)
+
+	applyScoreExplanation(entry, SummaryResult{
+		ScoreReason: strings.Repeat("é", 600),
+		Influences:  []string{"1", "2", "3", "4", "5", "6", strings.Repeat("x", 300)},
+	})
+
+	reason := entry.GetString("score_reason")
+	if n := len(reason); n > maxScoreReasonLength || !utf8.ValidString(reason) || !strings.HasSuffix(reason, "...") {
+		t.Errorf("expected reason truncated to %d bytes, got %d", maxScoreReasonLength, n)
+	}
+	if got := EntryScoreInfluences(entry); !reflect.DeepEqual(got, []string{"1", "2", "3", "4", "5"}) {
+		t.Errorf("influences = %v", got)
+	}
+}
+
+func TestBuildPreferencePrompt_IncludesScoreReasons(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	explained := testutil.CreateEntryWithStars(t, app, res.Id, "Raft in practice", "https://example.com/raft", 2, 5)
+	explained.Set("score_reason", "Too academic for the profile.")
+	explained.Set("score_influences", []string{"Prefers practical guides"})
+	app.Save(explained)
+	plain := testutil.CreateEntryWithStars(t, app, res.Id, "Plain", "https://example.com/plain", 4, 1)
+
+	prompt := buildPreferencePrompt([]*core.Record{explained, plain}, nil)
+	if !strings.Contains(prompt, `AI=2, User=5; AI reason: Too academic for the profile.; AI relied on: Prefers practical guides`) {
+		t.Errorf("expected reason and influences for the explained correction:\n%s", prompt)
+	}
+	if !strings.Contains(prompt, "AI=4, User=1\n") {
+		t.Errorf("expected the unexplained correction unchanged:\n%s", prompt)
+	}
+}
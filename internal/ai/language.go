@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// SupportedLanguages maps the ISO 639-1 codes a summary can be translated
+// into to their English names, which are used in prompts.
+var SupportedLanguages = map[string]string{
+	"en": "English",
+	"nl": "Dutch",
+	"de": "German",
+	"fr": "French",
+	"es": "Spanish",
+	"it": "Italian",
+	"pt": "Portuguese",
+}
+
+// LanguageName returns the English name of a supported language code, or ""
+// for an unknown one.
+func LanguageName(code string) string {
+	return SupportedLanguages[code]
+}
+
+// languageStopwords are frequent function words that occur in one of the
+// detectable languages only; words shared between them, such as "de" (Dutch,
+// French and Spanish) or "als" (Dutch and German), are left out.
+var languageStopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "that", "it", "for", "with", "were", "are", "this", "has", "be", "you", "have", "not", "but", "they", "which", "from"},
+	"nl": {"het", "een", "van", "dat", "niet", "op", "hij", "met", "voor", "zijn", "maar", "ook", "geen", "heeft", "nog", "wordt", "bij", "naar", "deze", "wij"},
+	"de": {"der", "das", "und", "ist", "nicht", "ein", "eine", "zu", "mit", "auf", "den", "von", "sich", "auch", "ich", "für", "wird", "dem", "wir", "werden"},
+	"fr": {"le", "les", "et", "est", "une", "des", "dans", "pour", "pas", "qui", "sur", "avec", "aux", "au", "ce", "sont", "par", "mais", "nous", "cette"},
+	"es": {"el", "los", "las", "y", "del", "por", "para", "con", "su", "como", "pero", "más", "está", "lo", "sus", "muy", "también", "fue", "una", "han"},
+}
+
+var stopwordLanguage = func() map[string]string {
+	m := map[string]string{}
+	for lang, words := range languageStopwords {
+		for _, w := range words {
+			m[w] = lang
+		}
+	}
+	return m
+}()
+
+// languageSampleBytes bounds how much text detection reads.
+const languageSampleBytes = 5000
+
+// DetectLanguage guesses the ISO 639-1 code of a text (en, nl, de, fr or es)
+// from its function words. It returns "" when the text is too short or too
+// mixed to tell.
+func DetectLanguage(text string) string {
+	text = TruncateUTF8(text, languageSampleBytes)
+	counts := map[string]int{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		if lang, ok := stopwordLanguage[word]; ok {
+			counts[lang]++
+		}
+	}
+
+	best, bestCount, second := "", 0, 0
+	for lang, n := range counts {
+		switch {
+		case n > bestCount:
+			best, bestCount, second = lang, n, bestCount
+		case n > second:
+			second = n
+		}
+	}
+	if bestCount < 3 || bestCount*2 < second*3 {
+		return ""
+	}
+	return best
+}
+
+// DetectEntryLanguage detects the language of an entry's raw content,
+// falling back to its title.
+func DetectEntryLanguage(entry *core.Record) string {
+	text := HTMLToMarkdown(entry.GetString("raw_content"))
+	if strings.TrimSpace(text) == "" {
+		text = entry.GetString("title")
+	}
+	return DetectLanguage(text)
+}
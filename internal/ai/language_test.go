@@ -0,0 +1,47 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	cases := map[string]string{
+		"The new release of the compiler makes builds faster, and it is easier to read the output for the people that use it.":              "en",
+		"De nieuwe versie van de compiler maakt het bouwen sneller en het is ook makkelijker om de uitvoer te lezen voor wie er mee werkt.": "nl",
+		"Die neue Version des Compilers ist schneller und es wird auch einfacher, die Ausgabe zu lesen, weil sich das Format nicht ändert.": "de",
+		"La nouvelle version du compilateur est plus rapide et les messages sont plus clairs pour les développeurs qui travaillent avec.":   "fr",
+		"La nueva versión del compilador es más rápida y los mensajes son muy claros para los desarrolladores que trabajan con él.":         "es",
+		"El precio de la casa de mi padre es de dos millones de pesos y está en el centro de la ciudad.":                                    "es",
+		"Le prix de la maison de mon père est de deux millions de francs et elle est au centre de la ville.":                                "fr",
+		"Kubernetes":  "",
+		"":            "",
+		"12345 67890": "",
+	}
+	for text, want := range cases {
+		if got := DetectLanguage(text); got != want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestDetectEntryLanguage(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Het weer van deze week: nog geen zon", "https://example.com/weer", "weer")
+
+	entry.Set("raw_content", "<p>De nieuwe versie van het programma is er. Het is sneller dan de vorige en ook veel stabieler.</p>")
+	if got := DetectEntryLanguage(entry); got != "nl" {
+		t.Errorf("expected nl from the content, got %q", got)
+	}
+	entry.Set("raw_content", "")
+	if got := DetectEntryLanguage(entry); got != "nl" {
+		t.Errorf("expected nl from the title, got %q", got)
+	}
+	entry.Set("title", "Raft")
+	if got := DetectEntryLanguage(entry); got != "" {
+		t.Errorf("expected a one-word title to be undetectable, got %q", got)
+	}
+}
@@ -0,0 +1,105 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+)
+
+func TestOnboardingSamples_AlternatesResources(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	blog := testutil.CreateResource(t, app, "Blog", "https://blog.example.com", "rss", "healthy", 0, true)
+	news := testutil.CreateResource(t, app, "News", "https://news.example.com", "rss", "healthy", 0, true)
+	for i := 0; i < 4; i++ {
+		testutil.CreateEntry(t, app, blog.Id, fmt.Sprintf("Blog %d", i), fmt.Sprintf("https://blog.example.com/%d", i), fmt.Sprintf("b%d", i))
+	}
+	testutil.CreateEntry(t, app, news.Id, "News 0", "https://news.example.com/0", "n0")
+	testutil.CreateEntryWithStars(t, app, news.Id, "Rated", "https://news.example.com/rated", 3, 4)
+
+	samples, err := OnboardingSamples(app, 3)
+	if err != nil {
+		t.Fatalf("OnboardingSamples: %v", err)
+	}
+	resources := map[string]int{}
+	for _, s := range samples {
+		resources[s.GetString("resource")]++
+		if s.GetString("title") == "Rated" {
+			t.Error("expected rated entries to be left out")
+		}
+	}
+	if len(samples) != 3 || resources[news.Id] != 1 || resources[blog.Id] != 2 {
+		t.Errorf("expected samples from both resources, got %v", resources)
+	}
+}
+
+func TestCompleteOnboarding_ImportOnly(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	if !NeedsOnboarding(app) {
+		t.Fatal("expected onboarding on a fresh install")
+	}
+	if _, err := CompleteOnboarding(app, OnboardingInput{Interests: "  "}); !errors.Is(err, ErrEmptyOnboarding) {
+		t.Fatalf("expected ErrEmptyOnboarding, got %v", err)
+	}
+
+	restore := SetCompleteFunc(func(_, _ string, _ []Message) (string, error) {
+		t.Fatal("an imported profile alone should not call the LLM")
+		return "", nil
+	})
+	defer restore()
+
+	record, err := CompleteOnboarding(app, OnboardingInput{ImportedProfile: " Likes Go and databases. "})
+	if err != nil {
+		t.Fatalf("CompleteOnboarding: %v", err)
+	}
+	if record.GetString("profile_text") != "Likes Go and databases." || record.GetString("source") != PreferenceSourceManual {
+		t.Errorf("unexpected profile: %q (%s)", record.GetString("profile_text"), record.GetString("source"))
+	}
+	if NeedsOnboarding(app) {
+		t.Error("expected onboarding to be done")
+	}
+}
+
+func TestCompleteOnboarding_GeneratesProfile(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	liked := testutil.CreateEntryWithStars(t, app, res.Id, "Postgres query planning", "https://example.com/pg", 3, 0)
+	disliked := testutil.CreateEntryWithStars(t, app, res.Id, "Celebrity gossip", "https://example.com/gossip", 3, 0)
+
+	var prompt string
+	restore := SetCompleteFunc(func(_, _ string, msgs []Message) (string, error) {
+		prompt = msgs[len(msgs)-1].Content
+		return "Values database internals.", nil
+	})
+	defer restore()
+
+	record, err := CompleteOnboarding(app, OnboardingInput{
+		Interests:       "Databases and distributed systems.",
+		Ratings:         []OnboardingRating{{EntryID: liked.Id, Stars: 5}, {EntryID: disliked.Id, Stars: 1}},
+		ImportedProfile: "Old profile text.",
+	})
+	if err != nil {
+		t.Fatalf("CompleteOnboarding: %v", err)
+	}
+	for _, want := range []string{"<interests>\nDatabases and distributed systems.\n</interests>", `"Postgres query planning"`, ": 5 stars", ": 1 stars", "<profile>\nOld profile text.\n</profile>"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+	if record.GetString("profile_text") != "Values database internals." || record.GetString("source") != PreferenceSourceOnboarding {
+		t.Errorf("unexpected profile: %q (%s)", record.GetString("profile_text"), record.GetString("source"))
+	}
+	if got := PreferenceVersionCorrections(record); len(got) != 2 || got[0].UserStars != 5 {
+		t.Errorf("expected the ratings as sources, got %+v", got)
+	}
+	if saved, _ := app.FindRecordById("entries", liked.Id); saved.GetInt("user_stars") != 5 {
+		t.Errorf("expected the sample rating to be saved, got %d", saved.GetInt("user_stars"))
+	}
+}
@@ -0,0 +1,200 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// PageChunk is a span of PDF text covering one or more consecutive pages.
+type PageChunk struct {
+	FirstPage int // 1-based
+	LastPage  int
+	Text      string
+}
+
+// Label returns the page reference for the chunk ("Page 3" or "Pages 3-4").
+func (c PageChunk) Label() string {
+	if c.FirstPage == c.LastPage {
+		return fmt.Sprintf("Page %d", c.FirstPage)
+	}
+	return fmt.Sprintf("Pages %d-%d", c.FirstPage, c.LastPage)
+}
+
+// EntryPDFPages returns the per-page text stored for a PDF entry, or nil.
+func EntryPDFPages(entry *core.Record) []string {
+	raw := strings.TrimSpace(entry.GetString("pdf_pages"))
+	if raw == "" || raw == "null" {
+		return nil
+	}
+	var pages []string
+	if err := json.Unmarshal([]byte(raw), &pages); err != nil {
+		return nil
+	}
+	return pages
+}
+
+// ChunkPDFPages splits page text into chunks of at most maxChars. Consecutive
+// short pages are merged; long pages are split at paragraph boundaries (and
+// at maxChars for paragraphs that are longer still). Chunks never span a
+// page partially, so every chunk keeps an exact page range.
+func ChunkPDFPages(pages []string, maxChars int) []PageChunk {
+	var chunks []PageChunk
+	var cur *PageChunk
+
+	add := func(page int, text string) {
+		if cur != nil && len(cur.Text)+2+len(text) <= maxChars {
+			cur.Text += "\n\n" + text
+			cur.LastPage = page
+			return
+		}
+		if cur != nil {
+			chunks = append(chunks, *cur)
+		}
+		cur = &PageChunk{FirstPage: page, LastPage: page, Text: text}
+	}
+
+	for i, page := range pages {
+		page = strings.TrimSpace(page)
+		if page == "" {
+			continue
+		}
+		if len(page) <= maxChars {
+			add(i+1, page)
+			continue
+		}
+		// Long page: start a fresh chunk and fill it paragraph by paragraph.
+		if cur != nil {
+			chunks = append(chunks, *cur)
+			cur = nil
+		}
+		for _, para := range strings.Split(page, "\n\n") {
+			for para = strings.TrimSpace(para); len(para) > maxChars; {
+				cut := splitPoint(para, maxChars)
+				add(i+1, strings.TrimSpace(para[:cut]))
+				para = strings.TrimSpace(para[cut:])
+			}
+			if para != "" {
+				add(i+1, para)
+			}
+		}
+		if cur != nil {
+			chunks = append(chunks, *cur)
+			cur = nil
+		}
+	}
+	if cur != nil {
+		chunks = append(chunks, *cur)
+	}
+	return chunks
+}
+
+// splitPoint returns a byte offset at most maxChars into s, preferring the
+// last space and never splitting a UTF-8 sequence.
+func splitPoint(s string, maxChars int) int {
+	if i := strings.LastIndexByte(s[:maxChars], ' '); i > maxChars/2 {
+		return i
+	}
+	cut := maxChars
+	for cut > 0 && !isRuneStart(s[cut]) {
+		cut--
+	}
+	if cut == 0 {
+		return maxChars
+	}
+	return cut
+}
+
+func isRuneStart(b byte) bool { return b&0xC0 != 0x80 }
+
+// SelectPDFContext builds chat context from PDF pages: the chunks sharing the
+// most words with the question are selected until budget characters are
+// used, then emitted in page order with "[Page N]" labels so the model can
+// cite pages. Without a usable question the document is included from the
+// start.
+func SelectPDFContext(pages []string, question string, budget int) string {
+	chunkSize := budget / 4
+	if chunkSize < 500 {
+		chunkSize = 500
+	}
+	chunks := ChunkPDFPages(pages, chunkSize)
+	labels := make([]string, len(chunks))
+	texts := make([]string, len(chunks))
+	for i, c := range chunks {
+		labels[i], texts[i] = c.Label(), c.Text
+	}
+	return selectRelevantChunks(labels, texts, question, budget)
+}
+
+// selectRelevantChunks picks the chunks sharing the most words with the
+// question until budget characters are used, and joins them in their
+// original order, each under its "[label]".
+func selectRelevantChunks(labels, texts []string, question string, budget int) string {
+	terms := queryTerms(question)
+	type scored struct {
+		index int
+		score int
+	}
+	ranked := make([]scored, len(texts))
+	for i, text := range texts {
+		ranked[i] = scored{index: i, score: termOverlap(text, terms)}
+	}
+	sort.SliceStable(ranked, func(a, b int) bool { return ranked[a].score > ranked[b].score })
+
+	selected := map[int]bool{}
+	used := 0
+	for _, r := range ranked {
+		size := len(texts[r.index]) + len(labels[r.index]) + 4
+		if used+size > budget {
+			continue
+		}
+		selected[r.index] = true
+		used += size
+	}
+
+	var sb strings.Builder
+	for i, text := range texts {
+		if !selected[i] {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n\n")
+		}
+		fmt.Fprintf(&sb, "[%s]\n%s", labels[i], text)
+	}
+	return sb.String()
+}
+
+// queryTerms returns the distinct lower-cased words of at least three
+// letters in a question.
+func queryTerms(question string) map[string]bool {
+	terms := map[string]bool{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(question), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(w)) >= 3 {
+			terms[w] = true
+		}
+	}
+	return terms
+}
+
+// termOverlap counts occurrences of the query terms in text.
+func termOverlap(text string, terms map[string]bool) int {
+	if len(terms) == 0 {
+		return 0
+	}
+	score := 0
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if terms[w] {
+			score++
+		}
+	}
+	return score
+}
@@ -0,0 +1,66 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChunkPDFPages_MergesShortPages(t *testing.T) {
+	chunks := ChunkPDFPages([]string{"one", "two", "", "three"}, 100)
+	if len(chunks) != 1 {
+		t.Fatalf("expected 1 chunk, got %d", len(chunks))
+	}
+	if chunks[0].Label() != "Pages 1-4" || chunks[0].Text != "one\n\ntwo\n\nthree" {
+		t.Errorf("unexpected chunk: %+v (%s)", chunks[0], chunks[0].Label())
+	}
+}
+
+func TestChunkPDFPages_SplitsLongPages(t *testing.T) {
+	long := strings.Repeat("word ", 30) + "\n\n" + strings.Repeat("more ", 30)
+	chunks := ChunkPDFPages([]string{"intro", long, "outro"}, 100)
+
+	if len(chunks) < 4 {
+		t.Fatalf("expected the long page to be split, got %d chunks", len(chunks))
+	}
+	if chunks[0].Label() != "Page 1" || chunks[len(chunks)-1].Label() != "Page 3" {
+		t.Errorf("unexpected labels: %s ... %s", chunks[0].Label(), chunks[len(chunks)-1].Label())
+	}
+	for _, c := range chunks {
+		if len(c.Text) > 100 {
+			t.Errorf("chunk exceeds limit: %d chars", len(c.Text))
+		}
+		if c.FirstPage == 2 && c.LastPage != 2 {
+			t.Errorf("chunk of a long page spans other pages: %s", c.Label())
+		}
+	}
+}
+
+func TestChunkPDFPages_SplitsOnRuneBoundaries(t *testing.T) {
+	chunks := ChunkPDFPages([]string{strings.Repeat("é", 100)}, 51)
+	for _, c := range chunks {
+		if !strings.HasPrefix(c.Text, "é") || strings.ContainsRune(c.Text, '�') {
+			t.Fatalf("chunk split inside a character: %q", c.Text)
+		}
+	}
+}
+
+func TestSelectPDFContext(t *testing.T) {
+	pages := []string{
+		strings.Repeat("Background on neural networks. ", 30),
+		strings.Repeat("Unrelated appendix material. ", 30),
+		"Results: the transformer reaches 28.4 BLEU. " + strings.Repeat("Details follow. ", 25),
+	}
+
+	got := SelectPDFContext(pages, "What BLEU score does the transformer reach?", 1000)
+	if !strings.Contains(got, "[Page 3]\nResults: the transformer reaches 28.4 BLEU.") {
+		t.Errorf("expected the relevant page, got %q", got)
+	}
+	if strings.Contains(got, "appendix") {
+		t.Errorf("expected irrelevant pages to be dropped, got %q", got)
+	}
+
+	all := SelectPDFContext([]string{"First page.", "Second page."}, "", 1000)
+	if all != "[Pages 1-2]\nFirst page.\n\nSecond page." {
+		t.Errorf("unexpected context without a question: %q", all)
+	}
+}
@@ -29,7 +29,8 @@ func CheckAndRegeneratePreferences(app core.App) {
 }
 
 // GeneratePreferenceProfile collects all entries where user_stars differs from
-// ai_stars and sends them to the LLM to generate a preference profile.
+// ai_stars, plus implicit signals from recent reading behaviour, and sends
+// them to the LLM to generate a preference profile.
 func GeneratePreferenceProfile(app core.App) error {
 	apiKey, err := GetAPIKey(app)
 	if err != nil {
@@ -39,16 +40,20 @@ func GeneratePreferenceProfile(app core.App) error {
 
 	corrections, err := app.FindRecordsByFilter(
 		"entries",
-		"user_stars > 0 && ai_stars > 0 && user_stars != ai_stars",
+		correctionFilter,
 		"-created",
 		100, 0,
 		nil,
 	)
-	if err != nil || len(corrections) == 0 {
+	if err != nil {
+		corrections = nil
+	}
+	signals := ImplicitSignals(app, time.Now())
+	if len(corrections) == 0 && len(signals) == 0 {
 		return fmt.Errorf("no corrections found")
 	}
 
-	prompt := buildPreferencePrompt(corrections)
+	prompt := buildPreferencePrompt(corrections, signals)
 
 	response, err := clientCompleteFunc(apiKey, model, []Message{
 		{Role: "system", Content: "You are a helpful assistant that analyzes reading preferences. Be concise and specific."},
@@ -58,21 +63,53 @@ func GeneratePreferenceProfile(app core.App) error {
 		return fmt.Errorf("AI completion failed: %w", err)
 	}
 
-	return savePreferenceProfile(app, strings.TrimSpace(response))
+	sources := make([]PreferenceCorrection, 0, len(corrections))
+	for _, r := range corrections {
+		sources = append(sources, PreferenceCorrection{
+			EntryID:   r.Id,
+			Title:     r.GetString("title"),
+			AIStars:   r.GetInt("ai_stars"),
+			UserStars: r.GetInt("user_stars"),
+		})
+	}
+	_, err = savePreferenceVersion(app, preferenceVersion{
+		Text:        strings.TrimSpace(response),
+		Model:       model,
+		Source:      PreferenceSourceGenerated,
+		Corrections: sources,
+		Signals:     signals,
+	})
+	return err
 }
 
-func buildPreferencePrompt(corrections []*core.Record) string {
+func buildPreferencePrompt(corrections []*core.Record, signals []ImplicitSignal) string {
 	var sb strings.Builder
 	sb.WriteString("Based on the following rating corrections, generate a brief preference profile describing what topics and content the user values highly vs. finds less interesting.\n\n")
-	sb.WriteString("Rating corrections (AI rating → User rating):\n")
+	if len(corrections) > 0 {
+		sb.WriteString("Rating corrections (AI rating → User rating). Where given, the AI's reason for its rating shows what it misjudged:\n")
+	}
 
 	for _, r := range corrections {
-		sb.WriteString(fmt.Sprintf("- \"%s\" (summary: %s): AI=%d, User=%d\n",
+		sb.WriteString(fmt.Sprintf("- \"%s\" (summary: %s): AI=%d, User=%d",
 			r.GetString("title"),
 			truncateText(r.GetString("summary"), 100),
 			r.GetInt("ai_stars"),
 			r.GetInt("user_stars"),
 		))
+		if reason := r.GetString("score_reason"); reason != "" {
+			sb.WriteString(fmt.Sprintf("; AI reason: %s", reason))
+		}
+		if influences := EntryScoreInfluences(r); len(influences) > 0 {
+			sb.WriteString(fmt.Sprintf("; AI relied on: %s", strings.Join(influences, " | ")))
+		}
+		sb.WriteString("\n")
+	}
+
+	if section := implicitSignalsSection(signals); section != "" {
+		if len(corrections) > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(section + "\n")
 	}
 
 	sb.WriteString("\nGenerate a concise preference profile (3-5 paragraphs) that can guide future article scoring.")
@@ -80,46 +117,181 @@ func buildPreferencePrompt(corrections []*core.Record) string {
 }
 
 func truncateText(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
-	}
-	return s[:maxLen] + "..."
+	return TruncateUTF8(s, maxLen)
+}
+
+// Sources of a preference profile version.
+const (
+	PreferenceSourceGenerated = "generated"
+	PreferenceSourceRollback  = "rollback"
+	PreferenceSourceManual    = "manual"
+)
+
+// PreferenceCorrection is a rating correction a profile version was
+// generated from.
+type PreferenceCorrection struct {
+	EntryID   string `json:"entry_id"`
+	Title     string `json:"title"`
+	AIStars   int    `json:"ai_stars"`
+	UserStars int    `json:"user_stars"`
+}
+
+type preferenceVersion struct {
+	Text         string
+	Model        string
+	Source       string
+	RestoredFrom string
+	Corrections  []PreferenceCorrection
+	// Signals are the implicit signals scoring uses with this version;
+	// computed on save when nil.
+	Signals []ImplicitSignal
 }
 
+// savePreferenceProfile stores a hand-written profile as a new version.
 func savePreferenceProfile(app core.App, profileText string) error {
-	// Try to find existing preference record
-	records, err := app.FindRecordsByFilter("preferences", "1=1", "-generated_at", 1, 0, nil)
-	if err == nil && len(records) > 0 {
-		records[0].Set("profile_text", profileText)
-		records[0].Set("generated_at", time.Now().UTC().Format(time.RFC3339))
-		return app.Save(records[0])
-	}
+	_, err := savePreferenceVersion(app, preferenceVersion{Text: profileText, Source: PreferenceSourceManual})
+	return err
+}
 
-	// Create new preference record
+// savePreferenceVersion stores a profile as a new version; earlier versions
+// are kept so a bad regeneration can be rolled back. The implicit signals are
+// stored with it, so scoring does not aggregate the interactions log for
+// every entry.
+func savePreferenceVersion(app core.App, v preferenceVersion) (*core.Record, error) {
 	collection, err := app.FindCollectionByNameOrId("preferences")
 	if err != nil {
-		return fmt.Errorf("preferences collection not found: %w", err)
+		return nil, fmt.Errorf("preferences collection not found: %w", err)
+	}
+
+	version := 1
+	if latest, err := latestPreferenceProfile(app); err == nil {
+		version = latest.GetInt("version") + 1
+	}
+	if v.Corrections == nil {
+		v.Corrections = []PreferenceCorrection{}
+	}
+	if v.Signals == nil {
+		v.Signals = ImplicitSignals(app, time.Now())
+	}
+	if v.Signals == nil {
+		v.Signals = []ImplicitSignal{}
 	}
 
 	record := core.NewRecord(collection)
-	record.Set("profile_text", profileText)
+	record.Set("profile_text", v.Text)
 	record.Set("generated_at", time.Now().UTC().Format(time.RFC3339))
-	return app.Save(record)
+	record.Set("version", version)
+	record.Set("model", v.Model)
+	record.Set("source", v.Source)
+	record.Set("restored_from", v.RestoredFrom)
+	record.Set("source_corrections", v.Corrections)
+	record.Set("implicit_signals", v.Signals)
+	if err := app.Save(record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// RollbackPreferenceProfile makes an earlier profile version current again by
+// saving a copy of it as the newest version.
+func RollbackPreferenceProfile(app core.App, versionID string) (*core.Record, error) {
+	old, err := app.FindRecordById("preferences", versionID)
+	if err != nil {
+		return nil, fmt.Errorf("profile version not found: %w", err)
+	}
+	return savePreferenceVersion(app, preferenceVersion{
+		Text:         old.GetString("profile_text"),
+		Model:        old.GetString("model"),
+		Source:       PreferenceSourceRollback,
+		RestoredFrom: old.Id,
+	})
+}
+
+// PreferenceVersions returns every profile version, newest first.
+func PreferenceVersions(app core.App) ([]*core.Record, error) {
+	return app.FindRecordsByFilter("preferences", "1=1", "-generated_at,-version", 0, 0, nil)
+}
+
+// PreferenceVersionCorrections returns the corrections a profile version was
+// generated from.
+func PreferenceVersionCorrections(record *core.Record) []PreferenceCorrection {
+	var corrections []PreferenceCorrection
+	if err := record.UnmarshalJSONField("source_corrections", &corrections); err != nil || corrections == nil {
+		return []PreferenceCorrection{}
+	}
+	return corrections
+}
+
+// preferenceVersionSignals returns the implicit signals stored with a
+// profile version.
+func preferenceVersionSignals(record *core.Record) []ImplicitSignal {
+	var signals []ImplicitSignal
+	if err := record.UnmarshalJSONField("implicit_signals", &signals); err != nil {
+		return nil
+	}
+	return signals
+}
+
+// latestPreferenceProfile returns the current profile version.
+func latestPreferenceProfile(app core.App) (*core.Record, error) {
+	records, err := app.FindRecordsByFilter("preferences", "1=1", "-generated_at,-version", 1, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no preference profile")
+	}
+	return records[0], nil
+}
+
+// PinnedPreferenceRules returns the user's pinned scoring rules, oldest first.
+func PinnedPreferenceRules(app core.App) []string {
+	records, err := app.FindRecordsByFilter("preference_rules", "1=1", "created", 0, 0, nil)
+	if err != nil {
+		return nil
+	}
+	var rules []string
+	for _, r := range records {
+		if rule := strings.TrimSpace(r.GetString("rule")); rule != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
 }
 
+// pinnedRulesSection formats pinned rules for a scoring prompt.
+func pinnedRulesSection(rules []string) string {
+	if len(rules) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("Pinned rules set by the user (always follow these, even where the profile above disagrees):\n")
+	for _, rule := range rules {
+		sb.WriteString("- ")
+		sb.WriteString(rule)
+		sb.WriteString("\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// correctionFilter matches entries the user rated differently from the LLM.
+// Entries rated by the local relevance model are left out: their ai_stars is
+// the local model's guess, not an LLM misjudgement.
+const correctionFilter = "user_stars > 0 && ai_stars > 0 && user_stars != ai_stars && score_source != 'local'"
+
 func countCorrectionsSinceLastProfile(app core.App) (int, error) {
 	// Get last profile generation time
-	profiles, err := app.FindRecordsByFilter("preferences", "1=1", "-generated_at", 1, 0, nil)
+	profiles, err := app.FindRecordsByFilter("preferences", "1=1", "-generated_at,-version", 1, 0, nil)
 
 	var filter string
 	params := map[string]any{}
 
 	if err != nil || len(profiles) == 0 {
 		// No profile ever generated — count all corrections
-		filter = "user_stars > 0 && ai_stars > 0 && user_stars != ai_stars"
+		filter = correctionFilter
 	} else {
 		generatedAt := profiles[0].GetString("generated_at")
-		filter = "user_stars > 0 && ai_stars > 0 && user_stars != ai_stars && created > {:since}"
+		filter = correctionFilter + " && created > {:since}"
 		params["since"] = generatedAt
 	}
 
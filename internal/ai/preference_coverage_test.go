@@ -85,7 +85,7 @@ func TestCountCorrectionsSinceLastProfile_WithProfile(t *testing.T) {
 	}
 }
 
-func TestSavePreferenceProfile_UpdatesExistingRecord(t *testing.T) {
+func TestSavePreferenceProfile_KeepsEarlierVersion(t *testing.T) {
 	app, cleanup := testutil.NewTestApp(t)
 	defer cleanup()
 
@@ -95,15 +95,15 @@ func TestSavePreferenceProfile_UpdatesExistingRecord(t *testing.T) {
 		t.Fatalf("first save error: %v", err)
 	}
 
-	// Second save should update
+	// Second save should add a version
 	err = savePreferenceProfile(app, "Updated profile")
 	if err != nil {
 		t.Fatalf("second save error: %v", err)
 	}
 
-	profiles, _ := app.FindRecordsByFilter("preferences", "1=1", "", 0, 0, nil)
-	if len(profiles) != 1 {
-		t.Fatalf("expected 1 profile (updated), got %d", len(profiles))
+	profiles, _ := PreferenceVersions(app)
+	if len(profiles) != 2 {
+		t.Fatalf("expected 2 versions, got %d", len(profiles))
 	}
 	if profiles[0].GetString("profile_text") != "Updated profile" {
 		t.Errorf("profile_text = %q, want 'Updated profile'", profiles[0].GetString("profile_text"))
@@ -148,7 +148,7 @@ func TestBuildPreferencePrompt_ContainsCorrections(t *testing.T) {
 	r1 := testutil.CreateEntryWithStars(t, app, resource.Id, "Go Cov Article", "https://example.com/cov-go2", 2, 5)
 
 	records := []*core.Record{r1}
-	prompt := buildPreferencePrompt(records)
+	prompt := buildPreferencePrompt(records, nil)
 
 	if prompt == "" {
 		t.Fatal("expected non-empty prompt")
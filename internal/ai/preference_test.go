@@ -67,7 +67,7 @@ func TestGeneratePreferenceProfile_NoCorrections(t *testing.T) {
 	}
 }
 
-func TestGeneratePreferenceProfile_UpdatesExisting(t *testing.T) {
+func TestGeneratePreferenceProfile_AddsVersion(t *testing.T) {
 	app, cleanup := testutil.NewTestApp(t)
 	defer cleanup()
 
@@ -87,13 +87,23 @@ func TestGeneratePreferenceProfile_UpdatesExisting(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	records, _ := app.FindRecordsByFilter("preferences", "1=1", "", 0, 0, nil)
-	if len(records) != 1 {
-		t.Errorf("expected exactly 1 preference record, got %d", len(records))
+	records, _ := PreferenceVersions(app)
+	if len(records) != 2 {
+		t.Fatalf("expected the old profile kept as a version, got %d records", len(records))
 	}
-	if got := records[0].GetString("profile_text"); got != "Updated preference profile." {
+	latest := records[0]
+	if got := latest.GetString("profile_text"); got != "Updated preference profile." {
 		t.Errorf("profile_text = %q, want updated", got)
 	}
+	if latest.GetInt("version") != 1 || latest.GetString("source") != PreferenceSourceGenerated || latest.GetString("model") != DefaultModel {
+		t.Errorf("unexpected version metadata: version=%d source=%q model=%q", latest.GetInt("version"), latest.GetString("source"), latest.GetString("model"))
+	}
+	if corrections := PreferenceVersionCorrections(latest); len(corrections) != 1 || corrections[0].Title != "Article A" || corrections[0].UserStars != 5 {
+		t.Errorf("unexpected source corrections: %+v", corrections)
+	}
+	if loadPreferenceProfile(app) != "Updated preference profile." {
+		t.Errorf("expected the newest version to be used for scoring")
+	}
 }
 
 func TestCountCorrectionsSinceLastProfile(t *testing.T) {
@@ -206,7 +216,7 @@ func TestBuildPreferencePrompt(t *testing.T) {
 	entry := testutil.CreateEntryWithStars(t, app, resource.Id, "Go Article", "https://example.com/go", 2, 5)
 
 	records, _ := app.FindRecordsByFilter("entries", "id = {:id}", "", 1, 0, map[string]any{"id": entry.Id})
-	prompt := buildPreferencePrompt(records)
+	prompt := buildPreferencePrompt(records, nil)
 
 	if !strings.Contains(prompt, "Go Article") {
 		t.Error("prompt should contain article title")
@@ -237,3 +247,31 @@ func TestTruncateText(t *testing.T) {
 		})
 	}
 }
+
+func TestCorrections_SkipLocallyScoredEntries(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+
+	resource := testutil.CreateResource(t, app, "test", "https://example.com", "rss", "healthy", 0, true)
+	local := testutil.CreateEntryWithStars(t, app, resource.Id, "Local guess", "https://example.com/local", 2, 5)
+	local.Set("score_source", "local")
+	if err := app.Save(local); err != nil {
+		t.Fatalf("save entry: %v", err)
+	}
+
+	if count, err := countCorrectionsSinceLastProfile(app); err != nil || count != 0 {
+		t.Errorf("expected no corrections, got %d %v", count, err)
+	}
+	if corrections := loadRecentCorrections(app); corrections != "" {
+		t.Errorf("expected no recent corrections, got %q", corrections)
+	}
+	if err := GeneratePreferenceProfile(app); err == nil || !strings.Contains(err.Error(), "no corrections") {
+		t.Errorf("expected no corrections to generate from, got %v", err)
+	}
+
+	testutil.CreateEntryWithStars(t, app, resource.Id, "LLM guess", "https://example.com/llm", 2, 5)
+	if count, _ := countCorrectionsSinceLastProfile(app); count != 1 {
+		t.Errorf("expected the LLM-scored entry to count, got %d", count)
+	}
+}
@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func createPinnedRule(t *testing.T, app core.App, rule string) {
+	t.Helper()
+	col, err := app.FindCollectionByNameOrId("preference_rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := core.NewRecord(col)
+	r.Set("rule", rule)
+	if err := app.Save(r); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRollbackPreferenceProfile(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	good, err := savePreferenceVersion(app, preferenceVersion{Text: "Likes Go.", Model: "model-a", Source: PreferenceSourceGenerated})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := savePreferenceVersion(app, preferenceVersion{Text: "Likes nothing.", Source: PreferenceSourceGenerated}); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := RollbackPreferenceProfile(app, good.Id)
+	if err != nil {
+		t.Fatalf("RollbackPreferenceProfile: %v", err)
+	}
+	if restored.GetInt("version") != 3 || restored.GetString("source") != PreferenceSourceRollback ||
+		restored.GetString("restored_from") != good.Id || restored.GetString("model") != "model-a" {
+		t.Errorf("unexpected rollback version: version=%d source=%q from=%q model=%q",
+			restored.GetInt("version"), restored.GetString("source"), restored.GetString("restored_from"), restored.GetString("model"))
+	}
+	if got := loadPreferenceProfile(app); got != "Likes Go." {
+		t.Errorf("profile after rollback = %q", got)
+	}
+	if versions, _ := PreferenceVersions(app); len(versions) != 3 {
+		t.Errorf("expected all versions kept, got %d", len(versions))
+	}
+
+	if _, err := RollbackPreferenceProfile(app, "missing"); err == nil {
+		t.Error("expected an error for an unknown version")
+	}
+}
+
+func TestLoadPreferenceProfile_PinnedRules(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	createPinnedRule(t, app, "Never rate crypto above 2")
+	if got := loadPreferenceProfile(app); !strings.HasPrefix(got, "Pinned rules") || !strings.Contains(got, "- Never rate crypto above 2") {
+		t.Errorf("expected pinned rules without a profile, got %q", got)
+	}
+
+	savePreferenceProfile(app, "Likes Go.")
+	createPinnedRule(t, app, "  ")
+	createPinnedRule(t, app, "Always rate Go release notes 5")
+	got := loadPreferenceProfile(app)
+	if !strings.HasPrefix(got, "Likes Go.\n\nPinned rules") || !strings.HasSuffix(got, "- Never rate crypto above 2\n- Always rate Go release notes 5") {
+		t.Errorf("unexpected profile with pinned rules:\n%s", got)
+	}
+}
+
+func TestGeneratePreferenceProfile_KeepsPinnedRules(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	testutil.CreateEntryWithStars(t, app, res.Id, "Bitcoin rally", "https://example.com/btc", 2, 4)
+	createPinnedRule(t, app, "Never rate crypto above 2")
+
+	restore := SetCompleteFunc(func(_, _ string, _ []Message) (string, error) {
+		return "Enjoys crypto news.", nil
+	})
+	defer restore()
+	if err := GeneratePreferenceProfile(app); err != nil {
+		t.Fatalf("GeneratePreferenceProfile: %v", err)
+	}
+
+	var prompt string
+	restore2 := SetCompleteFunc(func(_, _ string, msgs []Message) (string, error) {
+		prompt = msgs[len(msgs)-1].Content
+		return `{"summary":"s","stars":2}`, nil
+	})
+	defer restore2()
+	entry := testutil.CreateEntry(t, app, res.Id, "Ether update", "https://example.com/eth", "eth")
+	if err := ScoreOnly(app, entry); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(prompt, "Enjoys crypto news.") || !strings.Contains(prompt, "- Never rate crypto above 2") {
+		t.Errorf("expected the regenerated profile and the pinned rule in the prompt:\n%s", prompt)
+	}
+}
@@ -0,0 +1,142 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// MaxScoreBatchSize bounds the fragments rated in one call.
+const MaxScoreBatchSize = 20
+
+// batchContentChars bounds each fragment in a batch prompt, so a full batch
+// stays about the size of a single summary prompt.
+const batchContentChars = 3000
+
+// batchScoreResult is the rating of one fragment in a batch response.
+type batchScoreResult struct {
+	ID string `json:"id"`
+	SummaryResult
+}
+
+// ScoreOnlyBatch rates several fragments in one call that shares the
+// profile, corrections and instructions between them, using the active
+// score_only_batch prompt. Results are matched to entries by ID; entries that
+// are missing from the response or whose result could not be saved are
+// returned so the caller can score them one by one. An error means no entry
+// was scored.
+func ScoreOnlyBatch(app core.App, entries []*core.Record, model string) ([]*core.Record, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	if len(entries) > MaxScoreBatchSize {
+		return nil, fmt.Errorf("batch of %d entries exceeds %d", len(entries), MaxScoreBatchSize)
+	}
+	apiKey, err := GetAPIKey(app)
+	if err != nil {
+		return nil, fmt.Errorf("no API key configured: %w", err)
+	}
+
+	profile := loadPreferenceProfile(app)
+	corrections := loadRecentCorrections(app)
+	taxonomy := LoadTagTaxonomy(app)
+	prompt := renderScoreBatchPrompt(ActivePrompt(app, PromptScoreOnlyBatch), entries, profile, corrections) +
+		tagPromptInstruction(taxonomy.Names()) + scoreReasonInstruction(profile, corrections) + dimensionScoresInstruction()
+
+	results, _, err := requestScoreBatch(apiKey, model, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []*core.Record
+	for _, entry := range entries {
+		result, ok := results[entry.Id]
+		if !ok {
+			missing = append(missing, entry)
+			continue
+		}
+		applyDimensionScores(app, entry, result)
+		ApplyEntryTags(app, taxonomy, entry, result.Tags)
+		applyScoreExplanation(entry, result)
+		entry.Set("processing_status", "done")
+		if err := app.Save(entry); err != nil {
+			missing = append(missing, entry)
+		}
+	}
+	return missing, nil
+}
+
+// scoreBatchSystemPrompt is the system message of a batch scoring call.
+const scoreBatchSystemPrompt = "You are a helpful assistant that rates article relevance. Always respond with valid JSON."
+
+// requestScoreBatch sends a rendered batch prompt and returns the valid
+// results by entry ID, along with the raw response.
+func requestScoreBatch(apiKey, model, prompt string) (map[string]SummaryResult, string, error) {
+	response, err := callComplete(apiKey, model, []Message{
+		{Role: "system", Content: scoreBatchSystemPrompt},
+		{Role: "user", Content: prompt},
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("AI completion failed: %w", err)
+	}
+	results, err := parseScoreBatchResult(response)
+	if err != nil {
+		return nil, response, fmt.Errorf("parsing AI response: %w", err)
+	}
+	return results, response, nil
+}
+
+func buildScoreBatchPrompt(entries []*core.Record, profile, corrections string) string {
+	return renderScoreBatchPrompt(BuiltinPrompt(PromptScoreOnlyBatch), entries, profile, corrections)
+}
+
+// renderScoreBatchPrompt renders a score_only_batch template. Each fragment
+// is converted to Markdown and truncated to batchContentChars.
+func renderScoreBatchPrompt(tmpl PromptTemplate, entries []*core.Record, profile, corrections string) string {
+	var sb strings.Builder
+	for _, entry := range entries {
+		title := entry.GetString("title")
+		content := entryArticleContent(entry)
+		if content == "" {
+			content = title
+		}
+		fmt.Fprintf(&sb, "<fragment id=%q>\nTitle: %s\n\n%s\n</fragment>\n\n",
+			entry.Id, title, TruncateUTF8(HTMLToMarkdown(content), batchContentChars))
+	}
+	return RenderPrompt(tmpl, ScoreBatchPromptData{
+		Count:       len(entries),
+		Profile:     profile,
+		Corrections: corrections,
+		Fragments:   sb.String(),
+	})
+}
+
+// parseScoreBatchResult returns the valid results of a batch response by
+// entry ID. The first result for an ID wins; results without an ID or
+// rating are dropped so those entries fall back to single calls.
+func parseScoreBatchResult(response string) (map[string]SummaryResult, error) {
+	response = extractJSON(response)
+
+	var parsed struct {
+		Results []batchScoreResult `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(response), &parsed); err != nil {
+		return nil, fmt.Errorf("invalid JSON %q: %w", response, err)
+	}
+
+	results := make(map[string]SummaryResult, len(parsed.Results))
+	for _, r := range parsed.Results {
+		id := strings.TrimSpace(r.ID)
+		if id == "" || r.Stars == 0 {
+			continue
+		}
+		if _, dup := results[id]; dup {
+			continue
+		}
+		r.Stars = clampStars(r.Stars)
+		results[id] = r.SummaryResult
+	}
+	return results, nil
+}
@@ -0,0 +1,148 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestScoreOnlyBatch(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	testutil.CreatePreference(t, app, "Likes distributed systems.", "2024-01-01 00:00:00.000Z")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	var entries []*core.Record
+	for _, title := range []string{"Raft", "Paxos", "Gossip"} {
+		e := testutil.CreateEntry(t, app, res.Id, title, "https://example.com/"+title, title)
+		e.Set("raw_content", "<p>About "+title+".</p>")
+		e.Set("processing_status", "pending")
+		if err := app.Save(e); err != nil {
+			t.Fatalf("save entry: %v", err)
+		}
+		entries = append(entries, e)
+	}
+
+	var calls int
+	var prompt string
+	restore := SetCompleteFunc(func(_, _ string, msgs []Message) (string, error) {
+		calls++
+		prompt = msgs[len(msgs)-1].Content
+		return "```json\n{\"results\": [" +
+			"{\"id\": \"" + entries[0].Id + "\", \"stars\": 5, \"tags\": [\"consensus\"], \"score_reason\": \"Core topic.\"}," +
+			"{\"id\": \"" + entries[1].Id + "\", \"stars\": 9}," +
+			"{\"id\": \"unknown\", \"stars\": 2}" +
+			"]}\n```", nil
+	})
+	defer restore()
+
+	missing, err := ScoreOnlyBatch(app, entries, "test/model")
+	if err != nil {
+		t.Fatalf("ScoreOnlyBatch: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected one call, got %d", calls)
+	}
+	if len(missing) != 1 || missing[0].Id != entries[2].Id {
+		t.Fatalf("expected only Gossip to be missing, got %v", missing)
+	}
+	if strings.Count(prompt, "Likes distributed systems.") != 1 {
+		t.Errorf("expected the profile once in the shared header:\n%s", prompt)
+	}
+	for _, e := range entries {
+		if !strings.Contains(prompt, `<fragment id="`+e.Id+`">`) {
+			t.Errorf("expected fragment %s in the prompt", e.Id)
+		}
+	}
+
+	raft, _ := app.FindRecordById("entries", entries[0].Id)
+	if raft.GetInt("ai_stars") != 5 || raft.GetString("processing_status") != "done" || raft.GetString("score_reason") != "Core topic." {
+		t.Errorf("unexpected Raft entry: stars %d status %q reason %q", raft.GetInt("ai_stars"), raft.GetString("processing_status"), raft.GetString("score_reason"))
+	}
+	if len(raft.GetStringSlice("tags")) != 1 {
+		t.Errorf("expected Raft to be tagged, got %v", raft.GetStringSlice("tags"))
+	}
+	paxos, _ := app.FindRecordById("entries", entries[1].Id)
+	if paxos.GetInt("ai_stars") != 5 {
+		t.Errorf("expected Paxos stars clamped to 5, got %d", paxos.GetInt("ai_stars"))
+	}
+	gossip, _ := app.FindRecordById("entries", entries[2].Id)
+	if gossip.GetString("processing_status") != "pending" {
+		t.Error("expected the missing entry to stay unscored")
+	}
+}
+
+func TestScoreOnlyBatch_Errors(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Raft", "https://example.com/raft", "raft")
+
+	if missing, err := ScoreOnlyBatch(app, nil, "m"); err != nil || missing != nil {
+		t.Errorf("expected an empty batch to be a no-op, got %v %v", missing, err)
+	}
+	if _, err := ScoreOnlyBatch(app, []*core.Record{entry}, "m"); err == nil {
+		t.Error("expected an error without an API key")
+	}
+	tooMany := make([]*core.Record, MaxScoreBatchSize+1)
+	if _, err := ScoreOnlyBatch(app, tooMany, "m"); err == nil {
+		t.Error("expected an oversized batch to be rejected")
+	}
+
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	restore := SetCompleteFunc(func(_, _ string, _ []Message) (string, error) {
+		return "not json", nil
+	})
+	defer restore()
+	if _, err := ScoreOnlyBatch(app, []*core.Record{entry}, "m"); err == nil {
+		t.Error("expected an error for an invalid response")
+	}
+}
+
+func TestScoreOnlyBatch_UsesActivePrompt(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Raft", "https://example.com/raft", "raft")
+	custom, err := SavePromptVersion(app, PromptScoreOnlyBatch, "EXPERIMENT {{.Count}}\n{{.Fragments}}", "")
+	if err != nil {
+		t.Fatalf("SavePromptVersion: %v", err)
+	}
+	if _, err := ActivatePromptVersion(app, PromptScoreOnlyBatch, custom.Version); err != nil {
+		t.Fatalf("ActivatePromptVersion: %v", err)
+	}
+
+	var prompt string
+	restore := SetCompleteFunc(func(_, _ string, msgs []Message) (string, error) {
+		prompt = msgs[len(msgs)-1].Content
+		return `{"results": [{"id": "` + entry.Id + `", "stars": 4}]}`, nil
+	})
+	defer restore()
+
+	if _, err := ScoreOnlyBatch(app, []*core.Record{entry}, "m"); err != nil {
+		t.Fatalf("ScoreOnlyBatch: %v", err)
+	}
+	if !strings.HasPrefix(prompt, "EXPERIMENT 1\n<fragment id=\""+entry.Id+"\">") {
+		t.Errorf("expected the active batch prompt, got:\n%s", prompt)
+	}
+}
+
+func TestParseScoreBatchResult(t *testing.T) {
+	results, err := parseScoreBatchResult(`{"results": [
+		{"id": "a", "stars": 4},
+		{"id": "a", "stars": 1},
+		{"id": "", "stars": 3},
+		{"id": "b"},
+		{"id": " c ", "stars": 0},
+		{"id": "d", "stars": -2, "scores": {"relevance": 2}}
+	]}`)
+	if err != nil {
+		t.Fatalf("parseScoreBatchResult: %v", err)
+	}
+	if len(results) != 2 || results["a"].Stars != 4 || results["d"].Stars != 1 {
+		t.Errorf("unexpected results: %+v", results)
+	}
+}
@@ -0,0 +1,359 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Score dimensions. Each is rated 1-5 and stored on the entry as
+// "score_<dimension>"; ai_stars is their weighted combination.
+const (
+	DimensionRelevance   = "relevance"
+	DimensionDepth       = "depth"
+	DimensionNovelty     = "novelty"
+	DimensionCredibility = "credibility"
+)
+
+// ScoreDimensions lists every score dimension in display order.
+var ScoreDimensions = []string{DimensionRelevance, DimensionDepth, DimensionNovelty, DimensionCredibility}
+
+// SettingScoreWeights is the app_settings key holding the dimension weights
+// as a JSON object.
+const SettingScoreWeights = "score_weights"
+
+const (
+	// minWeightSamples is the number of rated entries with dimension scores
+	// needed before weights are learned from user ratings.
+	minWeightSamples = 10
+	maxWeightSamples = 200
+	// noveltyHistoryLimit caps how many read or rated entries a new entry's
+	// title is compared against.
+	noveltyHistoryLimit = 300
+)
+
+// ErrTooFewRatings is returned by LearnScoreWeights when there are not yet
+// enough rated entries with dimension scores to learn from.
+var ErrTooFewRatings = errors.New("too few rated entries with dimension scores")
+
+// ScoreWeights maps each dimension to its weight in ai_stars.
+type ScoreWeights map[string]float64
+
+// DefaultScoreWeights keeps ai_stars close to the plain relevance rating used
+// before scores had dimensions.
+var DefaultScoreWeights = ScoreWeights{
+	DimensionRelevance:   0.6,
+	DimensionDepth:       0.2,
+	DimensionNovelty:     0.1,
+	DimensionCredibility: 0.1,
+}
+
+// Validate checks that only known dimensions are weighted, no weight is
+// negative and at least one is positive.
+func (w ScoreWeights) Validate() error {
+	total := 0.0
+	for dim, weight := range w {
+		if !isScoreDimension(dim) {
+			return fmt.Errorf("unknown score dimension %q", dim)
+		}
+		if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return fmt.Errorf("weight for %s must be a non-negative number", dim)
+		}
+		total += weight
+	}
+	if total <= 0 {
+		return fmt.Errorf("at least one weight must be positive")
+	}
+	return nil
+}
+
+// GetScoreWeights reads the configured weights, returning DefaultScoreWeights
+// when none are set or the stored value is invalid.
+func GetScoreWeights(app core.App) ScoreWeights {
+	value, err := getSetting(app, SettingScoreWeights)
+	if err != nil || strings.TrimSpace(value) == "" {
+		return cloneWeights(DefaultScoreWeights)
+	}
+	var weights ScoreWeights
+	if err := json.Unmarshal([]byte(value), &weights); err != nil || weights.Validate() != nil {
+		return cloneWeights(DefaultScoreWeights)
+	}
+	return weights
+}
+
+// SaveScoreWeights validates and stores the dimension weights.
+func SaveScoreWeights(app core.App, weights ScoreWeights) error {
+	if err := weights.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(weights)
+	if err != nil {
+		return err
+	}
+
+	record, err := app.FindFirstRecordByFilter("app_settings", "key = {:key}", map[string]any{"key": SettingScoreWeights})
+	if err != nil {
+		collection, err := app.FindCollectionByNameOrId("app_settings")
+		if err != nil {
+			return fmt.Errorf("app_settings collection not found: %w", err)
+		}
+		record = core.NewRecord(collection)
+		record.Set("key", SettingScoreWeights)
+	}
+	record.Set("value", string(data))
+	return app.Save(record)
+}
+
+// CombineScores returns the weighted average of the given dimension scores,
+// rounded to whole stars. Dimensions without a score are left out so their
+// weight does not drag the result down. It returns 0 when nothing is scored.
+func CombineScores(scores map[string]int, weights ScoreWeights) int {
+	sum, total := 0.0, 0.0
+	for _, dim := range ScoreDimensions {
+		score, ok := scores[dim]
+		if !ok || score <= 0 {
+			continue
+		}
+		sum += weights[dim] * float64(score)
+		total += weights[dim]
+	}
+	if total <= 0 {
+		return 0
+	}
+	return clampStars(int(math.Round(sum / total)))
+}
+
+// LearnScoreWeights fits the weights that best reproduce the user's own
+// ratings from the dimension scores of rated entries, and saves them. It
+// needs at least minWeightSamples rated entries with dimension scores.
+func LearnScoreWeights(app core.App) (ScoreWeights, int, error) {
+	records, err := app.FindRecordsByFilter(
+		"entries",
+		"user_stars > 0 && score_relevance > 0 && score_depth > 0 && score_novelty > 0 && score_credibility > 0",
+		"-updated",
+		maxWeightSamples, 0,
+		nil,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(records) < minWeightSamples {
+		return nil, len(records), fmt.Errorf("%w: need %d, have %d", ErrTooFewRatings, minWeightSamples, len(records))
+	}
+
+	samples := make([][]float64, len(records))
+	targets := make([]float64, len(records))
+	for i, r := range records {
+		row := make([]float64, len(ScoreDimensions))
+		for j, dim := range ScoreDimensions {
+			row[j] = float64(r.GetInt("score_" + dim))
+		}
+		samples[i] = row
+		targets[i] = float64(r.GetInt("user_stars"))
+	}
+
+	fitted := fitSimplexWeights(samples, targets)
+	weights := ScoreWeights{}
+	for j, dim := range ScoreDimensions {
+		weights[dim] = math.Round(fitted[j]*1000) / 1000
+	}
+	if err := SaveScoreWeights(app, weights); err != nil {
+		return nil, len(records), err
+	}
+	return weights, len(records), nil
+}
+
+// fitSimplexWeights minimises the squared error of samples·w against targets
+// with projected gradient descent, keeping w non-negative and summing to 1 so
+// the combination stays on the 1-5 scale.
+func fitSimplexWeights(samples [][]float64, targets []float64) []float64 {
+	n := len(ScoreDimensions)
+	w := make([]float64, n)
+	for j, dim := range ScoreDimensions {
+		w[j] = DefaultScoreWeights[dim]
+	}
+
+	// The gradient's Lipschitz constant is at most twice the mean squared
+	// norm of the samples; stepping by its inverse keeps descent stable.
+	lipschitz := 0.0
+	for _, x := range samples {
+		for _, v := range x {
+			lipschitz += 2 * v * v / float64(len(samples))
+		}
+	}
+	if lipschitz == 0 {
+		return w
+	}
+	step := 1 / lipschitz
+	for iter := 0; iter < 2000; iter++ {
+		grad := make([]float64, n)
+		for i, x := range samples {
+			residual := -targets[i]
+			for j := range x {
+				residual += w[j] * x[j]
+			}
+			for j := range x {
+				grad[j] += 2 * residual * x[j] / float64(len(samples))
+			}
+		}
+		for j := range w {
+			w[j] -= step * grad[j]
+		}
+		w = projectOntoSimplex(w)
+	}
+	return w
+}
+
+// projectOntoSimplex returns the closest point to v whose elements are
+// non-negative and sum to 1.
+func projectOntoSimplex(v []float64) []float64 {
+	sorted := append([]float64(nil), v...)
+	sort.Sort(sort.Reverse(sort.Float64Slice(sorted)))
+
+	cumulative, theta := 0.0, 0.0
+	for i, u := range sorted {
+		cumulative += u
+		if t := (cumulative - 1) / float64(i+1); u-t > 0 {
+			theta = t
+		}
+	}
+
+	out := make([]float64, len(v))
+	for i, x := range v {
+		out[i] = math.Max(x-theta, 0)
+	}
+	return out
+}
+
+// dimensionScoresInstruction asks the model to rate the dimensions it can
+// judge from the text. Novelty is computed locally from reading history.
+func dimensionScoresInstruction() string {
+	return "\n\nAlso include \"scores\": an object rating the article from 1 to 5 on \"relevance\" (fit with the user's interests, the same value as \"stars\"), \"depth\" (1 = shallow news or announcement, 5 = in-depth analysis or tutorial) and \"credibility\" (1 = unsourced opinion or marketing, 5 = well-sourced, expert or primary source)."
+}
+
+// applyDimensionScores stores the per-dimension scores on the entry and sets
+// ai_stars to their weighted combination. When the model returned no
+// dimension scores, ai_stars is its plain star rating and earlier dimension
+// scores are cleared.
+func applyDimensionScores(app core.App, entry *core.Record, result SummaryResult) {
+	scores := map[string]int{}
+	for _, dim := range []string{DimensionRelevance, DimensionDepth, DimensionCredibility} {
+		if score := result.Scores[dim]; score > 0 {
+			scores[dim] = clampStars(score)
+		}
+	}
+	if len(scores) == 0 {
+		for _, dim := range ScoreDimensions {
+			entry.Set("score_"+dim, 0)
+		}
+		entry.Set("ai_stars", result.Stars)
+		return
+	}
+
+	if _, ok := scores[DimensionRelevance]; !ok && result.Stars > 0 {
+		scores[DimensionRelevance] = clampStars(result.Stars)
+	}
+	scores[DimensionNovelty] = entryNovelty(app, entry)
+
+	for _, dim := range ScoreDimensions {
+		entry.Set("score_"+dim, scores[dim])
+	}
+	entry.Set("ai_stars", CombineScores(scores, GetScoreWeights(app)))
+}
+
+// entryNovelty rates how new an entry is compared to what the user has
+// already read or rated: 5 when no earlier title resembles it, down to 1 for
+// a near-identical title.
+func entryNovelty(app core.App, entry *core.Record) int {
+	history, err := app.FindRecordsByFilter(
+		"entries",
+		"(is_read = true || user_stars > 0) && id != {:id}",
+		"-updated",
+		noveltyHistoryLimit, 0,
+		map[string]any{"id": entry.Id},
+	)
+	if err != nil {
+		return 5
+	}
+
+	title := entry.GetString("title")
+	maxSim := 0.0
+	for _, r := range history {
+		if sim := TitleSimilarity(title, r.GetString("title")); sim > maxSim {
+			maxSim = sim
+		}
+	}
+	return 5 - int(math.Round(4*maxSim))
+}
+
+// TitleSimilarity returns the word-level Jaccard similarity between two
+// titles, from 0.0 (no overlap) to 1.0 (identical words).
+func TitleSimilarity(a, b string) float64 {
+	wordsA := titleWords(a)
+	wordsB := titleWords(b)
+	if len(wordsA) == 0 && len(wordsB) == 0 {
+		return 1.0
+	}
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return 0.0
+	}
+
+	intersection := 0
+	for w := range wordsA {
+		if wordsB[w] {
+			intersection++
+		}
+	}
+
+	union := len(wordsA)
+	for w := range wordsB {
+		if !wordsA[w] {
+			union++
+		}
+	}
+
+	return float64(intersection) / float64(union)
+}
+
+func titleWords(s string) map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.Fields(strings.ToLower(s)) {
+		w = strings.TrimRight(w, ".,;:!?\"'")
+		if w != "" {
+			words[w] = true
+		}
+	}
+	return words
+}
+
+func isScoreDimension(dim string) bool {
+	for _, d := range ScoreDimensions {
+		if d == dim {
+			return true
+		}
+	}
+	return false
+}
+
+func cloneWeights(w ScoreWeights) ScoreWeights {
+	out := make(ScoreWeights, len(w))
+	for k, v := range w {
+		out[k] = v
+	}
+	return out
+}
+
+func clampStars(stars int) int {
+	if stars < 1 {
+		return 1
+	}
+	if stars > 5 {
+		return 5
+	}
+	return stars
+}
@@ -0,0 +1,239 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+)
+
+func TestCombineScores(t *testing.T) {
+	weights := ScoreWeights{DimensionRelevance: 0.5, DimensionDepth: 0.5}
+	tests := []struct {
+		name   string
+		scores map[string]int
+		want   int
+	}{
+		{"weighted average", map[string]int{DimensionRelevance: 5, DimensionDepth: 2}, 4},
+		{"unweighted dimensions ignored", map[string]int{DimensionRelevance: 2, DimensionNovelty: 5}, 2},
+		{"missing dimensions left out", map[string]int{DimensionDepth: 3}, 3},
+		{"nothing scored", map[string]int{}, 0},
+		{"only zero weights", map[string]int{DimensionCredibility: 4}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CombineScores(tt.scores, weights); got != tt.want {
+				t.Errorf("CombineScores(%v) = %d, want %d", tt.scores, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScoreWeights_Validate(t *testing.T) {
+	if err := DefaultScoreWeights.Validate(); err != nil {
+		t.Errorf("default weights invalid: %v", err)
+	}
+	for _, w := range []ScoreWeights{
+		{},
+		{DimensionRelevance: 0},
+		{DimensionRelevance: -1, DimensionDepth: 2},
+		{"funny": 1},
+		{DimensionDepth: math.NaN()},
+	} {
+		if err := w.Validate(); err == nil {
+			t.Errorf("expected %v to be invalid", w)
+		}
+	}
+}
+
+func TestGetAndSaveScoreWeights(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	if got := GetScoreWeights(app); got[DimensionRelevance] != DefaultScoreWeights[DimensionRelevance] {
+		t.Errorf("expected defaults, got %v", got)
+	}
+
+	if err := SaveScoreWeights(app, ScoreWeights{DimensionRelevance: 1, DimensionDepth: 1}); err != nil {
+		t.Fatalf("SaveScoreWeights: %v", err)
+	}
+	if err := SaveScoreWeights(app, ScoreWeights{DimensionDepth: 2}); err != nil {
+		t.Fatalf("SaveScoreWeights (update): %v", err)
+	}
+	got := GetScoreWeights(app)
+	if len(got) != 1 || got[DimensionDepth] != 2 {
+		t.Errorf("unexpected weights: %v", got)
+	}
+	if err := SaveScoreWeights(app, ScoreWeights{}); err == nil {
+		t.Error("expected invalid weights to be rejected")
+	}
+
+	records, _ := app.FindRecordsByFilter("app_settings", "key = {:key}", "", 0, 0, map[string]any{"key": SettingScoreWeights})
+	if len(records) != 1 {
+		t.Errorf("expected a single settings record, got %d", len(records))
+	}
+}
+
+func TestGetScoreWeights_InvalidStoredValue(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, SettingScoreWeights, `{"relevance":-1}`)
+
+	if got := GetScoreWeights(app); got[DimensionRelevance] != DefaultScoreWeights[DimensionRelevance] {
+		t.Errorf("expected defaults for an invalid stored value, got %v", got)
+	}
+}
+
+func TestSummarizeAndScore_CombinesDimensionScores(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	testutil.CreateSetting(t, app, SettingScoreWeights, `{"relevance":1,"depth":1,"novelty":1,"credibility":1}`)
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	read := testutil.CreateEntry(t, app, res.Id, "Raft consensus explained", "https://example.com/raft", "raft")
+	read.Set("is_read", true)
+	app.Save(read)
+	entry := testutil.CreateEntry(t, app, res.Id, "Raft consensus explained", "https://example.com/raft-again", "raft-again")
+
+	var prompt string
+	restore := SetCompleteFunc(func(_, _ string, msgs []Message) (string, error) {
+		prompt = msgs[len(msgs)-1].Content
+		return `{"summary":"Raft.","stars":5,"scores":{"relevance":5,"depth":4,"credibility":7}}`, nil
+	})
+	defer restore()
+
+	if err := SummarizeAndScore(app, entry); err != nil {
+		t.Fatalf("SummarizeAndScore: %v", err)
+	}
+	if !strings.Contains(prompt, `"scores"`) || !strings.Contains(prompt, `"credibility"`) {
+		t.Errorf("expected dimension instructions in prompt:\n%s", prompt)
+	}
+
+	saved, _ := app.FindRecordById("entries", entry.Id)
+	want := map[string]int{"relevance": 5, "depth": 4, "novelty": 1, "credibility": 5}
+	for dim, score := range want {
+		if got := saved.GetInt("score_" + dim); got != score {
+			t.Errorf("score_%s = %d, want %d", dim, got, score)
+		}
+	}
+	// (5 + 4 + 1 + 5) / 4 = 3.75
+	if got := saved.GetInt("ai_stars"); got != 4 {
+		t.Errorf("ai_stars = %d, want 4", got)
+	}
+}
+
+func TestScoreOnly_WithoutDimensionScores(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	res := testutil.CreateResource(t, app, "Links", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Note", "https://example.com/note", "note")
+	entry.Set("score_depth", 5)
+	app.Save(entry)
+
+	restore := SetCompleteFunc(func(_, _ string, _ []Message) (string, error) {
+		return `{"summary":"","stars":2}`, nil
+	})
+	defer restore()
+
+	if err := ScoreOnly(app, entry); err != nil {
+		t.Fatalf("ScoreOnly: %v", err)
+	}
+	saved, _ := app.FindRecordById("entries", entry.Id)
+	if saved.GetInt("ai_stars") != 2 || saved.GetInt("score_depth") != 0 {
+		t.Errorf("expected plain stars and cleared dimensions, got ai_stars=%d depth=%d", saved.GetInt("ai_stars"), saved.GetInt("score_depth"))
+	}
+}
+
+func TestEntryNovelty(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Go generics in practice", "https://example.com/new", "new")
+
+	if got := entryNovelty(app, entry); got != 5 {
+		t.Errorf("novelty without history = %d, want 5", got)
+	}
+
+	// Unread, unrated entries are not history.
+	testutil.CreateEntry(t, app, res.Id, "Go generics in practice", "https://example.com/unread", "unread")
+	if got := entryNovelty(app, entry); got != 5 {
+		t.Errorf("novelty with only unread entries = %d, want 5", got)
+	}
+
+	testutil.CreateEntryWithStars(t, app, res.Id, "Go generics explained", "https://example.com/rated", 3, 4)
+	if got := entryNovelty(app, entry); got != 3 {
+		t.Errorf("novelty with a similar rated title = %d, want 3", got)
+	}
+}
+
+func TestLearnScoreWeights(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+
+	addRated := func(i, userStars, relevance, depth int) {
+		entry := testutil.CreateEntryWithStars(t, app, res.Id, fmt.Sprintf("Post %d", i), fmt.Sprintf("https://example.com/%d", i), 3, userStars)
+		entry.Set("score_relevance", relevance)
+		entry.Set("score_depth", depth)
+		entry.Set("score_novelty", 3)
+		entry.Set("score_credibility", 3)
+		if err := app.Save(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	addRated(0, 1, 5, 1)
+	if _, samples, err := LearnScoreWeights(app); !errors.Is(err, ErrTooFewRatings) || samples != 1 {
+		t.Fatalf("expected ErrTooFewRatings with 1 sample, got %v (%d)", err, samples)
+	}
+
+	// The user's ratings follow depth exactly and ignore relevance.
+	for i := 1; i < 12; i++ {
+		depth := 1 + i%5
+		addRated(i, depth, 6-depth, depth)
+	}
+
+	weights, samples, err := LearnScoreWeights(app)
+	if err != nil {
+		t.Fatalf("LearnScoreWeights: %v", err)
+	}
+	if samples != 12 {
+		t.Errorf("samples = %d, want 12", samples)
+	}
+	if weights[DimensionDepth] < 0.8 || weights[DimensionRelevance] > 0.1 {
+		t.Errorf("expected depth to dominate, got %v", weights)
+	}
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	if math.Abs(total-1) > 0.01 {
+		t.Errorf("weights should sum to 1, got %.3f", total)
+	}
+	if saved := GetScoreWeights(app); saved[DimensionDepth] != weights[DimensionDepth] {
+		t.Errorf("learned weights not saved: %v", saved)
+	}
+}
+
+func TestProjectOntoSimplex(t *testing.T) {
+	got := projectOntoSimplex([]float64{0.8, 0.6, -0.2, 0})
+	want := []float64{0.6, 0.4, 0, 0}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Fatalf("projectOntoSimplex = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestTitleSimilarity(t *testing.T) {
+	if got := TitleSimilarity("Go generics explained", "go generics, explained!"); got != 1 {
+		t.Errorf("expected identical words to score 1, got %v", got)
+	}
+	if got := TitleSimilarity("Go generics", "Rust traits"); got != 0 {
+		t.Errorf("expected no overlap to score 0, got %v", got)
+	}
+}
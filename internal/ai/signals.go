@@ -0,0 +1,188 @@
+package ai
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Interaction types recorded in the interactions collection.
+const (
+	InteractionOpened         = "opened"
+	InteractionRead           = "read"
+	InteractionBookmarked     = "bookmarked"
+	InteractionChatted        = "chatted"
+	InteractionLinkSummarized = "link_summarized"
+	InteractionDismissed      = "dismissed"
+)
+
+// InteractionTypes lists every interaction type.
+var InteractionTypes = []string{
+	InteractionOpened, InteractionRead, InteractionBookmarked,
+	InteractionChatted, InteractionLinkSummarized, InteractionDismissed,
+}
+
+// interactionWeights is how strongly each interaction signals interest.
+// Reading is weighted by duration instead (readWeightPerMinute).
+var interactionWeights = map[string]float64{
+	InteractionOpened:         0.5,
+	InteractionBookmarked:     2,
+	InteractionChatted:        1.5,
+	InteractionLinkSummarized: 1,
+	InteractionDismissed:      -1.5,
+}
+
+const (
+	readWeightPerMinute = 0.25
+	maxReadWeight       = 2
+	// MaxInteractionDuration caps a recorded read duration, in seconds.
+	MaxInteractionDuration = 4 * 60 * 60
+	// signalHalfLife is how long it takes an interaction to lose half its
+	// weight; interactions older than maxSignalAge are ignored.
+	signalHalfLife   = 30 * 24 * time.Hour
+	maxSignalAge     = 180 * 24 * time.Hour
+	maxInteractions  = 2000
+	maxSignalEntries = 10
+	minSignalScore   = 0.25
+)
+
+// ImplicitSignal is the decayed interest an entry's interactions show.
+type ImplicitSignal struct {
+	EntryID string  `json:"entry_id"`
+	Title   string  `json:"title"`
+	Score   float64 `json:"score"`
+	// Actions describes the interactions, e.g. "bookmarked" or "read 4 min".
+	Actions []string `json:"actions"`
+}
+
+// RecordInteraction adds an event to the interactions log.
+func RecordInteraction(app core.App, entryID, kind string, durationSeconds int) (*core.Record, error) {
+	if !IsInteractionType(kind) {
+		return nil, fmt.Errorf("unknown interaction type %q", kind)
+	}
+	if durationSeconds < 0 || durationSeconds > MaxInteractionDuration {
+		return nil, fmt.Errorf("duration must be between 0 and %d seconds", MaxInteractionDuration)
+	}
+	if _, err := app.FindRecordById("entries", entryID); err != nil {
+		return nil, fmt.Errorf("entry not found: %w", err)
+	}
+	collection, err := app.FindCollectionByNameOrId("interactions")
+	if err != nil {
+		return nil, fmt.Errorf("interactions collection not found: %w", err)
+	}
+	record := core.NewRecord(collection)
+	record.Set("entry", entryID)
+	record.Set("type", kind)
+	record.Set("duration_seconds", durationSeconds)
+	if err := app.Save(record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// ImplicitSignals aggregates recent interactions into a decayed interest
+// score per entry. Entries the user rated explicitly are left out, as the
+// rating already says more. The strongest signals, positive or negative,
+// come first.
+func ImplicitSignals(app core.App, now time.Time) []ImplicitSignal {
+	records, err := app.FindRecordsByFilter(
+		"interactions",
+		"created >= {:since}",
+		"-created",
+		maxInteractions, 0,
+		map[string]any{"since": now.Add(-maxSignalAge).UTC()},
+	)
+	if err != nil || len(records) == 0 {
+		return nil
+	}
+
+	type tally struct {
+		decay       map[string]float64 // strongest decay per interaction type
+		readMinutes float64
+		readWeight  float64
+	}
+	byEntry := map[string]*tally{}
+	var order []string
+	for _, r := range records {
+		entryID := r.GetString("entry")
+		t := byEntry[entryID]
+		if t == nil {
+			t = &tally{decay: map[string]float64{}}
+			byEntry[entryID] = t
+			order = append(order, entryID)
+		}
+		age := now.Sub(r.GetDateTime("created").Time())
+		decay := math.Pow(0.5, age.Hours()/signalHalfLife.Hours())
+		kind := r.GetString("type")
+		if kind == InteractionRead {
+			minutes := float64(r.GetInt("duration_seconds")) / 60
+			t.readMinutes += minutes
+			t.readWeight += minutes * readWeightPerMinute * decay
+		}
+		// Repeating an action does not make it count more.
+		if decay > t.decay[kind] {
+			t.decay[kind] = decay
+		}
+	}
+
+	var signals []ImplicitSignal
+	for _, entryID := range order {
+		entry, err := app.FindRecordById("entries", entryID)
+		if err != nil || entry.GetInt("user_stars") > 0 {
+			continue
+		}
+		t := byEntry[entryID]
+		signal := ImplicitSignal{EntryID: entryID, Title: entry.GetString("title")}
+		for _, kind := range InteractionTypes {
+			decay, ok := t.decay[kind]
+			if !ok {
+				continue
+			}
+			if kind == InteractionRead {
+				signal.Score += math.Min(t.readWeight, maxReadWeight)
+				signal.Actions = append(signal.Actions, fmt.Sprintf("read %d min", int(math.Round(t.readMinutes))))
+				continue
+			}
+			signal.Score += interactionWeights[kind] * decay
+			signal.Actions = append(signal.Actions, strings.ReplaceAll(kind, "_", " "))
+		}
+		if math.Abs(signal.Score) >= minSignalScore {
+			signals = append(signals, signal)
+		}
+	}
+
+	sort.SliceStable(signals, func(i, j int) bool {
+		return math.Abs(signals[i].Score) > math.Abs(signals[j].Score)
+	})
+	if len(signals) > maxSignalEntries {
+		signals = signals[:maxSignalEntries]
+	}
+	return signals
+}
+
+// implicitSignalsSection formats implicit signals for a prompt.
+func implicitSignalsSection(signals []ImplicitSignal) string {
+	if len(signals) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("Implicit signals from reading behaviour (weaker evidence than explicit ratings; positive means the user engaged, negative means they dismissed it unread; recent behaviour weighs more):\n")
+	for _, s := range signals {
+		sb.WriteString(fmt.Sprintf("- \"%s\": %s (signal %+.1f)\n", s.Title, strings.Join(s.Actions, ", "), s.Score))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// IsInteractionType reports whether kind is a known interaction type.
+func IsInteractionType(kind string) bool {
+	for _, t := range InteractionTypes {
+		if t == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package ai
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+)
+
+func TestRecordInteraction_Validates(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Post", "https://example.com/post", "post")
+
+	if _, err := RecordInteraction(app, entry.Id, "liked", 0); err == nil {
+		t.Error("expected an unknown type to be rejected")
+	}
+	if _, err := RecordInteraction(app, entry.Id, InteractionRead, -1); err == nil {
+		t.Error("expected a negative duration to be rejected")
+	}
+	if _, err := RecordInteraction(app, "missing", InteractionOpened, 0); err == nil {
+		t.Error("expected a missing entry to be rejected")
+	}
+	record, err := RecordInteraction(app, entry.Id, InteractionRead, 120)
+	if err != nil {
+		t.Fatalf("RecordInteraction: %v", err)
+	}
+	if record.GetString("type") != InteractionRead || record.GetInt("duration_seconds") != 120 {
+		t.Errorf("unexpected record: %v", record)
+	}
+}
+
+func TestImplicitSignals(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	engaged := testutil.CreateEntry(t, app, res.Id, "Raft consensus explained", "https://example.com/raft", "raft")
+	dismissed := testutil.CreateEntry(t, app, res.Id, "Celebrity gossip", "https://example.com/gossip", "gossip")
+	glanced := testutil.CreateEntry(t, app, res.Id, "Release notes", "https://example.com/notes", "notes")
+	rated := testutil.CreateEntryWithStars(t, app, res.Id, "Rated post", "https://example.com/rated", 3, 3)
+
+	record := func(entryID, kind string, seconds int) {
+		t.Helper()
+		if _, err := RecordInteraction(app, entryID, kind, seconds); err != nil {
+			t.Fatal(err)
+		}
+	}
+	record(engaged.Id, InteractionOpened, 0)
+	record(engaged.Id, InteractionOpened, 0)
+	record(engaged.Id, InteractionRead, 240)
+	record(engaged.Id, InteractionRead, 120)
+	record(engaged.Id, InteractionBookmarked, 0)
+	record(dismissed.Id, InteractionDismissed, 0)
+	record(glanced.Id, InteractionRead, 30)
+	record(rated.Id, InteractionBookmarked, 0)
+
+	signals := ImplicitSignals(app, time.Now())
+	if len(signals) != 2 {
+		t.Fatalf("expected the engaged and dismissed entries, got %+v", signals)
+	}
+	// opened 0.5 (counted once) + read 6 min × 0.25 + bookmarked 2
+	if s := signals[0]; s.EntryID != engaged.Id || math.Abs(s.Score-4) > 0.01 {
+		t.Errorf("unexpected top signal: %+v", s)
+	}
+	if got := strings.Join(signals[0].Actions, ", "); got != "opened, read 6 min, bookmarked" {
+		t.Errorf("unexpected actions: %q", got)
+	}
+	if s := signals[1]; s.EntryID != dismissed.Id || math.Abs(s.Score+1.5) > 0.01 {
+		t.Errorf("unexpected dismissed signal: %+v", s)
+	}
+
+	// Two half-lives later the signals carry a quarter of their weight.
+	later := ImplicitSignals(app, time.Now().Add(60*24*time.Hour))
+	if len(later) != 2 || math.Abs(later[0].Score-1) > 0.01 {
+		t.Errorf("expected decayed signals, got %+v", later)
+	}
+	if old := ImplicitSignals(app, time.Now().Add(200*24*time.Hour)); len(old) != 0 {
+		t.Errorf("expected old interactions to be ignored, got %+v", old)
+	}
+}
+
+func TestImplicitSignals_InPrompts(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	engaged := testutil.CreateEntry(t, app, res.Id, "Raft consensus explained", "https://example.com/raft", "raft")
+	if _, err := RecordInteraction(app, engaged.Id, InteractionChatted, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var prompt string
+	restore := SetCompleteFunc(func(_, _ string, msgs []Message) (string, error) {
+		prompt = msgs[len(msgs)-1].Content
+		return "Likes distributed systems.", nil
+	})
+	defer restore()
+
+	// Signals alone are enough to generate a profile.
+	if err := GeneratePreferenceProfile(app); err != nil {
+		t.Fatalf("GeneratePreferenceProfile: %v", err)
+	}
+	if strings.Contains(prompt, "Rating corrections") || !strings.Contains(prompt, "chatted (signal +1.5)") {
+		t.Errorf("unexpected preference prompt:\n%s", prompt)
+	}
+
+	// Scoring uses the signals stored with the profile version.
+	if profile := loadPreferenceProfile(app); !strings.Contains(profile, "Implicit signals") || !strings.Contains(profile, `"Raft consensus explained": chatted`) {
+		t.Errorf("expected implicit signals in the scoring profile:\n%s", profile)
+	}
+	later := testutil.CreateEntry(t, app, res.Id, "Paxos made simple", "https://example.com/paxos", "paxos")
+	if _, err := RecordInteraction(app, later.Id, InteractionBookmarked, 0); err != nil {
+		t.Fatal(err)
+	}
+	if profile := loadPreferenceProfile(app); strings.Contains(profile, "Paxos") {
+		t.Errorf("expected newer interactions to wait for the next profile version:\n%s", profile)
+	}
+}
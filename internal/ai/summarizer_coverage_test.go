@@ -137,14 +137,14 @@ func TestBuildScoreOnlyPrompt_WithProfileAndCorrections(t *testing.T) {
 }
 
 func TestHtmlToMarkdown_PlainText(t *testing.T) {
-	result := htmlToMarkdown("Hello world, no HTML here")
+	result := HTMLToMarkdown("Hello world, no HTML here")
 	if result != "Hello world, no HTML here" {
 		t.Errorf("plain text should pass through unchanged: %q", result)
 	}
 }
 
 func TestHtmlToMarkdown_HTMLContent(t *testing.T) {
-	result := htmlToMarkdown("<p>Hello <strong>world</strong></p>")
+	result := HTMLToMarkdown("<p>Hello <strong>world</strong></p>")
 	if result == "" {
 		t.Error("expected non-empty result")
 	}
@@ -154,7 +154,7 @@ func TestHtmlToMarkdown_HTMLContent(t *testing.T) {
 }
 
 func TestHtmlToMarkdown_EmptyString(t *testing.T) {
-	result := htmlToMarkdown("")
+	result := HTMLToMarkdown("")
 	if result != "" {
 		t.Errorf("empty string should remain empty: %q", result)
 	}
@@ -273,3 +273,75 @@ func TestLoadRecentCorrections(t *testing.T) {
 		t.Error("corrections should mention Article A")
 	}
 }
+
+func TestSummarizeAndScore_PageChangeUsesDiffPrompt(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	resource := testutil.CreateResource(t, app, "Pricing", "https://example.com/pricing", "pagewatch", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, resource.Id, "Pricing — changed", "https://example.com/pricing", "https://example.com/pricing#change-1")
+	entry.Set("raw_content", "<p>The whole pricing page text</p>")
+	entry.Set("change_diff", "- Starter: $10/month\n+ Starter: $12/month")
+
+	var prompt string
+	restore := SetCompleteFunc(func(apiKey, model string, messages []Message) (string, error) {
+		prompt = messages[1].Content
+		return `{"summary":"Starter plan price rose to $12.","stars":4}`, nil
+	})
+	defer restore()
+
+	if err := SummarizeAndScore(app, entry); err != nil {
+		t.Fatalf("SummarizeAndScore: %v", err)
+	}
+	if !strings.Contains(prompt, "<diff>\n- Starter: $10/month\n+ Starter: $12/month\n</diff>") {
+		t.Errorf("prompt does not contain the diff:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "whole pricing page") {
+		t.Errorf("prompt should not include the full page:\n%s", prompt)
+	}
+	if entry.GetString("summary") != "Starter plan price rose to $12." {
+		t.Errorf("summary = %q", entry.GetString("summary"))
+	}
+}
+
+func TestSummarizeAndScore_PrefersStructuredContent(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	resource := testutil.CreateResource(t, app, "Blog", "https://example.com", "watchlist", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, resource.Id, "Channels", "https://example.com/channels", "https://example.com/channels")
+	entry.Set("raw_content", "Buffered channels Channels block when full")
+	entry.Set("content_html", "<h2>Buffered channels</h2><ul><li>Channels block when full</li></ul>")
+
+	var prompt string
+	restore := SetCompleteFunc(func(apiKey, model string, messages []Message) (string, error) {
+		prompt = messages[1].Content
+		return `{"summary":"About channels.","stars":3}`, nil
+	})
+	defer restore()
+
+	if err := SummarizeAndScore(app, entry); err != nil {
+		t.Fatalf("SummarizeAndScore: %v", err)
+	}
+	if !strings.Contains(prompt, "## Buffered channels") || !strings.Contains(prompt, "- Channels block when full") {
+		t.Errorf("prompt should contain the Markdown rendering of content_html:\n%s", prompt)
+	}
+}
+
+func TestEntryMarkdown(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	resource := testutil.CreateResource(t, app, "Blog", "https://example.com", "watchlist", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, resource.Id, "Post", "https://example.com/p", "https://example.com/p")
+	entry.Set("raw_content", "plain text")
+	if got := EntryMarkdown(entry); got != "plain text" {
+		t.Errorf("EntryMarkdown without markdown = %q, want raw_content", got)
+	}
+	entry.Set("content_markdown", "# Post\n\n- point")
+	if got := EntryMarkdown(entry); got != "# Post\n\n- point" {
+		t.Errorf("EntryMarkdown = %q, want content_markdown", got)
+	}
+}
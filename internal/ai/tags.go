@@ -0,0 +1,225 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// MaxEntryTags caps the number of tags the AI assigns to one entry.
+const MaxEntryTags = 5
+
+// maxTagLength is the longest tag name kept, in characters.
+const maxTagLength = 50
+
+// maxPromptTags bounds the existing tags listed in a prompt for reuse.
+const maxPromptTags = 100
+
+// tagContentChars bounds the summary or article text sent when tagging.
+const tagContentChars = 4000
+
+// TagTaxonomy resolves tag names and their synonyms to canonical tags, as
+// managed in the tags collection.
+type TagTaxonomy struct {
+	canonical map[string]string // normalized name or synonym -> canonical name
+	names     []string          // canonical names, sorted
+}
+
+// LoadTagTaxonomy reads the tags collection. A missing or empty collection
+// yields an empty taxonomy in which every tag is its own canonical form.
+func LoadTagTaxonomy(app core.App) *TagTaxonomy {
+	t := &TagTaxonomy{canonical: map[string]string{}}
+	records, err := app.FindRecordsByFilter("tags", "1=1", "name", 0, 0)
+	if err != nil {
+		return t
+	}
+	for _, r := range records {
+		name := r.GetString("name")
+		t.names = append(t.names, name)
+		t.canonical[NormalizeTag(name)] = name
+	}
+	// Synonyms never shadow a canonical tag name.
+	for _, r := range records {
+		for _, synonym := range TagSynonyms(r) {
+			if t.canonical[synonym] == "" {
+				t.canonical[synonym] = r.GetString("name")
+			}
+		}
+	}
+	sort.Strings(t.names)
+	return t
+}
+
+// Resolve returns the canonical form of a tag: the tag it is a name or
+// synonym of, or the normalized tag itself when it is unknown. It returns ""
+// for tags that are empty after normalization.
+func (t *TagTaxonomy) Resolve(tag string) string {
+	key := NormalizeTag(tag)
+	if key == "" {
+		return ""
+	}
+	if name, ok := t.canonical[key]; ok {
+		return name
+	}
+	return key
+}
+
+// Names returns the canonical tag names, sorted.
+func (t *TagTaxonomy) Names() []string {
+	return t.names
+}
+
+// NormalizeTag lowercases a tag, drops a leading "#" and collapses
+// whitespace, so "  #Machine   Learning" becomes "machine learning".
+func NormalizeTag(tag string) string {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+	tag = strings.Join(strings.Fields(strings.ToLower(tag)), " ")
+	if utf8.RuneCountInString(tag) > maxTagLength {
+		tag = strings.TrimSpace(string([]rune(tag)[:maxTagLength]))
+	}
+	return tag
+}
+
+// EntryTags returns the tags stored on an entry.
+func EntryTags(entry *core.Record) []string {
+	return jsonStrings(entry, "tags")
+}
+
+// TagSynonyms returns the normalized synonyms of a tags record, without
+// empty values or duplicates.
+func TagSynonyms(tag *core.Record) []string {
+	var synonyms []string
+	for _, synonym := range jsonStrings(tag, "synonyms") {
+		if synonym = NormalizeTag(synonym); synonym != "" && !slices.Contains(synonyms, synonym) {
+			synonyms = append(synonyms, synonym)
+		}
+	}
+	return synonyms
+}
+
+// ApplyEntryTags merges AI-suggested tags into an entry's tags. Existing tags
+// (e.g. from a bookmark import or an earlier scoring) are kept and
+// suggestions are added only while the entry has fewer than MaxEntryTags
+// tags, so scoring an entry again does not grow its tags. All tags are
+// resolved through the taxonomy and tags not yet in the tags collection are
+// created. The entry is not saved.
+func ApplyEntryTags(app core.App, taxonomy *TagTaxonomy, entry *core.Record, suggested []string) {
+	var tags []string
+	seen := map[string]bool{}
+	add := func(tag string) bool {
+		tag = taxonomy.Resolve(tag)
+		if tag == "" || seen[tag] {
+			return false
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+		return true
+	}
+	for _, tag := range EntryTags(entry) {
+		add(tag)
+	}
+	for _, tag := range suggested {
+		if len(tags) >= MaxEntryTags {
+			break
+		}
+		add(tag)
+	}
+	if len(tags) == 0 {
+		return
+	}
+	for _, tag := range tags {
+		ensureTag(app, taxonomy, tag)
+	}
+	entry.Set("tags", tags)
+}
+
+// ensureTag adds a tag to the tags collection unless the taxonomy knows it.
+func ensureTag(app core.App, taxonomy *TagTaxonomy, name string) {
+	if _, ok := taxonomy.canonical[name]; ok {
+		return
+	}
+	collection, err := app.FindCollectionByNameOrId("tags")
+	if err != nil {
+		return
+	}
+	record := core.NewRecord(collection)
+	record.Set("name", name)
+	if err := app.Save(record); err != nil {
+		// Another entry may have created it concurrently.
+		log.Printf("Failed to create tag %q: %v", name, err)
+	}
+	taxonomy.canonical[name] = name
+	taxonomy.names = append(taxonomy.names, name)
+	sort.Strings(taxonomy.names)
+}
+
+// tagPromptInstruction asks for tags in the JSON response, listing existing
+// tags so the model reuses the taxonomy instead of inventing variants.
+func tagPromptInstruction(known []string) string {
+	var sb strings.Builder
+	sb.WriteString("\n\nAlso include a \"tags\" array of 1-5 short, lowercase topic tags (for example \"go\", \"databases\", \"security\") describing what the content is about.")
+	if len(known) > 0 {
+		if len(known) > maxPromptTags {
+			known = known[:maxPromptTags]
+		}
+		sb.WriteString(" Reuse these existing tags when they fit: ")
+		sb.WriteString(strings.Join(known, ", "))
+		sb.WriteString(".")
+	}
+	return sb.String()
+}
+
+// TagEntry asks the AI for tags only. Used to backfill entries processed
+// before tagging existed.
+func TagEntry(app core.App, entry *core.Record) error {
+	apiKey, err := GetAPIKey(app)
+	if err != nil {
+		return fmt.Errorf("no API key configured: %w", err)
+	}
+	model := GetModel(app)
+
+	content := entry.GetString("summary")
+	if content == "" {
+		content = HTMLToMarkdown(entryArticleContent(entry))
+	}
+
+	taxonomy := LoadTagTaxonomy(app)
+	prompt := RenderPrompt(ActivePrompt(app, PromptTagging), EntryPromptData{
+		Title:   entry.GetString("title"),
+		Content: TruncateUTF8(content, tagContentChars),
+	}) + tagPromptInstruction(taxonomy.Names())
+
+	response, err := callComplete(apiKey, model, []Message{
+		{Role: "system", Content: "You are a helpful assistant that categorizes articles. Always respond with valid JSON."},
+		{Role: "user", Content: prompt},
+	})
+	if err != nil {
+		return fmt.Errorf("AI completion failed: %w", err)
+	}
+	result, err := parseSummaryResult(response)
+	if err != nil {
+		return fmt.Errorf("parsing AI response: %w", err)
+	}
+
+	ApplyEntryTags(app, taxonomy, entry, result.Tags)
+	return app.Save(entry)
+}
+
+// jsonStrings reads a JSON string array field.
+func jsonStrings(record *core.Record, field string) []string {
+	raw := strings.TrimSpace(record.GetString(field))
+	if raw == "" || raw == "null" {
+		return nil
+	}
+	var values []string
+	if err := json.Unmarshal([]byte(raw), &values); err != nil {
+		return nil
+	}
+	return values
+}
@@ -0,0 +1,220 @@
+package ai
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	cases := map[string]string{
+		"Go":                     "go",
+		"  #Machine   Learning ": "machine learning",
+		"#":                      "",
+		"":                       "",
+		strings.Repeat("x", 60):  strings.Repeat("x", maxTagLength),
+	}
+	for in, want := range cases {
+		if got := NormalizeTag(in); got != want {
+			t.Errorf("NormalizeTag(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTagTaxonomy_Resolve(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateTag(t, app, "go", "golang", "Go Lang")
+	testutil.CreateTag(t, app, "databases", "db")
+	// A synonym never shadows a tag with that name.
+	testutil.CreateTag(t, app, "db-tools", "databases")
+
+	taxonomy := LoadTagTaxonomy(app)
+	cases := map[string]string{
+		"Golang":    "go",
+		"go lang":   "go",
+		"GO":        "go",
+		"DB":        "databases",
+		"databases": "databases",
+		"Rust":      "rust",
+		"  ":        "",
+	}
+	for in, want := range cases {
+		if got := taxonomy.Resolve(in); got != want {
+			t.Errorf("Resolve(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if !reflect.DeepEqual(taxonomy.Names(), []string{"databases", "db-tools", "go"}) {
+		t.Errorf("unexpected names: %v", taxonomy.Names())
+	}
+}
+
+func TestTagSynonyms(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	tag := testutil.CreateTag(t, app, "go", " Golang", "golang", "#Go  Lang", "  ")
+
+	if got := TagSynonyms(tag); !reflect.DeepEqual(got, []string{"golang", "go lang"}) {
+		t.Errorf("unexpected synonyms: %q", got)
+	}
+}
+
+func TestApplyEntryTags(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateTag(t, app, "go", "golang")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Post", "https://example.com/post", "post")
+	entry.Set("tags", []string{"Reading", "golang"})
+
+	taxonomy := LoadTagTaxonomy(app)
+	ApplyEntryTags(app, taxonomy, entry, []string{"Go", "Concurrency", "#Performance", "testing", "tooling", "compilers", "extra"})
+
+	want := []string{"reading", "go", "concurrency", "performance", "testing"}
+	if got := EntryTags(entry); !reflect.DeepEqual(got, want) {
+		t.Errorf("tags = %v, want %v", got, want)
+	}
+	for _, name := range []string{"reading", "concurrency", "testing"} {
+		if _, err := app.FindFirstRecordByFilter("tags", "name = {:name}", map[string]any{"name": name}); err != nil {
+			t.Errorf("expected tag %q created: %v", name, err)
+		}
+	}
+	if _, err := app.FindFirstRecordByFilter("tags", "name = 'tooling'"); err == nil {
+		t.Error("expected suggestions beyond the limit to be dropped")
+	}
+
+	// Scoring again keeps the tags at the limit.
+	ApplyEntryTags(app, taxonomy, entry, []string{"databases", "networking"})
+	if got := EntryTags(entry); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected a full tag list to stay unchanged, got %v", got)
+	}
+	if _, err := app.FindFirstRecordByFilter("tags", "name = 'golang'"); err == nil {
+		t.Error("expected synonym not to be created as a tag")
+	}
+}
+
+func TestSummarizeAndScore_Tags(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	testutil.CreateTag(t, app, "go", "golang")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Generics in Go", "https://example.com/generics", "generics")
+
+	var prompt string
+	restore := SetCompleteFunc(func(_, _ string, msgs []Message) (string, error) {
+		prompt = msgs[len(msgs)-1].Content
+		return `{"summary":"About generics.","stars":4,"tags":["golang","Generics"]}`, nil
+	})
+	defer restore()
+
+	if err := SummarizeAndScore(app, entry); err != nil {
+		t.Fatalf("SummarizeAndScore: %v", err)
+	}
+	if !strings.Contains(prompt, `"tags" array`) || !strings.Contains(prompt, "Reuse these existing tags when they fit: go.") {
+		t.Errorf("expected tag instructions with known tags in prompt:\n%s", prompt)
+	}
+	saved, _ := app.FindRecordById("entries", entry.Id)
+	if got := EntryTags(saved); !reflect.DeepEqual(got, []string{"go", "generics"}) {
+		t.Errorf("tags = %v", got)
+	}
+}
+
+func TestScoreOnly_Tags(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	res := testutil.CreateResource(t, app, "Links", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Short note", "https://example.com/note", "note")
+
+	restore := SetCompleteFunc(func(_, _ string, _ []Message) (string, error) {
+		return `{"summary":"","stars":2,"tags":["security"]}`, nil
+	})
+	defer restore()
+
+	if err := ScoreOnly(app, entry); err != nil {
+		t.Fatalf("ScoreOnly: %v", err)
+	}
+	saved, _ := app.FindRecordById("entries", entry.Id)
+	if got := EntryTags(saved); !reflect.DeepEqual(got, []string{"security"}) {
+		t.Errorf("tags = %v", got)
+	}
+}
+
+func TestTagEntry(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntryWithStars(t, app, res.Id, "Old post", "https://example.com/old", 3, 4)
+	entry.Set("summary", "A post about query planners.")
+
+	var prompt string
+	restore := SetCompleteFunc(func(_, _ string, msgs []Message) (string, error) {
+		prompt = msgs[len(msgs)-1].Content
+		return `{"tags":["Databases"]}`, nil
+	})
+	defer restore()
+
+	if err := TagEntry(app, entry); err != nil {
+		t.Fatalf("TagEntry: %v", err)
+	}
+	if !strings.Contains(prompt, "A post about query planners.") {
+		t.Errorf("expected the summary in the prompt:\n%s", prompt)
+	}
+	saved, _ := app.FindRecordById("entries", entry.Id)
+	if got := EntryTags(saved); !reflect.DeepEqual(got, []string{"databases"}) {
+		t.Errorf("tags = %v", got)
+	}
+	if saved.GetInt("user_stars") != 4 || saved.GetInt("ai_stars") != 3 {
+		t.Error("expected ratings untouched")
+	}
+}
+
+func TestTagEntry_ActivePromptAndUTF8Truncation(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Café", "https://example.com/cafe", "cafe")
+	// An odd byte offset lands in the middle of a two-byte rune.
+	entry.Set("raw_content", "x"+strings.Repeat("é", tagContentChars))
+
+	custom, err := SavePromptVersion(app, PromptTagging, "CUSTOM TAGS {{.Title}}\n{{.Content}}", "")
+	if err != nil {
+		t.Fatalf("SavePromptVersion: %v", err)
+	}
+	if _, err := ActivatePromptVersion(app, PromptTagging, custom.Version); err != nil {
+		t.Fatalf("ActivatePromptVersion: %v", err)
+	}
+
+	var prompt string
+	restore := SetCompleteFunc(func(_, _ string, msgs []Message) (string, error) {
+		prompt = msgs[len(msgs)-1].Content
+		return `{"tags":["coffee"]}`, nil
+	})
+	defer restore()
+
+	if err := TagEntry(app, entry); err != nil {
+		t.Fatalf("TagEntry: %v", err)
+	}
+	if !strings.HasPrefix(prompt, "CUSTOM TAGS Café") {
+		t.Errorf("expected the active tagging prompt, got %q", TruncateUTF8(prompt, 40))
+	}
+	if !utf8.ValidString(prompt) || !strings.Contains(prompt, "é...") {
+		t.Error("expected the content cut on a rune boundary")
+	}
+}
+
+func TestTagEntry_NoAPIKey(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Post", "https://example.com/p", "p")
+	if err := TagEntry(app, entry); err == nil {
+		t.Error("expected error without an API key")
+	}
+}
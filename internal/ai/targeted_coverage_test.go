@@ -86,7 +86,7 @@ func TestCheckAndRegenerate_TriggersRegeneration(t *testing.T) {
 	}
 }
 
-func TestSavePreferenceProfile_CreatesAndUpdates(t *testing.T) {
+func TestSavePreferenceProfile_CreatesVersions(t *testing.T) {
 	app, cleanup := testutil.NewTestApp(t)
 	defer cleanup()
 
@@ -100,12 +100,12 @@ func TestSavePreferenceProfile_CreatesAndUpdates(t *testing.T) {
 		t.Fatalf("update save: %v", err)
 	}
 
-	records, _ := app.FindRecordsByFilter("preferences", "1=1", "", 0, 0, nil)
-	if len(records) != 1 {
-		t.Errorf("expected 1 record, got %d", len(records))
+	records, _ := PreferenceVersions(app)
+	if len(records) != 2 {
+		t.Fatalf("expected 2 versions, got %d", len(records))
 	}
-	if records[0].GetString("profile_text") != "Updated" {
-		t.Error("expected updated profile text")
+	if records[0].GetString("profile_text") != "Updated" || records[0].GetInt("version") != 2 {
+		t.Error("expected updated profile text as version 2")
 	}
 }
 
@@ -139,7 +139,7 @@ func TestCountCorrections_AfterProfileGeneration(t *testing.T) {
 
 func TestHtmlToMarkdown_ComplexTags(t *testing.T) {
 	html := `<h1>Title</h1><p>Text with <a href="http://x.com">link</a></p><ul><li>A</li></ul>`
-	result := htmlToMarkdown(html)
+	result := HTMLToMarkdown(html)
 	if result == "" {
 		t.Error("expected non-empty markdown from complex HTML")
 	}
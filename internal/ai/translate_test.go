@@ -0,0 +1,199 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func createSummarizedEntry(t *testing.T, app core.App, language string) *core.Record {
+	t.Helper()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Raft", "https://example.com/raft", "raft")
+	entry.Set("summary", "Raft is a consensus algorithm.")
+	entry.Set("takeaways", []string{"Leaders replicate logs.", "Terms order elections."})
+	entry.Set("content_markdown", "## Intro\n\nRaft keeps replicated logs consistent.")
+	entry.Set("detected_language", language)
+	if err := app.Save(entry); err != nil {
+		t.Fatalf("save entry: %v", err)
+	}
+	return entry
+}
+
+func TestTranslateEntry(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	entry := createSummarizedEntry(t, app, "en")
+
+	var prompts []string
+	restore := SetCompleteFunc(func(_, _ string, msgs []Message) (string, error) {
+		prompt := msgs[len(msgs)-1].Content
+		prompts = append(prompts, prompt)
+		if strings.Contains(prompt, "SUMMARY_JSON") {
+			return "```json\n{\"summary\": \"Raft is een consensusalgoritme.\", \"takeaways\": [\"Leiders repliceren logs.\", \"Termen ordenen verkiezingen.\"]}\n```", nil
+		}
+		return "## Intro\n\nRaft houdt gerepliceerde logs consistent.", nil
+	})
+	defer restore()
+
+	if err := TranslateEntry(app, entry, "nl", true); err != nil {
+		t.Fatalf("TranslateEntry: %v", err)
+	}
+	if len(prompts) != 2 || !strings.Contains(prompts[0], "into Dutch") || !strings.Contains(prompts[1], "<part>") {
+		t.Fatalf("expected a summary and an article call, got %q", prompts)
+	}
+
+	stored, _ := app.FindRecordById("entries", entry.Id)
+	tr, ok := TranslationFor(stored, "nl")
+	if !ok {
+		t.Fatal("expected a Dutch translation")
+	}
+	if tr.Summary != "Raft is een consensusalgoritme." || len(tr.Takeaways) != 2 || !strings.Contains(tr.Content, "gerepliceerde") {
+		t.Errorf("unexpected translation: %+v", tr)
+	}
+
+	// A complete translation is not requested again.
+	if NeedsTranslation(stored, LanguagePreference{Language: "nl", TranslateArticles: true}) {
+		t.Error("expected the translation to be up to date")
+	}
+
+	// A regenerated summary makes the translation stale.
+	stored.Set("summary", "Raft elects a leader.")
+	if _, ok := TranslationFor(stored, "nl"); ok {
+		t.Error("expected the translation of an older summary to be ignored")
+	}
+	if !NeedsTranslation(stored, LanguagePreference{Language: "nl"}) {
+		t.Error("expected a stale translation to need translating")
+	}
+}
+
+func TestTranslateEntry_Errors(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	entry := createSummarizedEntry(t, app, "en")
+
+	if err := TranslateEntry(app, entry, "xx", false); err == nil {
+		t.Error("expected an unsupported language to be rejected")
+	}
+	if err := TranslateEntry(app, entry, "nl", false); err == nil {
+		t.Error("expected an error without an API key")
+	}
+
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	restore := SetCompleteFunc(func(_, _ string, _ []Message) (string, error) {
+		return `{"summary": ""}`, nil
+	})
+	defer restore()
+	if err := TranslateEntry(app, entry, "nl", false); err == nil {
+		t.Error("expected an empty translation to be rejected")
+	}
+	if len(EntryTranslations(entry)) != 0 {
+		t.Error("expected nothing to be stored after a failure")
+	}
+}
+
+func TestNeedsTranslation(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	entry := createSummarizedEntry(t, app, "nl")
+
+	if NeedsTranslation(entry, LanguagePreference{}) {
+		t.Error("expected no translation without a language")
+	}
+	if NeedsTranslation(entry, LanguagePreference{Language: "nl", TranslateArticles: true}) {
+		t.Error("expected no translation into the article's own language")
+	}
+	if !NeedsTranslation(entry, LanguagePreference{Language: "en"}) {
+		t.Error("expected a Dutch entry to need an English translation")
+	}
+	entry.Set("summary", "")
+	if NeedsTranslation(entry, LanguagePreference{Language: "en"}) {
+		t.Error("expected nothing to translate without a summary")
+	}
+	if !NeedsTranslation(entry, LanguagePreference{Language: "en", TranslateArticles: true}) {
+		t.Error("expected the article to need translating for chat")
+	}
+}
+
+func TestTranslateForPreferredLanguages(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	first := testutil.CreateSuperuser(t, app, "first@example.com")
+	second := testutil.CreateSuperuser(t, app, "second@example.com")
+	third := testutil.CreateSuperuser(t, app, "third@example.com")
+	testutil.CreateUserSettings(t, app, first.Id, "nl", false)
+	testutil.CreateUserSettings(t, app, second.Id, "en", false)
+	testutil.CreateUserSettings(t, app, third.Id, "nl", false)
+
+	prefs := PreferredLanguages(app)
+	if len(prefs) != 2 {
+		t.Fatalf("expected nl and en once each, got %+v", prefs)
+	}
+
+	entry := createSummarizedEntry(t, app, "en")
+	var calls int
+	restore := SetCompleteFunc(func(_, _ string, msgs []Message) (string, error) {
+		calls++
+		if !strings.Contains(msgs[len(msgs)-1].Content, "into Dutch") {
+			t.Errorf("expected only a Dutch translation, got:\n%s", msgs[len(msgs)-1].Content)
+		}
+		return `{"summary": "Raft is een consensusalgoritme."}`, nil
+	})
+	defer restore()
+
+	if err := TranslateForPreferredLanguages(app, entry); err != nil {
+		t.Fatalf("TranslateForPreferredLanguages: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected one call, got %d", calls)
+	}
+	if tr, ok := TranslationFor(entry, "nl"); !ok || tr.Takeaways != nil {
+		t.Errorf("expected a Dutch translation without takeaways, got %+v %v", tr, ok)
+	}
+	if pref := UserLanguagePreference(app, first.Id); pref.Language != "nl" {
+		t.Errorf("unexpected preference: %+v", pref)
+	}
+}
+
+func TestTranslateForPreferredLanguages_SummaryOnly(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	user := testutil.CreateSuperuser(t, app, "reader@example.com")
+	testutil.CreateUserSettings(t, app, user.Id, "nl", true)
+
+	var prompts []string
+	restore := SetCompleteFunc(func(_, _ string, msgs []Message) (string, error) {
+		prompts = append(prompts, msgs[len(msgs)-1].Content)
+		return `{"summary": "Raft is een consensusalgoritme."}`, nil
+	})
+	defer restore()
+
+	// The article body waits for a chat, even for users who translate articles.
+	entry := createSummarizedEntry(t, app, "en")
+	if err := TranslateForPreferredLanguages(app, entry); err != nil {
+		t.Fatalf("TranslateForPreferredLanguages: %v", err)
+	}
+	if tr, ok := TranslationFor(entry, "nl"); !ok || tr.Summary == "" || tr.Content != "" || len(prompts) != 1 {
+		t.Errorf("expected only the summary to be translated, got %+v after %d calls", tr, len(prompts))
+	}
+
+	// Entries the local model rated are not translated.
+	local := createSummarizedEntry(t, app, "en")
+	local.Set("score_source", "local")
+	if err := TranslateForPreferredLanguages(app, local); err != nil || len(prompts) != 1 {
+		t.Errorf("expected a locally scored entry to be skipped, got %d calls (%v)", len(prompts), err)
+	}
+
+	content, err := TranslateArticle(app, entry, "nl")
+	if err != nil || content == "" || len(prompts) != 2 || !strings.Contains(prompts[1], "<part>") {
+		t.Fatalf("expected the article to be translated on demand, got %q after %d calls (%v)", content, len(prompts), err)
+	}
+	if again, err := TranslateArticle(app, entry, "nl"); err != nil || again != content || len(prompts) != 2 {
+		t.Errorf("expected the stored article translation to be reused, got %d calls", len(prompts))
+	}
+}
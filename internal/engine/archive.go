@@ -0,0 +1,486 @@
+package engine
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+const (
+	// SettingArchiveEnabled turns on snapshotting of starred and bookmarked entries.
+	SettingArchiveEnabled = "archive_enabled"
+	// SettingArchiveRetentionDays is how long snapshots are kept after an
+	// entry is no longer starred or bookmarked (0 keeps them forever),
+	// counted from the entry's unarchivable_since.
+	SettingArchiveRetentionDays = "archive_retention_days"
+
+	defaultArchiveRetentionDays = 30
+
+	// archiveMinUserStars is the user rating from which an entry counts as starred.
+	archiveMinUserStars = 4
+
+	maxArchiveAssetSize    = 5 << 20
+	maxArchiveSnapshotSize = 40 << 20
+)
+
+// BrowserSnapshotFunc returns the rendered HTML of a page for archiving.
+// Override in tests to avoid needing a real browser.
+var BrowserSnapshotFunc = func(pageURL string) (string, error) {
+	return BrowserFetchBodyFunc(pageURL)
+}
+
+// ArchivingEnabled reports whether snapshotting is switched on in app_settings.
+func ArchivingEnabled(app core.App) bool {
+	return ruleBool(appSetting(app, SettingArchiveEnabled))
+}
+
+// archiveRetention returns the configured retention period; 0 means forever.
+func archiveRetention(app core.App) time.Duration {
+	days := defaultArchiveRetentionDays
+	if v := strings.TrimSpace(appSetting(app, SettingArchiveRetentionDays)); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil && parsed >= 0 {
+			days = parsed
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func appSetting(app core.App, key string) string {
+	record, err := app.FindFirstRecordByFilter("app_settings", "key = {:key}", map[string]any{"key": key})
+	if err != nil {
+		return ""
+	}
+	return record.GetString("value")
+}
+
+// IsArchivable reports whether an entry is starred (user rating of 4 or 5)
+// or bookmarked, i.e. worth protecting against link rot.
+func IsArchivable(entry *core.Record) bool {
+	return entry.GetBool("bookmarked") || entry.GetInt("user_stars") >= archiveMinUserStars
+}
+
+// TrackArchiveRetention records on an archived entry when it stopped being
+// starred or bookmarked, and clears that time once it is archivable again.
+// Call it before the entry is saved.
+func TrackArchiveRetention(entry *core.Record, now time.Time) {
+	switch {
+	case entry.GetString("archive") == "" || IsArchivable(entry):
+		if !entry.GetDateTime("unarchivable_since").IsZero() {
+			entry.Set("unarchivable_since", "")
+		}
+	case entry.GetDateTime("unarchivable_since").IsZero():
+		entry.Set("unarchivable_since", now.UTC())
+	}
+}
+
+// ArchiveEntry snapshots an entry's page as self-contained HTML (images and
+// CSS inlined, scripts removed) and links the snapshot to the entry.
+// Identical snapshots are stored once. JS-rendered pages, and pages of
+// resources marked use_browser, are captured with the headless browser.
+// The link is saved on the entry as currently stored, not on the given
+// record, which only has archive and archived_at updated in memory.
+func ArchiveEntry(app core.App, entry *core.Record, client *http.Client) (*core.Record, error) {
+	pageURL := entry.GetString("url")
+	parsed, err := url.Parse(pageURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, fmt.Errorf("entry %s has no archivable URL", entry.Id)
+	}
+
+	useBrowser := false
+	if resource, err := app.FindRecordById("resources", entry.GetString("resource")); err == nil {
+		useBrowser = resource.GetBool("use_browser")
+	}
+
+	pageHTML, capturedWith, err := captureArchivePage(pageURL, client, useBrowser)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot, err := BuildSnapshot(pageHTML, parsed, client)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshot) > maxArchiveSnapshotSize {
+		return nil, fmt.Errorf("snapshot of %s is too large (%d bytes)", pageURL, len(snapshot))
+	}
+
+	hash := contentSHA256(string(snapshot))
+	archive, err := app.FindFirstRecordByFilter("archives", "hash = {:hash}", map[string]any{"hash": hash})
+	if err != nil {
+		collection, err := app.FindCollectionByNameOrId("archives")
+		if err != nil {
+			return nil, err
+		}
+		file, err := filesystem.NewFileFromBytes(snapshot, "snapshot.html")
+		if err != nil {
+			return nil, err
+		}
+		archive = core.NewRecord(collection)
+		archive.Set("hash", hash)
+		archive.Set("url", pageURL)
+		archive.Set("size", len(snapshot))
+		archive.Set("captured_with", capturedWith)
+		archive.Set("file", file)
+		if err := app.Save(archive); err != nil {
+			return nil, fmt.Errorf("saving archive for %s: %w", pageURL, err)
+		}
+	}
+
+	// Capturing can take a while; link the snapshot on a freshly loaded
+	// entry so changes made in the meantime (stars, read state) survive.
+	current, err := app.FindRecordById("entries", entry.Id)
+	if err != nil {
+		return nil, fmt.Errorf("reloading entry %s: %w", entry.Id, err)
+	}
+	now := time.Now().UTC()
+	current.Set("archive", archive.Id)
+	current.Set("archived_at", now)
+	TrackArchiveRetention(current, now)
+	if err := app.Save(current); err != nil {
+		return nil, fmt.Errorf("linking archive to entry %s: %w", entry.Id, err)
+	}
+	entry.Set("archive", archive.Id)
+	entry.Set("archived_at", now)
+	return archive, nil
+}
+
+// captureArchivePage fetches the page over HTTP, switching to the browser
+// when the resource requires it or the page is rendered client-side.
+func captureArchivePage(pageURL string, client *http.Client, useBrowser bool) (string, string, error) {
+	if !useBrowser {
+		pageHTML, err := fetchArticleHTML(pageURL, client)
+		if err == nil && !looksJSRendered(pageHTML) {
+			return pageHTML, "http", nil
+		}
+		if err != nil && !looksLikeBotProtection(err) {
+			return "", "", err
+		}
+		rendered, browserErr := BrowserSnapshotFunc(pageURL)
+		if browserErr != nil {
+			if err == nil {
+				log.Printf("Browser snapshot failed for %s, keeping HTTP version: %v", pageURL, browserErr)
+				return pageHTML, "http", nil
+			}
+			return "", "", browserErr
+		}
+		return rendered, "browser", nil
+	}
+
+	rendered, err := BrowserSnapshotFunc(pageURL)
+	if err != nil {
+		return "", "", err
+	}
+	return rendered, "browser", nil
+}
+
+var cssURLRe = regexp.MustCompile(`url\(\s*(['"]?)([^'")]+)(['"]?)\s*\)`)
+var cssImportRe = regexp.MustCompile(`@import\s+(?:url\()?\s*['"]?([^'")\s;]+)['"]?\s*\)?[^;]*;`)
+
+// BuildSnapshot turns a page into a self-contained HTML document: scripts,
+// frames and event handlers are removed, stylesheets and images are inlined
+// as <style> blocks and data URIs, and links are made absolute. Assets that
+// cannot be fetched keep their absolute URL. The output is deterministic so
+// identical pages hash (and are stored) identically.
+func BuildSnapshot(pageHTML string, base *url.URL, client *http.Client) ([]byte, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(pageHTML))
+	if err != nil {
+		return nil, err
+	}
+	assets := &assetInliner{client: client, cache: map[string]string{}}
+
+	doc.Find("script, noscript, iframe, frame, frameset, object, embed, applet, base, meta[http-equiv]").Remove()
+	doc.Find("*").Each(func(_ int, s *goquery.Selection) {
+		node := s.Get(0)
+		kept := node.Attr[:0]
+		for _, attr := range node.Attr {
+			if strings.HasPrefix(strings.ToLower(attr.Key), "on") ||
+				strings.HasPrefix(strings.ToLower(strings.TrimSpace(attr.Val)), "javascript:") {
+				continue
+			}
+			kept = append(kept, attr)
+		}
+		node.Attr = kept
+	})
+
+	doc.Find("style").Each(func(_ int, s *goquery.Selection) {
+		s.SetText(assets.inlineCSS(s.Text(), base))
+	})
+	doc.Find("[style]").Each(func(_ int, s *goquery.Selection) {
+		s.SetAttr("style", assets.inlineCSS(s.AttrOr("style", ""), base))
+	})
+	doc.Find("link").Not(`[rel~="stylesheet"]`).Remove()
+	doc.Find(`link[rel~="stylesheet"]`).Each(func(_ int, s *goquery.Selection) {
+		href := resolveURL(base, strings.TrimSpace(s.AttrOr("href", "")))
+		css, err := assets.fetchText(href)
+		if href == "" || err != nil {
+			s.SetAttr("href", href)
+			return
+		}
+		cssBase, _ := url.Parse(href)
+		s.ReplaceWithHtml("<style>" + assets.inlineCSS(css, cssBase) + "</style>")
+	})
+
+	doc.Find("img").Each(func(_ int, s *goquery.Selection) {
+		src := s.AttrOr("src", "")
+		if lazy := s.AttrOr("data-src", ""); lazy != "" && (src == "" || strings.HasPrefix(src, "data:")) {
+			src = lazy
+		}
+		s.RemoveAttr("srcset")
+		s.RemoveAttr("sizes")
+		s.RemoveAttr("data-src")
+		s.RemoveAttr("loading")
+		if strings.HasPrefix(src, "data:") {
+			return
+		}
+		abs := resolveURL(base, strings.TrimSpace(src))
+		if dataURI, err := assets.dataURI(abs); err == nil {
+			s.SetAttr("src", dataURI)
+		} else {
+			s.SetAttr("src", abs)
+		}
+	})
+	doc.Find("picture source").Remove()
+
+	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		href := strings.TrimSpace(s.AttrOr("href", ""))
+		if strings.HasPrefix(href, "#") {
+			return
+		}
+		if abs := sanitizeArticleURL(base, href); abs != "" {
+			s.SetAttr("href", abs)
+		} else {
+			s.RemoveAttr("href")
+		}
+	})
+	doc.Find("form").RemoveAttr("action")
+
+	head := doc.Find("head")
+	if head.Length() > 0 {
+		head.PrependHtml(fmt.Sprintf(`<meta charset="utf-8"><meta name="knowledgehub-archived-from" content="%s">`, escapeAttr(base.String())))
+	}
+
+	out, err := doc.Html()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+func escapeAttr(s string) string {
+	return strings.NewReplacer(`&`, "&amp;", `"`, "&quot;", `<`, "&lt;", `>`, "&gt;").Replace(s)
+}
+
+// assetInliner fetches and caches page assets for a snapshot.
+type assetInliner struct {
+	client *http.Client
+	cache  map[string]string
+	total  int
+}
+
+func (a *assetInliner) fetch(assetURL string) ([]byte, string, error) {
+	if assetURL == "" {
+		return nil, "", fmt.Errorf("empty asset URL")
+	}
+	if a.total > maxArchiveSnapshotSize {
+		return nil, "", fmt.Errorf("snapshot size limit reached")
+	}
+	resp, err := a.client.Get(assetURL)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("HTTP %d for %s", resp.StatusCode, assetURL)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxArchiveAssetSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxArchiveAssetSize {
+		return nil, "", fmt.Errorf("asset %s is too large", assetURL)
+	}
+	a.total += len(data)
+
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mediaType
+	} else {
+		contentType = http.DetectContentType(data)
+	}
+	return data, contentType, nil
+}
+
+func (a *assetInliner) fetchText(assetURL string) (string, error) {
+	data, _, err := a.fetch(assetURL)
+	return string(data), err
+}
+
+// dataURI returns the asset as a base64 data URI.
+func (a *assetInliner) dataURI(assetURL string) (string, error) {
+	if cached, ok := a.cache[assetURL]; ok {
+		return cached, nil
+	}
+	data, contentType, err := a.fetch(assetURL)
+	if err != nil {
+		return "", err
+	}
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType = http.DetectContentType(data)
+	}
+	uri := "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data)
+	a.cache[assetURL] = uri
+	return uri, nil
+}
+
+// inlineCSS replaces url() references (fonts, background images) with data
+// URIs and inlines one level of @import.
+func (a *assetInliner) inlineCSS(css string, base *url.URL) string {
+	css = cssImportRe.ReplaceAllStringFunc(css, func(rule string) string {
+		m := cssImportRe.FindStringSubmatch(rule)
+		abs := resolveURL(base, m[1])
+		imported, err := a.fetchText(abs)
+		if err != nil {
+			return ""
+		}
+		importBase, _ := url.Parse(abs)
+		return a.inlineCSS(cssImportRe.ReplaceAllString(imported, ""), importBase)
+	})
+	return cssURLRe.ReplaceAllStringFunc(css, func(ref string) string {
+		m := cssURLRe.FindStringSubmatch(ref)
+		target := strings.TrimSpace(m[2])
+		if strings.HasPrefix(target, "data:") || strings.HasPrefix(target, "#") {
+			return ref
+		}
+		abs := resolveURL(base, target)
+		if abs == "" {
+			return ref
+		}
+		if dataURI, err := a.dataURI(abs); err == nil {
+			return `url("` + dataURI + `")`
+		}
+		return `url("` + abs + `")`
+	})
+}
+
+// ReadArchive returns the snapshot HTML stored on an archives record.
+func ReadArchive(app core.App, archive *core.Record) ([]byte, error) {
+	fsys, err := app.NewFilesystem()
+	if err != nil {
+		return nil, err
+	}
+	defer fsys.Close()
+
+	reader, err := fsys.GetReader(archive.BaseFilesPath() + "/" + archive.GetString("file"))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// ArchivePendingEntries snapshots starred and bookmarked entries that have no
+// archive yet. It does nothing unless archiving is enabled.
+func ArchivePendingEntries(app core.App, client *http.Client) int {
+	if !ArchivingEnabled(app) {
+		return 0
+	}
+	entries, err := app.FindRecordsByFilter(
+		"entries",
+		"archive = '' && (bookmarked = true || user_stars >= {:stars})",
+		"-updated",
+		20, 0,
+		map[string]any{"stars": archiveMinUserStars},
+	)
+	if err != nil {
+		log.Printf("Archive: failed to load entries: %v", err)
+		return 0
+	}
+
+	archived := 0
+	for _, entry := range entries {
+		if _, err := ArchiveEntry(app, entry, client); err != nil {
+			log.Printf("Archive: failed to snapshot entry %s: %v", entry.Id, err)
+			continue
+		}
+		archived++
+	}
+	return archived
+}
+
+// PruneArchives applies the retention policy: snapshots stay while their
+// entry is starred or bookmarked; once it is not, the entry's link to the
+// snapshot is dropped after the retention period, counted from the entry's
+// unarchivable_since. Archived entries that lost their star or bookmark
+// without it being recorded start their retention period now. Snapshots no
+// longer referenced by any entry are deleted. Returns the number of deleted
+// snapshots.
+func PruneArchives(app core.App, now time.Time) (int, error) {
+	if retention := archiveRetention(app); retention > 0 {
+		untracked, err := app.FindRecordsByFilter(
+			"entries",
+			"archive != '' && bookmarked != true && user_stars < {:stars} && unarchivable_since = ''",
+			"", 0, 0,
+			map[string]any{"stars": archiveMinUserStars},
+		)
+		if err != nil {
+			return 0, err
+		}
+		for _, entry := range untracked {
+			TrackArchiveRetention(entry, now)
+			if err := app.Save(entry); err != nil {
+				log.Printf("Archive: failed to start retention of entry %s: %v", entry.Id, err)
+			}
+		}
+
+		cutoff, _ := types.ParseDateTime(now.Add(-retention))
+		expired, err := app.FindRecordsByFilter(
+			"entries",
+			"archive != '' && bookmarked != true && user_stars < {:stars} && unarchivable_since != '' && unarchivable_since < {:cutoff}",
+			"", 0, 0,
+			map[string]any{"stars": archiveMinUserStars, "cutoff": cutoff.String()},
+		)
+		if err != nil {
+			return 0, err
+		}
+		for _, entry := range expired {
+			entry.Set("archive", "")
+			entry.Set("archived_at", "")
+			entry.Set("unarchivable_since", "")
+			if err := app.Save(entry); err != nil {
+				log.Printf("Archive: failed to release snapshot of entry %s: %v", entry.Id, err)
+			}
+		}
+	}
+
+	archives, err := app.FindAllRecords("archives")
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, archive := range archives {
+		refs, err := app.CountRecords("entries", dbx.HashExp{"archive": archive.Id})
+		if err != nil || refs > 0 {
+			continue
+		}
+		if err := app.Delete(archive); err != nil {
+			log.Printf("Archive: failed to delete snapshot %s: %v", archive.Id, err)
+			continue
+		}
+		deleted++
+	}
+	return deleted, nil
+}
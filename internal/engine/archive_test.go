@@ -0,0 +1,365 @@
+package engine
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+)
+
+// pngPixel is a 1x1 transparent PNG.
+var pngPixel = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00\x1f\x15\xc4\x89\x00\x00\x00\rIDATx\x9cc\xf8\x0f\x00\x00\x01\x01\x00\x05\x18\xd8N\x00\x00\x00\x00IEND\xaeB`\x82")
+
+func newArchiveServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/style.css":
+			w.Header().Set("Content-Type", "text/css")
+			fmt.Fprint(w, `@import "/extra.css"; body { background: url(/bg.png); }`)
+		case "/extra.css":
+			w.Header().Set("Content-Type", "text/css")
+			fmt.Fprint(w, `h1 { color: red; }`)
+		case "/bg.png", "/img/photo.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(pngPixel)
+		case "/missing.png":
+			http.NotFound(w, r)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, body)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+const archivePage = `<html><head><title>Archived</title>
+<link rel="stylesheet" href="/style.css"><link rel="preload" href="/font.woff2">
+<script>alert(1)</script></head>
+<body onload="track()">
+<h1>Archived article</h1>
+<p style="background-image: url('/bg.png')">Body text <a href="/other">other</a> <a href="javascript:evil()">bad</a></p>
+<img src="/img/photo.png" srcset="/img/photo-2x.png 2x" alt="photo">
+<img src="data:image/gif;base64,R0lGOD" data-src="/img/photo.png" alt="lazy">
+<img src="/missing.png" alt="gone">
+<iframe src="https://ads.example.com"></iframe>
+</body></html>`
+
+func TestBuildSnapshot_InlinesAssetsAndRemovesScripts(t *testing.T) {
+	srv := newArchiveServer(t, archivePage)
+	base, _ := url.Parse(srv.URL + "/post")
+
+	snapshot, err := BuildSnapshot(archivePage, base, srv.Client())
+	if err != nil {
+		t.Fatalf("BuildSnapshot: %v", err)
+	}
+	html := string(snapshot)
+
+	for _, unwanted := range []string{"<script", "alert(1)", "onload", "javascript:", "<iframe", "srcset", `rel="preload"`, `href="/style.css"`} {
+		if strings.Contains(html, unwanted) {
+			t.Errorf("snapshot still contains %q", unwanted)
+		}
+	}
+	for _, wanted := range []string{
+		"h1 { color: red; }",
+		`url("data:image/png;base64,`,
+		`src="data:image/png;base64,`,
+		`href="` + srv.URL + `/other"`,
+		`src="` + srv.URL + `/missing.png"`,
+		`name="knowledgehub-archived-from"`,
+	} {
+		if !strings.Contains(html, wanted) {
+			t.Errorf("snapshot missing %q", wanted)
+		}
+	}
+	if strings.Count(html, `src="data:image/png;base64,`) != 2 {
+		t.Errorf("expected regular and lazy-loaded image to be inlined")
+	}
+}
+
+func TestArchiveEntry_StoresAndDeduplicatesSnapshots(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	srv := newArchiveServer(t, archivePage)
+	res := testutil.CreateResource(t, app, "Blog", srv.URL, "rss", "healthy", 0, true)
+	first := testutil.CreateEntry(t, app, res.Id, "Post", srv.URL+"/post", "g1")
+	second := testutil.CreateEntry(t, app, res.Id, "Same post", srv.URL+"/post", "g2")
+
+	archive, err := ArchiveEntry(app, first, srv.Client())
+	if err != nil {
+		t.Fatalf("ArchiveEntry: %v", err)
+	}
+	if archive.GetString("captured_with") != "http" {
+		t.Errorf("captured_with = %q", archive.GetString("captured_with"))
+	}
+	snapshot, err := ReadArchive(app, archive)
+	if err != nil {
+		t.Fatalf("ReadArchive: %v", err)
+	}
+	if !strings.Contains(string(snapshot), "Archived article") || archive.GetInt("size") != len(snapshot) {
+		t.Errorf("unexpected snapshot (size %d): %q", archive.GetInt("size"), truncate(string(snapshot), 100))
+	}
+
+	// The same page archived for another entry reuses the stored snapshot.
+	again, err := ArchiveEntry(app, second, srv.Client())
+	if err != nil {
+		t.Fatalf("ArchiveEntry second: %v", err)
+	}
+	if total, _ := app.CountRecords("archives"); again.Id != archive.Id || total != 1 {
+		t.Errorf("expected deduplicated archive, got %s vs %s (%d records)", again.Id, archive.Id, total)
+	}
+
+	updated, _ := app.FindRecordById("entries", first.Id)
+	if updated.GetString("archive") != archive.Id || updated.GetDateTime("archived_at").IsZero() {
+		t.Errorf("entry not linked to archive: %q", updated.GetString("archive"))
+	}
+}
+
+func TestArchiveEntry_UsesBrowserForJSRenderedPages(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	srv := newArchiveServer(t, `<html><body><div id="root"></div><script src="/app.js"></script></body></html>`)
+	res := testutil.CreateResource(t, app, "SPA", srv.URL, "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "SPA post", srv.URL+"/spa", "g1")
+
+	old := BrowserSnapshotFunc
+	defer func() { BrowserSnapshotFunc = old }()
+	BrowserSnapshotFunc = func(pageURL string) (string, error) {
+		return `<html><head></head><body><div id="root"><h1>Rendered</h1></div></body></html>`, nil
+	}
+
+	archive, err := ArchiveEntry(app, entry, srv.Client())
+	if err != nil {
+		t.Fatalf("ArchiveEntry: %v", err)
+	}
+	if archive.GetString("captured_with") != "browser" {
+		t.Errorf("captured_with = %q, want browser", archive.GetString("captured_with"))
+	}
+	snapshot, _ := ReadArchive(app, archive)
+	if !strings.Contains(string(snapshot), "<h1>Rendered</h1>") {
+		t.Errorf("expected rendered snapshot, got %q", string(snapshot))
+	}
+}
+
+func TestArchiveEntry_KeepsChangesMadeDuringCapture(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	srv := newArchiveServer(t, archivePage)
+	res := testutil.CreateResource(t, app, "Blog", srv.URL, "rss", "healthy", 0, true)
+	res.Set("use_browser", true)
+	if err := app.Save(res); err != nil {
+		t.Fatal(err)
+	}
+	entry := testutil.CreateEntryWithStars(t, app, res.Id, "Post", srv.URL+"/post", 3, 4)
+
+	old := BrowserSnapshotFunc
+	defer func() { BrowserSnapshotFunc = old }()
+	BrowserSnapshotFunc = func(pageURL string) (string, error) {
+		// The user rates the entry while the page is being captured.
+		stored, err := app.FindRecordById("entries", entry.Id)
+		if err != nil {
+			return "", err
+		}
+		stored.Set("user_stars", 5)
+		if err := app.Save(stored); err != nil {
+			return "", err
+		}
+		return archivePage, nil
+	}
+
+	archive, err := ArchiveEntry(app, entry, srv.Client())
+	if err != nil {
+		t.Fatalf("ArchiveEntry: %v", err)
+	}
+
+	updated, _ := app.FindRecordById("entries", entry.Id)
+	if updated.GetInt("user_stars") != 5 {
+		t.Errorf("user_stars = %d, want 5 (set during capture)", updated.GetInt("user_stars"))
+	}
+	if updated.GetString("archive") != archive.Id {
+		t.Errorf("entry not linked to archive: %q", updated.GetString("archive"))
+	}
+	if entry.GetString("archive") != archive.Id || entry.GetDateTime("archived_at").IsZero() {
+		t.Errorf("given record not updated: archive=%q", entry.GetString("archive"))
+	}
+}
+
+func TestArchiveEntry_FetchError(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer srv.Close()
+	res := testutil.CreateResource(t, app, "Blog", srv.URL, "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Gone", srv.URL+"/gone", "g1")
+
+	if _, err := ArchiveEntry(app, entry, srv.Client()); err == nil {
+		t.Fatal("expected error for unavailable page")
+	}
+	if total, _ := app.CountRecords("archives"); total != 0 {
+		t.Errorf("archives = %d, want 0", total)
+	}
+}
+
+func TestArchivePendingEntries(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	srv := newArchiveServer(t, archivePage)
+	res := testutil.CreateResource(t, app, "Blog", srv.URL, "rss", "healthy", 0, true)
+	starred := testutil.CreateEntryWithStars(t, app, res.Id, "Starred", srv.URL+"/a", 3, 5)
+	bookmarked := testutil.CreateEntry(t, app, res.Id, "Bookmarked", srv.URL+"/b", "g-b")
+	bookmarked.Set("bookmarked", true)
+	if err := app.Save(bookmarked); err != nil {
+		t.Fatal(err)
+	}
+	plain := testutil.CreateEntryWithStars(t, app, res.Id, "Meh", srv.URL+"/c", 3, 2)
+
+	if n := ArchivePendingEntries(app, srv.Client()); n != 0 {
+		t.Fatalf("archived %d entries while archiving is disabled", n)
+	}
+
+	testutil.CreateSetting(t, app, SettingArchiveEnabled, "true")
+	if n := ArchivePendingEntries(app, srv.Client()); n != 2 {
+		t.Fatalf("archived %d entries, want 2", n)
+	}
+	for id, want := range map[string]bool{starred.Id: true, bookmarked.Id: true, plain.Id: false} {
+		entry, _ := app.FindRecordById("entries", id)
+		if got := entry.GetString("archive") != ""; got != want {
+			t.Errorf("entry %s archived = %v, want %v", entry.GetString("title"), got, want)
+		}
+	}
+}
+
+func TestPruneArchives_RetentionPolicy(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	srv := newArchiveServer(t, archivePage)
+	res := testutil.CreateResource(t, app, "Blog", srv.URL, "rss", "healthy", 0, true)
+	kept := testutil.CreateEntryWithStars(t, app, res.Id, "Still starred", srv.URL+"/a", 3, 5)
+	released := testutil.CreateEntryWithStars(t, app, res.Id, "Unstarred", srv.URL+"/b", 3, 5)
+	recent := testutil.CreateEntryWithStars(t, app, res.Id, "Just unstarred", srv.URL+"/c", 3, 5)
+
+	for _, entry := range []string{kept.Id, released.Id, recent.Id} {
+		record, _ := app.FindRecordById("entries", entry)
+		if _, err := ArchiveEntry(app, record, srv.Client()); err != nil {
+			t.Fatalf("ArchiveEntry: %v", err)
+		}
+	}
+
+	record, _ := app.FindRecordById("entries", released.Id)
+	record.Set("user_stars", 0)
+	record.Set("unarchivable_since", time.Now().Add(-40*24*time.Hour))
+	if err := app.Save(record); err != nil {
+		t.Fatal(err)
+	}
+	old, _ := app.FindRecordById("entries", kept.Id)
+	old.Set("archived_at", time.Now().Add(-400*24*time.Hour))
+	if err := app.Save(old); err != nil {
+		t.Fatal(err)
+	}
+	// Archived long ago but unstarred only now: retention starts now.
+	unstarred, _ := app.FindRecordById("entries", recent.Id)
+	unstarred.Set("user_stars", 0)
+	unstarred.Set("archived_at", time.Now().Add(-400*24*time.Hour))
+	if err := app.Save(unstarred); err != nil {
+		t.Fatal(err)
+	}
+
+	// Within the retention period nothing is released.
+	testutil.CreateSetting(t, app, SettingArchiveRetentionDays, "60")
+	if deleted, err := PruneArchives(app, time.Now()); err != nil || deleted != 0 {
+		t.Fatalf("PruneArchives = %d, %v; want 0", deleted, err)
+	}
+
+	setting, _ := app.FindFirstRecordByFilter("app_settings", "key = {:key}", map[string]any{"key": SettingArchiveRetentionDays})
+	setting.Set("value", "30")
+	if err := app.Save(setting); err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := PruneArchives(app, time.Now())
+	if err != nil {
+		t.Fatalf("PruneArchives: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("deleted = %d, want 1", deleted)
+	}
+
+	record, _ = app.FindRecordById("entries", released.Id)
+	if record.GetString("archive") != "" {
+		t.Error("expected unstarred entry to lose its archive after retention")
+	}
+	old, _ = app.FindRecordById("entries", kept.Id)
+	if old.GetString("archive") == "" {
+		t.Error("expected starred entry to keep its archive")
+	}
+	unstarred, _ = app.FindRecordById("entries", recent.Id)
+	if unstarred.GetString("archive") == "" || unstarred.GetDateTime("unarchivable_since").IsZero() {
+		t.Error("expected the recently unstarred entry to keep its archive and start its retention period")
+	}
+}
+
+func TestTrackArchiveRetention(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntryWithStars(t, app, res.Id, "Post", "https://example.com/p", 3, 5)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// Entries without a snapshot are not tracked.
+	entry.Set("user_stars", 0)
+	TrackArchiveRetention(entry, now)
+	if !entry.GetDateTime("unarchivable_since").IsZero() {
+		t.Error("expected no retention start without a snapshot")
+	}
+
+	entry.Set("archive", "snapshot")
+	TrackArchiveRetention(entry, now)
+	if !entry.GetDateTime("unarchivable_since").Time().Equal(now) {
+		t.Errorf("expected retention to start at %v, got %v", now, entry.GetDateTime("unarchivable_since"))
+	}
+	// Later saves keep the original start.
+	TrackArchiveRetention(entry, now.Add(time.Hour))
+	if !entry.GetDateTime("unarchivable_since").Time().Equal(now) {
+		t.Error("expected the retention start to be kept")
+	}
+
+	entry.Set("bookmarked", true)
+	TrackArchiveRetention(entry, now)
+	if !entry.GetDateTime("unarchivable_since").IsZero() {
+		t.Error("expected a bookmarked entry to stop its retention period")
+	}
+}
+
+func TestIsArchivable(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntryWithStars(t, app, res.Id, "Post", "https://example.com/p", 5, 3)
+	if IsArchivable(entry) {
+		t.Error("3-star entry should not be archivable")
+	}
+	entry.Set("user_stars", 4)
+	if !IsArchivable(entry) {
+		t.Error("4-star entry should be archivable")
+	}
+	entry.Set("user_stars", 0)
+	entry.Set("bookmarked", true)
+	if !IsArchivable(entry) {
+		t.Error("bookmarked entry should be archivable")
+	}
+}
@@ -19,6 +19,8 @@ import (
 var BrowserExtractFunc = defaultBrowserExtract
 
 func defaultBrowserExtract(articleURL string) (ExtractedContent, error) {
+	articleURL = RewriteArticleURL(articleURL)
+
 	browser, cleanup, err := launchBrowser()
 	if err != nil {
 		return ExtractedContent{}, err
@@ -42,30 +44,67 @@ func defaultBrowserExtract(articleURL string) (ExtractedContent, error) {
 	return ExtractContentFromHTML(html, articleURL), nil
 }
 
-// extractWithBrowserFallback tries plain HTTP extraction first, falling back to
-// browser-based extraction when bot protection is detected. If the browser
-// succeeds, the resource is marked with use_browser=true for future calls.
+// extractWithBrowserFallback runs the extraction chain for a resource: plain
+// HTTP extraction (site rule, then readability) first, then the browser
+// (which applies the site rule and readability to the rendered page). The
+// browser is used when bot protection is detected, or when the HTTP result
+// scores below QualityThreshold; the browser result is only kept when it
+// scores higher. PDF documents are never retried, as the browser cannot
+// extract them better. If the browser succeeds, the resource is marked with
+// use_browser=true for future calls.
 func extractWithBrowserFallback(app core.App, resource *core.Record, articleURL string, client *http.Client) (ExtractedContent, error) {
+	return withBrowserFallback(app, resource, articleURL,
+		func() (ExtractedContent, error) { return ExtractContent(articleURL, client) },
+		func() (ExtractedContent, error) { return BrowserExtractFunc(articleURL) },
+		func(e ExtractedContent) bool { return e.Quality < QualityThreshold && len(e.Pages) == 0 },
+		func(browser, plain ExtractedContent) bool { return browser.Quality > plain.Quality },
+	)
+}
+
+// withBrowserFallback fetches a page of a resource over plain HTTP, unless
+// the resource is marked use_browser, and falls back to the headless browser
+// when bot protection is detected or when weak reports that the HTTP result
+// may be incomplete. A weak HTTP result is kept when the browser fails or
+// better does not prefer its result. weak and better may be nil when every
+// HTTP result is good enough. A browser success marks the resource with
+// use_browser=true for future fetches, unless it only replaced a weak HTTP
+// result with another weak one.
+func withBrowserFallback[T any](app core.App, resource *core.Record, pageURL string, viaHTTP, viaBrowser func() (T, error), weak func(T) bool, better func(browser, plain T) bool) (T, error) {
 	useBrowser := resource.GetBool("use_browser")
 
+	var plain *T
 	if !useBrowser {
-		extracted, err := ExtractContent(articleURL, client)
-		if err == nil {
-			return extracted, nil
+		result, err := viaHTTP()
+		switch {
+		case err == nil && (weak == nil || !weak(result)):
+			return result, nil
+		case err == nil:
+			log.Printf("Incomplete page from %s, trying the browser", pageURL)
+			plain = &result
+		case !looksLikeBotProtection(err):
+			return result, err
+		default:
+			log.Printf("Bot protection detected for %s, trying the browser", pageURL)
 		}
-		if !looksLikeBotProtection(err) {
-			return extracted, err
-		}
-		log.Printf("Bot protection detected for %s, trying browser extraction", articleURL)
 	}
 
-	extracted, err := BrowserExtractFunc(articleURL)
+	result, err := viaBrowser()
+	if plain != nil {
+		if err != nil {
+			log.Printf("Browser fetch failed for %s: %v", pageURL, err)
+			return *plain, nil
+		}
+		if !better(result, *plain) {
+			return *plain, nil
+		}
+	}
 	if err != nil {
-		return ExtractedContent{}, err
+		var zero T
+		return zero, err
 	}
 
 	// Auto-learn: mark resource for browser extraction on future fetches
-	if !useBrowser {
+	if !useBrowser && (plain == nil || !weak(result)) {
 		resource.Set("use_browser", true)
 		if saveErr := app.Save(resource); saveErr != nil {
 			log.Printf("Failed to set use_browser for resource %s: %v", resource.Id, saveErr)
@@ -73,7 +112,7 @@ func extractWithBrowserFallback(app core.App, resource *core.Record, articleURL
 		log.Printf("Marked resource %q for browser extraction", resource.GetString("name"))
 	}
 
-	return extracted, nil
+	return result, nil
 }
 
 // BrowserFetchBodyFunc fetches a URL using a headless browser and returns the
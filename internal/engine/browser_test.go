@@ -39,7 +39,7 @@ func TestExtractWithBrowserFallback_PlainHTTPSuccess(t *testing.T) {
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html")
-		w.Write([]byte(testArticleHTML))
+		w.Write([]byte(`<html><head><title>Design notes</title></head><body><article>` + articleParagraphs(10, "caching") + `</article></body></html>`))
 	}))
 	defer server.Close()
 
@@ -283,7 +283,7 @@ func generateClaimedDailyNewsJob(app core.App, job *core.Record, now time.Time)
 		return FailDailyNewsRegeneration(app, job.Id, err.Error(), now)
 	}
 	stopHeartbeat := startDailyNewsHeartbeat(app, job.Id)
-	result, err := GenerateDailyNewsDigest(app, DailyNewsGenerateInput{APIKey: apiKey, Model: ai.GetModel(app), Window: window, Candidates: candidates, ExtraInstructions: settings.GetString("extra_instructions"), SourceNames: sourceNames})
+	result, err := GenerateDailyNewsDigest(app, DailyNewsGenerateInput{APIKey: apiKey, Model: ai.GetModel(app), Window: window, Candidates: candidates, ExtraInstructions: settings.GetString("extra_instructions"), SourceNames: sourceNames, RankBy: settings.GetString("rank_by"), Language: ai.UserLanguagePreference(app, job.GetString("user")).Language})
 	stopHeartbeat()
 	if err != nil {
 		return FailDailyNewsRegeneration(app, job.Id, err.Error(), now)
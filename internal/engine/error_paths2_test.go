@@ -34,8 +34,7 @@ func TestFetchSingleResource_RecordFailureError(t *testing.T) {
 
 	// Delete entries collection first (FK reference), then resources collection
 	// This makes RecordFailure's app.Save fail because the collection is gone
-	entriesCol, _ := app.FindCollectionByNameOrId("entries")
-	app.Delete(entriesCol)
+	testutil.DeleteEntriesCollection(t, app)
 	resCol, _ := app.FindCollectionByNameOrId("resources")
 	app.Delete(resCol)
 
@@ -67,8 +66,7 @@ func TestFetchSingleResource_RecordSuccessError(t *testing.T) {
 	defer func() { DefaultHTTPClient = origClient }()
 
 	// Delete entries + resources collections so RecordSuccess's app.Save fails
-	entriesCol, _ := app.FindCollectionByNameOrId("entries")
-	app.Delete(entriesCol)
+	testutil.DeleteEntriesCollection(t, app)
 	resCol, _ := app.FindCollectionByNameOrId("resources")
 	app.Delete(resCol)
 
@@ -308,8 +306,7 @@ func TestFetchRSSResource_FragmentFeed_CreateEntryError(t *testing.T) {
 	defer func() { DefaultHTTPClient = origClient }()
 
 	// Delete entries collection so createEntry fails
-	col, _ := app.FindCollectionByNameOrId("entries")
-	app.Delete(col)
+	testutil.DeleteEntriesCollection(t, app)
 
 	// Should not panic, just log errors
 	err := fetchRSSResource(app, resource, feedServer.Client())
@@ -353,8 +350,7 @@ func TestFetchWatchlistResource_CreateEntryError(t *testing.T) {
 	defer func() { BrowserExtractFunc = origBrowser }()
 
 	// Delete entries collection so createEntry fails
-	col, _ := app.FindCollectionByNameOrId("entries")
-	app.Delete(col)
+	testutil.DeleteEntriesCollection(t, app)
 
 	err := fetchWatchlistResource(app, resource, pageServer.Client())
 	// Should log error but not return it (individual entry errors are logged)
@@ -385,8 +381,7 @@ func TestFetchRSSResource_CreateEntryError(t *testing.T) {
 	defer func() { DefaultHTTPClient = origClient }()
 
 	// Delete entries collection so createEntry fails
-	col, _ := app.FindCollectionByNameOrId("entries")
-	app.Delete(col)
+	testutil.DeleteEntriesCollection(t, app)
 
 	err := fetchRSSResource(app, resource, feedServer.Client())
 	// Error is logged but not returned (individual entry errors)
@@ -19,13 +19,7 @@ func TestFetchAllResources_DBError(t *testing.T) {
 	defer cleanup()
 
 	// Delete entries first (has FK to resources), then resources
-	entriesCol, err := app.FindCollectionByNameOrId("entries")
-	if err != nil {
-		t.Fatalf("finding entries collection: %v", err)
-	}
-	if err := app.Delete(entriesCol); err != nil {
-		t.Fatalf("deleting entries collection: %v", err)
-	}
+	testutil.DeleteEntriesCollection(t, app)
 
 	col, err := app.FindCollectionByNameOrId("resources")
 	if err != nil {
@@ -49,13 +43,7 @@ func TestRetryFailedEntries_DBError(t *testing.T) {
 	defer cleanup()
 
 	// Delete entries collection
-	col, err := app.FindCollectionByNameOrId("entries")
-	if err != nil {
-		t.Fatalf("finding collection: %v", err)
-	}
-	if err := app.Delete(col); err != nil {
-		t.Fatalf("deleting collection: %v", err)
-	}
+	testutil.DeleteEntriesCollection(t, app)
 
 	s := NewSchedulerWithInterval(app, 1*time.Hour)
 	// Should not panic, should log error and return
@@ -71,15 +59,9 @@ func TestLoadExistingFragEntries_DBError(t *testing.T) {
 	app, cleanup := testutil.NewTestApp(t)
 	defer cleanup()
 
-	col, err := app.FindCollectionByNameOrId("entries")
-	if err != nil {
-		t.Fatalf("finding collection: %v", err)
-	}
-	if err := app.Delete(col); err != nil {
-		t.Fatalf("deleting collection: %v", err)
-	}
+	testutil.DeleteEntriesCollection(t, app)
 
-	_, err = loadExistingFragEntries(app, "nonexistent-resource")
+	_, err := loadExistingFragEntries(app, "nonexistent-resource")
 	if err == nil {
 		t.Error("expected error when entries collection is deleted")
 	}
@@ -93,15 +75,9 @@ func TestLoadExistingGUIDs_DBError(t *testing.T) {
 	app, cleanup := testutil.NewTestApp(t)
 	defer cleanup()
 
-	col, err := app.FindCollectionByNameOrId("entries")
-	if err != nil {
-		t.Fatalf("finding collection: %v", err)
-	}
-	if err := app.Delete(col); err != nil {
-		t.Fatalf("deleting collection: %v", err)
-	}
+	testutil.DeleteEntriesCollection(t, app)
 
-	_, err = loadExistingGUIDs(app, "nonexistent-resource")
+	_, err := loadExistingGUIDs(app, "nonexistent-resource")
 	if err == nil {
 		t.Error("expected error when entries collection is deleted")
 	}
@@ -115,15 +91,9 @@ func TestCreateEntry_CollectionMissing(t *testing.T) {
 	app, cleanup := testutil.NewTestApp(t)
 	defer cleanup()
 
-	col, err := app.FindCollectionByNameOrId("entries")
-	if err != nil {
-		t.Fatalf("finding collection: %v", err)
-	}
-	if err := app.Delete(col); err != nil {
-		t.Fatalf("deleting collection: %v", err)
-	}
+	testutil.DeleteEntriesCollection(t, app)
 
-	err = createEntry(app, "fake-resource", "Test", "https://example.com", "guid", "content", nil, false)
+	err := createEntry(app, "fake-resource", "Test", "https://example.com", "guid", "content", nil, false)
 	if err == nil {
 		t.Error("expected error when entries collection is missing")
 	}
@@ -137,16 +107,10 @@ func TestDeduplicateLinks_DBError(t *testing.T) {
 	app, cleanup := testutil.NewTestApp(t)
 	defer cleanup()
 
-	col, err := app.FindCollectionByNameOrId("entries")
-	if err != nil {
-		t.Fatalf("finding collection: %v", err)
-	}
-	if err := app.Delete(col); err != nil {
-		t.Fatalf("deleting collection: %v", err)
-	}
+	testutil.DeleteEntriesCollection(t, app)
 
 	links := []ScrapedLink{{Title: "Test", URL: "https://example.com"}}
-	_, err = deduplicateLinks(app, "fake-resource", links)
+	_, err := deduplicateLinks(app, "fake-resource", links)
 	if err == nil {
 		t.Error("expected error when entries collection is missing")
 	}
@@ -171,8 +135,7 @@ func TestScrapeArticleLinks_DeduplicateError(t *testing.T) {
 	app.Save(resource)
 
 	// Delete entries collection to make dedup fail
-	col, _ := app.FindCollectionByNameOrId("entries")
-	app.Delete(col)
+	testutil.DeleteEntriesCollection(t, app)
 
 	_, err := ScrapeArticleLinks(app, resource, server.Client())
 	if err == nil {
@@ -203,8 +166,7 @@ func TestFetchRSS_LoadGUIDsError(t *testing.T) {
 	defer func() { DefaultHTTPClient = origClient }()
 
 	// Delete entries collection to trigger loadExistingGUIDs error
-	col, _ := app.FindCollectionByNameOrId("entries")
-	app.Delete(col)
+	testutil.DeleteEntriesCollection(t, app)
 
 	_, err := FetchRSS(app, resource, feedServer.Client())
 	if err == nil {
@@ -19,7 +19,8 @@ var maxConcurrentAI = make(chan struct{}, 5)
 var PanicCount atomic.Int64
 
 // FetchResource fetches new entries for a resource and creates them in the DB.
-// It handles both RSS feeds and watchlist (scraper) resources.
+// It handles RSS feeds, JSON APIs, watchlist (scraper), sitemap and page watch
+// resources. Newsletter resources are push-based and fed by the SMTP server.
 func FetchResource(app core.App, resource *core.Record, client *http.Client) error {
 	resourceType := resource.GetString("type")
 
@@ -28,6 +29,15 @@ func FetchResource(app core.App, resource *core.Record, client *http.Client) err
 		return fetchRSSResource(app, resource, client)
 	case "watchlist":
 		return fetchWatchlistResource(app, resource, client)
+	case "sitemap":
+		return fetchSitemapResource(app, resource, client)
+	case "pagewatch":
+		return fetchPageWatchResource(app, resource, client)
+	case "json":
+		return fetchJSONResource(app, resource, client)
+	case "newsletter":
+		// Newsletters are pushed in over SMTP; there is nothing to poll.
+		return nil
 	default:
 		log.Printf("Unknown resource type: %s for resource %s", resourceType, resource.Id)
 		return nil
@@ -39,7 +49,21 @@ func fetchRSSResource(app core.App, resource *core.Record, client *http.Client)
 	if err != nil {
 		return err
 	}
+	return createFeedEntries(app, resource, entries, client)
+}
+
+func fetchJSONResource(app core.App, resource *core.Record, client *http.Client) error {
+	entries, err := FetchJSONItems(app, resource, client)
+	if err != nil {
+		return err
+	}
+	return createFeedEntries(app, resource, entries, client)
+}
 
+// createFeedEntries creates entries for new feed items. Fragment feeds are
+// split into one entry per fragment; items with thin content get their
+// article extracted from the item URL.
+func createFeedEntries(app core.App, resource *core.Record, entries []RSSEntry, client *http.Client) error {
 	isFragment := resource.GetBool("fragment_feed")
 
 	// For fragment feeds, pre-load state for per-fragment dedup and time detection
@@ -80,21 +104,7 @@ func fetchRSSResource(app core.App, resource *core.Record, client *http.Client)
 
 			content = resolveContentLinks(content, entry.URL)
 
-			var fragments []Fragment
-			fragMode := resource.GetString("fragment_mode")
-			fragSep := resource.GetString("fragment_separator")
-
-			if fragMode == "separated" && fragSep != "" {
-				fragments = SplitFragmentsBySeparator(content, fragSep)
-			} else {
-				apiKey, _ := ai.GetAPIKey(app)
-				model := ai.GetModel(app)
-				if apiKey != "" {
-					fragments = SplitFragmentsWithAI(content, apiKey, model)
-				} else {
-					fragments = SplitFragments(content)
-				}
-			}
+			fragments := splitResourceFragments(app, resource, content)
 
 			for _, frag := range fragments {
 				guid := FragmentGUID(entry.GUID, frag.HTML)
@@ -119,17 +129,31 @@ func fetchRSSResource(app core.App, resource *core.Record, client *http.Client)
 			continue
 		}
 
+		// Podcast and video items: summarize the transcript instead of the show notes
+		hasTranscript := false
+		if entry.Media != nil && entry.Media.TranscriptURL != "" {
+			transcript, err := FetchTranscript(entry.Media.TranscriptURL, entry.Media.TranscriptType, client)
+			if err != nil {
+				log.Printf("Failed to fetch transcript for %s: %v", entry.GUID, err)
+			} else {
+				content = transcriptContent(transcript, content)
+				hasTranscript = true
+			}
+		}
+
 		// If the feed provided no meaningful content, fetch the article directly
-		if isThinContent(content) && entry.URL != "" {
+		var article *ExtractedContent
+		if !hasTranscript && isThinContent(content) && entry.URL != "" {
 			extracted, err := extractWithBrowserFallback(app, resource, entry.URL, client)
 			if err != nil {
 				log.Printf("Failed to extract content for %s: %v", entry.URL, err)
 			} else if extracted.Content != "" {
 				content = extracted.Content
+				article = &extracted
 			}
 		}
 
-		if err := createEntry(app, resource.Id, entry.Title, entry.URL, entry.GUID, content, entry.PublishedAt, false); err != nil {
+		if err := createFeedEntry(app, resource.Id, entry, content, article); err != nil {
 			log.Printf("Failed to create entry %s: %v", entry.URL, err)
 		}
 	}
@@ -144,6 +168,22 @@ func fetchRSSResource(app core.App, resource *core.Record, client *http.Client)
 	return nil
 }
 
+// createFeedEntry creates an entry for a feed item, keeping its media
+// enclosure and, when the article was extracted, its structured content.
+func createFeedEntry(app core.App, resourceID string, entry RSSEntry, content string, article *ExtractedContent) error {
+	record, err := newEntryRecord(app, resourceID, entry.Title, entry.URL, entry.GUID, content, entry.PublishedAt, false)
+	if err != nil {
+		return err
+	}
+	if entry.Media != nil {
+		record.Set("media", entry.Media)
+	}
+	if article != nil {
+		SetExtractedFields(record, *article)
+	}
+	return saveAndProcessEntry(app, record)
+}
+
 // isThinContent returns true when RSS feed content is too minimal to summarize.
 func isThinContent(content string) bool {
 	return len(strings.TrimSpace(content)) < 200
@@ -186,12 +226,32 @@ func fetchWatchlistResource(app core.App, resource *core.Record, client *http.Cl
 	if err != nil {
 		return err
 	}
+	createLinkEntries(app, resource, links, client)
+	return nil
+}
+
+func fetchSitemapResource(app core.App, resource *core.Record, client *http.Client) error {
+	links, horizon, err := fetchSitemapLinks(app, resource, client)
+	if err != nil {
+		return err
+	}
+	createLinkEntries(app, resource, links, client)
 
+	resource.Set("sitemap_since", horizon)
+	if err := app.Save(resource); err != nil {
+		log.Printf("Failed to save sitemap horizon for resource %s: %v", resource.Id, err)
+	}
+	return nil
+}
+
+// createLinkEntries extracts the full content of each discovered link and
+// creates an entry for it.
+func createLinkEntries(app core.App, resource *core.Record, links []ScrapedLink, client *http.Client) {
 	for _, link := range links {
 		// Resource may have been deleted while we were fetching content
 		if _, err := app.FindRecordById("resources", resource.Id); err != nil {
 			log.Printf("Resource %s was deleted during fetch, stopping", resource.Id)
-			return nil
+			return
 		}
 
 		// Extract full content for each discovered article
@@ -206,19 +266,31 @@ func fetchWatchlistResource(app core.App, resource *core.Record, client *http.Cl
 			title = link.Title
 		}
 
-		if err := createEntry(app, resource.Id, title, link.URL, link.URL, extracted.Content, nil, false); err != nil {
+		record, err := newEntryRecord(app, resource.Id, title, link.URL, link.URL, extracted.Content, link.PublishedAt, false)
+		if err == nil {
+			SetExtractedFields(record, extracted)
+			err = saveAndProcessEntry(app, record)
+		}
+		if err != nil {
 			log.Printf("Failed to create entry %s: %v", link.URL, err)
 		}
 	}
-
-	return nil
 }
 
 func createEntry(app core.App, resourceID, title, entryURL, guid, content string, publishedAt *time.Time, isFragment bool) error {
-	collection, err := app.FindCollectionByNameOrId("entries")
+	record, err := newEntryRecord(app, resourceID, title, entryURL, guid, content, publishedAt, isFragment)
 	if err != nil {
 		return err
 	}
+	return saveAndProcessEntry(app, record)
+}
+
+// newEntryRecord builds an unsaved pending entry record.
+func newEntryRecord(app core.App, resourceID, title, entryURL, guid, content string, publishedAt *time.Time, isFragment bool) (*core.Record, error) {
+	collection, err := app.FindCollectionByNameOrId("entries")
+	if err != nil {
+		return nil, err
+	}
 
 	record := core.NewRecord(collection)
 	record.Set("resource", resourceID)
@@ -235,10 +307,20 @@ func createEntry(app core.App, resourceID, title, entryURL, guid, content string
 		record.Set("published_at", publishedAt.Format(time.RFC3339))
 	}
 
+	return record, nil
+}
+
+// saveAndProcessEntry persists a new entry and queues it for AI processing.
+func saveAndProcessEntry(app core.App, record *core.Record) error {
 	if err := app.Save(record); err != nil {
 		return err
 	}
 
+	// Fragments only need a rating, so they are scored several per call.
+	if record.GetBool("is_fragment") && fragmentBatcher.enqueue(app, record) {
+		return nil
+	}
+
 	// Trigger AI processing in the background (bounded concurrency)
 	maxConcurrentAI <- struct{}{}
 	go func() {
@@ -257,25 +339,47 @@ func processEntry(app core.App, record *core.Record) {
 		}
 	}()
 
+	// The local relevance model may rate the entry itself or route it to a
+	// cheaper model when it is confidently below the configured threshold.
+	source, cheapModel := relevanceGate(app, record)
+	record.Set("score_source", source)
+	record.Set("detected_language", ai.DetectEntryLanguage(record))
+
 	var err error
-	if record.GetBool("is_fragment") {
+	switch {
+	case source == ScoreSourceLocal:
+		err = scoreLocally(app, record)
+	case source == ScoreSourceCheapModel && record.GetBool("is_fragment"):
+		err = ai.ScoreOnlyWithModel(app, record, cheapModel)
+	case source == ScoreSourceCheapModel:
+		err = ai.SummarizeAndScoreWithModel(app, record, cheapModel)
+	case record.GetBool("is_fragment"):
 		err = ai.ScoreOnly(app, record)
-	} else {
+	default:
 		err = ai.SummarizeAndScore(app, record)
 	}
 	if err != nil {
-		log.Printf("AI processing failed for entry %s: %v", record.Id, err)
-		record.Set("processing_status", "failed")
-		if saveErr := app.Save(record); saveErr != nil {
-			log.Printf("Failed to update processing_status: %v", saveErr)
-		}
+		markProcessingFailed(app, record, err)
 		return
 	}
 
+	// Translation failures are logged and leave the original summary.
+	_ = ai.TranslateForPreferredLanguages(app, record)
+
 	// Check if preference regeneration is needed
 	ai.CheckAndRegeneratePreferences(app)
 }
 
+// markProcessingFailed records a failed AI processing attempt so the
+// scheduler retries the entry.
+func markProcessingFailed(app core.App, record *core.Record, err error) {
+	log.Printf("AI processing failed for entry %s: %v", record.Id, err)
+	record.Set("processing_status", "failed")
+	if saveErr := app.Save(record); saveErr != nil {
+		log.Printf("Failed to update processing_status: %v", saveErr)
+	}
+}
+
 // existingFragEntry holds metadata about an existing fragment entry for similarity matching.
 type existingFragEntry struct {
 	id          string
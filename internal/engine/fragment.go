@@ -171,6 +171,10 @@ func hasSeparatorBlock(blocks *goquery.Selection, separator string) bool {
 // the LLM to re-group fragments that belong to the same topic.
 // Falls back to the heuristic result on any AI error.
 func SplitFragmentsWithAI(html, apiKey, model string) []Fragment {
+	return splitFragmentsWithPrompt(html, apiKey, model, ai.BuiltinPrompt(ai.PromptFragmentGrouping))
+}
+
+func splitFragmentsWithPrompt(html, apiKey, model string, tmpl ai.PromptTemplate) []Fragment {
 	initial := SplitFragments(html)
 	if len(initial) <= 1 {
 		return initial
@@ -179,17 +183,11 @@ func SplitFragmentsWithAI(html, apiKey, model string) []Fragment {
 	// Build a numbered text preview for the LLM
 	var sb strings.Builder
 	for i, f := range initial {
-		text := extractText(f.HTML)
-		if len(text) > 300 {
-			text = text[:300] + "..."
-		}
+		text := ai.TruncateUTF8(extractText(f.HTML), 300)
 		sb.WriteString(fmt.Sprintf("[%d] %s\n", i, text))
 	}
 
-	prompt := fmt.Sprintf(`These numbered blocks are extracted from a blog post that contains multiple short topics/moments. Group consecutive blocks that belong to the same topic into fragments. A commentary paragraph about a preceding quote belongs with that quote.
-
-%s
-Return JSON only: {"groups": [[0, 1], [2], ...]}`, sb.String())
+	prompt := ai.RenderPrompt(tmpl, ai.FragmentPromptData{Blocks: sb.String()})
 
 	response, err := callFragmentComplete(apiKey, model, []ai.Message{
 		{Role: "system", Content: "You group content blocks into coherent fragments. Always respond with valid JSON."},
@@ -209,6 +207,24 @@ Return JSON only: {"groups": [[0, 1], [2], ...]}`, sb.String())
 	return mergeFragments(initial, groups)
 }
 
+// splitResourceFragments splits content using the resource's fragment settings:
+// the configured separator in "separated" mode, otherwise the heuristic
+// splitter refined by AI grouping when an API key is configured.
+func splitResourceFragments(app core.App, resource *core.Record, content string) []Fragment {
+	fragMode := resource.GetString("fragment_mode")
+	fragSep := resource.GetString("fragment_separator")
+
+	if fragMode == "separated" && fragSep != "" {
+		return SplitFragmentsBySeparator(content, fragSep)
+	}
+
+	apiKey, _ := ai.GetAPIKey(app)
+	if apiKey != "" {
+		return splitFragmentsWithPrompt(content, apiKey, ai.GetModel(app), ai.ActivePrompt(app, ai.PromptFragmentGrouping))
+	}
+	return SplitFragments(content)
+}
+
 // parseFragmentGroups parses the LLM response into groups of indices.
 func parseFragmentGroups(response string, maxIndex int) ([][]int, error) {
 	response = strings.TrimSpace(response)
@@ -334,41 +350,7 @@ func saveFragmentHashes(app core.App, resource *core.Record, hashes map[string]s
 // titleSimilarity returns the word-level Jaccard similarity between two titles.
 // Returns a value between 0.0 (no overlap) and 1.0 (identical words).
 func titleSimilarity(a, b string) float64 {
-	wordsA := titleWords(a)
-	wordsB := titleWords(b)
-	if len(wordsA) == 0 && len(wordsB) == 0 {
-		return 1.0
-	}
-	if len(wordsA) == 0 || len(wordsB) == 0 {
-		return 0.0
-	}
-
-	intersection := 0
-	for w := range wordsA {
-		if wordsB[w] {
-			intersection++
-		}
-	}
-
-	union := len(wordsA)
-	for w := range wordsB {
-		if !wordsA[w] {
-			union++
-		}
-	}
-
-	return float64(intersection) / float64(union)
-}
-
-func titleWords(s string) map[string]bool {
-	words := make(map[string]bool)
-	for _, w := range strings.Fields(strings.ToLower(s)) {
-		w = strings.TrimRight(w, ".,;:!?\"'")
-		if w != "" {
-			words[w] = true
-		}
-	}
-	return words
+	return ai.TitleSimilarity(a, b)
 }
 
 // resolveContentLinks resolves relative href and src attributes in HTML content
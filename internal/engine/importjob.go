@@ -0,0 +1,295 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Import job statuses.
+const (
+	ImportJobPending = "pending"
+	ImportJobRunning = "running"
+	ImportJobDone    = "done"
+	ImportJobFailed  = "failed"
+)
+
+// Per-item import results.
+const (
+	importItemAdded   = "added"
+	importItemSkipped = "skipped"
+	importItemFailed  = "failed"
+)
+
+// ImportJobItem is an import item with its processing result, as stored in
+// the job's items field.
+type ImportJobItem struct {
+	ImportItem
+	Status string `json:"status,omitempty"` // added, skipped or failed; empty while queued
+	Error  string `json:"error,omitempty"`
+	Entry  string `json:"entry,omitempty"` // ID of the created entry
+}
+
+// A job's items are saved every jobItemsSaveEvery items or
+// jobItemsSaveInterval, whichever comes first, and when the job ends. Its
+// counters are saved after every item.
+const (
+	jobItemsSaveEvery    = 10
+	jobItemsSaveInterval = 5 * time.Second
+)
+
+var (
+	runningImportsMu sync.Mutex
+	runningImports   = map[string]bool{}
+)
+
+// CreateImportJob stores a pending import job for the given items.
+func CreateImportJob(app core.App, source string, items []ImportItem) (*core.Record, error) {
+	if len(items) == 0 {
+		return nil, errors.New("no links to import")
+	}
+	collection, err := app.FindCollectionByNameOrId("import_jobs")
+	if err != nil {
+		return nil, err
+	}
+	jobItems := make([]ImportJobItem, len(items))
+	for i, item := range items {
+		jobItems[i] = ImportJobItem{ImportItem: item}
+	}
+
+	job := core.NewRecord(collection)
+	job.Set("source", source)
+	job.Set("status", ImportJobPending)
+	job.Set("items", jobItems)
+	job.Set("total", len(items))
+	job.Set("processed", 0)
+	job.Set("added", 0)
+	job.Set("skipped", 0)
+	job.Set("failed", 0)
+	if err := app.Save(job); err != nil {
+		return nil, fmt.Errorf("saving import job: %w", err)
+	}
+	return job, nil
+}
+
+// StartImportJob runs an import job in the background unless it is already
+// running.
+func StartImportJob(app core.App, jobID string, client *http.Client) {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				PanicCount.Add(1)
+				log.Printf("PANIC in import job %s: %v\n%s", jobID, r, debug.Stack())
+			}
+		}()
+		if err := RunImportJob(app, jobID, client); err != nil {
+			log.Printf("Import job %s failed: %v", jobID, err)
+		}
+	}()
+}
+
+// ResumeImportJobs restarts import jobs that were queued or interrupted
+// (e.g. by a restart). Items already processed are not repeated.
+func ResumeImportJobs(app core.App, client *http.Client) int {
+	jobs, err := app.FindRecordsByFilter("import_jobs", "status = 'pending' || status = 'running'", "created", 0, 0)
+	if err != nil {
+		return 0
+	}
+	for _, job := range jobs {
+		StartImportJob(app, job.Id, client)
+	}
+	return len(jobs)
+}
+
+// RunImportJob processes the remaining items of a job in order. Each link
+// is skipped when an entry with that URL exists; otherwise its content is
+// extracted, an entry is created under the Quick Add resource (keeping the
+// export's saved date and tags) and summarized and scored. The counters are
+// saved after every item and the items periodically; items processed again
+// after an interruption are not counted twice.
+func RunImportJob(app core.App, jobID string, client *http.Client) error {
+	runningImportsMu.Lock()
+	if runningImports[jobID] {
+		runningImportsMu.Unlock()
+		return nil
+	}
+	runningImports[jobID] = true
+	runningImportsMu.Unlock()
+	defer func() {
+		runningImportsMu.Lock()
+		delete(runningImports, jobID)
+		runningImportsMu.Unlock()
+	}()
+
+	job, err := app.FindRecordById("import_jobs", jobID)
+	if err != nil {
+		return err
+	}
+	var items []ImportJobItem
+	if err := json.Unmarshal([]byte(job.GetString("items")), &items); err != nil {
+		return failImportJob(app, job, fmt.Errorf("invalid items: %w", err))
+	}
+
+	resource, err := app.FindFirstRecordByFilter("resources", "type = 'quickadd'", nil)
+	if err != nil {
+		return failImportJob(app, job, errors.New("Quick Add resource not found"))
+	}
+
+	statuses := make([]string, len(items))
+	for i, item := range items {
+		statuses[i] = item.Status
+	}
+	progress := newJobProgress(app, job, statuses, importItemAdded, importItemSkipped, importItemFailed)
+	job.Set("status", ImportJobRunning)
+	if err := app.Save(job); err != nil {
+		return err
+	}
+
+	for i := range items {
+		if items[i].Status != "" {
+			continue
+		}
+		importItem(app, resource.Id, &items[i], client)
+		if err := progress.itemDone(items, items[i].Status); err != nil {
+			return fmt.Errorf("saving import progress: %w", err)
+		}
+	}
+
+	job.Set("status", ImportJobDone)
+	job.Set("finished_at", time.Now().UTC())
+	return progress.saveItems(items)
+}
+
+// importItem adds one link, recording the result on the item.
+func importItem(app core.App, resourceID string, item *ImportJobItem, client *http.Client) {
+	if existing, err := app.FindFirstRecordByFilter("entries", "url = {:url}", map[string]any{"url": item.URL}); err == nil {
+		item.Status = importItemSkipped
+		item.Entry = existing.Id
+		return
+	}
+
+	extracted, err := ExtractContent(item.URL, client)
+	if err != nil {
+		item.Status = importItemFailed
+		item.Error = err.Error()
+		return
+	}
+
+	title := extracted.Title
+	if title == "" || title == item.URL {
+		title = item.Title
+	}
+	if title == "" {
+		title = item.URL
+	}
+
+	savedAt := time.Now().UTC()
+	if item.SavedAt != nil {
+		savedAt = *item.SavedAt
+	}
+	record, err := newEntryRecord(app, resourceID, title, item.URL, item.URL, extracted.Content, &savedAt, false)
+	if err != nil {
+		item.Status = importItemFailed
+		item.Error = err.Error()
+		return
+	}
+	record.Set("discovered_at", savedAt.Format(time.RFC3339))
+	if len(item.Tags) > 0 {
+		record.Set("tags", item.Tags)
+	}
+	SetExtractedFields(record, extracted)
+	if err := app.Save(record); err != nil {
+		item.Status = importItemFailed
+		item.Error = err.Error()
+		return
+	}
+
+	item.Status = importItemAdded
+	item.Entry = record.Id
+
+	// Share the AI concurrency limit with fetching and batch scoring.
+	maxConcurrentAI <- struct{}{}
+	defer func() { <-maxConcurrentAI }()
+	processEntry(app, record)
+}
+
+// jobProgress saves the progress of an import or rescore job without
+// rewriting its (possibly large) items field after every item.
+type jobProgress struct {
+	app      core.App
+	job      *core.Record
+	counters []string
+	unsaved  int // items processed since the items were last saved
+	savedAt  time.Time
+}
+
+// newJobProgress prepares a job for progress saves, resetting its counters
+// from the saved item statuses: counters saved after the last items save
+// would otherwise count the items processed again twice. The counters are
+// named after the statuses they count.
+func newJobProgress(app core.App, job *core.Record, statuses []string, counters ...string) *jobProgress {
+	counts := map[string]int{}
+	processed := 0
+	for _, status := range statuses {
+		if status != "" {
+			counts[status]++
+			processed++
+		}
+	}
+	job.Set("processed", processed)
+	for _, counter := range counters {
+		job.Set(counter, counts[counter])
+	}
+	return &jobProgress{app: app, job: job, counters: counters, savedAt: time.Now()}
+}
+
+// itemDone counts a processed item under its status and saves the counters,
+// and the items when they are due.
+func (p *jobProgress) itemDone(items any, status string) error {
+	p.job.Set("processed", p.job.GetInt("processed")+1)
+	p.job.Set(status, p.job.GetInt(status)+1)
+	p.unsaved++
+	if p.unsaved >= jobItemsSaveEvery || time.Since(p.savedAt) >= jobItemsSaveInterval {
+		return p.saveItems(items)
+	}
+	return p.saveCounters()
+}
+
+// saveItems saves the whole job including its items.
+func (p *jobProgress) saveItems(items any) error {
+	p.job.Set("items", items)
+	if err := p.app.Save(p.job); err != nil {
+		return err
+	}
+	p.unsaved = 0
+	p.savedAt = time.Now()
+	return nil
+}
+
+// saveCounters updates only the counter columns of the job.
+func (p *jobProgress) saveCounters() error {
+	values := dbx.Params{"processed": p.job.GetInt("processed")}
+	for _, counter := range p.counters {
+		values[counter] = p.job.GetInt(counter)
+	}
+	_, err := p.app.DB().Update(p.job.Collection().Name, values, dbx.HashExp{"id": p.job.Id}).Execute()
+	return err
+}
+
+func failImportJob(app core.App, job *core.Record, cause error) error {
+	job.Set("status", ImportJobFailed)
+	job.Set("error", cause.Error())
+	job.Set("finished_at", time.Now().UTC())
+	if err := app.Save(job); err != nil {
+		log.Printf("Failed to save import job %s: %v", job.Id, err)
+	}
+	return cause
+}
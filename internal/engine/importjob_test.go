@@ -0,0 +1,349 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func importArticleServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/article/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><head><title>Article %s</title></head><body><article>
+<h1>Article %s</h1>
+<p>This article explains how the import pipeline keeps saved dates and tags while extracting the content of every link.</p>
+<p>It has enough paragraphs of real text for the readability extraction to pick it up as the main content of the page.</p>
+</article></body></html>`, r.URL.Path, r.URL.Path)
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func mockImportAI(t *testing.T, app core.App) {
+	t.Helper()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	restore := ai.SetCompleteFunc(func(_, _ string, _ []ai.Message) (string, error) {
+		return `{"summary":"Imported summary","stars":3,"takeaways":["point"]}`, nil
+	})
+	t.Cleanup(restore)
+}
+
+func TestRunImportJob(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	mockImportAI(t, app)
+	srv := importArticleServer(t)
+
+	resource := testutil.CreateResource(t, app, "Quick Add", "https://quickadd.local", "quickadd", "healthy", 0, true)
+	testutil.CreateEntry(t, app, resource.Id, "Existing", srv.URL+"/article/existing", srv.URL+"/article/existing")
+
+	saved := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	items := []ImportItem{
+		{URL: srv.URL + "/article/new", Title: "Saved title", SavedAt: &saved, Tags: []string{"go", "reading"}},
+		{URL: srv.URL + "/article/existing"},
+		{URL: srv.URL + "/missing"},
+	}
+	job, err := CreateImportJob(app, ImportPocket, items)
+	if err != nil {
+		t.Fatalf("CreateImportJob: %v", err)
+	}
+	if job.GetString("status") != ImportJobPending || job.GetInt("total") != 3 {
+		t.Fatalf("unexpected new job: status=%s total=%d", job.GetString("status"), job.GetInt("total"))
+	}
+
+	if err := RunImportJob(app, job.Id, srv.Client()); err != nil {
+		t.Fatalf("RunImportJob: %v", err)
+	}
+
+	job, _ = app.FindRecordById("import_jobs", job.Id)
+	if job.GetString("status") != ImportJobDone || job.GetDateTime("finished_at").IsZero() {
+		t.Errorf("expected finished job, got status %s", job.GetString("status"))
+	}
+	if job.GetInt("processed") != 3 || job.GetInt("added") != 1 || job.GetInt("skipped") != 1 || job.GetInt("failed") != 1 {
+		t.Errorf("unexpected counters: processed=%d added=%d skipped=%d failed=%d",
+			job.GetInt("processed"), job.GetInt("added"), job.GetInt("skipped"), job.GetInt("failed"))
+	}
+
+	var results []ImportJobItem
+	if err := json.Unmarshal([]byte(job.GetString("items")), &results); err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Status != "added" || results[1].Status != "skipped" || results[2].Status != "failed" || results[2].Error == "" {
+		t.Errorf("unexpected item results: %+v", results)
+	}
+
+	entry, err := app.FindRecordById("entries", results[0].Entry)
+	if err != nil {
+		t.Fatalf("imported entry not found: %v", err)
+	}
+	if entry.GetString("resource") != resource.Id {
+		t.Errorf("expected entry under the Quick Add resource")
+	}
+	if !entry.GetDateTime("published_at").Time().Equal(saved) || !entry.GetDateTime("discovered_at").Time().Equal(saved) {
+		t.Errorf("expected saved date kept, got published %v discovered %v",
+			entry.GetDateTime("published_at"), entry.GetDateTime("discovered_at"))
+	}
+	var tags []string
+	json.Unmarshal([]byte(entry.GetString("tags")), &tags)
+	if !reflect.DeepEqual(tags, []string{"go", "reading"}) {
+		t.Errorf("unexpected tags: %v", tags)
+	}
+	if entry.GetString("summary") != "Imported summary" || entry.GetString("processing_status") != "done" {
+		t.Errorf("expected entry processed, got status %s summary %q", entry.GetString("processing_status"), entry.GetString("summary"))
+	}
+}
+
+func TestImportItem_WaitsForAISlot(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	srv := importArticleServer(t)
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	called := make(chan struct{}, 1)
+	restore := ai.SetCompleteFunc(func(_, _ string, _ []ai.Message) (string, error) {
+		called <- struct{}{}
+		return `{"summary":"Imported summary","stars":3}`, nil
+	})
+	defer restore()
+	resource := testutil.CreateResource(t, app, "Quick Add", "https://quickadd.local", "quickadd", "healthy", 0, true)
+
+	// Hold every AI slot, as concurrent feed processing would.
+	for i := 0; i < cap(maxConcurrentAI); i++ {
+		maxConcurrentAI <- struct{}{}
+	}
+	item := ImportJobItem{ImportItem: ImportItem{URL: srv.URL + "/article/busy"}}
+	done := make(chan struct{})
+	go func() {
+		importItem(app, resource.Id, &item, srv.Client())
+		close(done)
+	}()
+
+	select {
+	case <-called:
+		t.Fatal("expected the import to wait for a free AI slot")
+	case <-time.After(200 * time.Millisecond):
+	}
+	for i := 0; i < cap(maxConcurrentAI); i++ {
+		<-maxConcurrentAI
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("import did not finish after the AI slots were released")
+	}
+	if item.Status != importItemAdded {
+		t.Errorf("status = %q, want %q", item.Status, importItemAdded)
+	}
+}
+
+func TestRunImportJob_ResumesWhereItStopped(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	mockImportAI(t, app)
+	srv := importArticleServer(t)
+	testutil.CreateResource(t, app, "Quick Add", "https://quickadd.local", "quickadd", "healthy", 0, true)
+
+	job, err := CreateImportJob(app, ImportText, []ImportItem{
+		{URL: srv.URL + "/article/first"},
+		{URL: srv.URL + "/article/second"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Simulate an interrupted run: the first item was already handled.
+	job.Set("status", ImportJobRunning)
+	job.Set("items", []ImportJobItem{
+		{ImportItem: ImportItem{URL: srv.URL + "/article/first"}, Status: "failed", Error: "interrupted"},
+		{ImportItem: ImportItem{URL: srv.URL + "/article/second"}},
+	})
+	job.Set("processed", 1)
+	job.Set("failed", 1)
+	if err := app.Save(job); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RunImportJob(app, job.Id, srv.Client()); err != nil {
+		t.Fatalf("RunImportJob: %v", err)
+	}
+	job, _ = app.FindRecordById("import_jobs", job.Id)
+	if job.GetInt("processed") != 2 || job.GetInt("added") != 1 || job.GetInt("failed") != 1 {
+		t.Errorf("unexpected counters: processed=%d added=%d failed=%d",
+			job.GetInt("processed"), job.GetInt("added"), job.GetInt("failed"))
+	}
+	if _, err := app.FindFirstRecordByFilter("entries", "url = {:url}", map[string]any{"url": srv.URL + "/article/first"}); err == nil {
+		t.Error("expected the already processed item not to be repeated")
+	}
+}
+
+func TestRunImportJob_RecountsUnsavedItems(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	mockImportAI(t, app)
+	srv := importArticleServer(t)
+	testutil.CreateResource(t, app, "Quick Add", "https://quickadd.local", "quickadd", "healthy", 0, true)
+
+	job, err := CreateImportJob(app, ImportText, []ImportItem{
+		{URL: srv.URL + "/article/first"},
+		{URL: srv.URL + "/article/second"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Simulate an interruption after the counters, but not the items, were
+	// saved for the second item.
+	job.Set("status", ImportJobRunning)
+	job.Set("items", []ImportJobItem{
+		{ImportItem: ImportItem{URL: srv.URL + "/article/first"}, Status: "failed", Error: "interrupted"},
+		{ImportItem: ImportItem{URL: srv.URL + "/article/second"}},
+	})
+	job.Set("processed", 2)
+	job.Set("failed", 1)
+	job.Set("added", 1)
+	if err := app.Save(job); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RunImportJob(app, job.Id, srv.Client()); err != nil {
+		t.Fatalf("RunImportJob: %v", err)
+	}
+	job, _ = app.FindRecordById("import_jobs", job.Id)
+	if job.GetInt("processed") != 2 || job.GetInt("added") != 1 || job.GetInt("failed") != 1 {
+		t.Errorf("unexpected counters: processed=%d added=%d failed=%d",
+			job.GetInt("processed"), job.GetInt("added"), job.GetInt("failed"))
+	}
+}
+
+func TestJobProgress_SavesItemsPeriodically(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	urls := make([]ImportItem, jobItemsSaveEvery+1)
+	for i := range urls {
+		urls[i] = ImportItem{URL: fmt.Sprintf("https://example.com/%d", i)}
+	}
+	job, err := CreateImportJob(app, ImportText, urls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var items []ImportJobItem
+	if err := json.Unmarshal([]byte(job.GetString("items")), &items); err != nil {
+		t.Fatal(err)
+	}
+	savedStatuses := func() []string {
+		t.Helper()
+		stored, err := app.FindRecordById("import_jobs", job.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var saved []ImportJobItem
+		if err := json.Unmarshal([]byte(stored.GetString("items")), &saved); err != nil {
+			t.Fatal(err)
+		}
+		var statuses []string
+		for _, item := range saved {
+			if item.Status != "" {
+				statuses = append(statuses, item.Status)
+			}
+		}
+		if stored.GetInt("processed") != stored.GetInt("skipped") {
+			t.Errorf("expected every processed item counted as skipped, got processed=%d skipped=%d",
+				stored.GetInt("processed"), stored.GetInt("skipped"))
+		}
+		return statuses
+	}
+
+	progress := newJobProgress(app, job, make([]string, len(items)), importItemAdded, importItemSkipped, importItemFailed)
+	for i := 0; i < jobItemsSaveEvery-1; i++ {
+		items[i].Status = importItemSkipped
+		if err := progress.itemDone(items, importItemSkipped); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := savedStatuses(); len(got) != 0 {
+		t.Errorf("expected items not saved yet, got %d processed", len(got))
+	}
+
+	items[jobItemsSaveEvery-1].Status = importItemSkipped
+	if err := progress.itemDone(items, importItemSkipped); err != nil {
+		t.Fatal(err)
+	}
+	if got := savedStatuses(); len(got) != jobItemsSaveEvery {
+		t.Errorf("expected %d saved items, got %d", jobItemsSaveEvery, len(got))
+	}
+
+	items[jobItemsSaveEvery].Status = importItemSkipped
+	if err := progress.itemDone(items, importItemSkipped); err != nil {
+		t.Fatal(err)
+	}
+	if err := progress.saveItems(items); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := app.FindRecordById("import_jobs", job.Id)
+	if got := savedStatuses(); len(got) != len(items) || stored.GetInt("processed") != len(items) {
+		t.Errorf("expected all %d items saved, got %d (processed=%d)", len(items), len(got), stored.GetInt("processed"))
+	}
+}
+
+func TestRunImportJob_NoQuickAddResource(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	job, err := CreateImportJob(app, ImportText, []ImportItem{{URL: "https://example.com/a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RunImportJob(app, job.Id, http.DefaultClient); err == nil {
+		t.Fatal("expected error without a Quick Add resource")
+	}
+	job, _ = app.FindRecordById("import_jobs", job.Id)
+	if job.GetString("status") != ImportJobFailed || job.GetString("error") == "" {
+		t.Errorf("expected failed job with error, got %s %q", job.GetString("status"), job.GetString("error"))
+	}
+}
+
+func TestCreateImportJob_NoItems(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	if _, err := CreateImportJob(app, ImportText, nil); err == nil {
+		t.Error("expected error for an empty import")
+	}
+}
+
+func TestResumeImportJobs(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	mockImportAI(t, app)
+	srv := importArticleServer(t)
+	testutil.CreateResource(t, app, "Quick Add", "https://quickadd.local", "quickadd", "healthy", 0, true)
+
+	job, err := CreateImportJob(app, ImportText, []ImportItem{{URL: srv.URL + "/article/resumed"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := ResumeImportJobs(app, srv.Client()); n != 1 {
+		t.Fatalf("expected 1 resumed job, got %d", n)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, _ = app.FindRecordById("import_jobs", job.Id)
+		if job.GetString("status") == ImportJobDone {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if job.GetString("status") != ImportJobDone || job.GetInt("added") != 1 {
+		t.Errorf("expected resumed job to finish, got status %s added %d", job.GetString("status"), job.GetInt("added"))
+	}
+}
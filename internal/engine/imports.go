@@ -0,0 +1,279 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Import sources, as stored on an import job.
+const (
+	ImportText       = "text"
+	ImportPocket     = "pocket"
+	ImportInstapaper = "instapaper"
+	ImportRaindrop   = "raindrop"
+	ImportNetscape   = "netscape"
+)
+
+// ImportSources lists the accepted import sources.
+var ImportSources = []string{ImportText, ImportPocket, ImportInstapaper, ImportRaindrop, ImportNetscape}
+
+// ImportItem is one link to add, with the metadata an export carries.
+type ImportItem struct {
+	URL     string     `json:"url"`
+	Title   string     `json:"title,omitempty"`
+	SavedAt *time.Time `json:"saved_at,omitempty"`
+	Tags    []string   `json:"tags,omitempty"`
+}
+
+// maxImportItems bounds the number of links accepted in one import.
+const maxImportItems = 10000
+
+var textURLRe = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"'\x60]+`)
+
+// ExtractURLs pulls every http(s) URL out of free text (a chat log, an email
+// or a list of links), in order of appearance and without duplicates.
+// Trailing punctuation and unbalanced closing brackets are not part of the
+// URL, so "(see https://example.com/a)." yields https://example.com/a.
+func ExtractURLs(text string) []ImportItem {
+	var items []ImportItem
+	seen := map[string]bool{}
+	for _, raw := range textURLRe.FindAllString(text, -1) {
+		u := trimURLPunctuation(raw)
+		parsed, err := url.Parse(u)
+		if err != nil || parsed.Host == "" || seen[u] {
+			continue
+		}
+		seen[u] = true
+		items = append(items, ImportItem{URL: u})
+		if len(items) >= maxImportItems {
+			break
+		}
+	}
+	return items
+}
+
+func trimURLPunctuation(u string) string {
+	for u != "" {
+		last := u[len(u)-1]
+		switch {
+		case strings.IndexByte(".,;:!?*_~", last) >= 0:
+			u = u[:len(u)-1]
+		case last == ')' && strings.Count(u, "(") < strings.Count(u, ")"),
+			last == ']' && strings.Count(u, "[") < strings.Count(u, "]"),
+			last == '}' && strings.Count(u, "{") < strings.Count(u, "}"):
+			u = u[:len(u)-1]
+		default:
+			return u
+		}
+	}
+	return u
+}
+
+// ParseImport reads a bookmark export. HTML exports (Netscape bookmark
+// files, which Pocket, Raindrop and browsers produce) and CSV exports
+// (Pocket, Instapaper, Raindrop) are detected from the content; source only
+// labels the job.
+func ParseImport(source string, data []byte) ([]ImportItem, error) {
+	if source == ImportText {
+		return ExtractURLs(string(data)), nil
+	}
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF")))
+	if len(trimmed) == 0 {
+		return nil, errors.New("the export is empty")
+	}
+
+	var items []ImportItem
+	var err error
+	if trimmed[0] == '<' {
+		items, err = parseBookmarkHTML(trimmed)
+	} else {
+		items, err = parseBookmarkCSV(trimmed)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no links found in the %s export", source)
+	}
+	return items, nil
+}
+
+// parseBookmarkHTML reads Netscape bookmark files: every <a href> with its
+// ADD_DATE (Pocket: time_added) and TAGS attributes.
+func parseBookmarkHTML(data []byte) ([]ImportItem, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("parsing bookmark HTML: %w", err)
+	}
+	var items []ImportItem
+	seen := map[string]bool{}
+	doc.Find("a[href]").EachWithBreak(func(_ int, a *goquery.Selection) bool {
+		href := strings.TrimSpace(a.AttrOr("href", ""))
+		if !isHTTPURL(href) || seen[href] {
+			return true
+		}
+		seen[href] = true
+		item := ImportItem{URL: href, Title: strings.TrimSpace(a.Text())}
+		for _, attr := range []string{"add_date", "time_added"} {
+			if t := parseImportTime(a.AttrOr(attr, "")); t != nil {
+				item.SavedAt = t
+				break
+			}
+		}
+		item.Tags = splitImportTags(a.AttrOr("tags", ""))
+		items = append(items, item)
+		return len(items) < maxImportItems
+	})
+	return items, nil
+}
+
+// parseBookmarkCSV reads CSV exports by header name:
+//
+//	Pocket:     title,url,time_added,tags,status (tags separated by "|")
+//	Instapaper: URL,Title,Selection,Folder,Timestamp[,Tags]
+//	Raindrop:   id,title,note,excerpt,url,folder,tags,created,...
+//
+// Instapaper and Raindrop folders become tags, except Instapaper's built-in
+// Unread and Archive folders.
+func parseBookmarkCSV(data []byte) ([]ImportItem, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+	col := map[string]int{}
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	urlCol, ok := col["url"]
+	if !ok {
+		return nil, errors.New("CSV export has no url column")
+	}
+	field := func(rec []string, names ...string) string {
+		for _, name := range names {
+			if i, ok := col[name]; ok && i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+		}
+		return ""
+	}
+
+	var items []ImportItem
+	seen := map[string]bool{}
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading CSV: %w", err)
+		}
+		if urlCol >= len(rec) {
+			continue
+		}
+		link := strings.TrimSpace(rec[urlCol])
+		if !isHTTPURL(link) || seen[link] {
+			continue
+		}
+		seen[link] = true
+
+		item := ImportItem{
+			URL:     link,
+			Title:   field(rec, "title"),
+			SavedAt: parseImportTime(field(rec, "time_added", "timestamp", "created")),
+			Tags:    splitImportTags(field(rec, "tags")),
+		}
+		if folder := field(rec, "folder"); folder != "" && !strings.EqualFold(folder, "unread") &&
+			!strings.EqualFold(folder, "archive") && !strings.EqualFold(folder, "unsorted") {
+			item.Tags = appendTag(item.Tags, folder)
+		}
+		items = append(items, item)
+		if len(items) >= maxImportItems {
+			break
+		}
+	}
+	return items, nil
+}
+
+// parseImportTime accepts Unix timestamps (seconds or milliseconds) and
+// RFC 3339 / ISO 8601 dates.
+func parseImportTime(s string) *time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil && n > 0 {
+		if n > 1e12 {
+			n /= 1000
+		}
+		t := time.Unix(n, 0).UTC()
+		return &t
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			t = t.UTC()
+			return &t
+		}
+	}
+	return nil
+}
+
+// splitImportTags splits a tag list: a JSON array (Instapaper), or values
+// separated by "|" (Pocket) or "," (Netscape, Raindrop).
+func splitImportTags(s string) []string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	var parts []string
+	if strings.HasPrefix(s, "[") {
+		if err := json.Unmarshal([]byte(s), &parts); err != nil {
+			parts = nil
+		}
+	}
+	if parts == nil {
+		sep := ","
+		if strings.Contains(s, "|") {
+			sep = "|"
+		}
+		parts = strings.Split(s, sep)
+	}
+	var tags []string
+	for _, p := range parts {
+		tags = appendTag(tags, p)
+	}
+	return tags
+}
+
+// appendTag adds a trimmed, non-empty tag unless it is already present
+// (case-insensitively).
+func appendTag(tags []string, tag string) []string {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return tags
+	}
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return tags
+		}
+	}
+	return append(tags, tag)
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,188 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func importURLs(items []ImportItem) []string {
+	var urls []string
+	for _, item := range items {
+		urls = append(urls, item.URL)
+	}
+	return urls
+}
+
+func TestExtractURLs(t *testing.T) {
+	text := `Worth reading: https://example.com/a, and (see https://example.com/wiki/Go_(language)).
+Also https://example.com/a again, <https://example.com/b> and "https://example.com/c?x=1&y=2".
+Not a link: ftp://example.com/file or example.com/d`
+
+	got := importURLs(ExtractURLs(text))
+	want := []string{
+		"https://example.com/a",
+		"https://example.com/wiki/Go_(language)",
+		"https://example.com/b",
+		"https://example.com/c?x=1&y=2",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractURLs = %v, want %v", got, want)
+	}
+}
+
+func TestExtractURLs_None(t *testing.T) {
+	if items := ExtractURLs("no links here"); len(items) != 0 {
+		t.Errorf("expected no items, got %v", items)
+	}
+}
+
+func TestParseImport_NetscapeHTML(t *testing.T) {
+	data := []byte(`<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<DL><p>
+  <DT><H3>Reading</H3>
+  <DL><p>
+    <DT><A HREF="https://example.com/one" ADD_DATE="1700000000" TAGS="go,databases">First article</A>
+    <DT><A HREF="https://example.com/two">Second article</A>
+    <DT><A HREF="javascript:alert(1)">Bookmarklet</A>
+    <DT><A HREF="https://example.com/one">Duplicate</A>
+  </DL><p>
+</DL><p>`)
+
+	items, err := ParseImport(ImportNetscape, data)
+	if err != nil {
+		t.Fatalf("ParseImport: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %+v", items)
+	}
+	first := items[0]
+	if first.URL != "https://example.com/one" || first.Title != "First article" {
+		t.Errorf("unexpected first item: %+v", first)
+	}
+	if first.SavedAt == nil || !first.SavedAt.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("expected saved date from ADD_DATE, got %v", first.SavedAt)
+	}
+	if !reflect.DeepEqual(first.Tags, []string{"go", "databases"}) {
+		t.Errorf("unexpected tags: %v", first.Tags)
+	}
+	if items[1].SavedAt != nil || items[1].Tags != nil {
+		t.Errorf("expected no date or tags on second item: %+v", items[1])
+	}
+}
+
+func TestParseImport_PocketHTML(t *testing.T) {
+	data := []byte(`<!DOCTYPE html>
+<html><head><title>Pocket Export</title></head><body>
+<h1>Unread</h1>
+<ul>
+<li><a href="https://example.com/pocket" time_added="1690000000" tags="later|research">Pocket article</a></li>
+</ul>
+</body></html>`)
+
+	items, err := ParseImport(ImportPocket, data)
+	if err != nil {
+		t.Fatalf("ParseImport: %v", err)
+	}
+	if len(items) != 1 || items[0].SavedAt == nil || !items[0].SavedAt.Equal(time.Unix(1690000000, 0)) {
+		t.Fatalf("unexpected items: %+v", items)
+	}
+	if !reflect.DeepEqual(items[0].Tags, []string{"later", "research"}) {
+		t.Errorf("unexpected tags: %v", items[0].Tags)
+	}
+}
+
+func TestParseImport_PocketCSV(t *testing.T) {
+	data := []byte("\xEF\xBB\xBFtitle,url,time_added,tags,status\n" +
+		"Pocket one,https://example.com/p1,1700000000,go|rust,unread\n" +
+		"Pocket two,https://example.com/p2,1700000100,,archive\n")
+
+	items, err := ParseImport(ImportPocket, data)
+	if err != nil {
+		t.Fatalf("ParseImport: %v", err)
+	}
+	if !reflect.DeepEqual(importURLs(items), []string{"https://example.com/p1", "https://example.com/p2"}) {
+		t.Fatalf("unexpected items: %+v", items)
+	}
+	if items[0].Title != "Pocket one" || !reflect.DeepEqual(items[0].Tags, []string{"go", "rust"}) {
+		t.Errorf("unexpected first item: %+v", items[0])
+	}
+	if items[1].SavedAt == nil || !items[1].SavedAt.Equal(time.Unix(1700000100, 0)) {
+		t.Errorf("unexpected saved date: %v", items[1].SavedAt)
+	}
+}
+
+func TestParseImport_InstapaperCSV(t *testing.T) {
+	data := []byte(`URL,Title,Selection,Folder,Timestamp,Tags
+https://example.com/i1,"Instapaper, one",,Unread,1700000000,"[""ml"",""papers""]"
+https://example.com/i2,Instapaper two,,Research,1700000200,[]
+`)
+
+	items, err := ParseImport(ImportInstapaper, data)
+	if err != nil {
+		t.Fatalf("ParseImport: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %+v", items)
+	}
+	if items[0].Title != "Instapaper, one" || !reflect.DeepEqual(items[0].Tags, []string{"ml", "papers"}) {
+		t.Errorf("unexpected first item: %+v", items[0])
+	}
+	if !reflect.DeepEqual(items[1].Tags, []string{"Research"}) {
+		t.Errorf("expected folder as tag, got %v", items[1].Tags)
+	}
+}
+
+func TestParseImport_RaindropCSV(t *testing.T) {
+	data := []byte(`id,title,note,excerpt,url,folder,tags,created,cover,highlights,favorite
+1,Raindrop one,,,https://example.com/r1,Unsorted,"design, ux",2023-11-14T22:13:20.000Z,,,false
+`)
+
+	items, err := ParseImport(ImportRaindrop, data)
+	if err != nil {
+		t.Fatalf("ParseImport: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item, got %+v", items)
+	}
+	if !reflect.DeepEqual(items[0].Tags, []string{"design", "ux"}) {
+		t.Errorf("unexpected tags: %v", items[0].Tags)
+	}
+	if items[0].SavedAt == nil || !items[0].SavedAt.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("unexpected saved date: %v", items[0].SavedAt)
+	}
+}
+
+func TestParseImport_Errors(t *testing.T) {
+	if _, err := ParseImport(ImportPocket, []byte("  ")); err == nil {
+		t.Error("expected error for an empty export")
+	}
+	if _, err := ParseImport(ImportPocket, []byte("title,link\nA,https://example.com\n")); err == nil {
+		t.Error("expected error for a CSV without url column")
+	}
+	if _, err := ParseImport(ImportNetscape, []byte("<html><body>No links</body></html>")); err == nil {
+		t.Error("expected error for an export without links")
+	}
+}
+
+func TestParseImportTime(t *testing.T) {
+	cases := map[string]int64{
+		"1700000000":           1700000000,
+		"1700000000000":        1700000000,
+		"2023-11-14T22:13:20Z": 1700000000,
+		"2023-11-14 22:13:20":  1700000000,
+	}
+	for in, want := range cases {
+		got := parseImportTime(in)
+		if got == nil || got.Unix() != want {
+			t.Errorf("parseImportTime(%q) = %v, want %d", in, got, want)
+		}
+	}
+	for _, in := range []string{"", "yesterday", "0"} {
+		if got := parseImportTime(in); got != nil {
+			t.Errorf("parseImportTime(%q) = %v, want nil", in, got)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// maxJSONSourceSize limits how much of a JSON API response is read.
+const maxJSONSourceSize = 20 << 20
+
+// JSONFieldMapping maps entry fields to paths inside each JSON item.
+// Paths use a small JSONPath subset: "$.data.items", "author.name",
+// "links[0].href". Alternatives separated by "|" are tried in order
+// ("body_html|body").
+type JSONFieldMapping struct {
+	Title     string `json:"title"`
+	URL       string `json:"url"`
+	GUID      string `json:"guid"`
+	Content   string `json:"content"`
+	Published string `json:"published"`
+}
+
+// defaultJSONFieldMapping covers the field names used by most APIs
+// (GitHub releases, Hacker News items, typical changelog endpoints).
+var defaultJSONFieldMapping = JSONFieldMapping{
+	Title:     "title|name",
+	URL:       "url|html_url|link|permalink",
+	GUID:      "id|guid|uuid",
+	Content:   "content_html|content|body_html|body|text|description|summary",
+	Published: "published_at|published|created_at|date|time|updated_at",
+}
+
+// FetchJSONItems fetches a JSON API resource and maps its items to feed
+// entries using the resource's json_items_path and json_mapping fields.
+// Items that already exist (by GUID) or are older than 12 months are skipped.
+func FetchJSONItems(app core.App, resource *core.Record, client *http.Client) ([]RSSEntry, error) {
+	apiURL := resource.GetString("url")
+
+	var headers map[string]string
+	if err := unmarshalResourceJSON(resource, "json_headers", &headers); err != nil {
+		return nil, fmt.Errorf("invalid json_headers: %w", err)
+	}
+
+	var custom JSONFieldMapping
+	if err := unmarshalResourceJSON(resource, "json_mapping", &custom); err != nil {
+		return nil, fmt.Errorf("invalid json_mapping: %w", err)
+	}
+	mapping := mergeJSONFieldMapping(defaultJSONFieldMapping, custom)
+
+	base, err := url.Parse(apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %s: %w", apiURL, err)
+	}
+
+	doc, err := fetchJSONDocument(apiURL, headers, client)
+	if err != nil {
+		return nil, err
+	}
+
+	itemsValue, ok := lookupJSONPath(doc, resource.GetString("json_items_path"))
+	if !ok {
+		return nil, fmt.Errorf("items path %q not found in %s", resource.GetString("json_items_path"), apiURL)
+	}
+	items, ok := itemsValue.([]any)
+	if !ok {
+		return nil, fmt.Errorf("items path %q in %s is not an array", resource.GetString("json_items_path"), apiURL)
+	}
+
+	existingGUIDs, err := loadExistingGUIDs(app, resource.Id)
+	if err != nil {
+		return nil, fmt.Errorf("loading existing GUIDs: %w", err)
+	}
+
+	var entries []RSSEntry
+	for _, item := range items {
+		entry, ok := mapJSONItem(item, mapping, base)
+		if !ok || existingGUIDs[entry.GUID] {
+			continue
+		}
+		// Skip items older than 12 months, same as RSS feeds
+		if entry.PublishedAt != nil && time.Since(*entry.PublishedAt) > 365*24*time.Hour {
+			continue
+		}
+		existingGUIDs[entry.GUID] = true
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// unmarshalResourceJSON decodes an optional JSON field; empty fields are left untouched.
+func unmarshalResourceJSON(resource *core.Record, field string, v any) error {
+	raw := strings.TrimSpace(resource.GetString(field))
+	if raw == "" || raw == "null" {
+		return nil
+	}
+	return json.Unmarshal([]byte(raw), v)
+}
+
+// mergeJSONFieldMapping overrides the defaults with every non-empty custom path.
+func mergeJSONFieldMapping(base, custom JSONFieldMapping) JSONFieldMapping {
+	if custom.Title != "" {
+		base.Title = custom.Title
+	}
+	if custom.URL != "" {
+		base.URL = custom.URL
+	}
+	if custom.GUID != "" {
+		base.GUID = custom.GUID
+	}
+	if custom.Content != "" {
+		base.Content = custom.Content
+	}
+	if custom.Published != "" {
+		base.Published = custom.Published
+	}
+	return base
+}
+
+// fetchJSONDocument GETs the URL with the configured headers and decodes the body.
+func fetchJSONDocument(apiURL string, headers map[string]string, client *http.Client) (any, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request for %s: %w", apiURL, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", apiURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d for %s", resp.StatusCode, apiURL)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxJSONSourceSize))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", apiURL, err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parsing JSON from %s: %w", apiURL, err)
+	}
+	return doc, nil
+}
+
+// mapJSONItem converts one JSON item to a feed entry. Items without a URL
+// or GUID are skipped; the URL doubles as GUID when no GUID is mapped.
+func mapJSONItem(item any, mapping JSONFieldMapping, base *url.URL) (RSSEntry, bool) {
+	entry := RSSEntry{
+		Title:   jsonString(item, mapping.Title),
+		URL:     jsonString(item, mapping.URL),
+		GUID:    jsonString(item, mapping.GUID),
+		Content: jsonString(item, mapping.Content),
+	}
+	if entry.URL != "" {
+		entry.URL = resolveURL(base, entry.URL)
+	}
+	if entry.GUID == "" {
+		entry.GUID = entry.URL
+	}
+	if entry.GUID == "" {
+		return RSSEntry{}, false
+	}
+	if entry.Content != "" && !strings.Contains(entry.Content, "<") {
+		entry.Content = plainTextToHTML(entry.Content)
+	}
+	if published, ok := firstJSONValue(item, mapping.Published); ok {
+		entry.PublishedAt = parseJSONTime(published)
+	}
+	return entry, true
+}
+
+// jsonString returns the first path alternative that resolves to a string or number.
+func jsonString(item any, paths string) string {
+	v, ok := firstJSONValue(item, paths)
+	if !ok {
+		return ""
+	}
+	switch val := v.(type) {
+	case string:
+		return strings.TrimSpace(val)
+	case json.Number:
+		return val.String()
+	case bool:
+		return strconv.FormatBool(val)
+	}
+	return ""
+}
+
+// firstJSONValue resolves "|"-separated path alternatives and returns the
+// first non-null, non-empty value.
+func firstJSONValue(item any, paths string) (any, bool) {
+	for _, path := range strings.Split(paths, "|") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		v, ok := lookupJSONPath(item, path)
+		if !ok || v == nil {
+			continue
+		}
+		if s, isString := v.(string); isString && strings.TrimSpace(s) == "" {
+			continue
+		}
+		return v, true
+	}
+	return nil, false
+}
+
+// lookupJSONPath resolves a dotted path with optional array indexes against
+// a decoded JSON value. An empty path or "$" returns the value itself.
+func lookupJSONPath(value any, path string) (any, bool) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+	path = strings.TrimPrefix(path, ".")
+
+	current := value
+	for path != "" {
+		var segment string
+		if strings.HasPrefix(path, "[") {
+			end := strings.Index(path, "]")
+			if end < 0 {
+				return nil, false
+			}
+			segment, path = path[:end+1], path[end+1:]
+		} else {
+			end := strings.IndexAny(path, ".[")
+			if end < 0 {
+				end = len(path)
+			}
+			segment, path = path[:end], path[end:]
+		}
+		path = strings.TrimPrefix(path, ".")
+
+		if strings.HasPrefix(segment, "[") {
+			idx, err := strconv.Atoi(strings.Trim(segment, "[]"))
+			arr, ok := current.([]any)
+			if err != nil || !ok {
+				return nil, false
+			}
+			if idx < 0 {
+				idx += len(arr)
+			}
+			if idx < 0 || idx >= len(arr) {
+				return nil, false
+			}
+			current = arr[idx]
+			continue
+		}
+
+		obj, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		current, ok = obj[segment]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// jsonTimeLayouts are the date formats accepted for the published field.
+var jsonTimeLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// parseJSONTime parses date strings and Unix timestamps (seconds or milliseconds).
+func parseJSONTime(v any) *time.Time {
+	var t time.Time
+	switch val := v.(type) {
+	case json.Number:
+		n, err := val.Int64()
+		if err != nil {
+			return nil
+		}
+		if n > 1e12 {
+			t = time.UnixMilli(n)
+		} else {
+			t = time.Unix(n, 0)
+		}
+	case string:
+		s := strings.TrimSpace(val)
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return parseJSONTime(json.Number(strconv.FormatInt(n, 10)))
+		}
+		parsed := false
+		for _, layout := range jsonTimeLayouts {
+			if p, err := time.Parse(layout, s); err == nil {
+				t, parsed = p, true
+				break
+			}
+		}
+		if !parsed {
+			return nil
+		}
+	default:
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
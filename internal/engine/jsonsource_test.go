@@ -0,0 +1,209 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+)
+
+func TestFetchJSONItems_MappingAndHeaders(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	recent := time.Now().UTC().Add(-2 * time.Hour).Format(time.RFC3339)
+	recentUnix := time.Now().Add(-48 * time.Hour).Unix()
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": {"changes": [
+			{"key": 101, "headline": "Faster sync", "links": [{"href": "/changes/101"}], "notes": "<p>Sync is now twice as fast.</p>", "at": "` + recent + `"},
+			{"key": 102, "headline": "Dark mode", "links": [{"href": "https://example.com/changes/102"}], "notes": "Plain text notes", "at": ` + strconv.FormatInt(recentUnix, 10) + `},
+			{"key": 103, "headline": "Ancient", "links": [{"href": "/changes/103"}], "at": "2001-01-01T00:00:00Z"},
+			{"headline": "No id or link"}
+		]}}`))
+	}))
+	defer server.Close()
+
+	resource := testutil.CreateResource(t, app, "Changelog", server.URL+"/api/changes", "json", "healthy", 0, true)
+	resource.Set("json_items_path", "$.data.changes")
+	resource.Set("json_mapping", `{"title": "headline", "url": "links[0].href", "guid": "key", "content": "notes", "published": "at"}`)
+	resource.Set("json_headers", `{"Authorization": "Bearer secret"}`)
+	if err := app.Save(resource); err != nil {
+		t.Fatalf("failed to update resource: %v", err)
+	}
+
+	entries, err := FetchJSONItems(app, resource, server.Client())
+	if err != nil {
+		t.Fatalf("FetchJSONItems: %v", err)
+	}
+	if gotAuth != "Bearer secret" {
+		t.Errorf("Authorization header = %q", gotAuth)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2: %+v", len(entries), entries)
+	}
+
+	first := entries[0]
+	if first.Title != "Faster sync" || first.GUID != "101" || first.URL != server.URL+"/changes/101" {
+		t.Errorf("first entry = %+v", first)
+	}
+	if first.Content != "<p>Sync is now twice as fast.</p>" {
+		t.Errorf("first content = %q", first.Content)
+	}
+	if first.PublishedAt == nil || first.PublishedAt.Format(time.RFC3339) != recent {
+		t.Errorf("first published = %v, want %s", first.PublishedAt, recent)
+	}
+
+	second := entries[1]
+	if second.Content != "<p>Plain text notes</p>\n" {
+		t.Errorf("plain-text content not wrapped: %q", second.Content)
+	}
+	if second.PublishedAt == nil || !second.PublishedAt.Equal(time.Unix(recentUnix, 0)) {
+		t.Errorf("unix timestamp published = %v", second.PublishedAt)
+	}
+}
+
+func TestFetchJSONItems_DefaultMappingAndDedup(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	// Shaped like the GitHub releases API: a top-level array.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"id": 1, "name": "v1.2.0", "html_url": "https://github.com/o/r/releases/v1.2.0", "body": "Bug fixes"},
+			{"id": 2, "name": "v1.1.0", "html_url": "https://github.com/o/r/releases/v1.1.0", "body": "Features"}
+		]`))
+	}))
+	defer server.Close()
+
+	resource := testutil.CreateResource(t, app, "Releases", server.URL, "json", "healthy", 0, true)
+	testutil.CreateEntry(t, app, resource.Id, "v1.1.0", "https://github.com/o/r/releases/v1.1.0", "2")
+
+	entries, err := FetchJSONItems(app, resource, server.Client())
+	if err != nil {
+		t.Fatalf("FetchJSONItems: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Title != "v1.2.0" || entries[0].GUID != "1" {
+		t.Fatalf("entries = %+v, want only v1.2.0", entries)
+	}
+}
+
+func TestFetchJSONItems_Errors(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		case "/object":
+			w.Write([]byte(`{"items": {"not": "an array"}}`))
+		default:
+			w.Write([]byte(`not json`))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path, itemsPath, want string
+	}{
+		{"/forbidden", "", "HTTP 403"},
+		{"/object", "items", "is not an array"},
+		{"/object", "missing", "not found"},
+		{"/garbage", "", "parsing JSON"},
+	}
+	for _, tt := range tests {
+		resource := testutil.CreateResource(t, app, "Bad", server.URL+tt.path, "json", "healthy", 0, true)
+		resource.Set("json_items_path", tt.itemsPath)
+		app.Save(resource)
+
+		_, err := FetchJSONItems(app, resource, server.Client())
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s (%s): err = %v, want %q", tt.path, tt.itemsPath, err, tt.want)
+		}
+	}
+}
+
+func TestFetchResource_JSON(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	longContent := "<p>" + strings.Repeat("A detailed release note. ", 20) + "</p>"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"items": []map[string]any{
+				{"id": "a", "title": "Release A", "url": "https://example.com/a", "content": longContent},
+			},
+		})
+	}))
+	defer server.Close()
+
+	resource := testutil.CreateResource(t, app, "API", server.URL, "json", "healthy", 0, true)
+	resource.Set("json_items_path", "items")
+	app.Save(resource)
+
+	if err := FetchResource(app, resource, server.Client()); err != nil {
+		t.Fatalf("FetchResource: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	entries, err := app.FindRecordsByFilter("entries", "resource = {:id}", "", 0, 0, map[string]any{"id": resource.Id})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("found %d entries (err %v), want 1", len(entries), err)
+	}
+	if entries[0].GetString("guid") != "a" || entries[0].GetString("raw_content") != longContent {
+		t.Errorf("entry guid=%q content=%q", entries[0].GetString("guid"), entries[0].GetString("raw_content"))
+	}
+}
+
+func TestLookupJSONPath(t *testing.T) {
+	var doc any
+	json.Unmarshal([]byte(`{"a": {"b": [{"c": "first"}, {"c": "last"}]}, "n": null}`), &doc)
+
+	tests := []struct {
+		path string
+		want any
+		ok   bool
+	}{
+		{"$.a.b[0].c", "first", true},
+		{"a.b[-1].c", "last", true},
+		{"a.b[5].c", nil, false},
+		{"a.x", nil, false},
+		{"a.b.c", nil, false},
+		{"n", nil, true},
+	}
+	for _, tt := range tests {
+		got, ok := lookupJSONPath(doc, tt.path)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("lookupJSONPath(%q) = %v, %v; want %v, %v", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+	if got, ok := lookupJSONPath(doc, ""); !ok || got == nil {
+		t.Errorf("empty path should return the document")
+	}
+}
+
+func TestMapJSONItem_Alternatives(t *testing.T) {
+	base, _ := url.Parse("https://example.com/api")
+	var item any
+	json.Unmarshal([]byte(`{"body_html": "", "body": "<b>fallback</b>", "link": "/post/1"}`), &item)
+
+	entry, ok := mapJSONItem(item, defaultJSONFieldMapping, base)
+	if !ok {
+		t.Fatal("expected item to be mapped")
+	}
+	if entry.Content != "<b>fallback</b>" {
+		t.Errorf("content = %q, want fallback alternative", entry.Content)
+	}
+	if entry.URL != "https://example.com/post/1" || entry.GUID != entry.URL {
+		t.Errorf("url=%q guid=%q, want resolved URL used as GUID", entry.URL, entry.GUID)
+	}
+}
@@ -0,0 +1,343 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mmcdole/gofeed"
+)
+
+// maxTranscriptSize limits how much of a transcript file is downloaded.
+const maxTranscriptSize = 10 << 20
+
+// transcriptParagraphChars is the soft length at which transcript text is
+// broken into a new paragraph (at the next sentence end).
+const transcriptParagraphChars = 600
+
+// MediaEnclosure describes the audio or video attached to a feed item.
+// It is stored as JSON in the entries.media field.
+type MediaEnclosure struct {
+	URL             string `json:"url"`
+	Type            string `json:"type,omitempty"`
+	Length          int64  `json:"length,omitempty"`   // bytes, as advertised by the feed
+	DurationSeconds int    `json:"duration,omitempty"` // from itunes:duration
+	Image           string `json:"image,omitempty"`
+	TranscriptURL   string `json:"transcript_url,omitempty"`
+	TranscriptType  string `json:"transcript_type,omitempty"`
+}
+
+// itemMedia extracts the primary audio/video enclosure from a feed item,
+// including the preferred transcript advertised via podcast:transcript.
+// Returns nil when the item has no media enclosure.
+func itemMedia(item *gofeed.Item, feedImage string) *MediaEnclosure {
+	var enclosure *gofeed.Enclosure
+	for _, e := range item.Enclosures {
+		if e == nil || e.URL == "" {
+			continue
+		}
+		if strings.HasPrefix(e.Type, "audio/") || strings.HasPrefix(e.Type, "video/") {
+			enclosure = e
+			break
+		}
+		if enclosure == nil && e.Type == "" {
+			enclosure = e
+		}
+	}
+	if enclosure == nil {
+		return nil
+	}
+
+	media := &MediaEnclosure{URL: enclosure.URL, Type: enclosure.Type}
+	if n, err := strconv.ParseInt(strings.TrimSpace(enclosure.Length), 10, 64); err == nil && n > 0 {
+		media.Length = n
+	}
+
+	if item.ITunesExt != nil {
+		media.DurationSeconds = parseITunesDuration(item.ITunesExt.Duration)
+		media.Image = item.ITunesExt.Image
+	}
+	if media.Image == "" && item.Image != nil {
+		media.Image = item.Image.URL
+	}
+	if media.Image == "" {
+		media.Image = feedImage
+	}
+
+	media.TranscriptURL, media.TranscriptType = itemTranscript(item)
+	return media
+}
+
+// transcriptTypePreference orders transcript formats by how much structure
+// they keep (speakers, paragraphs); lower is better.
+var transcriptTypePreference = map[string]int{
+	"application/json":     0,
+	"text/vtt":             1,
+	"application/x-subrip": 2,
+	"application/srt":      2,
+	"text/srt":             2,
+	"text/html":            3,
+	"text/plain":           4,
+}
+
+// itemTranscript returns the URL and MIME type of the best podcast:transcript
+// element on the item.
+func itemTranscript(item *gofeed.Item) (string, string) {
+	bestURL, bestType, bestRank := "", "", len(transcriptTypePreference)+1
+	for _, e := range item.Extensions["podcast"]["transcript"] {
+		u := strings.TrimSpace(e.Attrs["url"])
+		if u == "" {
+			continue
+		}
+		t := strings.ToLower(strings.TrimSpace(e.Attrs["type"]))
+		rank, ok := transcriptTypePreference[t]
+		if !ok {
+			rank = len(transcriptTypePreference)
+		}
+		if rank < bestRank {
+			bestURL, bestType, bestRank = u, t, rank
+		}
+	}
+	return bestURL, bestType
+}
+
+// parseITunesDuration parses itunes:duration values ("3600", "59:30", "1:02:03").
+func parseITunesDuration(s string) int {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	total := 0
+	for _, part := range strings.Split(s, ":") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 0 {
+			return 0
+		}
+		total = total*60 + n
+	}
+	return total
+}
+
+// FetchTranscript downloads a transcript and converts it to HTML paragraphs.
+// SRT, WebVTT, podcast JSON, HTML and plain-text transcripts are supported;
+// the format is taken from transcriptType or sniffed from the body.
+func FetchTranscript(transcriptURL, transcriptType string, client *http.Client) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, transcriptURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("creating request for %s: %w", transcriptURL, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetching transcript %s: %w", transcriptURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP %d for transcript %s", resp.StatusCode, transcriptURL)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTranscriptSize))
+	if err != nil {
+		return "", fmt.Errorf("reading transcript %s: %w", transcriptURL, err)
+	}
+
+	if transcriptType == "" {
+		transcriptType = resp.Header.Get("Content-Type")
+	}
+	content, err := ParseTranscript(string(body), transcriptType)
+	if err != nil {
+		return "", fmt.Errorf("parsing transcript %s: %w", transcriptURL, err)
+	}
+	return content, nil
+}
+
+// transcriptSegment is a piece of spoken text with an optional speaker.
+type transcriptSegment struct {
+	speaker string
+	text    string
+}
+
+// ParseTranscript converts a transcript in the given MIME type to HTML.
+func ParseTranscript(body, transcriptType string) (string, error) {
+	body = strings.TrimPrefix(strings.ReplaceAll(body, "\r\n", "\n"), "\ufeff")
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(transcriptType, ";")[0]))
+	trimmed := strings.TrimSpace(body)
+
+	var segments []transcriptSegment
+	switch {
+	case mediaType == "application/json" || (mediaType == "" && strings.HasPrefix(trimmed, "{")):
+		var err error
+		if segments, err = parseJSONTranscript(trimmed); err != nil {
+			return "", err
+		}
+	case mediaType == "text/vtt" || strings.HasPrefix(trimmed, "WEBVTT"):
+		segments = parseCueTranscript(trimmed)
+	case strings.Contains(mediaType, "srt") || strings.Contains(mediaType, "subrip"):
+		segments = parseCueTranscript(trimmed)
+	case mediaType == "text/html" || (mediaType == "" && strings.HasPrefix(trimmed, "<")):
+		return cleanTranscriptHTML(trimmed)
+	default:
+		if cueTimingRe.MatchString(trimmed) {
+			segments = parseCueTranscript(trimmed)
+		} else {
+			return plainTextToHTML(trimmed), nil
+		}
+	}
+
+	if len(segments) == 0 {
+		return "", fmt.Errorf("transcript contains no text")
+	}
+	return renderTranscript(segments), nil
+}
+
+// cueTimingRe matches SRT ("00:00:01,000 --> ...") and VTT ("00:01.000 --> ...") timing lines.
+var cueTimingRe = regexp.MustCompile(`(?m)^\s*(\d+:)?\d{1,2}:\d{2}[.,]\d{3}\s+-->\s+`)
+
+// vttVoiceRe matches WebVTT voice spans: <v Speaker Name>.
+var vttVoiceRe = regexp.MustCompile(`<v(?:\.[^ >]*)?\s+([^>]+)>`)
+
+// cueTagRe matches the remaining inline cue tags (<i>, </v>, <00:01.000>, ...).
+var cueTagRe = regexp.MustCompile(`</?[^>]*>`)
+
+// parseCueTranscript extracts the spoken text from SRT or WebVTT cues,
+// dropping headers, cue numbers, timings and NOTE/STYLE blocks.
+func parseCueTranscript(body string) []transcriptSegment {
+	var segments []transcriptSegment
+	for _, block := range strings.Split(body, "\n\n") {
+		lines := strings.Split(strings.TrimSpace(block), "\n")
+		timing := -1
+		for i, line := range lines {
+			if strings.Contains(line, "-->") {
+				timing = i
+				break
+			}
+		}
+		if timing < 0 {
+			continue // header, NOTE, STYLE or REGION block
+		}
+
+		for _, line := range lines[timing+1:] {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			speaker := ""
+			if m := vttVoiceRe.FindStringSubmatch(line); m != nil {
+				speaker = strings.TrimSpace(m[1])
+			}
+			text := strings.TrimSpace(html.UnescapeString(cueTagRe.ReplaceAllString(line, "")))
+			if text == "" {
+				continue
+			}
+			// Continuation lines without a voice tag keep the previous speaker.
+			if speaker == "" && len(segments) > 0 && vttVoiceRe.MatchString(body) {
+				speaker = segments[len(segments)-1].speaker
+			}
+			segments = append(segments, transcriptSegment{speaker: speaker, text: text})
+		}
+	}
+	return segments
+}
+
+// parseJSONTranscript parses the podcast namespace JSON transcript format:
+// {"segments": [{"speaker": "...", "body": "..."}]}.
+func parseJSONTranscript(body string) ([]transcriptSegment, error) {
+	var doc struct {
+		Segments []struct {
+			Speaker string `json:"speaker"`
+			Body    string `json:"body"`
+		} `json:"segments"`
+	}
+	if err := json.Unmarshal([]byte(body), &doc); err != nil {
+		return nil, err
+	}
+	segments := make([]transcriptSegment, 0, len(doc.Segments))
+	for _, s := range doc.Segments {
+		text := strings.TrimSpace(s.Body)
+		if text == "" {
+			continue
+		}
+		segments = append(segments, transcriptSegment{speaker: strings.TrimSpace(s.Speaker), text: text})
+	}
+	return segments, nil
+}
+
+// renderTranscript joins segments into paragraphs, starting a new paragraph
+// when the speaker changes or the paragraph grows long and a sentence ends.
+func renderTranscript(segments []transcriptSegment) string {
+	var sb strings.Builder
+	var para strings.Builder
+	speaker := ""
+
+	flush := func() {
+		if para.Len() == 0 {
+			return
+		}
+		sb.WriteString("<p>")
+		if speaker != "" {
+			sb.WriteString("<strong>")
+			sb.WriteString(html.EscapeString(speaker))
+			sb.WriteString(":</strong> ")
+		}
+		sb.WriteString(html.EscapeString(para.String()))
+		sb.WriteString("</p>\n")
+		para.Reset()
+	}
+
+	for _, seg := range segments {
+		if seg.speaker != speaker {
+			flush()
+			speaker = seg.speaker
+		}
+		if para.Len() > 0 {
+			para.WriteByte(' ')
+		}
+		para.WriteString(seg.text)
+		if para.Len() >= transcriptParagraphChars && strings.ContainsAny(seg.text[len(seg.text)-1:], ".?!") {
+			flush()
+		}
+	}
+	flush()
+	return sb.String()
+}
+
+// cleanTranscriptHTML keeps the body of an HTML transcript without scripts and styles.
+func cleanTranscriptHTML(body string) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	doc.Find("script, style, noscript, nav, header, footer").Remove()
+	if strings.TrimSpace(doc.Find("body").Text()) == "" {
+		return "", fmt.Errorf("transcript contains no text")
+	}
+	cleaned, err := doc.Find("body").Html()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(cleaned), nil
+}
+
+// transcriptContent combines a transcript with the item's show notes. The
+// transcript comes first so summarization and chat work on what was said.
+func transcriptContent(transcript, showNotes string) string {
+	var sb strings.Builder
+	sb.WriteString("<h2>Transcript</h2>\n")
+	sb.WriteString(transcript)
+	if strings.TrimSpace(showNotes) != "" {
+		sb.WriteString("\n<h2>Show notes</h2>\n")
+		sb.WriteString(showNotes)
+	}
+	return sb.String()
+}
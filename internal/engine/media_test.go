@@ -0,0 +1,199 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+)
+
+func podcastFeed(baseURL string) string {
+	pub := time.Now().UTC().Add(-time.Hour).Format(time.RFC1123Z)
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:podcast="https://podcastindex.org/namespace/1.0">
+<channel>
+  <title>Test Podcast</title>
+  <image><url>https://example.com/show.jpg</url><title>Show</title><link>https://example.com</link></image>
+  <item>
+    <title>Episode 1</title>
+    <guid>ep-1</guid>
+    <pubDate>` + pub + `</pubDate>
+    <description>Short notes.</description>
+    <enclosure url="https://cdn.example.com/ep1.mp3" length="12345678" type="audio/mpeg"/>
+    <itunes:duration>1:02:03</itunes:duration>
+    <podcast:transcript url="` + baseURL + `/ep1.html" type="text/html"/>
+    <podcast:transcript url="` + baseURL + `/ep1.vtt" type="text/vtt"/>
+  </item>
+  <item>
+    <title>Episode 2</title>
+    <pubDate>` + pub + `</pubDate>
+    <description>No transcript for this one.</description>
+    <enclosure url="https://cdn.example.com/ep2.mp3" length="42" type="audio/mpeg"/>
+  </item>
+</channel>
+</rss>`
+}
+
+const testVTT = `WEBVTT
+
+NOTE This is a comment
+
+1
+00:00:00.000 --> 00:00:03.000
+<v Alice>Welcome to the show.
+
+2
+00:00:03.000 --> 00:00:06.000
+<v Alice>Today we talk about Go.
+
+00:00:06.000 --> 00:00:09.000
+<v Bob>Thanks for having me &amp; hello!`
+
+func TestFetchResource_PodcastWithTranscript(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed":
+			w.Header().Set("Content-Type", "application/rss+xml")
+			w.Write([]byte(podcastFeed(server.URL)))
+		case "/ep1.vtt":
+			w.Write([]byte(testVTT))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	resource := testutil.CreateResource(t, app, "Podcast", server.URL+"/feed", "rss", "healthy", 0, true)
+
+	if err := FetchResource(app, resource, server.Client()); err != nil {
+		t.Fatalf("FetchResource: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	ep1, err := app.FindFirstRecordByFilter("entries", "guid = 'ep-1'")
+	if err != nil {
+		t.Fatalf("episode 1 not created: %v", err)
+	}
+	raw := ep1.GetString("raw_content")
+	if !strings.HasPrefix(raw, "<h2>Transcript</h2>") {
+		t.Errorf("raw_content should start with the transcript:\n%s", raw)
+	}
+	for _, want := range []string{
+		"<strong>Alice:</strong> Welcome to the show. Today we talk about Go.",
+		"<strong>Bob:</strong> Thanks for having me &amp; hello!",
+		"<h2>Show notes</h2>",
+	} {
+		if !strings.Contains(raw, want) {
+			t.Errorf("raw_content missing %q:\n%s", want, raw)
+		}
+	}
+
+	var media MediaEnclosure
+	if err := json.Unmarshal([]byte(ep1.GetString("media")), &media); err != nil {
+		t.Fatalf("media not stored: %v", err)
+	}
+	if media.URL != "https://cdn.example.com/ep1.mp3" || media.Type != "audio/mpeg" || media.Length != 12345678 {
+		t.Errorf("media = %+v", media)
+	}
+	if media.DurationSeconds != 3723 {
+		t.Errorf("duration = %d, want 3723", media.DurationSeconds)
+	}
+	if media.Image != "https://example.com/show.jpg" {
+		t.Errorf("image = %q, want feed image fallback", media.Image)
+	}
+	if media.TranscriptType != "text/vtt" {
+		t.Errorf("transcript type = %q, want preferred text/vtt", media.TranscriptType)
+	}
+
+	// Episode without GUID or link falls back to the enclosure URL.
+	ep2, err := app.FindFirstRecordByFilter("entries", "guid = 'https://cdn.example.com/ep2.mp3'")
+	if err != nil {
+		t.Fatalf("episode 2 not created: %v", err)
+	}
+	if ep2.GetString("url") != "https://cdn.example.com/ep2.mp3" {
+		t.Errorf("episode 2 url = %q", ep2.GetString("url"))
+	}
+}
+
+func TestParseTranscript_SRT(t *testing.T) {
+	srt := "1\r\n00:00:01,000 --> 00:00:02,500\r\nHello <i>there</i>.\r\n\r\n2\r\n00:00:02,500 --> 00:00:04,000\r\nSecond line\r\ncontinues here.\r\n"
+	got, err := ParseTranscript(srt, "application/x-subrip")
+	if err != nil {
+		t.Fatalf("ParseTranscript: %v", err)
+	}
+	want := "<p>Hello there. Second line continues here.</p>\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Untyped SRT is detected from the cue timings.
+	if sniffed, _ := ParseTranscript(srt, ""); sniffed != want {
+		t.Errorf("sniffed SRT = %q", sniffed)
+	}
+}
+
+func TestParseTranscript_JSON(t *testing.T) {
+	body := `{"version": "1.0.0", "segments": [
+		{"speaker": "Host", "startTime": 0, "body": "Hi."},
+		{"speaker": "Host", "startTime": 1, "body": "Welcome."},
+		{"speaker": "Guest", "startTime": 2, "body": "Glad to <be> here."}
+	]}`
+	got, err := ParseTranscript(body, "application/json")
+	if err != nil {
+		t.Fatalf("ParseTranscript: %v", err)
+	}
+	want := "<p><strong>Host:</strong> Hi. Welcome.</p>\n<p><strong>Guest:</strong> Glad to &lt;be&gt; here.</p>\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestParseTranscript_HTMLAndPlain(t *testing.T) {
+	got, err := ParseTranscript(`<html><body><script>x()</script><p>Spoken words.</p></body></html>`, "text/html")
+	if err != nil || got != "<p>Spoken words.</p>" {
+		t.Errorf("HTML transcript = %q, %v", got, err)
+	}
+
+	got, err = ParseTranscript("Line one.\n\nLine two.", "text/plain")
+	if err != nil || got != "<p>Line one.</p>\n<p>Line two.</p>\n" {
+		t.Errorf("plain transcript = %q, %v", got, err)
+	}
+
+	if _, err := ParseTranscript("WEBVTT\n\nNOTE nothing spoken", "text/vtt"); err == nil {
+		t.Error("expected error for transcript without cues")
+	}
+}
+
+func TestRenderTranscript_SplitsLongParagraphs(t *testing.T) {
+	var segments []transcriptSegment
+	for i := 0; i < 40; i++ {
+		segments = append(segments, transcriptSegment{text: "This is one spoken sentence."})
+	}
+	got := renderTranscript(segments)
+	if n := strings.Count(got, "<p>"); n < 2 {
+		t.Errorf("expected long monologue to be split into paragraphs, got %d", n)
+	}
+}
+
+func TestParseITunesDuration(t *testing.T) {
+	tests := map[string]int{
+		"":        0,
+		"3600":    3600,
+		"59:30":   3570,
+		"1:02:03": 3723,
+		"abc":     0,
+	}
+	for in, want := range tests {
+		if got := parseITunesDuration(in); got != want {
+			t.Errorf("parseITunesDuration(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
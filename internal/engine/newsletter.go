@@ -0,0 +1,372 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// ErrUnknownSender is returned when no active newsletter resource is mapped to
+// the sender of an incoming message.
+var ErrUnknownSender = errors.New("no newsletter resource for sender")
+
+// NewsletterMessage holds the parts of an email newsletter used to build an entry.
+type NewsletterMessage struct {
+	From      string // lower-cased sender address
+	Subject   string
+	MessageID string
+	Date      *time.Time
+	HTML      string // HTML body (plain-text bodies are converted to paragraphs)
+}
+
+// ParseNewsletterMessage parses a raw RFC 5322 message, decoding MIME
+// multipart bodies, transfer encodings and encoded-word headers. The HTML
+// part is preferred; a plain-text part is used when no HTML part exists.
+func ParseNewsletterMessage(raw []byte) (NewsletterMessage, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return NewsletterMessage{}, fmt.Errorf("reading message: %w", err)
+	}
+
+	from, err := mail.ParseAddress(msg.Header.Get("From"))
+	if err != nil {
+		return NewsletterMessage{}, fmt.Errorf("parsing From header: %w", err)
+	}
+
+	dec := new(mime.WordDecoder)
+	subject, err := dec.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+
+	result := NewsletterMessage{
+		From:      strings.ToLower(from.Address),
+		Subject:   strings.TrimSpace(subject),
+		MessageID: strings.Trim(strings.TrimSpace(msg.Header.Get("Message-ID")), "<>"),
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		d := date.UTC()
+		result.Date = &d
+	}
+
+	htmlBody, textBody, err := readMessageBodies(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+	if err != nil {
+		return NewsletterMessage{}, err
+	}
+	switch {
+	case strings.TrimSpace(htmlBody) != "":
+		result.HTML = htmlBody
+	case strings.TrimSpace(textBody) != "":
+		result.HTML = plainTextToHTML(textBody)
+	default:
+		return NewsletterMessage{}, fmt.Errorf("message has no text or HTML body")
+	}
+	return result, nil
+}
+
+// readMessageBodies walks a (possibly nested) MIME body and returns the first
+// text/html and text/plain parts it finds.
+func readMessageBodies(contentType, transferEncoding string, body io.Reader) (string, string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		var htmlBody, textBody string
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", "", fmt.Errorf("reading multipart body: %w", err)
+			}
+			// multipart.Reader already decodes quoted-printable parts.
+			encoding := part.Header.Get("Content-Transfer-Encoding")
+			if strings.EqualFold(encoding, "quoted-printable") {
+				encoding = ""
+			}
+			h, t, err := readMessageBodies(part.Header.Get("Content-Type"), encoding, part)
+			if err != nil {
+				return "", "", err
+			}
+			if htmlBody == "" {
+				htmlBody = h
+			}
+			if textBody == "" {
+				textBody = t
+			}
+		}
+		return htmlBody, textBody, nil
+	}
+
+	if mediaType != "text/html" && mediaType != "text/plain" {
+		return "", "", nil
+	}
+
+	decoded, err := io.ReadAll(decodeTransferEncoding(transferEncoding, body))
+	if err != nil {
+		return "", "", fmt.Errorf("decoding %s body: %w", mediaType, err)
+	}
+	if mediaType == "text/html" {
+		return string(decoded), "", nil
+	}
+	return "", string(decoded), nil
+}
+
+func decodeTransferEncoding(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &newlineStripper{r: r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	default:
+		return r
+	}
+}
+
+// newlineStripper drops CR/LF so base64 bodies wrapped at 76 columns decode.
+type newlineStripper struct {
+	r io.Reader
+}
+
+func (n *newlineStripper) Read(p []byte) (int, error) {
+	for {
+		count, err := n.r.Read(p)
+		out := 0
+		for _, b := range p[:count] {
+			if b != '\r' && b != '\n' {
+				p[out] = b
+				out++
+			}
+		}
+		if out > 0 || err != nil {
+			return out, err
+		}
+	}
+}
+
+func plainTextToHTML(text string) string {
+	var sb strings.Builder
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	for _, para := range strings.Split(text, "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		sb.WriteString("<p>")
+		sb.WriteString(strings.ReplaceAll(html.EscapeString(para), "\n", "<br>"))
+		sb.WriteString("</p>\n")
+	}
+	return sb.String()
+}
+
+// newsletterFooterMarkers identify boilerplate blocks that are removed from
+// newsletter bodies (unsubscribe footers, preference links, address blocks).
+var newsletterFooterMarkers = []string{
+	"unsubscribe",
+	"manage your subscription",
+	"update your preferences",
+	"email preferences",
+	"you are receiving this",
+	"you received this email",
+	"view in browser",
+	"view this email in your browser",
+	"view online",
+}
+
+// maxFooterBlockText bounds how much text a block can hold and still be
+// treated as boilerplate; larger blocks likely contain real content.
+const maxFooterBlockText = 400
+
+// CleanNewsletterHTML removes tracking pixels, scripts, hidden preheaders and
+// footer boilerplate from a newsletter body. It also returns the "view in
+// browser" link when one is present, for use as the entry URL.
+func CleanNewsletterHTML(body string) (string, string) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+	if err != nil {
+		return body, ""
+	}
+
+	webURL := ""
+	doc.Find("a[href]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		text := strings.ToLower(normalizeFragmentText(s.Text()))
+		href, _ := s.Attr("href")
+		if (strings.Contains(text, "view in browser") || strings.Contains(text, "view online") ||
+			strings.Contains(text, "view this email in your browser") || strings.Contains(text, "read online")) &&
+			(strings.HasPrefix(href, "https://") || strings.HasPrefix(href, "http://")) {
+			webURL = href
+			return false
+		}
+		return true
+	})
+
+	doc.Find("script, style, head, meta, link, noscript").Remove()
+
+	doc.Find("img").Each(func(_ int, s *goquery.Selection) {
+		if isTrackingPixel(s) {
+			s.Remove()
+		}
+	})
+
+	doc.Find("[style]").Each(func(_ int, s *goquery.Selection) {
+		style := strings.ToLower(strings.ReplaceAll(s.AttrOr("style", ""), " ", ""))
+		if strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") || strings.Contains(style, "max-height:0") {
+			s.Remove()
+		}
+	})
+
+	// Remove the smallest block that contains each footer marker.
+	for _, marker := range newsletterFooterMarkers {
+		doc.Find("p, td, div, footer, table, span, center").Each(func(_ int, s *goquery.Selection) {
+			text := strings.ToLower(normalizeFragmentText(s.Text()))
+			if !strings.Contains(text, marker) || len(text) > maxFooterBlockText {
+				return
+			}
+			// Prefer the innermost matching block.
+			if s.Find("p, td, div, span").FilterFunction(func(_ int, c *goquery.Selection) bool {
+				return strings.Contains(strings.ToLower(c.Text()), marker)
+			}).Length() > 0 {
+				return
+			}
+			s.Remove()
+		})
+	}
+
+	cleaned, err := doc.Find("body").Html()
+	if err != nil {
+		return body, webURL
+	}
+	return strings.TrimSpace(cleaned), webURL
+}
+
+// isTrackingPixel reports whether an <img> is a 1x1 (or hidden) tracking beacon.
+func isTrackingPixel(s *goquery.Selection) bool {
+	tiny := func(attr string) bool {
+		v, ok := s.Attr(attr)
+		if !ok {
+			return false
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(v), "px"))
+		return err == nil && n <= 1
+	}
+	if tiny("width") || tiny("height") {
+		return true
+	}
+	style := strings.ToLower(strings.ReplaceAll(s.AttrOr("style", ""), " ", ""))
+	return strings.Contains(style, "width:1px") || strings.Contains(style, "height:1px") ||
+		strings.Contains(style, "width:0") || strings.Contains(style, "height:0") ||
+		strings.Contains(style, "display:none")
+}
+
+// FindNewsletterResource returns the active newsletter resource whose
+// newsletter_senders list matches the sender. Entries are comma or newline
+// separated, either full addresses ("news@example.com") or domains
+// ("@example.com").
+func FindNewsletterResource(app core.App, sender string) (*core.Record, error) {
+	sender = strings.ToLower(strings.TrimSpace(sender))
+	resources, err := app.FindRecordsByFilter("resources", "type = 'newsletter' && active = true", "", 0, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	domain := ""
+	if at := strings.LastIndex(sender, "@"); at >= 0 {
+		domain = sender[at:]
+	}
+	for _, resource := range resources {
+		for _, pattern := range strings.FieldsFunc(resource.GetString("newsletter_senders"), func(r rune) bool {
+			return r == ',' || r == '\n' || r == ';' || r == ' '
+		}) {
+			pattern = strings.ToLower(strings.TrimSpace(pattern))
+			if pattern == sender || (strings.HasPrefix(pattern, "@") && pattern == domain) {
+				return resource, nil
+			}
+		}
+	}
+	return nil, ErrUnknownSender
+}
+
+// IngestNewsletter parses a raw email, maps its sender to a newsletter
+// resource and creates an entry for the issue. When the resource is a
+// fragment feed the issue is split into one entry per fragment.
+// Returns the number of entries created.
+func IngestNewsletter(app core.App, raw []byte) (int, error) {
+	msg, err := ParseNewsletterMessage(raw)
+	if err != nil {
+		return 0, err
+	}
+
+	resource, err := FindNewsletterResource(app, msg.From)
+	if err != nil {
+		return 0, err
+	}
+
+	content, webURL := CleanNewsletterHTML(msg.HTML)
+
+	guid := "newsletter:" + msg.MessageID
+	if msg.MessageID == "" {
+		guid = "newsletter:" + contentSHA256(msg.From + "\n" + msg.Subject + "\n" + content)[:24]
+	}
+	// A redelivered message is ignored. Fragments are stored under their own
+	// GUIDs, which also mark the message GUID as seen.
+	existingGUIDs, err := loadExistingGUIDs(app, resource.Id)
+	if err != nil {
+		return 0, fmt.Errorf("loading existing entries: %w", err)
+	}
+	if existingGUIDs[guid] {
+		return 0, nil
+	}
+
+	entryURL := webURL
+	if entryURL == "" {
+		entryURL = strings.TrimRight(resource.GetString("url"), "#") + "#" + contentSHA256(guid)[:12]
+	}
+
+	title := msg.Subject
+	if title == "" {
+		title = resource.GetString("name")
+	}
+
+	publishedAt := msg.Date
+	if publishedAt == nil {
+		now := time.Now().UTC()
+		publishedAt = &now
+	}
+
+	if !resource.GetBool("fragment_feed") {
+		if err := createEntry(app, resource.Id, title, entryURL, guid, content, publishedAt, false); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+
+	created := 0
+	for _, frag := range splitResourceFragments(app, resource, content) {
+		fragGUID := FragmentGUID(guid, frag.HTML)
+		if existingGUIDs[fragGUID] {
+			continue
+		}
+		if err := createEntry(app, resource.Id, frag.Title, entryURL, fragGUID, frag.HTML, publishedAt, true); err != nil {
+			log.Printf("Failed to create newsletter fragment entry: %v", err)
+			continue
+		}
+		created++
+	}
+	return created, nil
+}
@@ -0,0 +1,256 @@
+package engine
+
+import (
+	"errors"
+	"net"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const testNewsletterHTML = "From: Weekly Digest <News@Digest.example>\r\n" +
+	"To: inbox@knowledgehub.local\r\n" +
+	"Subject: =?UTF-8?Q?Issue_42_=E2=80=94_Go_news?=\r\n" +
+	"Message-ID: <issue-42@digest.example>\r\n" +
+	"Date: Mon, 02 Mar 2026 08:00:00 +0000\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"b1\"\r\n" +
+	"\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"\r\n" +
+	"Plain version\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"<html><body><p><a href=3D\"https://digest.example/42\">View in browser</a></p>\r\n" +
+	"<h2>Go 1.27 released</h2><p>The new release brings faster builds and =\r\n" +
+	"a new iterator package.</p>\r\n" +
+	"<img src=3D\"https://track.example/open.gif\" width=3D\"1\" height=3D\"1\">\r\n" +
+	"<div style=3D\"display:none\">preheader text</div>\r\n" +
+	"<p>You are receiving this because you subscribed. <a href=3D\"https://digest.example/u\">Unsubscribe</a></p>\r\n" +
+	"</body></html>\r\n" +
+	"--b1--\r\n"
+
+func createNewsletterResource(t *testing.T, app core.App, senders string) *core.Record {
+	t.Helper()
+	resource := testutil.CreateResource(t, app, "Weekly Digest", "https://digest.example", "newsletter", "healthy", 0, true)
+	resource.Set("newsletter_senders", senders)
+	if err := app.Save(resource); err != nil {
+		t.Fatalf("failed to update resource: %v", err)
+	}
+	return resource
+}
+
+func TestParseNewsletterMessage(t *testing.T) {
+	msg, err := ParseNewsletterMessage([]byte(testNewsletterHTML))
+	if err != nil {
+		t.Fatalf("ParseNewsletterMessage: %v", err)
+	}
+	if msg.From != "news@digest.example" {
+		t.Errorf("From = %q", msg.From)
+	}
+	if msg.Subject != "Issue 42 — Go news" {
+		t.Errorf("Subject = %q", msg.Subject)
+	}
+	if msg.MessageID != "issue-42@digest.example" {
+		t.Errorf("MessageID = %q", msg.MessageID)
+	}
+	if msg.Date == nil || msg.Date.Day() != 2 {
+		t.Errorf("Date = %v", msg.Date)
+	}
+	if !strings.Contains(msg.HTML, "faster builds and a new iterator package") {
+		t.Errorf("quoted-printable HTML part not decoded: %q", msg.HTML)
+	}
+}
+
+func TestParseNewsletterMessage_PlainTextBase64(t *testing.T) {
+	raw := "From: a@b.example\r\n" +
+		"Subject: Hello\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		"Rmlyc3QgcGFyYWdyYXBoLgoKU2Vjb25kIDwg\r\n" +
+		"cGFyYWdyYXBoLg==\r\n"
+	msg, err := ParseNewsletterMessage([]byte(raw))
+	if err != nil {
+		t.Fatalf("ParseNewsletterMessage: %v", err)
+	}
+	want := "<p>First paragraph.</p>\n<p>Second &lt; paragraph.</p>\n"
+	if msg.HTML != want {
+		t.Errorf("HTML = %q, want %q", msg.HTML, want)
+	}
+}
+
+func TestCleanNewsletterHTML(t *testing.T) {
+	msg, err := ParseNewsletterMessage([]byte(testNewsletterHTML))
+	if err != nil {
+		t.Fatalf("ParseNewsletterMessage: %v", err)
+	}
+	cleaned, webURL := CleanNewsletterHTML(msg.HTML)
+	if webURL != "https://digest.example/42" {
+		t.Errorf("webURL = %q", webURL)
+	}
+	for _, unwanted := range []string{"track.example", "preheader", "Unsubscribe", "View in browser"} {
+		if strings.Contains(cleaned, unwanted) {
+			t.Errorf("cleaned HTML still contains %q:\n%s", unwanted, cleaned)
+		}
+	}
+	if !strings.Contains(cleaned, "Go 1.27 released") {
+		t.Errorf("cleaned HTML lost content:\n%s", cleaned)
+	}
+}
+
+func TestFindNewsletterResource(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	byAddress := createNewsletterResource(t, app, "news@digest.example")
+	byDomain := createNewsletterResource(t, app, "other@x.example, @letters.example")
+
+	if r, err := FindNewsletterResource(app, "News@Digest.example"); err != nil || r.Id != byAddress.Id {
+		t.Errorf("address match = %v, %v", r, err)
+	}
+	if r, err := FindNewsletterResource(app, "editor@letters.example"); err != nil || r.Id != byDomain.Id {
+		t.Errorf("domain match = %v, %v", r, err)
+	}
+	if _, err := FindNewsletterResource(app, "spam@elsewhere.example"); !errors.Is(err, ErrUnknownSender) {
+		t.Errorf("unknown sender err = %v, want ErrUnknownSender", err)
+	}
+}
+
+func TestIngestNewsletter(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	resource := createNewsletterResource(t, app, "@digest.example")
+
+	created, err := IngestNewsletter(app, []byte(testNewsletterHTML))
+	if err != nil || created != 1 {
+		t.Fatalf("IngestNewsletter = %d, %v; want 1 entry", created, err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	entries, err := app.FindRecordsByFilter("entries", "resource = {:id}", "", 0, 0, map[string]any{"id": resource.Id})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("found %d entries (err %v), want 1", len(entries), err)
+	}
+	entry := entries[0]
+	if entry.GetString("title") != "Issue 42 — Go news" {
+		t.Errorf("title = %q", entry.GetString("title"))
+	}
+	if entry.GetString("url") != "https://digest.example/42" {
+		t.Errorf("url = %q", entry.GetString("url"))
+	}
+	if entry.GetString("guid") != "newsletter:issue-42@digest.example" {
+		t.Errorf("guid = %q", entry.GetString("guid"))
+	}
+	if strings.Contains(entry.GetString("raw_content"), "track.example") {
+		t.Error("tracking pixel was not removed")
+	}
+
+	// Redelivery of the same message is ignored.
+	created, err = IngestNewsletter(app, []byte(testNewsletterHTML))
+	if err != nil || created != 0 {
+		t.Errorf("redelivery = %d, %v; want 0 entries", created, err)
+	}
+}
+
+func TestIngestNewsletter_FragmentFeed(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	resource := createNewsletterResource(t, app, "@digest.example")
+	resource.Set("fragment_feed", true)
+	resource.Set("fragment_mode", "separated")
+	resource.Set("fragment_separator", "***")
+	app.Save(resource)
+
+	raw := "From: news@digest.example\r\n" +
+		"Subject: Links\r\n" +
+		"Message-ID: <links-1@digest.example>\r\n" +
+		"Content-Type: text/html\r\n" +
+		"\r\n" +
+		"<h2>First story</h2><p>Something about the first story that is long enough.</p><p>***</p>" +
+		"<h2>Second story</h2><p>Something about the second story that is long enough.</p>"
+
+	created, err := IngestNewsletter(app, []byte(raw))
+	if err != nil {
+		t.Fatalf("IngestNewsletter: %v", err)
+	}
+	if created != 2 {
+		t.Fatalf("created = %d, want 2 fragments", created)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	entries, _ := app.FindRecordsByFilter("entries", "resource = {:id} && is_fragment = true", "", 0, 0, map[string]any{"id": resource.Id})
+	if len(entries) != 2 {
+		t.Errorf("found %d fragment entries, want 2", len(entries))
+	}
+
+	// An SMTP client retrying the same message does not duplicate fragments.
+	created, err = IngestNewsletter(app, []byte(raw))
+	if err != nil || created != 0 {
+		t.Errorf("redelivery = %d, %v; want 0 entries", created, err)
+	}
+	entries, _ = app.FindRecordsByFilter("entries", "resource = {:id}", "", 0, 0, map[string]any{"id": resource.Id})
+	if len(entries) != 2 {
+		t.Errorf("found %d entries after redelivery, want 2", len(entries))
+	}
+}
+
+func TestSMTPServer_DeliversNewsletter(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	resource := createNewsletterResource(t, app, "news@digest.example")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := NewSMTPServer(app)
+	go server.Serve(ln)
+	defer server.Close()
+
+	addr := ln.Addr().String()
+	if err := smtp.SendMail(addr, nil, "news@digest.example", []string{"inbox@knowledgehub.local"}, []byte(testNewsletterHTML)); err != nil {
+		t.Fatalf("SendMail: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	entries, _ := app.FindRecordsByFilter("entries", "resource = {:id}", "", 0, 0, map[string]any{"id": resource.Id})
+	if len(entries) != 1 {
+		t.Fatalf("found %d entries, want 1", len(entries))
+	}
+
+	unknown := strings.Replace(testNewsletterHTML, "News@Digest.example", "someone@else.example", 1)
+	err = smtp.SendMail(addr, nil, "someone@else.example", []string{"inbox@knowledgehub.local"}, []byte(unknown))
+	if err == nil || !strings.Contains(err.Error(), "550") {
+		t.Errorf("unknown sender err = %v, want 550 rejection", err)
+	}
+}
+
+func TestSMTPPathArg(t *testing.T) {
+	tests := []struct {
+		arg, prefix, want string
+		ok                bool
+	}{
+		{"FROM:<a@b.example>", "FROM:", "a@b.example", true},
+		{"from: <a@b.example> BODY=8BITMIME", "FROM:", "a@b.example", true},
+		{"TO:<>", "TO:", "", true},
+		{"FROM:a@b.example", "FROM:", "", false},
+		{"TO:<a@b.example>", "FROM:", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := smtpPathArg(tt.arg, tt.prefix)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("smtpPathArg(%q, %q) = %q, %v; want %q, %v", tt.arg, tt.prefix, got, ok, tt.want, tt.ok)
+		}
+	}
+}
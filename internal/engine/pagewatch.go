@@ -0,0 +1,357 @@
+package engine
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	readability "github.com/go-shiori/go-readability"
+	"github.com/pocketbase/pocketbase/core"
+	xhtml "golang.org/x/net/html"
+)
+
+const (
+	// pageDiffContext is the number of unchanged lines shown around each change.
+	pageDiffContext = 2
+	// maxPageDiffLines bounds the LCS table; larger snapshots fall back to a
+	// whole-region replacement diff.
+	maxPageDiffLines = 2000
+)
+
+// fetchPageWatchResource extracts the watched region of a page and creates an
+// entry when its normalized text differs from the stored snapshot. The first
+// fetch only records a baseline snapshot.
+func fetchPageWatchResource(app core.App, resource *core.Record, client *http.Client) error {
+	pageURL := resource.GetString("url")
+
+	body, err := fetchPageWithBrowserFallback(app, resource, pageURL, client)
+	if err != nil {
+		return err
+	}
+
+	title, snapshot, err := extractPageRegion(body, pageURL, resource.GetString("content_selector"))
+	if err != nil {
+		return err
+	}
+	if title == "" {
+		title = resource.GetString("name")
+	}
+
+	hash := contentSHA256(snapshot)
+	previousHash := resource.GetString("page_snapshot_hash")
+	if hash == previousHash {
+		return nil
+	}
+
+	// The change entry is created before the snapshot is stored, so a change
+	// is never lost when saving fails; its GUID keeps the retry from
+	// reporting it twice.
+	if previousHash != "" {
+		if diff := DiffLines(resource.GetString("page_snapshot"), snapshot); diff != "" {
+			now := time.Now().UTC()
+			entryTitle := fmt.Sprintf("%s — changed %s", title, now.Format("2006-01-02"))
+			guid := fmt.Sprintf("%s#change-%s", pageURL, hash[:12])
+			content := renderPageChange(diff, snapshot)
+			if err := createPageChangeEntry(app, resource.Id, entryTitle, pageURL, guid, content, diff, &now); err != nil {
+				return err
+			}
+		}
+	}
+
+	resource.Set("page_snapshot", snapshot)
+	resource.Set("page_snapshot_hash", hash)
+	if err := app.Save(resource); err != nil {
+		return fmt.Errorf("saving page snapshot: %w", err)
+	}
+	if previousHash == "" {
+		log.Printf("Stored baseline snapshot for page watch %q", resource.GetString("name"))
+	}
+	return nil
+}
+
+// fetchPageWithBrowserFallback fetches raw page HTML over plain HTTP, falling
+// back to the headless browser when bot protection is detected.
+func fetchPageWithBrowserFallback(app core.App, resource *core.Record, pageURL string, client *http.Client) (string, error) {
+	return withBrowserFallback(app, resource, pageURL,
+		func() (string, error) { return fetchPageBody(pageURL, client) },
+		func() (string, error) { return BrowserFetchBodyFunc(pageURL) },
+		nil, nil,
+	)
+}
+
+func fetchPageBody(pageURL string, client *http.Client) (string, error) {
+	resp, err := client.Get(pageURL)
+	if err != nil {
+		return "", fmt.Errorf("fetching %s: %w", pageURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP %d for %s", resp.StatusCode, pageURL)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("reading body: %w", err)
+	}
+	return string(body), nil
+}
+
+// extractPageRegion returns the page title and the normalized text of the
+// watched region: the elements matching selector, or the readability article
+// when selector is empty.
+func extractPageRegion(pageHTML, pageURL, selector string) (string, string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(pageHTML))
+	if err != nil {
+		return "", "", fmt.Errorf("parsing HTML: %w", err)
+	}
+	title := strings.TrimSpace(doc.Find("title").First().Text())
+
+	var lines []string
+	if selector != "" {
+		region := doc.Find(selector)
+		if region.Length() == 0 {
+			return "", "", fmt.Errorf("content_selector %q matched nothing on %s", selector, pageURL)
+		}
+		region.Each(func(_ int, s *goquery.Selection) {
+			for _, n := range s.Nodes {
+				lines = append(lines, blockTextLines(n)...)
+			}
+		})
+	} else {
+		parsed, _ := url.Parse(pageURL)
+		if parsed == nil {
+			parsed = &url.URL{}
+		}
+		article, err := readability.FromReader(strings.NewReader(pageHTML), parsed)
+		if err != nil {
+			return "", "", fmt.Errorf("extracting content from %s: %w", pageURL, err)
+		}
+		if article.Title != "" {
+			title = article.Title
+		}
+		root, err := xhtml.Parse(strings.NewReader(article.Content))
+		if err != nil {
+			return "", "", fmt.Errorf("parsing article HTML: %w", err)
+		}
+		lines = blockTextLines(root)
+	}
+
+	snapshot := strings.Join(lines, "\n")
+	if snapshot == "" {
+		return "", "", fmt.Errorf("watched region on %s is empty", pageURL)
+	}
+	return title, snapshot, nil
+}
+
+// pageBlockElements start a new line in a normalized snapshot.
+var pageBlockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true,
+	"dd": true, "details": true, "div": true, "dl": true, "dt": true, "fieldset": true,
+	"figcaption": true, "figure": true, "footer": true, "form": true, "h1": true,
+	"h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true,
+	"hr": true, "li": true, "main": true, "nav": true, "ol": true, "p": true,
+	"pre": true, "section": true, "summary": true, "table": true, "td": true,
+	"th": true, "tr": true, "ul": true,
+}
+
+// blockTextLines flattens an HTML subtree into whitespace-normalized text
+// lines, one per block element, so cosmetic markup changes don't register as
+// content changes.
+func blockTextLines(root *xhtml.Node) []string {
+	var sb strings.Builder
+	var walk func(n *xhtml.Node)
+	walk = func(n *xhtml.Node) {
+		switch n.Type {
+		case xhtml.TextNode:
+			sb.WriteString(n.Data)
+			return
+		case xhtml.ElementNode:
+			switch n.Data {
+			case "script", "style", "noscript", "template", "svg":
+				return
+			}
+		}
+		block := n.Type == xhtml.ElementNode && pageBlockElements[n.Data]
+		if block {
+			sb.WriteString("\n")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if block {
+			sb.WriteString("\n")
+		}
+	}
+	walk(root)
+
+	var lines []string
+	for _, line := range strings.Split(sb.String(), "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// DiffLines returns a line diff between two snapshots with a few lines of
+// context around each change. Removed lines are prefixed with "- ", added
+// lines with "+ " and context lines with "  ". Returns "" when they are equal.
+func DiffLines(oldText, newText string) string {
+	oldLines := splitSnapshotLines(oldText)
+	newLines := splitSnapshotLines(newText)
+	ops := diffOps(oldLines, newLines)
+
+	changed := false
+	for _, op := range ops {
+		if op.kind != ' ' {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	// Mark which ops fall within pageDiffContext of a change.
+	show := make([]bool, len(ops))
+	for i, op := range ops {
+		if op.kind == ' ' {
+			continue
+		}
+		for j := max(0, i-pageDiffContext); j <= min(len(ops)-1, i+pageDiffContext); j++ {
+			show[j] = true
+		}
+	}
+
+	var sb strings.Builder
+	skipped := false
+	for i, op := range ops {
+		if !show[i] {
+			skipped = true
+			continue
+		}
+		if skipped && sb.Len() > 0 {
+			sb.WriteString("  …\n")
+		}
+		skipped = false
+		sb.WriteByte(op.kind)
+		sb.WriteByte(' ')
+		sb.WriteString(op.line)
+		sb.WriteByte('\n')
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+type diffOp struct {
+	kind byte // ' ', '-', '+'
+	line string
+}
+
+func splitSnapshotLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// diffOps computes an edit script using a longest-common-subsequence table.
+func diffOps(a, b []string) []diffOp {
+	if len(a) > maxPageDiffLines || len(b) > maxPageDiffLines {
+		ops := make([]diffOp, 0, len(a)+len(b))
+		for _, l := range a {
+			ops = append(ops, diffOp{'-', l})
+		}
+		for _, l := range b {
+			ops = append(ops, diffOp{'+', l})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// renderPageChange renders a change entry's raw_content: the diff with
+// <del>/<ins> markup followed by the full current snapshot for chat context.
+func renderPageChange(diff, snapshot string) string {
+	var sb strings.Builder
+	sb.WriteString("<h2>What changed</h2>\n<pre class=\"page-diff\">")
+	for _, line := range strings.Split(diff, "\n") {
+		escaped := html.EscapeString(line)
+		switch {
+		case strings.HasPrefix(line, "- "):
+			sb.WriteString("<del>" + escaped + "</del>\n")
+		case strings.HasPrefix(line, "+ "):
+			sb.WriteString("<ins>" + escaped + "</ins>\n")
+		default:
+			sb.WriteString(escaped + "\n")
+		}
+	}
+	sb.WriteString("</pre>\n<h2>Current version</h2>\n")
+	for _, line := range strings.Split(snapshot, "\n") {
+		sb.WriteString("<p>" + html.EscapeString(line) + "</p>\n")
+	}
+	return sb.String()
+}
+
+func createPageChangeEntry(app core.App, resourceID, title, pageURL, guid, content, diff string, publishedAt *time.Time) error {
+	existing, err := loadExistingGUIDs(app, resourceID)
+	if err != nil {
+		return fmt.Errorf("loading existing GUIDs: %w", err)
+	}
+	if existing[guid] {
+		// The page reverted to a version we already reported.
+		return nil
+	}
+
+	record, err := newEntryRecord(app, resourceID, title, pageURL, guid, content, publishedAt, false)
+	if err != nil {
+		return err
+	}
+	record.Set("change_diff", diff)
+	return saveAndProcessEntry(app, record)
+}
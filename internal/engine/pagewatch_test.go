@@ -0,0 +1,283 @@
+package engine
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const pricingPageV1 = `<!DOCTYPE html>
+<html><head><title>Pricing</title></head><body>
+<nav>Home | Blog</nav>
+<div id="pricing">
+  <h2>Plans</h2>
+  <ul>
+    <li>Starter: $10/month</li>
+    <li>Team:   $50/month</li>
+  </ul>
+  <p>Cancel anytime.</p>
+</div>
+<footer>Copyright 2025 <script>var t = Date.now();</script></footer>
+</body></html>`
+
+const pricingPageV2 = `<!DOCTYPE html>
+<html><head><title>Pricing</title></head><body>
+<nav>Home | Blog | Careers</nav>
+<div id="pricing">
+  <h2>Plans</h2>
+  <ul>
+    <li>Starter: $12/month</li>
+    <li>Team:   $50/month</li>
+    <li>Enterprise: contact us</li>
+  </ul>
+  <p>Cancel anytime.</p>
+</div>
+<footer>Copyright 2026</footer>
+</body></html>`
+
+func TestFetchResource_PageWatch(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	var version atomic.Int32
+	version.Store(1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if version.Load() == 1 {
+			w.Write([]byte(pricingPageV1))
+			return
+		}
+		w.Write([]byte(pricingPageV2))
+	}))
+	defer server.Close()
+
+	resource := testutil.CreateResource(t, app, "Pricing", server.URL, "pagewatch", "healthy", 0, true)
+	resource.Set("content_selector", "#pricing")
+	if err := app.Save(resource); err != nil {
+		t.Fatalf("failed to update resource: %v", err)
+	}
+
+	countEntries := func() int {
+		t.Helper()
+		entries, err := app.FindRecordsByFilter("entries", "resource = {:id}", "", 0, 0, map[string]any{"id": resource.Id})
+		if err != nil {
+			t.Fatalf("failed to find entries: %v", err)
+		}
+		return len(entries)
+	}
+
+	// First fetch stores a baseline without creating an entry.
+	if err := FetchResource(app, resource, server.Client()); err != nil {
+		t.Fatalf("baseline fetch: %v", err)
+	}
+	if n := countEntries(); n != 0 {
+		t.Fatalf("baseline fetch created %d entries, want 0", n)
+	}
+	if resource.GetString("page_snapshot_hash") == "" {
+		t.Fatal("expected baseline snapshot hash to be stored")
+	}
+
+	// Unchanged content creates nothing.
+	if err := FetchResource(app, resource, server.Client()); err != nil {
+		t.Fatalf("unchanged fetch: %v", err)
+	}
+	if n := countEntries(); n != 0 {
+		t.Fatalf("unchanged fetch created %d entries, want 0", n)
+	}
+
+	// Changes inside the watched region create one entry with a diff.
+	version.Store(2)
+	if err := FetchResource(app, resource, server.Client()); err != nil {
+		t.Fatalf("changed fetch: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	entries, err := app.FindRecordsByFilter("entries", "resource = {:id}", "", 0, 0, map[string]any{"id": resource.Id})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("changed fetch created %d entries (err %v), want 1", len(entries), err)
+	}
+	entry := entries[0]
+	diff := entry.GetString("change_diff")
+	for _, want := range []string{"- Starter: $10/month", "+ Starter: $12/month", "+ Enterprise: contact us", "  Team: $50/month"} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("diff missing %q:\n%s", want, diff)
+		}
+	}
+	if strings.Contains(diff, "Careers") || strings.Contains(diff, "Copyright") {
+		t.Errorf("diff includes changes outside the watched region:\n%s", diff)
+	}
+	if !strings.HasPrefix(entry.GetString("title"), "Pricing — changed ") {
+		t.Errorf("title = %q", entry.GetString("title"))
+	}
+	raw := entry.GetString("raw_content")
+	if !strings.Contains(raw, "<ins>+ Enterprise: contact us</ins>") || !strings.Contains(raw, "<del>- Starter: $10/month</del>") {
+		t.Errorf("raw_content missing rendered diff:\n%s", raw)
+	}
+	if !strings.Contains(entry.GetString("guid"), "#change-") {
+		t.Errorf("guid = %q, want change suffix", entry.GetString("guid"))
+	}
+}
+
+func TestFetchResource_PageWatchKeepsSnapshotWhenEntryFails(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	var version atomic.Int32
+	version.Store(1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if version.Load() == 1 {
+			w.Write([]byte(pricingPageV1))
+			return
+		}
+		w.Write([]byte(pricingPageV2))
+	}))
+	defer server.Close()
+
+	resource := testutil.CreateResource(t, app, "Pricing", server.URL, "pagewatch", "healthy", 0, true)
+	resource.Set("content_selector", "#pricing")
+	app.Save(resource)
+	if err := FetchResource(app, resource, server.Client()); err != nil {
+		t.Fatalf("baseline fetch: %v", err)
+	}
+	baseline := resource.GetString("page_snapshot_hash")
+
+	var failCreate atomic.Bool
+	failCreate.Store(true)
+	app.OnRecordCreate("entries").BindFunc(func(e *core.RecordEvent) error {
+		if failCreate.Load() {
+			return fmt.Errorf("disk full")
+		}
+		return e.Next()
+	})
+
+	version.Store(2)
+	if err := FetchResource(app, resource, server.Client()); err == nil {
+		t.Fatal("expected the failed entry to fail the fetch")
+	}
+	stored, _ := app.FindRecordById("resources", resource.Id)
+	if stored.GetString("page_snapshot_hash") != baseline {
+		t.Fatal("expected the snapshot to stay at the baseline so the change is retried")
+	}
+
+	// The next fetch reports the change.
+	failCreate.Store(false)
+	if err := FetchResource(app, stored, server.Client()); err != nil {
+		t.Fatalf("retry fetch: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	entries, _ := app.FindRecordsByFilter("entries", "resource = {:id}", "", 0, 0, map[string]any{"id": resource.Id})
+	if len(entries) != 1 || stored.GetString("page_snapshot_hash") == baseline {
+		t.Errorf("expected the retry to create the entry and store the snapshot, got %d entries", len(entries))
+	}
+}
+
+func TestFetchResource_PageWatchSelectorMissing(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(pricingPageV1))
+	}))
+	defer server.Close()
+
+	resource := testutil.CreateResource(t, app, "Pricing", server.URL, "pagewatch", "healthy", 0, true)
+	resource.Set("content_selector", "#does-not-exist")
+	app.Save(resource)
+
+	err := FetchResource(app, resource, server.Client())
+	if err == nil || !strings.Contains(err.Error(), "matched nothing") {
+		t.Fatalf("err = %v, want selector error", err)
+	}
+}
+
+func TestFetchResource_PageWatchBrowserFallback(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	orig := BrowserFetchBodyFunc
+	BrowserFetchBodyFunc = func(targetURL string) (string, error) {
+		return pricingPageV1, nil
+	}
+	defer func() { BrowserFetchBodyFunc = orig }()
+
+	resource := testutil.CreateResource(t, app, "Pricing", server.URL, "pagewatch", "healthy", 0, true)
+	resource.Set("content_selector", "#pricing")
+	app.Save(resource)
+
+	if err := FetchResource(app, resource, server.Client()); err != nil {
+		t.Fatalf("FetchResource: %v", err)
+	}
+	if !resource.GetBool("use_browser") {
+		t.Error("expected resource to be marked use_browser")
+	}
+	if resource.GetString("page_snapshot") == "" {
+		t.Error("expected snapshot from browser body")
+	}
+}
+
+func TestExtractPageRegion_Readability(t *testing.T) {
+	title, snapshot, err := extractPageRegion(testArticleHTML, "https://example.com/post", "")
+	if err != nil {
+		t.Fatalf("extractPageRegion: %v", err)
+	}
+	if title == "" || snapshot == "" {
+		t.Fatalf("title=%q snapshot=%q, want both set", title, snapshot)
+	}
+	for _, line := range strings.Split(snapshot, "\n") {
+		if line != strings.TrimSpace(line) || strings.Contains(line, "  ") {
+			t.Errorf("snapshot line not normalized: %q", line)
+		}
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	if got := DiffLines("a\nb\nc", "a\nb\nc"); got != "" {
+		t.Errorf("identical snapshots diff = %q, want empty", got)
+	}
+
+	var oldLines, newLines []string
+	for i := 0; i < 20; i++ {
+		oldLines = append(oldLines, fmt.Sprintf("line %d", i))
+		newLines = append(newLines, fmt.Sprintf("line %d", i))
+	}
+	newLines[2] = "line two"
+	newLines[17] = "line seventeen"
+
+	got := DiffLines(strings.Join(oldLines, "\n"), strings.Join(newLines, "\n"))
+	want := strings.Join([]string{
+		"  line 0",
+		"  line 1",
+		"- line 2",
+		"+ line two",
+		"  line 3",
+		"  line 4",
+		"  …",
+		"  line 15",
+		"  line 16",
+		"- line 17",
+		"+ line seventeen",
+		"  line 18",
+		"  line 19",
+	}, "\n")
+	if got != want {
+		t.Errorf("DiffLines =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDiffLines_FromEmpty(t *testing.T) {
+	if got := DiffLines("", "new"); got != "+ new" {
+		t.Errorf("DiffLines from empty = %q", got)
+	}
+}
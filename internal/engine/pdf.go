@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"fmt"
+	"html"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/jgordijn/knowledgehub/internal/pdf"
+)
+
+// maxPDFTitleLength bounds a title taken from the first line of a PDF.
+const maxPDFTitleLength = 200
+
+// isPDFContentType reports whether a Content-Type header denotes a PDF.
+func isPDFContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/pdf" || mediaType == "application/x-pdf"
+}
+
+// isPDFDocument detects PDFs by Content-Type or by file signature (servers
+// often send PDFs as application/octet-stream).
+func isPDFDocument(body []byte, contentType string) bool {
+	return isPDFContentType(contentType) || pdf.IsPDF(body)
+}
+
+// ExtractPDFContent extracts the text of a PDF document. Title, author and
+// subject come from the document info dictionary; when the title is missing
+// or just a file name, the first line of the first page is used. The HTML
+// rendering has one section per page, and Pages keeps the per-page text for
+// page-aware chat context.
+func ExtractPDFContent(data []byte, sourceURL string) (ExtractedContent, error) {
+	doc, err := pdf.Extract(data)
+	if err != nil {
+		return ExtractedContent{}, fmt.Errorf("extracting PDF %s: %w", sourceURL, err)
+	}
+
+	extracted := ExtractedContent{
+		Title:   pdfTitle(doc, sourceURL),
+		Content: doc.Text(),
+		Byline:  doc.Author,
+		Excerpt: doc.Subject,
+		Pages:   doc.Pages,
+	}
+	if parsed, err := url.Parse(sourceURL); err == nil {
+		extracted.SiteName = parsed.Host
+	}
+	extracted.HTML = pdfPagesHTML(doc.Pages)
+	extracted.Markdown = ai.HTMLToMarkdown(extracted.HTML)
+	extracted.WordCount = len(strings.Fields(extracted.Content))
+	extracted.Quality = ExtractionQuality(extracted)
+	return extracted, nil
+}
+
+// pdfTitle picks the document title. Producers often store the source file
+// name ("paper.pdf", "Microsoft Word - draft.docx") in the info dictionary.
+func pdfTitle(doc *pdf.Document, sourceURL string) string {
+	title := strings.TrimSpace(doc.Title)
+	lower := strings.ToLower(title)
+	fileLike := strings.HasPrefix(lower, "microsoft word - ") || (path.Ext(lower) != "" && !strings.Contains(lower, " "))
+	if title != "" && !fileLike {
+		return title
+	}
+	if len(doc.Pages) > 0 {
+		first, _, _ := strings.Cut(strings.TrimSpace(doc.Pages[0]), "\n")
+		if first = strings.TrimSpace(first); first != "" {
+			return truncate(first, maxPDFTitleLength)
+		}
+	}
+	if title != "" {
+		return title
+	}
+	return sourceURL
+}
+
+// pdfPagesHTML renders page text as HTML, one section per page.
+func pdfPagesHTML(pages []string) string {
+	var sb strings.Builder
+	for i, page := range pages {
+		if strings.TrimSpace(page) == "" {
+			continue
+		}
+		fmt.Fprintf(&sb, "<section id=\"page-%d\">\n<h2>Page %d</h2>\n", i+1, i+1)
+		for _, para := range strings.Split(page, "\n\n") {
+			if para = strings.TrimSpace(para); para != "" {
+				sb.WriteString("<p>" + html.EscapeString(para) + "</p>\n")
+			}
+		}
+		sb.WriteString("</section>\n")
+	}
+	return sb.String()
+}
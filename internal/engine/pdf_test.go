@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/jgordijn/knowledgehub/internal/pdf"
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+)
+
+func servePDF(t *testing.T, contentType string, data []byte) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestExtractContent_PDFByContentType(t *testing.T) {
+	data := testutil.BuildPDF("Attention Is All You Need", "A. Vaswani",
+		"Abstract\n\nThe dominant sequence transduction models are based on\ncomplex recurrent networks.",
+		"Introduction\n\nRecurrent models factor computation along positions.")
+	srv := servePDF(t, "application/pdf", data)
+
+	extracted, err := ExtractContent(srv.URL+"/paper.pdf", srv.Client())
+	if err != nil {
+		t.Fatalf("ExtractContent: %v", err)
+	}
+	if extracted.Title != "Attention Is All You Need" || extracted.Byline != "A. Vaswani" {
+		t.Errorf("unexpected metadata: title=%q byline=%q", extracted.Title, extracted.Byline)
+	}
+	if len(extracted.Pages) != 2 {
+		t.Fatalf("expected 2 pages, got %d", len(extracted.Pages))
+	}
+	if !strings.Contains(extracted.Content, "based on complex recurrent networks.") {
+		t.Errorf("expected joined lines in content, got %q", extracted.Content)
+	}
+	if !strings.Contains(extracted.HTML, `<section id="page-2">`) || !strings.Contains(extracted.HTML, "<h2>Page 2</h2>") {
+		t.Errorf("expected per-page sections in HTML, got %q", extracted.HTML)
+	}
+	if !strings.Contains(extracted.Markdown, "## Page 1") {
+		t.Errorf("expected page headings in Markdown, got %q", extracted.Markdown)
+	}
+	if extracted.WordCount == 0 {
+		t.Error("expected a word count")
+	}
+}
+
+func TestExtractContent_PDFSniffedFromBody(t *testing.T) {
+	data := testutil.BuildPDF("", "", "Slides on Distributed Systems\n\nConsensus is hard.")
+	srv := servePDF(t, "application/octet-stream", data)
+
+	extracted, err := ExtractContent(srv.URL+"/download?id=1", srv.Client())
+	if err != nil {
+		t.Fatalf("ExtractContent: %v", err)
+	}
+	if extracted.Title != "Slides on Distributed Systems" {
+		t.Errorf("expected title from first line, got %q", extracted.Title)
+	}
+	if len(extracted.Pages) != 1 {
+		t.Errorf("expected 1 page, got %d", len(extracted.Pages))
+	}
+}
+
+func TestExtractContent_InvalidPDF(t *testing.T) {
+	srv := servePDF(t, "application/pdf", []byte("%PDF-1.4\ngarbage"))
+
+	if _, err := ExtractContent(srv.URL+"/broken.pdf", srv.Client()); err == nil {
+		t.Fatal("expected error for a PDF without pages")
+	}
+}
+
+func TestPDFTitle(t *testing.T) {
+	cases := []struct {
+		title string
+		want  string
+	}{
+		{"A Survey of Vector Databases", "A Survey of Vector Databases"},
+		{"paper_final_v2.pdf", "First Line"},
+		{"Microsoft Word - draft.docx", "First Line"},
+		{"", "First Line"},
+	}
+	for _, tc := range cases {
+		doc := &pdf.Document{Title: tc.title, Pages: []string{"First Line\n\nBody"}}
+		if got := pdfTitle(doc, "https://example.com/x.pdf"); got != tc.want {
+			t.Errorf("pdfTitle(%q) = %q, want %q", tc.title, got, tc.want)
+		}
+	}
+	if got := pdfTitle(&pdf.Document{Pages: []string{""}}, "https://example.com/x.pdf"); got != "https://example.com/x.pdf" {
+		t.Errorf("expected URL fallback, got %q", got)
+	}
+}
+
+func TestIsPDFContentType(t *testing.T) {
+	for ct, want := range map[string]bool{
+		"application/pdf":                 true,
+		"application/pdf; charset=binary": true,
+		"Application/PDF":                 true,
+		"text/html; charset=utf-8":        false,
+		"":                                false,
+	} {
+		if got := isPDFContentType(ct); got != want {
+			t.Errorf("isPDFContentType(%q) = %v, want %v", ct, got, want)
+		}
+	}
+}
+
+func TestFeedItemLinkingToPDF(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	origBrowser := BrowserExtractFunc
+	BrowserExtractFunc = func(string) (ExtractedContent, error) {
+		t.Fatal("browser must not be used for PDFs")
+		return ExtractedContent{}, nil
+	}
+	defer func() { BrowserExtractFunc = origBrowser }()
+
+	srv := servePDF(t, "application/pdf", testutil.BuildPDF("Quarterly Report", "Finance Team",
+		"Revenue grew in every region.", "Outlook remains positive."))
+	resource := testutil.CreateResource(t, app, "Reports", srv.URL+"/feed", "rss", "healthy", 0, true)
+
+	extracted, err := extractWithBrowserFallback(app, resource, srv.URL+"/q3.pdf", srv.Client())
+	if err != nil {
+		t.Fatalf("extractWithBrowserFallback: %v", err)
+	}
+
+	entry := testutil.CreateEntry(t, app, resource.Id, extracted.Title, srv.URL+"/q3.pdf", "guid-q3")
+	SetExtractedFields(entry, extracted)
+	if err := app.Save(entry); err != nil {
+		t.Fatalf("saving entry: %v", err)
+	}
+
+	saved, err := app.FindRecordById("entries", entry.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages := ai.EntryPDFPages(saved)
+	if len(pages) != 2 || pages[1] != "Outlook remains positive." {
+		t.Errorf("unexpected stored pages: %q", pages)
+	}
+	if saved.GetString("byline") != "Finance Team" {
+		t.Errorf("expected byline from PDF author, got %q", saved.GetString("byline"))
+	}
+	if reloaded, _ := app.FindRecordById("resources", resource.Id); reloaded.GetBool("use_browser") {
+		t.Error("expected use_browser to stay false for PDF extraction")
+	}
+}
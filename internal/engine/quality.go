@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// QualityThreshold is the minimum ExtractionQuality for an extraction step to
+// be accepted; below it the chain moves on to the next step.
+const QualityThreshold = 0.5
+
+// qualityFullWords is the word count at which the length component maxes out.
+const qualityFullWords = 300
+
+// lowQualityPhrases indicate that the page content is a placeholder (JS
+// required, paywall, consent wall) rather than the article.
+var lowQualityPhrases = []string{
+	"enable javascript",
+	"javascript is disabled",
+	"javascript is required",
+	"please enable cookies",
+	"subscribe to continue reading",
+	"subscribe to read",
+	"access denied",
+	"are you a robot",
+}
+
+// ExtractionQuality scores an extraction from 0 to 1 based on text length,
+// link density and paragraph structure, penalizing JS and paywall notices.
+func ExtractionQuality(extracted ExtractedContent) float64 {
+	text := strings.TrimSpace(extracted.Content)
+	words := len(strings.Fields(text))
+	if words == 0 {
+		return 0
+	}
+
+	lengthScore := float64(words) / qualityFullWords
+	if lengthScore > 1 {
+		lengthScore = 1
+	}
+
+	linkScore, paragraphScore := 1.0, 0.5
+	if extracted.HTML != "" {
+		if doc, err := goquery.NewDocumentFromReader(strings.NewReader(extracted.HTML)); err == nil {
+			linkWords := 0
+			doc.Find("a").Each(func(_ int, s *goquery.Selection) {
+				linkWords += len(strings.Fields(s.Text()))
+			})
+			linkScore = 1 - float64(linkWords)/float64(words)
+			if linkScore < 0 {
+				linkScore = 0
+			}
+			paragraphs := doc.Find("p, pre, li, blockquote").Length()
+			paragraphScore = float64(paragraphs) / 5
+			if paragraphScore > 1 {
+				paragraphScore = 1
+			}
+		}
+	}
+
+	score := 0.6*lengthScore + 0.2*linkScore + 0.2*paragraphScore
+
+	if words < qualityFullWords {
+		lower := strings.ToLower(text)
+		for _, phrase := range lowQualityPhrases {
+			if strings.Contains(lower, phrase) {
+				score /= 2
+				break
+			}
+		}
+	}
+	return score
+}
+
+// jsAppMarkers are found in pages whose content is rendered client-side.
+var jsAppMarkers = []string{
+	`id="__next"`,
+	`__NEXT_DATA__`,
+	`id="__nuxt"`,
+	`id="root"></div>`,
+	`id="app"></div>`,
+	`ng-version=`,
+}
+
+// looksJSRendered reports whether a page's HTML suggests its content is
+// rendered by JavaScript, in which case a browser may extract it better.
+func looksJSRendered(pageHTML string) bool {
+	lower := strings.ToLower(pageHTML)
+	for _, marker := range jsAppMarkers {
+		if strings.Contains(lower, strings.ToLower(marker)) {
+			return true
+		}
+	}
+	return strings.Contains(lower, "<noscript") && strings.Contains(lower, "enable javascript")
+}
@@ -2,82 +2,251 @@ package engine
 
 import (
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
 
+	"github.com/PuerkitoBio/goquery"
 	readability "github.com/go-shiori/go-readability"
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/jgordijn/knowledgehub/internal/pdf"
+	"github.com/pocketbase/pocketbase/core"
 )
 
 // ExtractedContent holds the result of readability extraction.
 type ExtractedContent struct {
-	Title   string
-	Content string
+	Title     string
+	Content   string // plain text
+	HTML      string // sanitized article HTML (headings, lists, code, links)
+	Markdown  string // Markdown rendering of HTML
+	Byline    string
+	SiteName  string
+	Excerpt   string
+	Image     string // lead image URL
+	Language  string
+	WordCount int
+	Quality   float64  // ExtractionQuality of the chosen extraction step
+	Pages     []string // per-page text when the URL is a PDF document
 }
 
-// ExtractContent fetches a URL and extracts its main content using readability.
+// maxArticleSize caps how much of an article page is read; PDF documents
+// may be up to maxPDFSize.
+const (
+	maxArticleSize = 10 << 20
+	maxPDFSize     = 50 << 20
+)
+
+// ExtractContent fetches a URL and extracts its main content. It applies the
+// domain's site rule first (URL rewrites, single-page/AMP versions, body
+// selectors, multi-page articles) and falls back to readability when the rule
+// is missing or its result scores below QualityThreshold. PDF documents
+// (by Content-Type or file signature) are handled by ExtractPDFContent.
 // Falls back to title + first 500 chars on failure.
 func ExtractContent(articleURL string, client *http.Client) (ExtractedContent, error) {
-	parsed, err := url.Parse(articleURL)
-	if err != nil {
-		return ExtractedContent{}, fmt.Errorf("invalid URL %s: %w", articleURL, err)
+	fetchURL := articleURL
+	rule := siteRuleForURL(articleURL)
+	if rule != nil {
+		fetchURL = rule.RewriteURL(articleURL)
 	}
 
-	resp, err := client.Get(articleURL)
+	parsed, err := url.Parse(fetchURL)
 	if err != nil {
-		return ExtractedContent{}, fmt.Errorf("fetching %s: %w", articleURL, err)
+		return ExtractedContent{}, fmt.Errorf("invalid URL %s: %w", fetchURL, err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return ExtractedContent{}, fmt.Errorf("HTTP %d for %s", resp.StatusCode, articleURL)
+	fetchPage := func(pageURL string) (string, error) {
+		return fetchArticleHTML(pageURL, client)
 	}
-
-	article, err := readability.FromReader(resp.Body, parsed)
+	body, contentType, err := fetchArticle(fetchURL, client)
 	if err != nil {
-		// Fallback: return empty content with the URL as title
-		return ExtractedContent{Title: articleURL}, nil
+		return ExtractedContent{}, err
 	}
-
-	content := article.TextContent
-	title := article.Title
-
-	if content == "" && article.Content != "" {
-		content = article.Content
+	if isPDFDocument(body, contentType) {
+		return ExtractPDFContent(body, articleURL)
 	}
+	pageHTML := string(body)
 
-	// Fallback: if readability produced no content, use title + first 500 chars
-	if strings.TrimSpace(content) == "" {
-		content = truncate(article.Content, 500)
+	if rule != nil {
+		if alt := alternatePageURL(rule, pageHTML, parsed); alt != "" {
+			if altHTML, err := fetchPage(alt); err == nil {
+				pageHTML = altHTML
+				parsed, _ = url.Parse(alt)
+			} else {
+				log.Printf("Failed to fetch alternate page %s: %v", alt, err)
+			}
+		}
 	}
 
-	return ExtractedContent{
-		Title:   title,
-		Content: content,
-	}, nil
+	extracted, err := extractPage(pageHTML, parsed, rule, fetchPage)
+	if err != nil {
+		// Fallback: return empty content with the URL as title
+		return ExtractedContent{Title: articleURL}, nil
+	}
+	return extracted, nil
 }
 
-// ExtractContentFromHTML parses HTML content directly without fetching.
+// ExtractContentFromHTML parses HTML content directly without fetching,
+// applying the domain's site rule (without following next-page links).
 func ExtractContentFromHTML(htmlContent string, sourceURL string) ExtractedContent {
 	parsed, _ := url.Parse(sourceURL)
 	if parsed == nil {
 		parsed = &url.URL{}
 	}
 
-	article, err := readability.FromReader(strings.NewReader(htmlContent), parsed)
+	extracted, err := extractPage(htmlContent, parsed, siteRuleFor(parsed.Host), nil)
 	if err != nil {
 		return ExtractedContent{Title: sourceURL, Content: truncate(htmlContent, 500)}
 	}
+	return extracted
+}
+
+// fetchArticleHTML GETs a page and returns its body.
+func fetchArticleHTML(pageURL string, client *http.Client) (string, error) {
+	body, _, err := fetchArticle(pageURL, client)
+	return string(body), err
+}
+
+// fetchArticle GETs a page and returns its body and Content-Type. Bodies
+// are capped at maxArticleSize, or maxPDFSize for PDF documents (declared or
+// sniffed).
+func fetchArticle(pageURL string, client *http.Client) ([]byte, string, error) {
+	resp, err := client.Get(pageURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("fetching %s: %w", pageURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("HTTP %d for %s", resp.StatusCode, pageURL)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	limit := int64(maxArticleSize)
+	if isPDFContentType(contentType) {
+		limit = maxPDFSize
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err == nil && int64(len(body)) == limit && limit < maxPDFSize && pdf.IsPDF(body) {
+		var rest []byte
+		rest, err = io.ReadAll(io.LimitReader(resp.Body, maxPDFSize-limit))
+		body = append(body, rest...)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("reading %s: %w", pageURL, err)
+	}
+	return body, contentType, nil
+}
+
+// extractPage runs the page-level extraction chain: the site rule (if any),
+// then readability. The first result reaching QualityThreshold wins;
+// otherwise the best-scoring result is returned.
+func extractPage(pageHTML string, base *url.URL, rule *SiteRule, fetchPage func(string) (string, error)) (ExtractedContent, error) {
+	article, readErr := parseArticle(strings.NewReader(pageHTML), base)
+
+	var ruled *ExtractedContent
+	if rule != nil && len(rule.Body) > 0 {
+		if title, body, ok := applySiteRule(rule, pageHTML, base, fetchPage); ok {
+			extracted := siteRuleContent(article, title, body, base)
+			extracted.Quality = ExtractionQuality(extracted)
+			if extracted.Quality >= QualityThreshold || !rule.AutodetectOnErr {
+				return extracted, nil
+			}
+			ruled = &extracted
+		}
+	}
+
+	if readErr != nil {
+		if ruled != nil {
+			return *ruled, nil
+		}
+		return ExtractedContent{}, readErr
+	}
 
 	content := article.TextContent
 	if strings.TrimSpace(content) == "" {
 		content = truncate(article.Content, 500)
 	}
+	extracted := newExtractedContent(article, content, base)
+	extracted.Quality = ExtractionQuality(extracted)
 
-	return ExtractedContent{
-		Title:   article.Title,
-		Content: content,
+	if ruled != nil && ruled.Quality > extracted.Quality {
+		return *ruled, nil
+	}
+	return extracted, nil
+}
+
+// siteRuleContent builds the extraction result for a site rule body,
+// borrowing metadata (byline, site name, image) from the readability parse.
+func siteRuleContent(article readability.Article, title, bodyHTML string, base *url.URL) ExtractedContent {
+	if title == "" {
+		title = article.Title
+	}
+	article.Title = title
+	extracted := newExtractedContent(article, "", base)
+	extracted.HTML = SanitizeArticleHTML(bodyHTML, base)
+	extracted.Markdown = ai.HTMLToMarkdown(extracted.HTML)
+	if doc, err := goquery.NewDocumentFromReader(strings.NewReader(extracted.HTML)); err == nil {
+		extracted.Content = strings.TrimSpace(doc.Text())
+	}
+	extracted.WordCount = len(strings.Fields(extracted.Content))
+	return extracted
+}
+
+// parseArticle runs readability, keeping class attributes so code block
+// languages (class="language-go") survive; SanitizeArticleHTML drops the rest.
+func parseArticle(r io.Reader, pageURL *url.URL) (readability.Article, error) {
+	parser := readability.NewParser()
+	parser.KeepClasses = true
+	return parser.Parse(r, pageURL)
+}
+
+// newExtractedContent builds the extraction result from a readability
+// article: plain text, sanitized HTML, Markdown and article metadata.
+func newExtractedContent(article readability.Article, content string, base *url.URL) ExtractedContent {
+	extracted := ExtractedContent{
+		Title:     article.Title,
+		Content:   content,
+		Byline:    strings.TrimSpace(article.Byline),
+		SiteName:  strings.TrimSpace(article.SiteName),
+		Excerpt:   strings.TrimSpace(article.Excerpt),
+		Language:  strings.TrimSpace(article.Language),
+		WordCount: len(strings.Fields(article.TextContent)),
+	}
+	if article.Image != "" {
+		extracted.Image = resolveURL(base, article.Image)
+	}
+	if strings.TrimSpace(article.Content) != "" {
+		extracted.HTML = SanitizeArticleHTML(article.Content, base)
+		extracted.Markdown = ai.HTMLToMarkdown(extracted.HTML)
+	}
+	return extracted
+}
+
+// SetExtractedFields copies the structured content and readability metadata
+// of an extraction onto an entry record. Empty values are left unset.
+func SetExtractedFields(record *core.Record, extracted ExtractedContent) {
+	fields := map[string]string{
+		"content_html":     extracted.HTML,
+		"content_markdown": extracted.Markdown,
+		"byline":           extracted.Byline,
+		"site_name":        extracted.SiteName,
+		"excerpt":          extracted.Excerpt,
+		"lead_image":       extracted.Image,
+		"language":         extracted.Language,
+	}
+	for field, value := range fields {
+		if value != "" {
+			record.Set(field, value)
+		}
+	}
+	if extracted.WordCount > 0 {
+		record.Set("word_count", extracted.WordCount)
+	}
+	if len(extracted.Pages) > 0 {
+		record.Set("pdf_pages", extracted.Pages)
 	}
 }
 
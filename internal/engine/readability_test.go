@@ -5,6 +5,9 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+	"github.com/pocketbase/pocketbase/core"
 )
 
 const testArticleHTML = `<!DOCTYPE html>
@@ -119,3 +122,88 @@ func TestTruncate(t *testing.T) {
 		})
 	}
 }
+
+const testTechArticleHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <title>Understanding Go Channels</title>
+  <meta property="og:site_name" content="Go Blog">
+  <meta property="og:image" content="/images/channels.png">
+  <meta name="description" content="A practical guide to channels.">
+  <meta name="author" content="Jane Gopher">
+</head>
+<body>
+  <article>
+    <h1>Understanding Go Channels</h1>
+    <h2>Buffered channels</h2>
+    <p>Channels are the pipes that connect concurrent goroutines. You can send values into channels
+    from one goroutine and receive those values into another goroutine. See the
+    <a href="/ref/spec#Channel_types" onclick="track()">language spec</a> for details.</p>
+    <ul><li>Unbuffered channels block until both sides are ready.</li><li>Buffered channels block only when full.</li></ul>
+    <pre><code class="language-go">ch := make(chan int, 2)
+ch &lt;- 1</code></pre>
+    <p>Closing a channel signals that no more values will be sent, which is useful to communicate
+    completion to the channel's receivers. Receivers can test whether a channel has been closed.</p>
+    <script>alert("x")</script>
+  </article>
+</body>
+</html>`
+
+func TestExtractContentFromHTML_StructuredContentAndMetadata(t *testing.T) {
+	result := ExtractContentFromHTML(testTechArticleHTML, "https://go.example/blog/channels")
+
+	if strings.Contains(result.Content, "<h2>") || strings.Contains(result.Content, "<p>") {
+		t.Errorf("Content should stay plain text, got %q", result.Content)
+	}
+	for _, want := range []string{"<h2>Buffered channels</h2>", "<li>Buffered channels block only when full.</li>", `<code class="language-go">`, `href="https://go.example/ref/spec#Channel_types"`} {
+		if !strings.Contains(result.HTML, want) {
+			t.Errorf("HTML missing %q:\n%s", want, result.HTML)
+		}
+	}
+	if strings.Contains(result.HTML, "onclick") || strings.Contains(result.HTML, "alert") {
+		t.Errorf("HTML not sanitized:\n%s", result.HTML)
+	}
+	for _, want := range []string{"## Buffered channels", "- Buffered channels block only when full.", "```go", "[language spec](https://go.example/ref/spec#Channel_types)"} {
+		if !strings.Contains(result.Markdown, want) {
+			t.Errorf("Markdown missing %q:\n%s", want, result.Markdown)
+		}
+	}
+
+	if result.Byline != "Jane Gopher" {
+		t.Errorf("Byline = %q", result.Byline)
+	}
+	if result.SiteName != "Go Blog" {
+		t.Errorf("SiteName = %q", result.SiteName)
+	}
+	if result.Excerpt != "A practical guide to channels." {
+		t.Errorf("Excerpt = %q", result.Excerpt)
+	}
+	if result.Image != "https://go.example/images/channels.png" {
+		t.Errorf("Image = %q", result.Image)
+	}
+	if result.Language != "en" {
+		t.Errorf("Language = %q", result.Language)
+	}
+	if result.WordCount < 50 {
+		t.Errorf("WordCount = %d, want the article's word count", result.WordCount)
+	}
+}
+
+func TestSetExtractedFields(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	collection, _ := app.FindCollectionByNameOrId("entries")
+	record := core.NewRecord(collection)
+	SetExtractedFields(record, ExtractContentFromHTML(testTechArticleHTML, "https://go.example/blog/channels"))
+
+	if record.GetString("content_markdown") == "" || record.GetString("content_html") == "" {
+		t.Error("expected structured content to be set")
+	}
+	if record.GetString("byline") != "Jane Gopher" || record.GetString("site_name") != "Go Blog" || record.GetString("language") != "en" {
+		t.Errorf("metadata not set: byline=%q site=%q lang=%q", record.GetString("byline"), record.GetString("site_name"), record.GetString("language"))
+	}
+	if record.GetInt("word_count") == 0 {
+		t.Error("expected word_count to be set")
+	}
+}
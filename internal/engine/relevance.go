@@ -0,0 +1,485 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Settings for the local relevance model, stored in app_settings.
+const (
+	// SettingRelevanceSkipBelow gates entries predicted below this many stars
+	// (0 or unset disables gating; predictions are still recorded).
+	SettingRelevanceSkipBelow = "relevance_skip_below"
+	// SettingRelevanceMinConfidence is the confidence a prediction needs
+	// before it can gate an entry.
+	SettingRelevanceMinConfidence = "relevance_min_confidence"
+	// SettingRelevanceCheapModel, when set, is used for gated entries instead
+	// of skipping the LLM altogether.
+	SettingRelevanceCheapModel = "relevance_cheap_model"
+)
+
+// MinRelevanceSamples is the number of user-rated entries needed to train
+// the local relevance model.
+const MinRelevanceSamples = 20
+
+const (
+	defaultRelevanceMinConfidence = 0.8
+	maxRelevanceSamples           = 5000
+	relevanceRetrainInterval      = 24 * time.Hour
+	// relevanceHoldoutEvery puts every Nth rated entry in the holdout set used
+	// to report the accuracy of a newly trained model.
+	relevanceHoldoutEvery = 5
+	// relevanceMinTokenDocs drops words and bigrams seen in fewer entries,
+	// keeping the stored model small.
+	relevanceMinTokenDocs = 2
+	relevanceModelHistory = 30
+)
+
+// Score sources recorded on entries that did not get a regular LLM rating.
+const (
+	ScoreSourceLocal      = "local"
+	ScoreSourceCheapModel = "cheap_model"
+)
+
+// RelevanceModel is a multinomial naive Bayes classifier over star ratings
+// 1-5. Features are title and summary words and bigrams, the resource and
+// the tags of an entry.
+type RelevanceModel struct {
+	// Docs counts training entries per star rating; index 0 is 1 star.
+	Docs [5]int `json:"docs"`
+	// Tokens counts, per feature, the training entries of each rating that
+	// contain it.
+	Tokens map[string][5]int `json:"tokens"`
+	// Totals is the summed feature count per rating.
+	Totals [5]int `json:"totals"`
+}
+
+// RelevancePrediction is the local model's guess for an entry.
+type RelevancePrediction struct {
+	Stars      int
+	Confidence float64
+}
+
+// TrainRelevanceModel fits a model on the given user-rated entries.
+func TrainRelevanceModel(entries []*core.Record) *RelevanceModel {
+	docFreq := map[string]int{}
+	features := make([][]string, len(entries))
+	for i, entry := range entries {
+		features[i] = relevanceFeatures(entry)
+		for _, f := range features[i] {
+			docFreq[f]++
+		}
+	}
+
+	m := &RelevanceModel{Tokens: map[string][5]int{}}
+	for i, entry := range entries {
+		class := entry.GetInt("user_stars") - 1
+		if class < 0 || class > 4 {
+			continue
+		}
+		m.Docs[class]++
+		for _, f := range features[i] {
+			if docFreq[f] < relevanceMinTokenDocs && !strings.HasPrefix(f, "r:") {
+				continue
+			}
+			counts := m.Tokens[f]
+			counts[class]++
+			m.Tokens[f] = counts
+			m.Totals[class]++
+		}
+	}
+	return m
+}
+
+// Predict returns the most likely star rating for an entry and the model's
+// posterior probability for it.
+func (m *RelevanceModel) Predict(entry *core.Record) RelevancePrediction {
+	totalDocs := 0
+	for _, n := range m.Docs {
+		totalDocs += n
+	}
+	if totalDocs == 0 {
+		return RelevancePrediction{}
+	}
+
+	vocab := float64(len(m.Tokens))
+	var logp [5]float64
+	for c := range logp {
+		// Laplace smoothing keeps unseen ratings and features possible.
+		logp[c] = math.Log(float64(m.Docs[c]+1) / float64(totalDocs+5))
+	}
+	for _, f := range relevanceFeatures(entry) {
+		counts, ok := m.Tokens[f]
+		if !ok {
+			continue
+		}
+		for c := range logp {
+			logp[c] += math.Log(float64(counts[c]+1) / (float64(m.Totals[c]) + vocab))
+		}
+	}
+
+	best, maxLog := 0, logp[0]
+	for c, v := range logp {
+		if v > maxLog {
+			best, maxLog = c, v
+		}
+	}
+	sum := 0.0
+	for _, v := range logp {
+		sum += math.Exp(v - maxLog)
+	}
+	return RelevancePrediction{Stars: best + 1, Confidence: 1 / sum}
+}
+
+// relevanceFeatures extracts the distinct features of an entry. Entries not
+// yet summarized use their excerpt in place of the summary.
+func relevanceFeatures(entry *core.Record) []string {
+	text := entry.GetString("summary")
+	if text == "" {
+		text = entry.GetString("excerpt")
+	}
+	words := relevanceWords(entry.GetString("title") + " " + text)
+
+	seen := map[string]bool{}
+	var out []string
+	add := func(f string) {
+		if !seen[f] {
+			seen[f] = true
+			out = append(out, f)
+		}
+	}
+	for i, w := range words {
+		add("w:" + w)
+		if i > 0 {
+			add("b:" + words[i-1] + " " + w)
+		}
+	}
+	if res := entry.GetString("resource"); res != "" {
+		add("r:" + res)
+	}
+	for _, tag := range ai.EntryTags(entry) {
+		add("t:" + tag)
+	}
+	return out
+}
+
+var relevanceStopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "has": true, "in": true, "is": true, "it": true, "its": true, "of": true,
+	"on": true, "or": true, "that": true, "the": true, "this": true, "to": true, "was": true, "with": true,
+}
+
+func relevanceWords(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	words := fields[:0]
+	for _, w := range fields {
+		if len(w) > 1 && !relevanceStopwords[w] {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// TrainRelevanceModelIfDue trains a new model when the latest one is older
+// than relevanceRetrainInterval and enough entries are rated.
+func TrainRelevanceModelIfDue(app core.App, now time.Time) (*core.Record, error) {
+	latest, err := app.FindRecordsByFilter("relevance_models", "1=1", "-trained_at", 1, 0, nil)
+	if err == nil && len(latest) > 0 && now.Sub(latest[0].GetDateTime("trained_at").Time()) < relevanceRetrainInterval {
+		return nil, nil
+	}
+	return TrainAndSaveRelevanceModel(app, now)
+}
+
+// TrainAndSaveRelevanceModel trains a model on every user-rated entry and
+// stores it with its holdout accuracy. Returns nil without error when there
+// are too few rated entries.
+func TrainAndSaveRelevanceModel(app core.App, now time.Time) (*core.Record, error) {
+	rated, err := app.FindRecordsByFilter("entries", "user_stars > 0", "-created", maxRelevanceSamples, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(rated) < MinRelevanceSamples {
+		return nil, nil
+	}
+
+	// Score a model trained without the holdout entries, then train the
+	// stored model on all of them.
+	var train, holdout []*core.Record
+	for i, r := range rated {
+		if i%relevanceHoldoutEvery == relevanceHoldoutEvery-1 {
+			holdout = append(holdout, r)
+		} else {
+			train = append(train, r)
+		}
+	}
+	trial := TrainRelevanceModel(train)
+	correct, absErr := 0, 0
+	for _, r := range holdout {
+		p := trial.Predict(r)
+		if p.Stars == r.GetInt("user_stars") {
+			correct++
+		}
+		absErr += abs(p.Stars - r.GetInt("user_stars"))
+	}
+
+	model := TrainRelevanceModel(rated)
+	data, err := json.Marshal(model)
+	if err != nil {
+		return nil, err
+	}
+
+	collection, err := app.FindCollectionByNameOrId("relevance_models")
+	if err != nil {
+		return nil, fmt.Errorf("relevance_models collection not found: %w", err)
+	}
+	record := core.NewRecord(collection)
+	record.Set("trained_at", now.UTC())
+	record.Set("samples", len(rated))
+	record.Set("holdout", len(holdout))
+	record.Set("accuracy", float64(correct)/float64(len(holdout)))
+	record.Set("mae", float64(absErr)/float64(len(holdout)))
+	record.Set("model", string(data))
+	if err := app.Save(record); err != nil {
+		return nil, err
+	}
+	pruneRelevanceModels(app)
+	return record, nil
+}
+
+// pruneRelevanceModels keeps the training history for the evaluation report
+// but only the parameters of the latest model.
+func pruneRelevanceModels(app core.App) {
+	records, err := app.FindRecordsByFilter("relevance_models", "1=1", "-trained_at", 0, 0, nil)
+	if err != nil {
+		return
+	}
+	for i, r := range records {
+		switch {
+		case i >= relevanceModelHistory:
+			if err := app.Delete(r); err != nil {
+				log.Printf("Relevance model: failed to delete old model %s: %v", r.Id, err)
+			}
+		case i > 0 && r.GetString("model") != "":
+			r.Set("model", nil)
+			if err := app.Save(r); err != nil {
+				log.Printf("Relevance model: failed to clear old model %s: %v", r.Id, err)
+			}
+		}
+	}
+}
+
+var relevanceModelCache struct {
+	sync.Mutex
+	id    string
+	model *RelevanceModel
+}
+
+// LoadRelevanceModel returns the latest trained model, or nil if none has
+// been trained yet.
+func LoadRelevanceModel(app core.App) *RelevanceModel {
+	records, err := app.FindRecordsByFilter("relevance_models", "1=1", "-trained_at", 1, 0, nil)
+	if err != nil || len(records) == 0 {
+		return nil
+	}
+	record := records[0]
+
+	relevanceModelCache.Lock()
+	defer relevanceModelCache.Unlock()
+	if relevanceModelCache.id == record.Id {
+		return relevanceModelCache.model
+	}
+	var model RelevanceModel
+	if err := json.Unmarshal([]byte(record.GetString("model")), &model); err != nil || model.Tokens == nil {
+		return nil
+	}
+	relevanceModelCache.id = record.Id
+	relevanceModelCache.model = &model
+	return &model
+}
+
+// relevanceGate records the local prediction on an entry and decides how to
+// process it: "" for the regular LLM call, ScoreSourceLocal to skip the LLM
+// or ScoreSourceCheapModel to use the cheaper model, which is returned too.
+func relevanceGate(app core.App, entry *core.Record) (string, string) {
+	model := LoadRelevanceModel(app)
+	if model == nil {
+		return "", ""
+	}
+	prediction := model.Predict(entry)
+	entry.Set("local_stars", prediction.Stars)
+	entry.Set("local_confidence", math.Round(prediction.Confidence*1000)/1000)
+
+	threshold, _ := strconv.Atoi(strings.TrimSpace(appSetting(app, SettingRelevanceSkipBelow)))
+	minConfidence := defaultRelevanceMinConfidence
+	if v, err := strconv.ParseFloat(strings.TrimSpace(appSetting(app, SettingRelevanceMinConfidence)), 64); err == nil {
+		minConfidence = v
+	}
+	if threshold <= 0 || prediction.Stars >= threshold || prediction.Confidence < minConfidence {
+		return "", ""
+	}
+	if cheap := strings.TrimSpace(appSetting(app, SettingRelevanceCheapModel)); cheap != "" {
+		return ScoreSourceCheapModel, cheap
+	}
+	return ScoreSourceLocal, ""
+}
+
+// scoreLocally rates an entry with the local prediction alone, without a
+// summary.
+func scoreLocally(app core.App, entry *core.Record) error {
+	entry.Set("ai_stars", entry.GetInt("local_stars"))
+	entry.Set("score_source", ScoreSourceLocal)
+	entry.Set("processing_status", "done")
+	return app.Save(entry)
+}
+
+// RelevanceWeek compares local predictions with AI and user ratings for the
+// entries created in one week.
+type RelevanceWeek struct {
+	Week string `json:"week"`
+	// Predicted counts entries with a local prediction; Skipped those that
+	// were rated by the local model alone.
+	Predicted int `json:"predicted"`
+	Skipped   int `json:"skipped"`
+	// ComparedAI counts entries with both a prediction and an LLM rating.
+	ComparedAI  int     `json:"compared_ai"`
+	AgreementAI float64 `json:"agreement_ai"`
+	MAEAI       float64 `json:"mae_ai"`
+	// ComparedUser counts predicted entries the user rated; the AI columns
+	// show how the LLM did on the same entries.
+	ComparedUser    int     `json:"compared_user"`
+	AgreementUser   float64 `json:"agreement_user"`
+	MAEUser         float64 `json:"mae_user"`
+	AIAgreementUser float64 `json:"ai_agreement_user"`
+	AIMAEUser       float64 `json:"ai_mae_user"`
+}
+
+// RelevanceTraining summarizes one training run.
+type RelevanceTraining struct {
+	TrainedAt time.Time `json:"trained_at"`
+	Samples   int       `json:"samples"`
+	Holdout   int       `json:"holdout"`
+	Accuracy  float64   `json:"accuracy"`
+	MAE       float64   `json:"mae"`
+}
+
+// RelevanceTrainingFromRecord summarizes a relevance_models record.
+func RelevanceTrainingFromRecord(record *core.Record) RelevanceTraining {
+	return RelevanceTraining{
+		TrainedAt: record.GetDateTime("trained_at").Time(),
+		Samples:   record.GetInt("samples"),
+		Holdout:   record.GetInt("holdout"),
+		Accuracy:  record.GetFloat("accuracy"),
+		MAE:       record.GetFloat("mae"),
+	}
+}
+
+// RelevanceReport is the evaluation of the local relevance model.
+type RelevanceReport struct {
+	Trainings []RelevanceTraining `json:"trainings"`
+	Weeks     []RelevanceWeek     `json:"weeks"`
+}
+
+// RelevanceEvaluation reports, per ISO week since the given time, how local
+// predictions compare with ai_stars and user_stars, and lists the training
+// runs with their holdout accuracy.
+func RelevanceEvaluation(app core.App, since time.Time) (RelevanceReport, error) {
+	report := RelevanceReport{Trainings: []RelevanceTraining{}, Weeks: []RelevanceWeek{}}
+
+	models, err := app.FindRecordsByFilter("relevance_models", "trained_at >= {:since}", "trained_at", 0, 0, map[string]any{"since": since.UTC()})
+	if err != nil {
+		return report, err
+	}
+	for _, m := range models {
+		report.Trainings = append(report.Trainings, RelevanceTrainingFromRecord(m))
+	}
+
+	entries, err := app.FindRecordsByFilter("entries", "local_stars > 0 && created >= {:since}", "created", 0, 0, map[string]any{"since": since.UTC()})
+	if err != nil {
+		return report, err
+	}
+	type tally struct {
+		week                               RelevanceWeek
+		agreeAI, errAI, agreeUser, errUser int
+		aiAgreeUser, aiErrUser, aiVsUser   int
+	}
+	weeks := map[string]*tally{}
+	for _, e := range entries {
+		year, wk := e.GetDateTime("created").Time().ISOWeek()
+		key := fmt.Sprintf("%d-W%02d", year, wk)
+		t := weeks[key]
+		if t == nil {
+			t = &tally{week: RelevanceWeek{Week: key}}
+			weeks[key] = t
+		}
+		local, aiStars, userStars := e.GetInt("local_stars"), e.GetInt("ai_stars"), e.GetInt("user_stars")
+		llmRated := e.GetString("score_source") != ScoreSourceLocal && aiStars > 0
+
+		t.week.Predicted++
+		if e.GetString("score_source") == ScoreSourceLocal {
+			t.week.Skipped++
+		}
+		if llmRated {
+			t.week.ComparedAI++
+			t.errAI += abs(local - aiStars)
+			if local == aiStars {
+				t.agreeAI++
+			}
+		}
+		if userStars > 0 {
+			t.week.ComparedUser++
+			t.errUser += abs(local - userStars)
+			if local == userStars {
+				t.agreeUser++
+			}
+			if llmRated {
+				t.aiVsUser++
+				t.aiErrUser += abs(aiStars - userStars)
+				if aiStars == userStars {
+					t.aiAgreeUser++
+				}
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(weeks))
+	for k := range weeks {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		t := weeks[k]
+		w := t.week
+		w.AgreementAI, w.MAEAI = ratio(t.agreeAI, w.ComparedAI), ratio(t.errAI, w.ComparedAI)
+		w.AgreementUser, w.MAEUser = ratio(t.agreeUser, w.ComparedUser), ratio(t.errUser, w.ComparedUser)
+		w.AIAgreementUser, w.AIMAEUser = ratio(t.aiAgreeUser, t.aiVsUser), ratio(t.aiErrUser, t.aiVsUser)
+		report.Weeks = append(report.Weeks, w)
+	}
+	return report, nil
+}
+
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return math.Round(float64(n)/float64(d)*1000) / 1000
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
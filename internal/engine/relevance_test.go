@@ -0,0 +1,201 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// createRatedEntries adds n entries rated 5 (Kubernetes articles) and n rated
+// 1 (celebrity gossip), with AI and user agreeing so no preference profile
+// regeneration is triggered.
+func createRatedEntries(t *testing.T, app core.App, resourceID string, n int) []*core.Record {
+	t.Helper()
+	var out []*core.Record
+	for i := 0; i < n; i++ {
+		out = append(out,
+			testutil.CreateEntryWithStars(t, app, resourceID, fmt.Sprintf("Kubernetes operator patterns part %d", i), fmt.Sprintf("https://example.com/k8s-%d", i), 5, 5),
+			testutil.CreateEntryWithStars(t, app, resourceID, fmt.Sprintf("Celebrity gossip roundup %d", i), fmt.Sprintf("https://example.com/gossip-%d", i), 1, 1),
+		)
+	}
+	return out
+}
+
+func TestRelevanceModel_Predict(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	model := TrainRelevanceModel(createRatedEntries(t, app, res.Id, 10))
+
+	k8s := testutil.CreateEntry(t, app, res.Id, "Writing a Kubernetes operator", "https://example.com/new-k8s", "new-k8s")
+	if p := model.Predict(k8s); p.Stars != 5 || p.Confidence < 0.8 {
+		t.Errorf("expected a confident 5, got %+v", p)
+	}
+	gossip := testutil.CreateEntry(t, app, res.Id, "More celebrity gossip", "https://example.com/new-gossip", "new-gossip")
+	if p := model.Predict(gossip); p.Stars != 1 || p.Confidence < 0.8 {
+		t.Errorf("expected a confident 1, got %+v", p)
+	}
+
+	if p := (&RelevanceModel{}).Predict(k8s); p.Stars != 0 {
+		t.Errorf("expected no prediction from an empty model, got %+v", p)
+	}
+}
+
+func TestRelevanceFeatures(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "The Go Memory Model", "https://example.com/go", "go")
+	entry.Set("excerpt", "Go memory, explained.")
+	entry.Set("tags", []string{"go"})
+
+	got := map[string]bool{}
+	for _, f := range relevanceFeatures(entry) {
+		got[f] = true
+	}
+	for _, want := range []string{"w:go", "w:memory", "b:go memory", "b:memory model", "w:explained", "r:" + res.Id, "t:go"} {
+		if !got[want] {
+			t.Errorf("missing feature %q in %v", want, got)
+		}
+	}
+	if got["w:the"] {
+		t.Error("expected stopwords to be dropped")
+	}
+}
+
+func TestTrainAndSaveRelevanceModel(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	now := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+
+	createRatedEntries(t, app, res.Id, 5)
+	if record, err := TrainAndSaveRelevanceModel(app, now); err != nil || record != nil {
+		t.Fatalf("expected no model from 10 ratings, got %v %v", record, err)
+	}
+
+	createRatedEntries(t, app, res.Id, 10)
+	first, err := TrainAndSaveRelevanceModel(app, now)
+	if err != nil || first == nil {
+		t.Fatalf("TrainAndSaveRelevanceModel: %v", err)
+	}
+	if first.GetInt("samples") != 30 || first.GetInt("holdout") != 6 || first.GetFloat("accuracy") < 0.8 {
+		t.Errorf("unexpected training stats: samples=%d holdout=%d accuracy=%v", first.GetInt("samples"), first.GetInt("holdout"), first.GetFloat("accuracy"))
+	}
+
+	if record, _ := TrainRelevanceModelIfDue(app, now.Add(time.Hour)); record != nil {
+		t.Error("expected no retraining within a day")
+	}
+	second, err := TrainRelevanceModelIfDue(app, now.Add(25*time.Hour))
+	if err != nil || second == nil {
+		t.Fatalf("expected retraining after a day: %v", err)
+	}
+
+	first, _ = app.FindRecordById("relevance_models", first.Id)
+	if first.GetString("model") != "" && first.GetString("model") != "null" {
+		t.Error("expected the older model's parameters to be cleared")
+	}
+	if model := LoadRelevanceModel(app); model == nil || len(model.Tokens) == 0 {
+		t.Error("expected the latest model to load")
+	}
+}
+
+func TestProcessEntry_RelevanceGate(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	createRatedEntries(t, app, res.Id, 10)
+	if _, err := TrainAndSaveRelevanceModel(app, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	var models []string
+	restore := ai.SetCompleteFunc(func(_, model string, _ []ai.Message) (string, error) {
+		models = append(models, model)
+		return `{"summary":"Summary.","stars":4}`, nil
+	})
+	defer restore()
+
+	// Without a threshold every entry goes to the LLM, but the prediction is kept.
+	gossip := testutil.CreateEntry(t, app, res.Id, "Celebrity gossip special", "https://example.com/g1", "g1")
+	processEntry(app, gossip)
+	saved, _ := app.FindRecordById("entries", gossip.Id)
+	if len(models) != 1 || saved.GetInt("local_stars") != 1 || saved.GetFloat("local_confidence") < 0.8 || saved.GetString("score_source") != "" {
+		t.Fatalf("expected an LLM call and a recorded prediction, got calls=%d local=%d conf=%v source=%q",
+			len(models), saved.GetInt("local_stars"), saved.GetFloat("local_confidence"), saved.GetString("score_source"))
+	}
+
+	testutil.CreateSetting(t, app, SettingRelevanceSkipBelow, "3")
+	gossip = testutil.CreateEntry(t, app, res.Id, "Celebrity gossip extra", "https://example.com/g2", "g2")
+	processEntry(app, gossip)
+	saved, _ = app.FindRecordById("entries", gossip.Id)
+	if len(models) != 1 || saved.GetString("score_source") != ScoreSourceLocal || saved.GetInt("ai_stars") != 1 || saved.GetString("processing_status") != "done" {
+		t.Errorf("expected the entry rated locally, got calls=%d source=%q ai_stars=%d status=%q",
+			len(models), saved.GetString("score_source"), saved.GetInt("ai_stars"), saved.GetString("processing_status"))
+	}
+
+	k8s := testutil.CreateEntry(t, app, res.Id, "Kubernetes operator deep dive", "https://example.com/k1", "k1")
+	processEntry(app, k8s)
+	if len(models) != 2 {
+		t.Errorf("expected an entry above the threshold to use the LLM")
+	}
+
+	testutil.CreateSetting(t, app, SettingRelevanceCheapModel, "cheap/model")
+	gossip = testutil.CreateEntry(t, app, res.Id, "Celebrity gossip weekly", "https://example.com/g3", "g3")
+	processEntry(app, gossip)
+	saved, _ = app.FindRecordById("entries", gossip.Id)
+	if len(models) != 3 || models[2] != "cheap/model" || saved.GetString("score_source") != ScoreSourceCheapModel || saved.GetString("summary") != "Summary." {
+		t.Errorf("expected the cheap model to be used, got models=%v source=%q", models, saved.GetString("score_source"))
+	}
+
+	// A prediction below the required confidence is not trusted.
+	testutil.CreateSetting(t, app, SettingRelevanceMinConfidence, "1.01")
+	gossip = testutil.CreateEntry(t, app, res.Id, "Celebrity gossip daily", "https://example.com/g4", "g4")
+	processEntry(app, gossip)
+	if len(models) != 4 || models[3] == "cheap/model" {
+		t.Errorf("expected the regular model below the confidence threshold, got %v", models)
+	}
+}
+
+func TestRelevanceEvaluation(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	since := time.Now().AddDate(0, 0, -1)
+
+	add := func(slug string, local, aiStars, userStars int, source string) {
+		e := testutil.CreateEntryWithStars(t, app, res.Id, slug, "https://example.com/"+slug, aiStars, userStars)
+		e.Set("local_stars", local)
+		e.Set("score_source", source)
+		if err := app.Save(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add("agree", 4, 4, 4, "")
+	add("off-by-two", 2, 4, 5, "")
+	add("skipped", 1, 1, 0, ScoreSourceLocal)
+	testutil.CreateEntryWithStars(t, app, res.Id, "Unpredicted", "https://example.com/none", 3, 3)
+
+	report, err := RelevanceEvaluation(app, since)
+	if err != nil {
+		t.Fatalf("RelevanceEvaluation: %v", err)
+	}
+	if len(report.Weeks) != 1 {
+		t.Fatalf("expected one week, got %+v", report.Weeks)
+	}
+	w := report.Weeks[0]
+	if w.Predicted != 3 || w.Skipped != 1 || w.ComparedAI != 2 || w.ComparedUser != 2 {
+		t.Errorf("unexpected counts: %+v", w)
+	}
+	if w.AgreementAI != 0.5 || w.MAEAI != 1 || w.AgreementUser != 0.5 || w.MAEUser != 1.5 || w.AIAgreementUser != 0.5 || w.AIMAEUser != 0.5 {
+		t.Errorf("unexpected metrics: %+v", w)
+	}
+	if len(report.Trainings) != 0 {
+		t.Errorf("expected no trainings, got %v", report.Trainings)
+	}
+}
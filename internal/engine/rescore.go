@@ -0,0 +1,359 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Settings for rescoring entries after the preference profile changes.
+const (
+	// SettingRescoreOnProfileChange turns off automatic rescoring when "false".
+	SettingRescoreOnProfileChange = "rescore_on_profile_change"
+	SettingRescoreDays            = "rescore_days"
+	SettingRescoreMaxEntries      = "rescore_max_entries"
+	// SettingRescoreMaxTokens caps the estimated prompt and response tokens
+	// of a single rescore job.
+	SettingRescoreMaxTokens = "rescore_max_tokens"
+)
+
+const (
+	defaultRescoreDays       = 7
+	defaultRescoreMaxEntries = 200
+	defaultRescoreMaxTokens  = 500000
+	// MaxRescoreDays and MaxRescoreEntries bound what a request may ask for.
+	MaxRescoreDays    = 90
+	MaxRescoreEntries = 2000
+)
+
+// Rescore job triggers.
+const (
+	RescoreTriggerProfile = "profile_change"
+	RescoreTriggerManual  = "manual"
+)
+
+// Per-entry rescore results.
+const (
+	rescoreItemChanged   = "changed"
+	rescoreItemUnchanged = "unchanged"
+	rescoreItemFailed    = "failed"
+)
+
+// RescoreOptions selects the entries of a rescore job and its cost limits.
+type RescoreOptions struct {
+	Trigger    string
+	Days       int
+	MaxEntries int
+	MaxTokens  int
+}
+
+// RescoreJobItem is an entry queued for rescoring with its old and new
+// score, as stored in the job's items field.
+type RescoreJobItem struct {
+	Entry           string `json:"entry"`
+	Title           string `json:"title"`
+	OldStars        int    `json:"old_stars"`
+	NewStars        int    `json:"new_stars,omitempty"`
+	EstimatedTokens int    `json:"estimated_tokens"`
+	Status          string `json:"status,omitempty"` // changed, unchanged or failed; empty while queued
+	Error           string `json:"error,omitempty"`
+}
+
+var (
+	runningRescoresMu sync.Mutex
+	runningRescores   = map[string]bool{}
+)
+
+// DefaultRescoreOptions returns the configured window and cost limits.
+func DefaultRescoreOptions(app core.App, trigger string) RescoreOptions {
+	return RescoreOptions{
+		Trigger:    trigger,
+		Days:       intSetting(app, SettingRescoreDays, defaultRescoreDays),
+		MaxEntries: intSetting(app, SettingRescoreMaxEntries, defaultRescoreMaxEntries),
+		MaxTokens:  intSetting(app, SettingRescoreMaxTokens, defaultRescoreMaxTokens),
+	}
+}
+
+// CreateRescoreJob queues the unread, unrated entries discovered in the last
+// opts.Days days for rescoring, newest first. Entries the local relevance
+// model rated are left out, as the gate kept them off the LLM. Entries beyond
+// the entry or token limit are counted as skipped rather than queued.
+func CreateRescoreJob(app core.App, opts RescoreOptions, now time.Time) (*core.Record, error) {
+	collection, err := app.FindCollectionByNameOrId("rescore_jobs")
+	if err != nil {
+		return nil, err
+	}
+	entries, err := app.FindRecordsByFilter(
+		"entries",
+		"is_read = false && user_stars = 0 && processing_status = 'done' && score_source != {:local} && created >= {:since}",
+		"-created",
+		0, 0,
+		map[string]any{"since": now.AddDate(0, 0, -opts.Days).UTC(), "local": ScoreSourceLocal},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("finding entries: %w", err)
+	}
+
+	estimates := ai.EstimateScoreTokens(app, entries)
+	var items []RescoreJobItem
+	tokens := 0
+	for i, entry := range entries {
+		if len(items) >= opts.MaxEntries || tokens+estimates[i] > opts.MaxTokens {
+			break
+		}
+		tokens += estimates[i]
+		items = append(items, RescoreJobItem{
+			Entry:           entry.Id,
+			Title:           entry.GetString("title"),
+			OldStars:        entry.GetInt("ai_stars"),
+			EstimatedTokens: estimates[i],
+		})
+	}
+
+	job := core.NewRecord(collection)
+	job.Set("trigger", opts.Trigger)
+	job.Set("status", ImportJobPending)
+	job.Set("days", opts.Days)
+	job.Set("max_entries", opts.MaxEntries)
+	job.Set("max_tokens", opts.MaxTokens)
+	job.Set("profile_version", profileVersion(app))
+	job.Set("items", items)
+	job.Set("total", len(items))
+	job.Set("skipped", len(entries)-len(items))
+	job.Set("estimated_tokens", tokens)
+	if err := app.Save(job); err != nil {
+		return nil, fmt.Errorf("saving rescore job: %w", err)
+	}
+	return job, nil
+}
+
+// profileVersion returns the version of the current preference profile, or
+// 0 when there is none.
+func profileVersion(app core.App) int {
+	if versions, err := ai.PreferenceVersions(app); err == nil && len(versions) > 0 {
+		return versions[0].GetInt("version")
+	}
+	return 0
+}
+
+// QueueProfileRescore starts a rescore job after the preference profile
+// changed, unless automatic rescoring is turned off or a job is already
+// queued or running. That job scores its remaining entries with the newer
+// profile, and queues a new profile rescore when it finishes, since the
+// entries it scored before the change still carry the old profile's scores.
+func QueueProfileRescore(app core.App) (*core.Record, error) {
+	if v := strings.TrimSpace(appSetting(app, SettingRescoreOnProfileChange)); v != "" && !ruleBool(v) {
+		return nil, nil
+	}
+	if active, err := app.FindFirstRecordByFilter("rescore_jobs", "status = 'pending' || status = 'running'", nil); err == nil && active != nil {
+		return nil, nil
+	}
+	job, err := CreateRescoreJob(app, DefaultRescoreOptions(app, RescoreTriggerProfile), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	StartRescoreJob(app, job.Id)
+	return job, nil
+}
+
+// StartRescoreJob runs a rescore job in the background unless it is already
+// running.
+func StartRescoreJob(app core.App, jobID string) {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				PanicCount.Add(1)
+				log.Printf("PANIC in rescore job %s: %v\n%s", jobID, r, debug.Stack())
+			}
+		}()
+		if err := RunRescoreJob(app, jobID); err != nil {
+			log.Printf("Rescore job %s failed: %v", jobID, err)
+		}
+	}()
+}
+
+// ResumeRescoreJobs restarts rescore jobs that were queued or interrupted.
+// Entries already rescored are not repeated.
+func ResumeRescoreJobs(app core.App) int {
+	jobs, err := app.FindRecordsByFilter("rescore_jobs", "status = 'pending' || status = 'running'", "created", 0, 0)
+	if err != nil {
+		return 0
+	}
+	for _, job := range jobs {
+		StartRescoreJob(app, job.Id)
+	}
+	return len(jobs)
+}
+
+// RunRescoreJob scores the remaining entries of a job with the current
+// profile, recording the new score next to the old one. Entries the user
+// read or rated since the job was queued are left alone. Entries are scored
+// in batches; the counters are saved after every entry and the items
+// periodically.
+func RunRescoreJob(app core.App, jobID string) error {
+	runningRescoresMu.Lock()
+	if runningRescores[jobID] {
+		runningRescoresMu.Unlock()
+		return nil
+	}
+	runningRescores[jobID] = true
+	runningRescoresMu.Unlock()
+	defer func() {
+		runningRescoresMu.Lock()
+		delete(runningRescores, jobID)
+		runningRescoresMu.Unlock()
+	}()
+
+	job, err := app.FindRecordById("rescore_jobs", jobID)
+	if err != nil {
+		return err
+	}
+	var items []RescoreJobItem
+	if err := json.Unmarshal([]byte(job.GetString("items")), &items); err != nil {
+		return failRescoreJob(app, job, fmt.Errorf("invalid items: %w", err))
+	}
+	if _, err := ai.GetAPIKey(app); err != nil {
+		return failRescoreJob(app, job, errors.New("no API key configured"))
+	}
+
+	statuses := make([]string, len(items))
+	for i, item := range items {
+		statuses[i] = item.Status
+	}
+	progress := newJobProgress(app, job, statuses, rescoreItemChanged, rescoreItemUnchanged, rescoreItemFailed)
+	job.Set("status", ImportJobRunning)
+	if err := app.Save(job); err != nil {
+		return err
+	}
+
+	// Pending entries are scored in batches of the score batch size, so a
+	// rescore shares the profile and instructions between entries like
+	// fragment scoring does.
+	size := max(scoreBatchSize(app), 1)
+	var pending []int
+	for i := range items {
+		if items[i].Status == "" {
+			pending = append(pending, i)
+		}
+	}
+	for start := 0; start < len(pending); start += size {
+		chunk := pending[start:min(start+size, len(pending))]
+		batch := make([]*RescoreJobItem, len(chunk))
+		for j, i := range chunk {
+			batch[j] = &items[i]
+		}
+		rescoreItems(app, batch)
+
+		for _, i := range chunk {
+			if err := progress.itemDone(items, items[i].Status); err != nil {
+				return fmt.Errorf("saving rescore progress: %w", err)
+			}
+		}
+	}
+
+	job.Set("status", ImportJobDone)
+	job.Set("finished_at", time.Now().UTC())
+	if err := progress.saveItems(items); err != nil {
+		return err
+	}
+
+	// A profile change while this job was active did not queue a job of its
+	// own (see QueueProfileRescore), so queue it now.
+	if profileVersion(app) != job.GetInt("profile_version") {
+		if _, err := QueueProfileRescore(app); err != nil {
+			log.Printf("Failed to queue rescoring for the changed profile: %v", err)
+		}
+	}
+	return nil
+}
+
+// rescoreItems scores the entries of a batch of items again with one batch
+// call, recording the results on the items. Entries the batch call missed
+// are scored one by one.
+func rescoreItems(app core.App, items []*RescoreJobItem) {
+	var entries []*core.Record
+	byEntry := map[string]*RescoreJobItem{}
+	for _, item := range items {
+		entry, err := app.FindRecordById("entries", item.Entry)
+		if err != nil {
+			item.Status = rescoreItemFailed
+			item.Error = "entry no longer exists"
+			continue
+		}
+		if entry.GetBool("is_read") || entry.GetInt("user_stars") > 0 {
+			item.Status = rescoreItemUnchanged
+			item.NewStars = entry.GetInt("ai_stars")
+			continue
+		}
+		entries = append(entries, entry)
+		byEntry[entry.Id] = item
+	}
+
+	if len(entries) == 0 {
+		return
+	}
+
+	// Share the AI concurrency limit with fetching and batch scoring.
+	maxConcurrentAI <- struct{}{}
+	defer func() { <-maxConcurrentAI }()
+
+	single := entries
+	if len(entries) > 1 {
+		missing, err := ai.ScoreOnlyBatch(app, entries, ai.GetModel(app))
+		if err != nil {
+			log.Printf("Batch rescoring of %d entries failed, scoring them one by one: %v", len(entries), err)
+		} else {
+			single = missing
+		}
+	}
+	failed := map[string]error{}
+	for _, entry := range single {
+		if err := ai.ScoreOnly(app, entry); err != nil {
+			failed[entry.Id] = err
+		}
+	}
+
+	for _, entry := range entries {
+		item := byEntry[entry.Id]
+		if err, ok := failed[entry.Id]; ok {
+			item.Status = rescoreItemFailed
+			item.Error = err.Error()
+			continue
+		}
+		item.NewStars = entry.GetInt("ai_stars")
+		if item.NewStars == item.OldStars {
+			item.Status = rescoreItemUnchanged
+		} else {
+			item.Status = rescoreItemChanged
+		}
+	}
+}
+
+func failRescoreJob(app core.App, job *core.Record, cause error) error {
+	job.Set("status", ImportJobFailed)
+	job.Set("error", cause.Error())
+	job.Set("finished_at", time.Now().UTC())
+	if err := app.Save(job); err != nil {
+		log.Printf("Failed to save rescore job %s: %v", job.Id, err)
+	}
+	return cause
+}
+
+// intSetting reads a positive integer app setting, falling back to def.
+func intSetting(app core.App, key string, def int) int {
+	if v := strings.TrimSpace(appSetting(app, key)); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return def
+}
@@ -0,0 +1,333 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func rescoreJobItems(t *testing.T, job *core.Record) []RescoreJobItem {
+	t.Helper()
+	var items []RescoreJobItem
+	if err := json.Unmarshal([]byte(job.GetString("items")), &items); err != nil {
+		t.Fatalf("invalid items: %v", err)
+	}
+	return items
+}
+
+func TestCreateRescoreJob_SelectsAndLimits(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	for i := 0; i < 4; i++ {
+		testutil.CreateEntryWithStars(t, app, res.Id, fmt.Sprintf("Unread %d", i), fmt.Sprintf("https://example.com/%d", i), 3, 0)
+	}
+	read := testutil.CreateEntryWithStars(t, app, res.Id, "Read", "https://example.com/read", 3, 0)
+	read.Set("is_read", true)
+	app.Save(read)
+	testutil.CreateEntryWithStars(t, app, res.Id, "Rated", "https://example.com/rated", 3, 5)
+	pending := testutil.CreateEntryWithStars(t, app, res.Id, "Pending", "https://example.com/pending", 0, 0)
+	pending.Set("processing_status", "pending")
+	app.Save(pending)
+	local := testutil.CreateEntryWithStars(t, app, res.Id, "Local", "https://example.com/local", 1, 0)
+	local.Set("score_source", ScoreSourceLocal)
+	app.Save(local)
+
+	opts := RescoreOptions{Trigger: RescoreTriggerManual, Days: 7, MaxEntries: 3, MaxTokens: 1000000}
+	job, err := CreateRescoreJob(app, opts, time.Now())
+	if err != nil {
+		t.Fatalf("CreateRescoreJob: %v", err)
+	}
+	items := rescoreJobItems(t, job)
+	if job.GetInt("total") != 3 || job.GetInt("skipped") != 1 || len(items) != 3 {
+		t.Fatalf("expected 3 queued and 1 skipped, got total=%d skipped=%d", job.GetInt("total"), job.GetInt("skipped"))
+	}
+	if items[0].OldStars != 3 || items[0].EstimatedTokens <= 0 || job.GetInt("estimated_tokens") <= 0 {
+		t.Errorf("unexpected item: %+v", items[0])
+	}
+
+	// A token budget smaller than two prompts queues a single entry.
+	opts.MaxEntries = 10
+	opts.MaxTokens = items[0].EstimatedTokens + 1
+	job, err = CreateRescoreJob(app, opts, time.Now())
+	if err != nil || job.GetInt("total") != 1 || job.GetInt("skipped") != 3 {
+		t.Errorf("expected the token budget to limit the job, got total=%d skipped=%d err=%v", job.GetInt("total"), job.GetInt("skipped"), err)
+	}
+
+	// Entries older than the window are not considered.
+	job, _ = CreateRescoreJob(app, opts, time.Now().AddDate(0, 0, 10))
+	if job.GetInt("total") != 0 || job.GetInt("skipped") != 0 {
+		t.Errorf("expected no entries outside the window, got total=%d", job.GetInt("total"))
+	}
+}
+
+func TestRunRescoreJob(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	testutil.CreateSetting(t, app, SettingScoreBatchSize, "1")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	raised := testutil.CreateEntryWithStars(t, app, res.Id, "Kubernetes operators", "https://example.com/k8s", 2, 0)
+	same := testutil.CreateEntryWithStars(t, app, res.Id, "Celebrity gossip", "https://example.com/gossip", 1, 0)
+	readLater := testutil.CreateEntryWithStars(t, app, res.Id, "Release notes", "https://example.com/notes", 3, 0)
+
+	job, err := CreateRescoreJob(app, DefaultRescoreOptions(app, RescoreTriggerProfile), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	readLater.Set("is_read", true)
+	app.Save(readLater)
+
+	calls := 0
+	restore := ai.SetCompleteFunc(func(_, _ string, msgs []ai.Message) (string, error) {
+		calls++
+		if strings.Contains(msgs[len(msgs)-1].Content, "Kubernetes") {
+			return `{"summary":"","stars":5}`, nil
+		}
+		return `{"summary":"","stars":1}`, nil
+	})
+	defer restore()
+
+	if err := RunRescoreJob(app, job.Id); err != nil {
+		t.Fatalf("RunRescoreJob: %v", err)
+	}
+	job, _ = app.FindRecordById("rescore_jobs", job.Id)
+	if job.GetString("status") != ImportJobDone || job.GetInt("processed") != 3 || job.GetInt("changed") != 1 || job.GetInt("unchanged") != 2 {
+		t.Errorf("unexpected job: status=%s processed=%d changed=%d unchanged=%d",
+			job.GetString("status"), job.GetInt("processed"), job.GetInt("changed"), job.GetInt("unchanged"))
+	}
+	if calls != 2 {
+		t.Errorf("expected the entry read meanwhile to be left alone, got %d calls", calls)
+	}
+	for _, item := range rescoreJobItems(t, job) {
+		if item.Entry == raised.Id && (item.OldStars != 2 || item.NewStars != 5 || item.Status != "changed") {
+			t.Errorf("unexpected item: %+v", item)
+		}
+		if item.Entry == same.Id && (item.OldStars != 1 || item.NewStars != 1 || item.Status != "unchanged") {
+			t.Errorf("unexpected item: %+v", item)
+		}
+	}
+	if saved, _ := app.FindRecordById("entries", raised.Id); saved.GetInt("ai_stars") != 5 {
+		t.Errorf("expected ai_stars to be updated, got %d", saved.GetInt("ai_stars"))
+	}
+
+	// Running a finished job again does nothing.
+	if err := RunRescoreJob(app, job.Id); err != nil || calls != 2 {
+		t.Errorf("expected no more calls, got %d (%v)", calls, err)
+	}
+}
+
+func TestRunRescoreJob_ScoresInBatches(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	raised := testutil.CreateEntryWithStars(t, app, res.Id, "Kubernetes operators", "https://example.com/k8s", 2, 0)
+	missed := testutil.CreateEntryWithStars(t, app, res.Id, "Celebrity gossip", "https://example.com/gossip", 1, 0)
+
+	job, err := CreateRescoreJob(app, DefaultRescoreOptions(app, RescoreTriggerManual), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batchCalls, singleCalls := 0, 0
+	restore := ai.SetCompleteFunc(func(_, _ string, msgs []ai.Message) (string, error) {
+		if strings.Contains(msgs[len(msgs)-1].Content, "<fragment id=") {
+			batchCalls++
+			// The gossip entry is missing from the batch response.
+			return `{"results":[{"id":"` + raised.Id + `","summary":"","stars":5}]}`, nil
+		}
+		singleCalls++
+		return `{"summary":"","stars":3}`, nil
+	})
+	defer restore()
+
+	if err := RunRescoreJob(app, job.Id); err != nil {
+		t.Fatalf("RunRescoreJob: %v", err)
+	}
+	if batchCalls != 1 || singleCalls != 1 {
+		t.Errorf("expected one batch call and one single call for the missed entry, got %d and %d", batchCalls, singleCalls)
+	}
+	job, _ = app.FindRecordById("rescore_jobs", job.Id)
+	for _, item := range rescoreJobItems(t, job) {
+		if item.Entry == raised.Id && (item.NewStars != 5 || item.Status != "changed") {
+			t.Errorf("unexpected item: %+v", item)
+		}
+		if item.Entry == missed.Id && (item.NewStars != 3 || item.Status != "changed") {
+			t.Errorf("unexpected item: %+v", item)
+		}
+	}
+}
+
+func TestRunRescoreJob_RecountsUnsavedItems(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	testutil.CreateSetting(t, app, SettingScoreBatchSize, "1")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	done := testutil.CreateEntryWithStars(t, app, res.Id, "Kubernetes operators", "https://example.com/k8s", 2, 0)
+	queued := testutil.CreateEntryWithStars(t, app, res.Id, "Celebrity gossip", "https://example.com/gossip", 1, 0)
+
+	job, err := CreateRescoreJob(app, DefaultRescoreOptions(app, RescoreTriggerManual), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Simulate an interruption after the counters, but not the items, were
+	// saved for the second entry.
+	job.Set("status", ImportJobRunning)
+	job.Set("items", []RescoreJobItem{
+		{Entry: done.Id, OldStars: 2, NewStars: 5, Status: rescoreItemChanged},
+		{Entry: queued.Id, OldStars: 1},
+	})
+	job.Set("processed", 2)
+	job.Set("changed", 2)
+	if err := app.Save(job); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	restore := ai.SetCompleteFunc(func(_, _ string, _ []ai.Message) (string, error) {
+		calls++
+		return `{"summary":"","stars":1}`, nil
+	})
+	defer restore()
+
+	if err := RunRescoreJob(app, job.Id); err != nil {
+		t.Fatalf("RunRescoreJob: %v", err)
+	}
+	job, _ = app.FindRecordById("rescore_jobs", job.Id)
+	if calls != 1 || job.GetInt("processed") != 2 || job.GetInt("changed") != 1 || job.GetInt("unchanged") != 1 {
+		t.Errorf("unexpected job: calls=%d processed=%d changed=%d unchanged=%d",
+			calls, job.GetInt("processed"), job.GetInt("changed"), job.GetInt("unchanged"))
+	}
+}
+
+func TestRunRescoreJob_NoAPIKey(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	job, err := CreateRescoreJob(app, DefaultRescoreOptions(app, RescoreTriggerManual), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RunRescoreJob(app, job.Id); err == nil {
+		t.Fatal("expected an error without an API key")
+	}
+	job, _ = app.FindRecordById("rescore_jobs", job.Id)
+	if job.GetString("status") != ImportJobFailed || job.GetString("error") == "" {
+		t.Errorf("expected a failed job, got %s %q", job.GetString("status"), job.GetString("error"))
+	}
+}
+
+func TestRunRescoreJob_QueuesRescoreForProfileChangedMeanwhile(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	testutil.CreateSetting(t, app, SettingScoreBatchSize, "1")
+	profile := testutil.CreatePreference(t, app, "Likes Go", "2026-01-01 00:00:00.000Z")
+	profile.Set("version", 1)
+	app.Save(profile)
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	testutil.CreateEntryWithStars(t, app, res.Id, "First", "https://example.com/1", 2, 0)
+	testutil.CreateEntryWithStars(t, app, res.Id, "Second", "https://example.com/2", 2, 0)
+
+	job, err := CreateRescoreJob(app, DefaultRescoreOptions(app, RescoreTriggerProfile), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	calls := 0
+	restore := ai.SetCompleteFunc(func(_, _ string, _ []ai.Message) (string, error) {
+		mu.Lock()
+		calls++
+		first := calls == 1
+		mu.Unlock()
+		if first {
+			// The profile changes after the job scored its first entry.
+			changed := testutil.CreatePreference(t, app, "Likes Rust", "2026-02-01 00:00:00.000Z")
+			changed.Set("version", 2)
+			app.Save(changed)
+			if queued, _ := QueueProfileRescore(app); queued != nil {
+				t.Error("expected no second job while one is running")
+			}
+		}
+		return `{"summary":"","stars":3}`, nil
+	})
+	defer restore()
+
+	if err := RunRescoreJob(app, job.Id); err != nil {
+		t.Fatalf("RunRescoreJob: %v", err)
+	}
+
+	followUp, err := app.FindFirstRecordByFilter("rescore_jobs", "id != {:id}", map[string]any{"id": job.Id})
+	if err != nil {
+		t.Fatal("expected a rescore job for the changed profile once the first finished")
+	}
+	if followUp.GetInt("profile_version") != 2 || followUp.GetString("trigger") != RescoreTriggerProfile || followUp.GetInt("total") != 2 {
+		t.Errorf("unexpected follow-up job: version=%d trigger=%s total=%d",
+			followUp.GetInt("profile_version"), followUp.GetString("trigger"), followUp.GetInt("total"))
+	}
+
+	// Let the follow-up finish; it does not queue another one.
+	for i := 0; i < 100; i++ {
+		if done, _ := app.FindRecordById("rescore_jobs", followUp.Id); done.GetString("status") == ImportJobDone {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if total, _ := app.CountRecords("rescore_jobs"); total != 2 {
+		t.Errorf("expected 2 rescore jobs, got %d", total)
+	}
+}
+
+func TestQueueProfileRescore(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	testutil.CreateSetting(t, app, SettingRescoreOnProfileChange, "false")
+	if job, err := QueueProfileRescore(app); err != nil || job != nil {
+		t.Fatalf("expected no job when disabled, got %v %v", job, err)
+	}
+
+	setting, _ := app.FindFirstRecordByFilter("app_settings", "key = {:key}", map[string]any{"key": SettingRescoreOnProfileChange})
+	setting.Set("value", "true")
+	app.Save(setting)
+	testutil.CreateSetting(t, app, SettingRescoreDays, "3")
+
+	collection, _ := app.FindCollectionByNameOrId("rescore_jobs")
+	active := core.NewRecord(collection)
+	active.Set("trigger", RescoreTriggerManual)
+	active.Set("status", ImportJobRunning)
+	if err := app.Save(active); err != nil {
+		t.Fatal(err)
+	}
+	if job, _ := QueueProfileRescore(app); job != nil {
+		t.Error("expected no new job while another is running")
+	}
+
+	active.Set("status", ImportJobDone)
+	app.Save(active)
+	job, err := QueueProfileRescore(app)
+	if err != nil || job == nil {
+		t.Fatalf("expected a job, got %v", err)
+	}
+	if job.GetString("trigger") != RescoreTriggerProfile || job.GetInt("days") != 3 || job.GetInt("max_entries") != defaultRescoreMaxEntries {
+		t.Errorf("unexpected job options: trigger=%s days=%d max=%d", job.GetString("trigger"), job.GetInt("days"), job.GetInt("max_entries"))
+	}
+
+	// Let the background job finish before the app is torn down.
+	for i := 0; i < 100; i++ {
+		if done, _ := app.FindRecordById("rescore_jobs", job.Id); done.GetString("status") == ImportJobDone || done.GetString("status") == ImportJobFailed {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Error("expected the queued job to finish")
+}
@@ -20,6 +20,7 @@ type RSSEntry struct {
 	GUID        string
 	Content     string
 	PublishedAt *time.Time
+	Media       *MediaEnclosure // audio/video enclosure, nil for plain articles
 }
 
 // FetchRSS fetches and parses an RSS/Atom/JSON feed, returning new entries
@@ -78,9 +79,18 @@ func FetchRSS(app core.App, resource *core.Record, client *http.Client) ([]RSSEn
 
 	isFragmentFeed := resource.GetBool("fragment_feed")
 
+	feedImage := ""
+	if feed.Image != nil {
+		feedImage = feed.Image.URL
+	}
+
 	var entries []RSSEntry
 	for _, item := range feed.Items {
+		media := itemMedia(item, feedImage)
 		guid := itemGUID(item)
+		if guid == "" && media != nil {
+			guid = media.URL
+		}
 		if guid == "" {
 			continue
 		}
@@ -111,6 +121,11 @@ func FetchRSS(app core.App, resource *core.Record, client *http.Client) ([]RSSEn
 			URL:     itemLink(item),
 			GUID:    guid,
 			Content: itemContent(item),
+			Media:   media,
+		}
+		// Podcast items often have no web page; link to the media file instead
+		if entry.URL == "" && media != nil {
+			entry.URL = media.URL
 		}
 		if item.PublishedParsed != nil {
 			t := item.PublishedParsed.UTC()
@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// unsafeArticleElements are removed from article HTML together with their content.
+const unsafeArticleElements = "script, style, noscript, iframe, frame, frameset, object, embed, applet, form, input, button, select, textarea, link, meta, base, svg, math, template"
+
+// allowedArticleAttrs lists the attributes kept per element; all other
+// attributes (event handlers, inline styles, ids) are dropped.
+var allowedArticleAttrs = map[string]map[string]bool{
+	"a":          {"href": true, "title": true},
+	"img":        {"src": true, "alt": true, "title": true, "width": true, "height": true},
+	"code":       {"class": true}, // language-go etc. for syntax highlighting
+	"pre":        {"class": true},
+	"td":         {"colspan": true, "rowspan": true},
+	"th":         {"colspan": true, "rowspan": true},
+	"ol":         {"start": true},
+	"blockquote": {"cite": true},
+	"time":       {"datetime": true},
+}
+
+// SanitizeArticleHTML strips scripts, embeds, forms, event handlers and
+// unsafe URLs from article HTML while keeping its structure (headings,
+// lists, tables, code blocks, links and images). Relative links and image
+// sources are resolved against base.
+func SanitizeArticleHTML(articleHTML string, base *url.URL) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(articleHTML))
+	if err != nil {
+		return ""
+	}
+
+	doc.Find(unsafeArticleElements).Remove()
+
+	doc.Find("body *").Each(func(_ int, s *goquery.Selection) {
+		node := s.Get(0)
+		allowed := allowedArticleAttrs[node.Data]
+		kept := node.Attr[:0]
+		for _, attr := range node.Attr {
+			if !allowed[attr.Key] {
+				continue
+			}
+			if attr.Key == "href" || attr.Key == "src" || attr.Key == "cite" {
+				resolved := sanitizeArticleURL(base, attr.Val)
+				if resolved == "" {
+					continue
+				}
+				attr.Val = resolved
+			}
+			kept = append(kept, html.Attribute{Key: attr.Key, Val: attr.Val})
+		}
+		node.Attr = kept
+	})
+
+	// Images without a usable source are dropped.
+	doc.Find("img").Each(func(_ int, s *goquery.Selection) {
+		if _, ok := s.Attr("src"); !ok {
+			s.Remove()
+		}
+	})
+
+	out, err := doc.Find("body").Html()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+// sanitizeArticleURL resolves a link against base and only allows http(s),
+// mailto and in-page anchors.
+func sanitizeArticleURL(base *url.URL, raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	if strings.HasPrefix(raw, "#") {
+		return raw
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		parsed = base.ResolveReference(parsed)
+	}
+	switch parsed.Scheme {
+	case "http", "https", "mailto":
+		return parsed.String()
+	}
+	return ""
+}
@@ -0,0 +1,42 @@
+package engine
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeArticleHTML(t *testing.T) {
+	base, _ := url.Parse("https://example.com/posts/1")
+	input := `<div id="x" style="color:red" onmouseover="evil()">
+<h2 class="title">Heading</h2>
+<p>Text with <a href="javascript:alert(1)">bad link</a>, <a href="../about" target="_blank">relative link</a> and <a href="#notes">anchor</a>.</p>
+<img src="/img/a.png" alt="A" onerror="evil()">
+<img src="data:image/png;base64,AAAA">
+<iframe src="https://ads.example.com"></iframe>
+<form action="/login"><input name="password"></form>
+<pre class="chroma"><code class="language-go">fmt.Println("hi")</code></pre>
+<table><tr><td colspan="2" bgcolor="red">cell</td></tr></table>
+</div>`
+
+	got := SanitizeArticleHTML(input, base)
+
+	for _, unwanted := range []string{"onmouseover", "onerror", "style=", "javascript:", "data:image", "iframe", "<form", "<input", `id="x"`, `target=`, `bgcolor`} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("sanitized HTML still contains %q:\n%s", unwanted, got)
+		}
+	}
+	for _, want := range []string{
+		"<h2>Heading</h2>",
+		"<a>bad link</a>",
+		`<a href="https://example.com/about">relative link</a>`,
+		`<a href="#notes">anchor</a>`,
+		`<img src="https://example.com/img/a.png" alt="A"/>`,
+		`<pre class="chroma"><code class="language-go">`,
+		`<td colspan="2">cell</td>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("sanitized HTML missing %q:\n%s", want, got)
+		}
+	}
+}
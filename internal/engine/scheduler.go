@@ -45,6 +45,8 @@ func (s *Scheduler) Start() {
 	// Also retry previously failed entries and queue due Daily News jobs.
 	s.retryFailedEntries()
 	s.runDailyNews(time.Now())
+	s.archiveEntries()
+	s.trainRelevanceModel(time.Now())
 
 	ticker := time.NewTicker(s.interval)
 	defer ticker.Stop()
@@ -55,6 +57,8 @@ func (s *Scheduler) Start() {
 			s.fetchAll()
 			s.retryFailedEntries()
 			s.runDailyNews(time.Now())
+			s.archiveEntries()
+			s.trainRelevanceModel(time.Now())
 		case <-s.stopCh:
 			log.Println("Scheduler stopped")
 			return
@@ -75,7 +79,7 @@ func (s *Scheduler) fetchAll() {
 func FetchAllResources(app core.App) {
 	resources, err := app.FindRecordsByFilter(
 		"resources",
-		"active = true && status != 'quarantined' && type != 'quickadd'",
+		"active = true && status != 'quarantined' && type != 'quickadd' && type != 'newsletter'",
 		"",
 		0, 0,
 		nil,
@@ -126,6 +130,34 @@ func (s *Scheduler) runDailyNews(now time.Time) {
 	}
 }
 
+// archiveEntries snapshots starred and bookmarked entries that still lack an
+// archive and applies the archive retention policy.
+func (s *Scheduler) archiveEntries() {
+	if archived := ArchivePendingEntries(s.app, DefaultHTTPClient); archived > 0 {
+		log.Printf("Scheduler: archived %d entries", archived)
+	}
+	deleted, err := PruneArchives(s.app, time.Now())
+	if err != nil {
+		log.Printf("Scheduler: archive retention failed: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Scheduler: deleted %d expired archives", deleted)
+	}
+}
+
+// trainRelevanceModel retrains the local relevance model once a day.
+func (s *Scheduler) trainRelevanceModel(now time.Time) {
+	record, err := TrainRelevanceModelIfDue(s.app, now)
+	if err != nil {
+		log.Printf("Scheduler: relevance model training failed: %v", err)
+		return
+	}
+	if record != nil {
+		log.Printf("Scheduler: trained relevance model on %d rated entries (holdout accuracy %.2f)", record.GetInt("samples"), record.GetFloat("accuracy"))
+	}
+}
+
 func (s *Scheduler) retryFailedEntries() {
 	entries, err := s.app.FindRecordsByFilter(
 		"entries",
@@ -140,6 +172,9 @@ func (s *Scheduler) retryFailedEntries() {
 	}
 
 	for _, entry := range entries {
+		if fragmentBatcher.isQueued(entry.Id) {
+			continue
+		}
 		go processEntry(s.app, entry)
 	}
 }
@@ -0,0 +1,169 @@
+package engine
+
+import (
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// App settings for batched fragment scoring. A batch size of 1 scores every
+// fragment in its own call.
+const (
+	SettingScoreBatchSize     = "score_batch_size"
+	SettingScoreBatchWindowMs = "score_batch_window_ms"
+
+	defaultScoreBatchSize     = 8
+	defaultScoreBatchWindowMs = 2000
+)
+
+// scoreBatcher collects new fragments for a short window so they can be
+// rated several per AI call with one shared profile header.
+type scoreBatcher struct {
+	mu      sync.Mutex
+	pending map[core.App]*scoreBatch
+	queued  map[string]bool
+}
+
+type scoreBatch struct {
+	entries []*core.Record
+	timer   *time.Timer
+}
+
+var fragmentBatcher = &scoreBatcher{
+	pending: map[core.App]*scoreBatch{},
+	queued:  map[string]bool{},
+}
+
+// scoreBatchSize returns the configured batch size, capped at
+// ai.MaxScoreBatchSize.
+func scoreBatchSize(app core.App) int {
+	size := intSetting(app, SettingScoreBatchSize, defaultScoreBatchSize)
+	if size > ai.MaxScoreBatchSize {
+		size = ai.MaxScoreBatchSize
+	}
+	return size
+}
+
+// enqueue adds a saved fragment to the app's pending batch. The batch is
+// scored when it is full or when the window since its first entry ends. It
+// returns false when batching is disabled.
+func (s *scoreBatcher) enqueue(app core.App, record *core.Record) bool {
+	size := scoreBatchSize(app)
+	if size <= 1 {
+		return false
+	}
+	window := time.Duration(intSetting(app, SettingScoreBatchWindowMs, defaultScoreBatchWindowMs)) * time.Millisecond
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.pending[app]
+	if b == nil {
+		b = &scoreBatch{}
+		b.timer = time.AfterFunc(window, func() { s.flush(app, b) })
+		s.pending[app] = b
+	}
+	b.entries = append(b.entries, record)
+	s.queued[record.Id] = true
+	if len(b.entries) >= size {
+		b.timer.Stop()
+		delete(s.pending, app)
+		go s.run(app, b.entries)
+	}
+	return true
+}
+
+// flush scores a batch whose window ended, unless it was already taken
+// because it filled up.
+func (s *scoreBatcher) flush(app core.App, b *scoreBatch) {
+	s.mu.Lock()
+	if s.pending[app] != b {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.pending, app)
+	s.mu.Unlock()
+	s.run(app, b.entries)
+}
+
+// isQueued reports whether an entry waits in or is being scored by a batch,
+// so the scheduler's retry does not process it a second time.
+func (s *scoreBatcher) isQueued(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queued[id]
+}
+
+func (s *scoreBatcher) run(app core.App, records []*core.Record) {
+	maxConcurrentAI <- struct{}{}
+	defer func() {
+		<-maxConcurrentAI
+		s.mu.Lock()
+		for _, r := range records {
+			delete(s.queued, r.Id)
+		}
+		s.mu.Unlock()
+		if r := recover(); r != nil {
+			PanicCount.Add(1)
+			log.Printf("PANIC in fragment batch scoring: %v\n%s", r, debug.Stack())
+		}
+	}()
+	scoreFragmentBatch(app, records)
+}
+
+// scoreFragmentBatch runs the relevance gate on every fragment, rates the
+// ones it lets through in one call per model, and falls back to single-entry
+// calls for fragments the batch response left out.
+func scoreFragmentBatch(app core.App, records []*core.Record) {
+	groups := map[string][]*core.Record{}
+	var models []string
+	for _, record := range records {
+		source, cheapModel := relevanceGate(app, record)
+		record.Set("score_source", source)
+		record.Set("detected_language", ai.DetectEntryLanguage(record))
+		if source == ScoreSourceLocal {
+			if err := scoreLocally(app, record); err != nil {
+				markProcessingFailed(app, record, err)
+			}
+			continue
+		}
+		model := cheapModel
+		if model == "" {
+			model = ai.GetModel(app)
+		}
+		if groups[model] == nil {
+			models = append(models, model)
+		}
+		groups[model] = append(groups[model], record)
+	}
+
+	for _, model := range models {
+		single := groups[model]
+		if len(single) > 1 {
+			missing, err := ai.ScoreOnlyBatch(app, single, model)
+			if err != nil {
+				log.Printf("Batch scoring of %d fragments failed, scoring them one by one: %v", len(single), err)
+			} else {
+				single = missing
+			}
+		}
+		for _, record := range single {
+			if err := ai.ScoreOnlyWithModel(app, record, model); err != nil {
+				markProcessingFailed(app, record, err)
+			}
+		}
+	}
+
+	// Translation failures are logged and leave the original fragment.
+	for _, record := range records {
+		if record.GetString("processing_status") == "done" {
+			_ = ai.TranslateForPreferredLanguages(app, record)
+		}
+	}
+
+	// Check if preference regeneration is needed
+	ai.CheckAndRegeneratePreferences(app)
+}
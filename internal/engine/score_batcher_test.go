@@ -0,0 +1,179 @@
+package engine
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func newPendingFragment(t *testing.T, app core.App, resourceID, title string) *core.Record {
+	t.Helper()
+	entry := testutil.CreateEntry(t, app, resourceID, title, "https://example.com/"+title, "guid-"+title)
+	entry.Set("raw_content", "<p>About "+title+".</p>")
+	entry.Set("processing_status", "pending")
+	entry.Set("is_fragment", true)
+	return entry
+}
+
+// waitForProcessed polls until every entry has left the pending status.
+func waitForProcessed(t *testing.T, app core.App, ids ...string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for _, id := range ids {
+		for {
+			record, err := app.FindRecordById("entries", id)
+			if err == nil && record.GetString("processing_status") != "pending" && !fragmentBatcher.isQueued(id) {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("entry %s was not processed in time", id)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+func TestSaveAndProcessEntry_BatchesFragments(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	testutil.CreateSetting(t, app, SettingScoreBatchSize, "2")
+	testutil.CreateSetting(t, app, SettingScoreBatchWindowMs, "60000")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	first := newPendingFragment(t, app, res.Id, "Raft")
+	second := newPendingFragment(t, app, res.Id, "Paxos")
+
+	var mu sync.Mutex
+	var batchCalls, singleCalls int
+	restore := ai.SetCompleteFunc(func(_, _ string, msgs []ai.Message) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if strings.Contains(msgs[len(msgs)-1].Content, "<fragment id=") {
+			batchCalls++
+			// The response leaves out the second fragment.
+			return `{"results": [{"id": "` + first.Id + `", "stars": 4}]}`, nil
+		}
+		singleCalls++
+		return `{"summary": "", "stars": 2}`, nil
+	})
+	defer restore()
+
+	if err := saveAndProcessEntry(app, first); err != nil {
+		t.Fatalf("saveAndProcessEntry: %v", err)
+	}
+	if !fragmentBatcher.isQueued(first.Id) {
+		t.Error("expected the first fragment to wait for the batch")
+	}
+	if err := saveAndProcessEntry(app, second); err != nil {
+		t.Fatalf("saveAndProcessEntry: %v", err)
+	}
+	waitForProcessed(t, app, first.Id, second.Id)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if batchCalls != 1 || singleCalls != 1 {
+		t.Errorf("expected one batch call and one fallback call, got %d and %d", batchCalls, singleCalls)
+	}
+	for id, want := range map[string]int{first.Id: 4, second.Id: 2} {
+		record, _ := app.FindRecordById("entries", id)
+		if record.GetString("processing_status") != "done" || record.GetInt("ai_stars") != want {
+			t.Errorf("entry %s: status %q stars %d, want done with %d", id, record.GetString("processing_status"), record.GetInt("ai_stars"), want)
+		}
+	}
+}
+
+func TestSaveAndProcessEntry_BatchWindowFlushes(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	testutil.CreateSetting(t, app, SettingScoreBatchWindowMs, "20")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := newPendingFragment(t, app, res.Id, "Raft")
+
+	restore := ai.SetCompleteFunc(func(_, _ string, _ []ai.Message) (string, error) {
+		return `{"summary": "", "stars": 3}`, nil
+	})
+	defer restore()
+
+	if err := saveAndProcessEntry(app, entry); err != nil {
+		t.Fatalf("saveAndProcessEntry: %v", err)
+	}
+	waitForProcessed(t, app, entry.Id)
+
+	record, _ := app.FindRecordById("entries", entry.Id)
+	if record.GetString("processing_status") != "done" || record.GetInt("ai_stars") != 3 {
+		t.Errorf("expected the lone fragment to be scored after the window, got %q with %d stars",
+			record.GetString("processing_status"), record.GetInt("ai_stars"))
+	}
+}
+
+func TestScoreFragmentBatch_BatchErrorFallsBack(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	first := newPendingFragment(t, app, res.Id, "Raft")
+	second := newPendingFragment(t, app, res.Id, "Paxos")
+	for _, e := range []*core.Record{first, second} {
+		if err := app.Save(e); err != nil {
+			t.Fatalf("save entry: %v", err)
+		}
+	}
+
+	restore := ai.SetCompleteFunc(func(_, _ string, msgs []ai.Message) (string, error) {
+		prompt := msgs[len(msgs)-1].Content
+		switch {
+		case strings.Contains(prompt, "<fragment id="):
+			return "not json", nil
+		case strings.Contains(prompt, "Paxos"):
+			return "still not json", nil
+		default:
+			return `{"summary": "", "stars": 5}`, nil
+		}
+	})
+	defer restore()
+
+	scoreFragmentBatch(app, []*core.Record{first, second})
+
+	raft, _ := app.FindRecordById("entries", first.Id)
+	if raft.GetString("processing_status") != "done" || raft.GetInt("ai_stars") != 5 {
+		t.Errorf("expected Raft to be scored on its own, got %q with %d stars", raft.GetString("processing_status"), raft.GetInt("ai_stars"))
+	}
+	paxos, _ := app.FindRecordById("entries", second.Id)
+	if paxos.GetString("processing_status") != "failed" {
+		t.Errorf("expected Paxos to be marked failed, got %q", paxos.GetString("processing_status"))
+	}
+}
+
+func TestFragmentBatcher_Disabled(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, SettingScoreBatchSize, "1")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := newPendingFragment(t, app, res.Id, "Raft")
+
+	if fragmentBatcher.enqueue(app, entry) {
+		t.Error("expected a batch size of 1 to disable batching")
+	}
+	if fragmentBatcher.isQueued(entry.Id) {
+		t.Error("expected nothing to be queued")
+	}
+}
+
+func TestScoreBatchSize(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	if got := scoreBatchSize(app); got != defaultScoreBatchSize {
+		t.Errorf("expected default %d, got %d", defaultScoreBatchSize, got)
+	}
+	testutil.CreateSetting(t, app, SettingScoreBatchSize, "500")
+	if got := scoreBatchSize(app); got != ai.MaxScoreBatchSize {
+		t.Errorf("expected the size capped at %d, got %d", ai.MaxScoreBatchSize, got)
+	}
+}
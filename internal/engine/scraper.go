@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/pocketbase/pocketbase/core"
@@ -13,8 +14,9 @@ import (
 
 // ScrapedLink holds a discovered article link.
 type ScrapedLink struct {
-	Title string
-	URL   string
+	Title       string
+	URL         string
+	PublishedAt *time.Time
 }
 
 // ScrapeArticleLinks fetches a page and extracts article links using the
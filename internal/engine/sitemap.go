@@ -0,0 +1,272 @@
+package engine
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const (
+	// maxSitemapDepth bounds how many levels of nested sitemap indexes are followed.
+	maxSitemapDepth = 3
+	// maxSitemapLinks caps the number of new pages extracted per fetch so a
+	// first run against a large site does not queue thousands of AI calls.
+	// The most recently modified pages win; older ones beyond the cap are
+	// left for the next fetches.
+	maxSitemapLinks = 25
+	// maxSitemapBytes caps the (decompressed) size of a single sitemap file.
+	// The sitemap protocol limits files to 50MB uncompressed.
+	maxSitemapBytes = 50 << 20
+)
+
+// sitemapDocument covers both <urlset> and <sitemapindex> documents.
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// SitemapURL is a page listed in a sitemap.
+type SitemapURL struct {
+	URL     string
+	LastMod *time.Time
+}
+
+// FetchSitemapLinks fetches the resource's sitemap (or sitemap index) and
+// returns pages that match the resource's url_pattern, were modified since the
+// resource's lastmod horizon, and don't already exist in the database.
+func FetchSitemapLinks(app core.App, resource *core.Record, client *http.Client) ([]ScrapedLink, error) {
+	links, _, err := fetchSitemapLinks(app, resource, client)
+	return links, err
+}
+
+// fetchSitemapLinks is FetchSitemapLinks that also returns the lastmod
+// horizon for the next fetch: the time of this fetch, or the current horizon
+// when the cap left new pages unprocessed, so they are not skipped later.
+func fetchSitemapLinks(app core.App, resource *core.Record, client *http.Client) ([]ScrapedLink, time.Time, error) {
+	sitemapURL := resource.GetString("url")
+	fetchedAt := time.Now().UTC()
+
+	var pattern *regexp.Regexp
+	if p := strings.TrimSpace(resource.GetString("url_pattern")); p != "" {
+		compiled, err := regexp.Compile(p)
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("invalid url_pattern %q: %w", p, err)
+		}
+		pattern = compiled
+	}
+
+	since := sitemapHorizon(resource)
+	pages, err := collectSitemapURLs(client, sitemapURL, since, 0)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	pages = filterSitemapURLs(pages, pattern, since)
+
+	links := make([]ScrapedLink, 0, len(pages))
+	for _, p := range pages {
+		links = append(links, ScrapedLink{Title: p.URL, URL: p.URL, PublishedAt: p.LastMod})
+	}
+
+	links, err = deduplicateLinks(app, resource.Id, links)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("deduplicating: %w", err)
+	}
+
+	if len(links) > maxSitemapLinks {
+		log.Printf("Sitemap %s has %d new pages, processing the %d most recent now and the rest on later fetches", sitemapURL, len(links), maxSitemapLinks)
+		return links[:maxSitemapLinks], since, nil
+	}
+
+	return links, fetchedAt, nil
+}
+
+// sitemapHorizon returns the time after which sitemap pages count as new.
+// Resources fetched before sitemap_since existed fall back to last_checked.
+func sitemapHorizon(resource *core.Record) time.Time {
+	if since := resource.GetDateTime("sitemap_since").Time(); !since.IsZero() {
+		return since.UTC()
+	}
+	if since := resource.GetDateTime("last_checked").Time(); !since.IsZero() {
+		return since.UTC()
+	}
+	// Same 12 month horizon FetchRSS uses to avoid importing ancient history.
+	return time.Now().UTC().AddDate(-1, 0, 0)
+}
+
+// collectSitemapURLs fetches a sitemap and returns its pages. Sitemap indexes
+// are followed recursively, skipping child sitemaps whose lastmod shows they
+// have not changed since the given time.
+func collectSitemapURLs(client *http.Client, sitemapURL string, since time.Time, depth int) ([]SitemapURL, error) {
+	if depth >= maxSitemapDepth {
+		return nil, fmt.Errorf("sitemap %s: index nesting exceeds %d levels", sitemapURL, maxSitemapDepth)
+	}
+
+	body, err := fetchSitemapBody(sitemapURL, client)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := parseSitemap(body)
+	if err != nil {
+		return nil, fmt.Errorf("parsing sitemap %s: %w", sitemapURL, err)
+	}
+
+	base, _ := url.Parse(sitemapURL)
+
+	if doc.XMLName.Local == "sitemapindex" {
+		var pages []SitemapURL
+		for _, child := range doc.Sitemaps {
+			childURL := resolveURL(base, strings.TrimSpace(child.Loc))
+			if childURL == "" {
+				continue
+			}
+			if lastMod := parseSitemapTime(child.LastMod); lastMod != nil && !lastMod.After(since) {
+				continue
+			}
+			childPages, err := collectSitemapURLs(client, childURL, since, depth+1)
+			if err != nil {
+				// One broken child sitemap should not block the others.
+				log.Printf("Skipping child sitemap %s: %v", childURL, err)
+				continue
+			}
+			pages = append(pages, childPages...)
+		}
+		return pages, nil
+	}
+
+	pages := make([]SitemapURL, 0, len(doc.URLs))
+	for _, u := range doc.URLs {
+		pageURL := resolveURL(base, strings.TrimSpace(u.Loc))
+		if pageURL == "" {
+			continue
+		}
+		pages = append(pages, SitemapURL{URL: pageURL, LastMod: parseSitemapTime(u.LastMod)})
+	}
+	return pages, nil
+}
+
+// filterSitemapURLs keeps pages whose path matches pattern (when set) and
+// whose lastmod is after since, ordered newest first. Pages without a lastmod
+// are kept and sorted last; URL dedup prevents them from being re-imported.
+func filterSitemapURLs(pages []SitemapURL, pattern *regexp.Regexp, since time.Time) []SitemapURL {
+	seen := make(map[string]bool, len(pages))
+	var filtered []SitemapURL
+	for _, p := range pages {
+		if seen[p.URL] {
+			continue
+		}
+		seen[p.URL] = true
+		if pattern != nil {
+			parsed, err := url.Parse(p.URL)
+			if err != nil || !pattern.MatchString(parsed.Path) {
+				continue
+			}
+		}
+		if p.LastMod != nil && !p.LastMod.After(since) {
+			continue
+		}
+		filtered = append(filtered, p)
+	}
+
+	sort.SliceStable(filtered, func(i, j int) bool {
+		li, lj := filtered[i].LastMod, filtered[j].LastMod
+		if li == nil || lj == nil {
+			return li != nil && lj == nil
+		}
+		return li.After(*lj)
+	})
+	return filtered
+}
+
+// fetchSitemapBody downloads a sitemap, transparently decompressing gzip
+// files (e.g. sitemap.xml.gz) regardless of the advertised content type.
+func fetchSitemapBody(sitemapURL string, client *http.Client) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sitemapURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request for %s: %w", sitemapURL, err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching sitemap %s: %w", sitemapURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d for sitemap %s", resp.StatusCode, sitemapURL)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSitemapBytes))
+	if err != nil {
+		return nil, fmt.Errorf("reading sitemap %s: %w", sitemapURL, err)
+	}
+
+	if len(body) >= 2 && body[0] == 0x1f && body[1] == 0x8b {
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("decompressing sitemap %s: %w", sitemapURL, err)
+		}
+		defer gz.Close()
+		body, err = io.ReadAll(io.LimitReader(gz, maxSitemapBytes))
+		if err != nil {
+			return nil, fmt.Errorf("decompressing sitemap %s: %w", sitemapURL, err)
+		}
+	}
+
+	return body, nil
+}
+
+func parseSitemap(body []byte) (sitemapDocument, error) {
+	var doc sitemapDocument
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return sitemapDocument{}, err
+	}
+	if doc.XMLName.Local != "urlset" && doc.XMLName.Local != "sitemapindex" {
+		return sitemapDocument{}, fmt.Errorf("unexpected root element <%s>", doc.XMLName.Local)
+	}
+	return doc, nil
+}
+
+// sitemapTimeLayouts are the W3C Datetime variants allowed in <lastmod>.
+var sitemapTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+func parseSitemapTime(value string) *time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	for _, layout := range sitemapTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			t = t.UTC()
+			return &t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package engine
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+	"github.com/pocketbase/dbx"
+)
+
+func gzipBytes(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(s)); err != nil {
+		t.Fatalf("gzip write: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func newSitemapServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	recent := time.Now().UTC().Add(-time.Hour).Format(time.RFC3339)
+	old := time.Now().UTC().AddDate(-2, 0, 0).Format("2006-01-02")
+
+	mux := http.NewServeMux()
+	var server *httptest.Server
+	mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>` + server.URL + `/posts.xml.gz</loc><lastmod>` + recent + `</lastmod></sitemap>
+  <sitemap><loc>/pages.xml</loc></sitemap>
+  <sitemap><loc>` + server.URL + `/archive.xml</loc><lastmod>` + old + `</lastmod></sitemap>
+</sitemapindex>`))
+	})
+	mux.HandleFunc("/posts.xml.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Write(gzipBytes(t, `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>`+server.URL+`/blog/new-post</loc><lastmod>`+recent+`</lastmod></url>
+  <url><loc>`+server.URL+`/blog/ancient-post</loc><lastmod>`+old+`</lastmod></url>
+</urlset>`))
+	})
+	mux.HandleFunc("/pages.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>` + server.URL + `/about</loc></url>
+  <url><loc>` + server.URL + `/blog/undated-post</loc></url>
+</urlset>`))
+	})
+	mux.HandleFunc("/archive.xml", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("archive sitemap should be skipped by lastmod")
+	})
+	mux.HandleFunc("/blog/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(testArticleHTML))
+	})
+	server = httptest.NewServer(mux)
+	return server
+}
+
+func TestFetchSitemapLinks_IndexGzipPatternAndLastmod(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	server := newSitemapServer(t)
+	defer server.Close()
+
+	resource := testutil.CreateResource(t, app, "sitemap", server.URL+"/sitemap.xml", "sitemap", "healthy", 0, true)
+	resource.Set("url_pattern", "^/blog/")
+	if err := app.Save(resource); err != nil {
+		t.Fatalf("failed to update resource: %v", err)
+	}
+
+	links, err := FetchSitemapLinks(app, resource, server.Client())
+	if err != nil {
+		t.Fatalf("FetchSitemapLinks returned error: %v", err)
+	}
+
+	if len(links) != 2 {
+		t.Fatalf("got %d links, want 2: %+v", len(links), links)
+	}
+	if links[0].URL != server.URL+"/blog/new-post" || links[0].PublishedAt == nil {
+		t.Errorf("first link = %+v, want dated new-post first", links[0])
+	}
+	if links[1].URL != server.URL+"/blog/undated-post" || links[1].PublishedAt != nil {
+		t.Errorf("second link = %+v, want undated-post", links[1])
+	}
+}
+
+func TestFetchSitemapLinks_DeduplicatesExistingEntries(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	server := newSitemapServer(t)
+	defer server.Close()
+
+	resource := testutil.CreateResource(t, app, "sitemap", server.URL+"/sitemap.xml", "sitemap", "healthy", 0, true)
+	testutil.CreateEntry(t, app, resource.Id, "Existing", server.URL+"/blog/new-post", server.URL+"/blog/new-post")
+
+	links, err := FetchSitemapLinks(app, resource, server.Client())
+	if err != nil {
+		t.Fatalf("FetchSitemapLinks returned error: %v", err)
+	}
+	for _, l := range links {
+		if strings.HasSuffix(l.URL, "/blog/new-post") {
+			t.Errorf("existing entry URL was not deduplicated: %+v", links)
+		}
+	}
+}
+
+func TestFetchSitemapLinks_InvalidPattern(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	resource := testutil.CreateResource(t, app, "sitemap", "https://example.com/sitemap.xml", "sitemap", "healthy", 0, true)
+	resource.Set("url_pattern", "([")
+	if err := app.Save(resource); err != nil {
+		t.Fatalf("failed to update resource: %v", err)
+	}
+
+	if _, err := FetchSitemapLinks(app, resource, http.DefaultClient); err == nil {
+		t.Fatal("expected error for invalid url_pattern")
+	}
+}
+
+func TestFetchSitemapLinks_HTTPError(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	resource := testutil.CreateResource(t, app, "sitemap", server.URL+"/sitemap.xml", "sitemap", "healthy", 0, true)
+
+	_, err := FetchSitemapLinks(app, resource, server.Client())
+	if err == nil || !strings.Contains(err.Error(), "HTTP 404") {
+		t.Fatalf("err = %v, want HTTP 404", err)
+	}
+}
+
+func TestFetchResource_Sitemap(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	server := newSitemapServer(t)
+	defer server.Close()
+
+	resource := testutil.CreateResource(t, app, "sitemap", server.URL+"/sitemap.xml", "sitemap", "healthy", 0, true)
+	resource.Set("url_pattern", "^/blog/")
+	if err := app.Save(resource); err != nil {
+		t.Fatalf("failed to update resource: %v", err)
+	}
+
+	if err := FetchResource(app, resource, server.Client()); err != nil {
+		t.Fatalf("FetchResource returned error: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	entries, err := app.FindRecordsByFilter("entries", "resource = {:id}", "", 0, 0, map[string]any{"id": resource.Id})
+	if err != nil {
+		t.Fatalf("failed to find entries: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	for _, e := range entries {
+		if e.GetString("url") == server.URL+"/blog/new-post" && e.GetDateTime("published_at").IsZero() {
+			t.Errorf("expected published_at from sitemap lastmod")
+		}
+	}
+}
+
+func TestFetchResource_SitemapCapLeavesOverflowForNextFetch(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	const total = maxSitemapLinks + 5
+	now := time.Now().UTC()
+	mux := http.NewServeMux()
+	var server *httptest.Server
+	mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		var urls strings.Builder
+		for i := 0; i < total; i++ {
+			lastmod := now.Add(-time.Duration(i+1) * time.Hour).Format(time.RFC3339)
+			fmt.Fprintf(&urls, "<url><loc>%s/p/%d</loc><lastmod>%s</lastmod></url>", server.URL, i, lastmod)
+		}
+		w.Write([]byte(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` + urls.String() + `</urlset>`))
+	})
+	mux.HandleFunc("/p/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(testArticleHTML))
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	oldBrowserFunc := BrowserExtractFunc
+	BrowserExtractFunc = func(url string) (ExtractedContent, error) {
+		return ExtractedContent{}, fmt.Errorf("no browser in tests")
+	}
+	defer func() { BrowserExtractFunc = oldBrowserFunc }()
+
+	resource := testutil.CreateResource(t, app, "sitemap", server.URL+"/sitemap.xml", "sitemap", "healthy", 0, true)
+	countEntries := func() int {
+		n, err := app.CountRecords("entries", dbx.HashExp{"resource": resource.Id})
+		if err != nil {
+			t.Fatalf("counting entries: %v", err)
+		}
+		return int(n)
+	}
+
+	if err := FetchResource(app, resource, server.Client()); err != nil {
+		t.Fatalf("first FetchResource: %v", err)
+	}
+	if err := RecordSuccess(app, resource); err != nil {
+		t.Fatalf("RecordSuccess: %v", err)
+	}
+	if got := countEntries(); got != maxSitemapLinks {
+		t.Fatalf("first fetch created %d entries, want %d", got, maxSitemapLinks)
+	}
+
+	if err := FetchResource(app, resource, server.Client()); err != nil {
+		t.Fatalf("second FetchResource: %v", err)
+	}
+	if got := countEntries(); got != total {
+		t.Fatalf("after second fetch: %d entries, want %d", got, total)
+	}
+	if since := resource.GetDateTime("sitemap_since").Time(); since.Before(now) {
+		t.Errorf("sitemap_since = %v, expected it to advance once every page was processed", since)
+	}
+}
+
+func TestFilterSitemapURLs(t *testing.T) {
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := since.Add(-time.Hour)
+	after := since.Add(time.Hour)
+	later := since.Add(2 * time.Hour)
+
+	pages := []SitemapURL{
+		{URL: "https://example.com/blog/a", LastMod: &after},
+		{URL: "https://example.com/blog/b", LastMod: &before},
+		{URL: "https://example.com/docs/c", LastMod: &later},
+		{URL: "https://example.com/blog/d"},
+		{URL: "https://example.com/blog/e", LastMod: &later},
+		{URL: "https://example.com/blog/a", LastMod: &after},
+	}
+
+	got := filterSitemapURLs(pages, regexp.MustCompile(`^/blog/`), since)
+	want := []string{"https://example.com/blog/e", "https://example.com/blog/a", "https://example.com/blog/d"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d pages, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i].URL != want[i] {
+			t.Errorf("page %d = %s, want %s", i, got[i].URL, want[i])
+		}
+	}
+}
+
+func TestParseSitemapTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"2025-03-04", "2025-03-04T00:00:00Z"},
+		{"2025-03-04T10:20:30+01:00", "2025-03-04T09:20:30Z"},
+		{"2025-03-04T10:20+02:00", "2025-03-04T08:20:00Z"},
+		{" 2025-03-04T10:20:30 ", "2025-03-04T10:20:30Z"},
+	}
+	for _, tt := range tests {
+		got := parseSitemapTime(tt.in)
+		if got == nil || got.Format(time.RFC3339) != tt.want {
+			t.Errorf("parseSitemapTime(%q) = %v, want %s", tt.in, got, tt.want)
+		}
+	}
+	if parseSitemapTime("") != nil || parseSitemapTime("yesterday") != nil {
+		t.Error("expected nil for empty or unparseable lastmod")
+	}
+}
+
+func TestParseSitemap_RejectsUnknownRoot(t *testing.T) {
+	if _, err := parseSitemap([]byte(`<rss><channel></channel></rss>`)); err == nil {
+		t.Fatal("expected error for non-sitemap XML")
+	}
+}
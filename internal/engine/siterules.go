@@ -0,0 +1,553 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// maxRulePages bounds how many next_page_link pages are merged into one article.
+const maxRulePages = 10
+
+// SiteRule holds per-domain extraction rules in the style of FiveFilters
+// ftr-site-config files. Selectors may be XPath (converted to CSS) or CSS.
+type SiteRule struct {
+	Domain          string
+	Title           []string
+	Body            []string
+	Strip           []string
+	StripIDOrClass  []string
+	NextPageLink    []string
+	SinglePageLink  []string
+	PreferAMP       bool
+	URLRewrites     []URLRewrite
+	AutodetectOnErr bool // fall back to readability when the body rule fails (default true)
+}
+
+// URLRewrite rewrites article URLs before fetching, e.g. to a print view.
+type URLRewrite struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// ParseSiteRule parses an ftr-site-config style rule file. Supported
+// directives: title, body, strip, strip_id_or_class, next_page_link,
+// single_page_link, autodetect_on_failure, plus prefer_amp (yes/no) and
+// rewrite_url ("<regexp> => <replacement>"). Unknown directives are ignored.
+func ParseSiteRule(domain, text string) (*SiteRule, error) {
+	rule := &SiteRule{Domain: normalizeRuleDomain(domain), AutodetectOnErr: true}
+	for lineNo, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		switch key {
+		case "title", "body", "strip", "next_page_link", "single_page_link":
+			selector, err := ruleSelector(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s: %w", lineNo+1, key, err)
+			}
+			switch key {
+			case "title":
+				rule.Title = append(rule.Title, selector)
+			case "body":
+				rule.Body = append(rule.Body, selector)
+			case "strip":
+				rule.Strip = append(rule.Strip, selector)
+			case "next_page_link":
+				rule.NextPageLink = append(rule.NextPageLink, selector)
+			case "single_page_link":
+				rule.SinglePageLink = append(rule.SinglePageLink, selector)
+			}
+		case "strip_id_or_class":
+			rule.StripIDOrClass = append(rule.StripIDOrClass, strings.Trim(value, `"'`))
+		case "prefer_amp":
+			rule.PreferAMP = ruleBool(value)
+		case "autodetect_on_failure":
+			rule.AutodetectOnErr = ruleBool(value)
+		case "rewrite_url":
+			pattern, replacement, ok := strings.Cut(value, "=>")
+			if !ok {
+				return nil, fmt.Errorf("line %d: rewrite_url must be \"<regexp> => <replacement>\"", lineNo+1)
+			}
+			re, err := regexp.Compile(strings.TrimSpace(pattern))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: rewrite_url: %w", lineNo+1, err)
+			}
+			rule.URLRewrites = append(rule.URLRewrites, URLRewrite{Pattern: re, Replacement: strings.TrimSpace(replacement)})
+		}
+	}
+	return rule, nil
+}
+
+func ruleBool(v string) bool {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "yes", "true", "1", "on":
+		return true
+	}
+	return false
+}
+
+func normalizeRuleDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	domain = strings.TrimSuffix(domain, ".txt")
+	domain = strings.TrimPrefix(domain, ".")
+	return strings.TrimPrefix(domain, "www.")
+}
+
+// RewriteURL applies the rule's URL rewrites (AMP/print views) in order.
+func (r *SiteRule) RewriteURL(articleURL string) string {
+	for _, rw := range r.URLRewrites {
+		articleURL = rw.Pattern.ReplaceAllString(articleURL, rw.Replacement)
+	}
+	return articleURL
+}
+
+// siteRuleRegistry caches the parsed rules from the site_rules collection.
+type siteRuleRegistry struct {
+	mu    sync.RWMutex
+	rules map[string]*SiteRule
+}
+
+var siteRules = &siteRuleRegistry{rules: map[string]*SiteRule{}}
+
+// LoadSiteRules (re)loads all rules from the site_rules collection. Rules
+// that fail to parse are logged and skipped.
+func LoadSiteRules(app core.App) error {
+	records, err := app.FindAllRecords("site_rules")
+	if err != nil {
+		return err
+	}
+	rules := make(map[string]*SiteRule, len(records))
+	for _, record := range records {
+		rule, err := ParseSiteRule(record.GetString("domain"), record.GetString("rules"))
+		if err != nil {
+			log.Printf("Skipping site rule for %s: %v", record.GetString("domain"), err)
+			continue
+		}
+		rules[rule.Domain] = rule
+	}
+
+	siteRules.mu.Lock()
+	siteRules.rules = rules
+	siteRules.mu.Unlock()
+	return nil
+}
+
+// siteRuleFor returns the most specific rule for a host: the host itself,
+// then each parent domain (rules for example.com also cover blog.example.com).
+func siteRuleFor(host string) *SiteRule {
+	host = normalizeRuleDomain(strings.Split(host, ":")[0])
+	siteRules.mu.RLock()
+	defer siteRules.mu.RUnlock()
+	for host != "" {
+		if rule, ok := siteRules.rules[host]; ok {
+			return rule
+		}
+		_, parent, found := strings.Cut(host, ".")
+		if !found || !strings.Contains(parent, ".") {
+			return nil
+		}
+		host = parent
+	}
+	return nil
+}
+
+// siteRuleForURL returns the site rule for an article URL, or nil.
+func siteRuleForURL(articleURL string) *SiteRule {
+	parsed, err := url.Parse(articleURL)
+	if err != nil || parsed.Host == "" {
+		return nil
+	}
+	return siteRuleFor(parsed.Host)
+}
+
+// RewriteArticleURL applies the URL rewrites of the article's site rule.
+func RewriteArticleURL(articleURL string) string {
+	if rule := siteRuleForURL(articleURL); rule != nil {
+		return rule.RewriteURL(articleURL)
+	}
+	return articleURL
+}
+
+// alternatePageURL returns the single-page (or, with prefer_amp, AMP) version
+// of an article advertised by the page, or "" when there is none.
+func alternatePageURL(rule *SiteRule, pageHTML string, pageURL *url.URL) string {
+	if len(rule.SinglePageLink) == 0 && !rule.PreferAMP {
+		return ""
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(pageHTML))
+	if err != nil {
+		return ""
+	}
+	alt := ruleLink(rule.SinglePageLink, doc, pageURL)
+	if alt == "" && rule.PreferAMP {
+		alt = ruleLink([]string{`link[rel="amphtml"]`}, doc, pageURL)
+	}
+	if alt == pageURL.String() {
+		return ""
+	}
+	return alt
+}
+
+// ImportSiteRules imports every *.txt rule file in dir into the site_rules
+// collection (the file name is the domain, e.g. "example.com.txt"). Imported
+// rules are updated on re-import; rules edited in the app (without
+// imported_from) are left alone. Returns the number of rules imported.
+func ImportSiteRules(app core.App, dir string) (int, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return 0, err
+	}
+	collection, err := app.FindCollectionByNameOrId("site_rules")
+	if err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Printf("Failed to read site rule %s: %v", file, err)
+			continue
+		}
+		domain := normalizeRuleDomain(filepath.Base(file))
+		if _, err := ParseSiteRule(domain, string(data)); err != nil {
+			log.Printf("Skipping invalid site rule %s: %v", file, err)
+			continue
+		}
+
+		record, err := app.FindFirstRecordByFilter("site_rules", "domain = {:domain}", map[string]any{"domain": domain})
+		if err != nil {
+			record = core.NewRecord(collection)
+			record.Set("domain", domain)
+		} else if record.GetString("imported_from") == "" {
+			continue // manually maintained rule takes precedence
+		}
+		record.Set("rules", string(data))
+		record.Set("imported_from", file)
+		if err := app.Save(record); err != nil {
+			log.Printf("Failed to save site rule %s: %v", domain, err)
+			continue
+		}
+		imported++
+	}
+
+	return imported, LoadSiteRules(app)
+}
+
+// applySiteRule extracts the article body with a site rule. fetchPage is used
+// to follow next_page_link pages. Returns ok=false when no body selector matched.
+func applySiteRule(rule *SiteRule, pageHTML string, pageURL *url.URL, fetchPage func(string) (string, error)) (title, bodyHTML string, ok bool) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(pageHTML))
+	if err != nil {
+		return "", "", false
+	}
+
+	for _, sel := range rule.Title {
+		if t := strings.TrimSpace(doc.Find(sel).First().Text()); t != "" {
+			title = t
+			break
+		}
+	}
+
+	var pages []string
+	seen := map[string]bool{pageURL.String(): true}
+	for page := 0; page < maxRulePages; page++ {
+		body, found := ruleBody(rule, doc)
+		if !found {
+			break
+		}
+		pages = append(pages, body)
+
+		next := ruleLink(rule.NextPageLink, doc, pageURL)
+		if next == "" || seen[next] || fetchPage == nil {
+			break
+		}
+		seen[next] = true
+		nextHTML, err := fetchPage(next)
+		if err != nil {
+			log.Printf("Failed to fetch next page %s: %v", next, err)
+			break
+		}
+		if doc, err = goquery.NewDocumentFromReader(strings.NewReader(nextHTML)); err != nil {
+			break
+		}
+		pageURL, _ = url.Parse(next)
+	}
+
+	if len(pages) == 0 {
+		return title, "", false
+	}
+	return title, strings.Join(pages, "\n"), true
+}
+
+// ruleBody strips unwanted elements and returns the HTML of every element
+// matched by the first body selector that matches.
+func ruleBody(rule *SiteRule, doc *goquery.Document) (string, bool) {
+	for _, sel := range rule.Strip {
+		doc.Find(sel).Remove()
+	}
+	for _, token := range rule.StripIDOrClass {
+		doc.Find("[id], [class]").FilterFunction(func(_ int, s *goquery.Selection) bool {
+			return strings.Contains(s.AttrOr("id", ""), token) || strings.Contains(s.AttrOr("class", ""), token)
+		}).Remove()
+	}
+
+	for _, sel := range rule.Body {
+		matches := doc.Find(sel)
+		if matches.Length() == 0 {
+			continue
+		}
+		var sb strings.Builder
+		matches.Each(func(_ int, s *goquery.Selection) {
+			if h, err := goquery.OuterHtml(s); err == nil {
+				sb.WriteString(h)
+				sb.WriteString("\n")
+			}
+		})
+		if strings.TrimSpace(sb.String()) != "" {
+			return sb.String(), true
+		}
+	}
+	return "", false
+}
+
+// ruleLink returns the absolute href of the first element matched by selectors.
+func ruleLink(selectors []string, doc *goquery.Document, base *url.URL) string {
+	for _, sel := range selectors {
+		href, ok := doc.Find(sel).First().Attr("href")
+		if !ok {
+			continue
+		}
+		if resolved := resolveURL(base, strings.TrimSpace(href)); resolved != "" {
+			return resolved
+		}
+	}
+	return ""
+}
+
+// ruleSelector returns a CSS selector for a rule value, translating XPath
+// expressions (those starting with "/", "./" or "(") to CSS.
+func ruleSelector(expr string) (string, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "/") || strings.HasPrefix(expr, "./") || strings.HasPrefix(expr, "(") {
+		return XPathToCSS(expr)
+	}
+	if _, err := cascadia.ParseGroup(expr); err != nil {
+		return "", err
+	}
+	return expr, nil
+}
+
+var (
+	xpathClassTokenRe = regexp.MustCompile(`^contains\(\s*concat\(\s*['"] ['"]\s*,\s*normalize-space\(\s*@class\s*\)\s*,\s*['"] ['"]\s*\)\s*,\s*['"] ([^'"]+) ['"]\s*\)$`)
+	xpathContainsRe   = regexp.MustCompile(`^contains\(\s*@([\w:-]+)\s*,\s*['"]([^'"]*)['"]\s*\)$`)
+	xpathStartsWithRe = regexp.MustCompile(`^starts-with\(\s*@([\w:-]+)\s*,\s*['"]([^'"]*)['"]\s*\)$`)
+	xpathEqualsRe     = regexp.MustCompile(`^@([\w:-]+)\s*=\s*['"]([^'"]*)['"]$`)
+	xpathHasAttrRe    = regexp.MustCompile(`^@([\w:-]+)$`)
+	xpathIndexRe      = regexp.MustCompile(`^\d+$`)
+	xpathNameRe       = regexp.MustCompile(`^(\*|[A-Za-z][\w-]*)$`)
+)
+
+// XPathToCSS converts the XPath subset used by site config files to a CSS
+// selector: absolute and descendant steps, unions, and predicates on
+// attributes (=, contains, starts-with, class tokens, presence), "and" and
+// positional indexes. Other XPath (axes, text(), functions) returns an error.
+func XPathToCSS(expr string) (string, error) {
+	var parts []string
+	for _, alt := range splitXPathUnion(expr) {
+		css, err := xpathPathToCSS(strings.TrimSpace(alt))
+		if err != nil {
+			return "", fmt.Errorf("unsupported XPath %q: %w", expr, err)
+		}
+		parts = append(parts, css)
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+// splitXPathUnion splits on "|" outside of predicates and quotes.
+func splitXPathUnion(expr string) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote rune
+	for i, r := range expr {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '[' || r == '(':
+			depth++
+		case r == ']' || r == ')':
+			depth--
+		case r == '|' && depth == 0:
+			parts = append(parts, expr[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, expr[start:])
+}
+
+func xpathPathToCSS(path string) (string, error) {
+	path = strings.TrimPrefix(path, "(")
+	path = strings.TrimSuffix(path, ")")
+	path = strings.TrimPrefix(path, ".")
+
+	var sb strings.Builder
+	for path != "" {
+		combinator := ""
+		switch {
+		case strings.HasPrefix(path, "//"):
+			path = path[2:]
+			combinator = " "
+		case strings.HasPrefix(path, "/"):
+			path = path[1:]
+			combinator = " > "
+		default:
+			return "", fmt.Errorf("expected / at %q", path)
+		}
+
+		step, rest := nextXPathStep(path)
+		path = rest
+		css, err := xpathStepToCSS(step)
+		if err != nil {
+			return "", err
+		}
+		if sb.Len() > 0 {
+			sb.WriteString(combinator)
+		}
+		sb.WriteString(css)
+	}
+	if sb.Len() == 0 {
+		return "", fmt.Errorf("empty path")
+	}
+	return sb.String(), nil
+}
+
+// nextXPathStep splits off the first step (name plus predicates).
+func nextXPathStep(path string) (string, string) {
+	depth := 0
+	var quote rune
+	for i, r := range path {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '[':
+			depth++
+		case r == ']':
+			depth--
+		case r == '/' && depth == 0:
+			return path[:i], path[i:]
+		}
+	}
+	return path, ""
+}
+
+func xpathStepToCSS(step string) (string, error) {
+	name := step
+	var predicates []string
+	if i := strings.Index(step, "["); i >= 0 {
+		name = step[:i]
+		rest := step[i:]
+		for rest != "" {
+			if rest[0] != '[' {
+				return "", fmt.Errorf("malformed predicate in %q", step)
+			}
+			end := matchingBracket(rest)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated predicate in %q", step)
+			}
+			predicates = append(predicates, strings.TrimSpace(rest[1:end]))
+			rest = rest[end+1:]
+		}
+	}
+	if !xpathNameRe.MatchString(name) {
+		return "", fmt.Errorf("unsupported step %q", step)
+	}
+
+	var sb strings.Builder
+	if name != "*" || len(predicates) == 0 {
+		sb.WriteString(strings.ToLower(name))
+	}
+	for _, pred := range predicates {
+		for _, cond := range strings.Split(pred, " and ") {
+			css, err := xpathPredicateToCSS(strings.TrimSpace(cond))
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(css)
+		}
+	}
+	return sb.String(), nil
+}
+
+func matchingBracket(s string) int {
+	depth := 0
+	var quote rune
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '[':
+			depth++
+		case r == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func xpathPredicateToCSS(cond string) (string, error) {
+	if m := xpathClassTokenRe.FindStringSubmatch(cond); m != nil {
+		return "." + m[1], nil
+	}
+	if m := xpathContainsRe.FindStringSubmatch(cond); m != nil {
+		return fmt.Sprintf(`[%s*=%q]`, m[1], m[2]), nil
+	}
+	if m := xpathStartsWithRe.FindStringSubmatch(cond); m != nil {
+		return fmt.Sprintf(`[%s^=%q]`, m[1], m[2]), nil
+	}
+	if m := xpathEqualsRe.FindStringSubmatch(cond); m != nil {
+		return fmt.Sprintf(`[%s=%q]`, m[1], m[2]), nil
+	}
+	if m := xpathHasAttrRe.FindStringSubmatch(cond); m != nil {
+		return "[" + m[1] + "]", nil
+	}
+	if xpathIndexRe.MatchString(cond) {
+		return ":nth-of-type(" + cond + ")", nil
+	}
+	return "", fmt.Errorf("unsupported predicate [%s]", cond)
+}
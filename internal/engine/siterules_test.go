@@ -0,0 +1,481 @@
+package engine
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// articleParagraphs returns n paragraphs of filler article text.
+func articleParagraphs(n int, topic string) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "<p>Paragraph %d about %s explains the design in detail, covering trade-offs, failure modes, benchmarks and the reasoning behind every decision the team made along the way.</p>\n", i+1, topic)
+	}
+	return sb.String()
+}
+
+func createSiteRule(t *testing.T, app core.App, domain, rules string) {
+	t.Helper()
+	col, err := app.FindCollectionByNameOrId("site_rules")
+	if err != nil {
+		t.Fatalf("site_rules collection not found: %v", err)
+	}
+	record := core.NewRecord(col)
+	record.Set("domain", domain)
+	record.Set("rules", rules)
+	if err := app.Save(record); err != nil {
+		t.Fatalf("failed to save site rule: %v", err)
+	}
+	if err := LoadSiteRules(app); err != nil {
+		t.Fatalf("LoadSiteRules: %v", err)
+	}
+	t.Cleanup(func() {
+		siteRules.mu.Lock()
+		siteRules.rules = map[string]*SiteRule{}
+		siteRules.mu.Unlock()
+	})
+}
+
+func TestParseSiteRule(t *testing.T) {
+	rule, err := ParseSiteRule("www.Example.com.txt", `
+# Example rules
+title: //h1[@class='headline']
+body: //div[@id='story']
+body: article .content
+strip: //div[contains(@class, 'share')]
+strip_id_or_class: newsletter-signup
+next_page_link: //a[@rel='next']
+single_page_link: a.print-view
+prefer_amp: yes
+rewrite_url: /amp/ => /
+autodetect_on_failure: no
+tidy: no
+test_url: https://example.com/story
+`)
+	if err != nil {
+		t.Fatalf("ParseSiteRule: %v", err)
+	}
+	if rule.Domain != "example.com" {
+		t.Errorf("Domain = %q", rule.Domain)
+	}
+	if len(rule.Title) != 1 || rule.Title[0] != `h1[class="headline"]` {
+		t.Errorf("Title = %v", rule.Title)
+	}
+	if len(rule.Body) != 2 || rule.Body[0] != `div[id="story"]` || rule.Body[1] != "article .content" {
+		t.Errorf("Body = %v", rule.Body)
+	}
+	if len(rule.Strip) != 1 || rule.Strip[0] != `div[class*="share"]` {
+		t.Errorf("Strip = %v", rule.Strip)
+	}
+	if len(rule.StripIDOrClass) != 1 || rule.StripIDOrClass[0] != "newsletter-signup" {
+		t.Errorf("StripIDOrClass = %v", rule.StripIDOrClass)
+	}
+	if len(rule.NextPageLink) != 1 || len(rule.SinglePageLink) != 1 {
+		t.Errorf("page links = %v / %v", rule.NextPageLink, rule.SinglePageLink)
+	}
+	if !rule.PreferAMP || rule.AutodetectOnErr {
+		t.Errorf("PreferAMP = %v, AutodetectOnErr = %v", rule.PreferAMP, rule.AutodetectOnErr)
+	}
+	if got := rule.RewriteURL("https://example.com/amp/story"); got != "https://example.com/story" {
+		t.Errorf("RewriteURL = %q", got)
+	}
+}
+
+func TestParseSiteRule_Errors(t *testing.T) {
+	cases := map[string]string{
+		"unsupported xpath": "body: //div[text()='x']",
+		"bad css":           "body: div[",
+		"bad rewrite":       "rewrite_url: /amp/",
+		"bad regexp":        "rewrite_url: ( => x",
+	}
+	for name, text := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseSiteRule("example.com", text); err == nil {
+				t.Errorf("expected error for %q", text)
+			}
+		})
+	}
+}
+
+func TestXPathToCSS(t *testing.T) {
+	cases := []struct {
+		xpath string
+		want  string
+	}{
+		{"//article", "article"},
+		{"//div[@id='main']", `div[id="main"]`},
+		{"//div[@id='main']//p", `div[id="main"] p`},
+		{"/html/body/div", "html > body > div"},
+		{"//div[contains(@class, 'post-body')]", `div[class*="post-body"]`},
+		{"//div[starts-with(@id, 'post-')]", `div[id^="post-"]`},
+		{"//div[contains(concat(' ',normalize-space(@class),' '),' entry ')]", "div.entry"},
+		{"//*[@itemprop='articleBody']", `[itemprop="articleBody"]`},
+		{"//img[@data-src]", "img[data-src]"},
+		{"//div[@class='a' and @id='b']", `div[class="a"][id="b"]`},
+		{"//ul/li[2]", "ul > li:nth-of-type(2)"},
+		{"//h1 | //h2[@class='title']", `h1, h2[class="title"]`},
+		{".//section", "section"},
+	}
+	for _, tc := range cases {
+		got, err := XPathToCSS(tc.xpath)
+		if err != nil {
+			t.Errorf("XPathToCSS(%q): %v", tc.xpath, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("XPathToCSS(%q) = %q, want %q", tc.xpath, got, tc.want)
+		}
+	}
+
+	for _, bad := range []string{"//div/following-sibling::p", "//p[last()]", "//div/text()"} {
+		if _, err := XPathToCSS(bad); err == nil {
+			t.Errorf("XPathToCSS(%q) expected error", bad)
+		}
+	}
+}
+
+func TestSiteRuleFor_MatchesParentDomains(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	createSiteRule(t, app, "example.com", "body: article")
+	createSiteRule(t, app, "news.example.com", "body: main")
+
+	if rule := siteRuleFor("www.example.com"); rule == nil || rule.Domain != "example.com" {
+		t.Errorf("www.example.com matched %+v", rule)
+	}
+	if rule := siteRuleFor("blog.example.com:8080"); rule == nil || rule.Domain != "example.com" {
+		t.Errorf("blog.example.com matched %+v", rule)
+	}
+	if rule := siteRuleFor("news.example.com"); rule == nil || rule.Domain != "news.example.com" {
+		t.Errorf("news.example.com matched %+v", rule)
+	}
+	if rule := siteRuleFor("example.org"); rule != nil {
+		t.Errorf("example.org matched %+v", rule)
+	}
+}
+
+func TestExtractContent_SiteRuleWithStripAndNextPage(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			fmt.Fprintf(w, `<html><body><div id="story"><p>Page two text.</p>%s</div>
+				<a rel="next" href="/story">Back to start</a></body></html>`, articleParagraphs(6, "eviction"))
+			return
+		}
+		fmt.Fprintf(w, `<html><head><title>Site title</title></head><body>
+			<div class="sidebar">%s</div>
+			<h1 class="headline">Rule Headline</h1>
+			<div id="story"><p>Page one text.</p><div class="share-bar">Share this</div>%s</div>
+			<a rel="next" href="/story?page=2">Next</a>
+		</body></html>`, articleParagraphs(12, "sidebar noise"), articleParagraphs(6, "caching"))
+	}))
+	defer srv.Close()
+
+	host := strings.Split(strings.TrimPrefix(srv.URL, "http://"), ":")[0]
+	createSiteRule(t, app, host, `
+title: //h1[@class='headline']
+body: //div[@id='story']
+strip: //div[contains(@class, 'share')]
+next_page_link: //a[@rel='next']
+`)
+
+	extracted, err := ExtractContent(srv.URL+"/story", srv.Client())
+	if err != nil {
+		t.Fatalf("ExtractContent: %v", err)
+	}
+	if extracted.Title != "Rule Headline" {
+		t.Errorf("Title = %q", extracted.Title)
+	}
+	if !strings.Contains(extracted.Content, "Page one text.") || !strings.Contains(extracted.Content, "Page two text.") {
+		t.Errorf("expected both pages in content, got %q", truncate(extracted.Content, 200))
+	}
+	if strings.Contains(extracted.Content, "Share this") || strings.Contains(extracted.Content, "sidebar noise") {
+		t.Error("expected stripped and non-body content to be excluded")
+	}
+	if strings.Count(extracted.Content, "Page one text.") != 1 {
+		t.Error("expected next-page loop to stop at already visited page")
+	}
+	if !strings.Contains(extracted.HTML, "<p>Page two text.</p>") {
+		t.Errorf("expected structured HTML, got %q", truncate(extracted.HTML, 200))
+	}
+	if extracted.Quality < QualityThreshold {
+		t.Errorf("Quality = %.2f, want >= %.2f", extracted.Quality, QualityThreshold)
+	}
+}
+
+func TestExtractContent_SiteRuleRewriteAndAMP(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	var requested []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		switch r.URL.Path {
+		case "/print/story":
+			fmt.Fprint(w, `<html><head><link rel="amphtml" href="/amp/story"></head><body><p>Teaser</p></body></html>`)
+		case "/amp/story":
+			fmt.Fprintf(w, `<html><head><title>AMP Story</title></head><body><article>%s</article></body></html>`, articleParagraphs(8, "amp"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	host := strings.Split(strings.TrimPrefix(srv.URL, "http://"), ":")[0]
+	createSiteRule(t, app, host, `
+body: article
+prefer_amp: yes
+rewrite_url: /news/ => /print/
+`)
+
+	extracted, err := ExtractContent(srv.URL+"/news/story", srv.Client())
+	if err != nil {
+		t.Fatalf("ExtractContent: %v", err)
+	}
+	if strings.Join(requested, ",") != "/print/story,/amp/story" {
+		t.Errorf("requested = %v", requested)
+	}
+	if !strings.Contains(extracted.Content, "Paragraph 1 about amp") {
+		t.Errorf("expected AMP content, got %q", truncate(extracted.Content, 200))
+	}
+}
+
+func TestExtractContent_SiteRuleFallsBackToReadability(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<html><head><title>Readable</title></head><body>
+			<div class="byline-box"><p>Short blurb</p></div>
+			<article>%s</article></body></html>`, articleParagraphs(10, "readability"))
+	}))
+	defer srv.Close()
+
+	host := strings.Split(strings.TrimPrefix(srv.URL, "http://"), ":")[0]
+	createSiteRule(t, app, host, "body: //div[@class='byline-box']")
+
+	extracted, err := ExtractContent(srv.URL+"/post", srv.Client())
+	if err != nil {
+		t.Fatalf("ExtractContent: %v", err)
+	}
+	if !strings.Contains(extracted.Content, "Paragraph 10 about readability") {
+		t.Errorf("expected readability result after low-quality rule, got %q", truncate(extracted.Content, 200))
+	}
+}
+
+func TestExtractionQuality(t *testing.T) {
+	good := ExtractContentFromHTML(`<html><body><article>`+articleParagraphs(10, "quality")+`</article></body></html>`, "https://example.com/a")
+	if good.Quality < QualityThreshold {
+		t.Errorf("full article quality = %.2f, want >= %.2f", good.Quality, QualityThreshold)
+	}
+
+	placeholder := ExtractedContent{Content: "You need to enable JavaScript to run this app.", HTML: "<p>You need to enable JavaScript to run this app.</p>"}
+	if q := ExtractionQuality(placeholder); q >= QualityThreshold {
+		t.Errorf("placeholder quality = %.2f, want < %.2f", q, QualityThreshold)
+	}
+
+	links := ExtractedContent{Content: strings.Repeat("link text ", 200), HTML: "<a href='#'>" + strings.Repeat("link text ", 200) + "</a>"}
+	if q := ExtractionQuality(links); q >= ExtractionQuality(good) {
+		t.Errorf("link list quality %.2f should be below article quality %.2f", q, ExtractionQuality(good))
+	}
+
+	if q := ExtractionQuality(ExtractedContent{}); q != 0 {
+		t.Errorf("empty quality = %.2f, want 0", q)
+	}
+}
+
+func TestExtractWithBrowserFallback_EscalatesLowQualityPages(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><title>App</title></head><body><div id="root"></div>
+			<noscript>You need to enable JavaScript to run this app.</noscript><script src="/app.js"></script></body></html>`)
+	}))
+	defer srv.Close()
+
+	resource := testutil.CreateResource(t, app, "SPA", srv.URL, "watchlist", "healthy", 0, true)
+
+	oldBrowser := BrowserExtractFunc
+	defer func() { BrowserExtractFunc = oldBrowser }()
+	browserCalls := 0
+	BrowserExtractFunc = func(url string) (ExtractedContent, error) {
+		browserCalls++
+		return ExtractContentFromHTML(`<html><body><article>`+articleParagraphs(10, "rendered")+`</article></body></html>`, url), nil
+	}
+
+	extracted, err := extractWithBrowserFallback(app, resource, srv.URL+"/post", srv.Client())
+	if err != nil {
+		t.Fatalf("extractWithBrowserFallback: %v", err)
+	}
+	if browserCalls != 1 {
+		t.Fatalf("browser calls = %d, want 1", browserCalls)
+	}
+	if !strings.Contains(extracted.Content, "about rendered") {
+		t.Errorf("expected browser content, got %q", truncate(extracted.Content, 200))
+	}
+
+	updated, _ := app.FindRecordById("resources", resource.Id)
+	if !updated.GetBool("use_browser") {
+		t.Error("expected use_browser to be learned after a better browser result")
+	}
+}
+
+func TestExtractWithBrowserFallback_EscalatesLowQualityStaticPages(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><p>A short teaser of the article.</p></body></html>`)
+	}))
+	defer srv.Close()
+
+	resource := testutil.CreateResource(t, app, "Static", srv.URL, "watchlist", "healthy", 0, true)
+
+	oldBrowser := BrowserExtractFunc
+	defer func() { BrowserExtractFunc = oldBrowser }()
+	BrowserExtractFunc = func(url string) (ExtractedContent, error) {
+		return ExtractContentFromHTML(`<html><body><article>`+articleParagraphs(10, "rendered")+`</article></body></html>`, url), nil
+	}
+
+	extracted, err := extractWithBrowserFallback(app, resource, srv.URL+"/post", srv.Client())
+	if err != nil {
+		t.Fatalf("extractWithBrowserFallback: %v", err)
+	}
+	if !strings.Contains(extracted.Content, "about rendered") {
+		t.Errorf("expected browser content for a low-quality page without JS markers, got %q", truncate(extracted.Content, 200))
+	}
+}
+
+func TestExtractWithBrowserFallback_KeepsHTTPResultWhenBrowserIsNotBetter(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><p>A short static note.</p></body></html>`)
+	}))
+	defer srv.Close()
+
+	resource := testutil.CreateResource(t, app, "Static", srv.URL, "watchlist", "healthy", 0, true)
+
+	oldBrowser := BrowserExtractFunc
+	defer func() { BrowserExtractFunc = oldBrowser }()
+	browserCalls := 0
+	BrowserExtractFunc = func(url string) (ExtractedContent, error) {
+		browserCalls++
+		return ExtractContentFromHTML(`<html><body><p>A short static note.</p></body></html>`, url), nil
+	}
+
+	extracted, err := extractWithBrowserFallback(app, resource, srv.URL+"/note", srv.Client())
+	if err != nil {
+		t.Fatalf("extractWithBrowserFallback: %v", err)
+	}
+	if browserCalls != 1 {
+		t.Errorf("browser calls = %d, want 1", browserCalls)
+	}
+	if !strings.Contains(extracted.Content, "short static note") {
+		t.Errorf("Content = %q", extracted.Content)
+	}
+	updated, _ := app.FindRecordById("resources", resource.Id)
+	if updated.GetBool("use_browser") {
+		t.Error("use_browser should not be learned when the browser is not better")
+	}
+}
+
+func TestExtractWithBrowserFallback_BrowserFailureKeepsHTTPResult(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><div id="__next"><p>Loading teaser text for the article.</p></div></body></html>`)
+	}))
+	defer srv.Close()
+
+	resource := testutil.CreateResource(t, app, "Next", srv.URL, "watchlist", "healthy", 0, true)
+
+	oldBrowser := BrowserExtractFunc
+	defer func() { BrowserExtractFunc = oldBrowser }()
+	BrowserExtractFunc = func(url string) (ExtractedContent, error) {
+		return ExtractedContent{}, fmt.Errorf("no browser")
+	}
+
+	extracted, err := extractWithBrowserFallback(app, resource, srv.URL+"/post", srv.Client())
+	if err != nil {
+		t.Fatalf("extractWithBrowserFallback: %v", err)
+	}
+	if !strings.Contains(extracted.Content, "Loading teaser text") {
+		t.Errorf("Content = %q", extracted.Content)
+	}
+	updated, _ := app.FindRecordById("resources", resource.Id)
+	if updated.GetBool("use_browser") {
+		t.Error("use_browser should not be learned when the browser fails")
+	}
+}
+
+func TestImportSiteRules(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	t.Cleanup(func() {
+		siteRules.mu.Lock()
+		siteRules.rules = map[string]*SiteRule{}
+		siteRules.mu.Unlock()
+	})
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("example.com.txt", "body: //article")
+	write(".blog.example.org.txt", "body: main")
+	write("broken.net.txt", "body: //div[text()='x']")
+	write("README.md", "not a rule")
+
+	count, err := ImportSiteRules(app, dir)
+	if err != nil {
+		t.Fatalf("ImportSiteRules: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("imported = %d, want 2", count)
+	}
+	if rule := siteRuleFor("www.example.com"); rule == nil || rule.Body[0] != "article" {
+		t.Errorf("expected imported example.com rule, got %+v", rule)
+	}
+	if siteRuleFor("blog.example.org") == nil {
+		t.Error("expected rule for blog.example.org")
+	}
+
+	// Manually maintained rules are not overwritten on re-import.
+	manual, err := app.FindFirstRecordByFilter("site_rules", "domain = 'example.com'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manual.Set("rules", "body: .manual")
+	manual.Set("imported_from", "")
+	if err := app.Save(manual); err != nil {
+		t.Fatal(err)
+	}
+	write(".blog.example.org.txt", "body: .updated")
+
+	if count, err = ImportSiteRules(app, dir); err != nil || count != 1 {
+		t.Fatalf("re-import = %d, %v; want 1", count, err)
+	}
+	if rule := siteRuleFor("example.com"); rule.Body[0] != ".manual" {
+		t.Errorf("manual rule overwritten: %v", rule.Body)
+	}
+	if rule := siteRuleFor("blog.example.org"); rule.Body[0] != ".updated" {
+		t.Errorf("imported rule not updated: %v", rule.Body)
+	}
+}
@@ -0,0 +1,191 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// maxNewsletterSize limits the size of a single incoming message.
+const maxNewsletterSize = 10 << 20
+
+// smtpCommandTimeout bounds how long a client may stay idle between commands.
+const smtpCommandTimeout = 2 * time.Minute
+
+// SMTPServer is a minimal receive-only SMTP server that turns incoming
+// newsletters into entries. It does no authentication or relaying, so it
+// should only listen on a trusted interface (localhost, Tailscale, ...).
+type SMTPServer struct {
+	app      core.App
+	hostname string
+
+	mu       sync.Mutex
+	listener net.Listener
+	closed   bool
+}
+
+// NewSMTPServer creates an SMTP server that ingests mail into app.
+func NewSMTPServer(app core.App) *SMTPServer {
+	return &SMTPServer{app: app, hostname: "knowledgehub"}
+}
+
+// ListenAndServe listens on addr and serves connections until Close is called.
+func (s *SMTPServer) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve accepts connections on ln until Close is called.
+func (s *SMTPServer) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	s.listener = ln
+	s.mu.Unlock()
+
+	log.Printf("SMTP: listening for newsletters on %s", ln.Addr())
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		go s.handleConn(conn)
+	}
+}
+
+// Close stops accepting new connections.
+func (s *SMTPServer) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Close()
+}
+
+func (s *SMTPServer) handleConn(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+
+	reply := func(code int, msg string) error {
+		return tp.PrintfLine("%d %s", code, msg)
+	}
+
+	if reply(220, s.hostname+" ESMTP ready") != nil {
+		return
+	}
+
+	var sender string
+	var recipients int
+	for {
+		conn.SetReadDeadline(time.Now().Add(smtpCommandTimeout))
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		verb = strings.ToUpper(verb)
+
+		switch verb {
+		case "HELO":
+			reply(250, s.hostname)
+		case "EHLO":
+			tp.PrintfLine("250-%s", s.hostname)
+			tp.PrintfLine("250-8BITMIME")
+			tp.PrintfLine("250 SIZE %d", maxNewsletterSize)
+		case "MAIL":
+			addr, ok := smtpPathArg(arg, "FROM:")
+			if !ok {
+				reply(501, "Syntax: MAIL FROM:<address>")
+				continue
+			}
+			sender = strings.ToLower(addr)
+			recipients = 0
+			reply(250, "OK")
+		case "RCPT":
+			if sender == "" {
+				reply(503, "Need MAIL command first")
+				continue
+			}
+			if _, ok := smtpPathArg(arg, "TO:"); !ok {
+				reply(501, "Syntax: RCPT TO:<address>")
+				continue
+			}
+			recipients++
+			reply(250, "OK")
+		case "DATA":
+			if sender == "" || recipients == 0 {
+				reply(503, "Need MAIL and RCPT commands first")
+				continue
+			}
+			reply(354, "End data with <CR><LF>.<CR><LF>")
+			conn.SetReadDeadline(time.Now().Add(smtpCommandTimeout))
+			raw, err := io.ReadAll(io.LimitReader(tp.DotReader(), maxNewsletterSize+1))
+			if err != nil {
+				return
+			}
+			if len(raw) > maxNewsletterSize {
+				// Drain the remainder so the connection stays in sync.
+				io.Copy(io.Discard, tp.DotReader())
+				reply(552, "Message exceeds maximum size")
+			} else {
+				s.deliver(sender, raw, reply)
+			}
+			sender, recipients = "", 0
+		case "RSET":
+			sender, recipients = "", 0
+			reply(250, "OK")
+		case "NOOP":
+			reply(250, "OK")
+		case "QUIT":
+			reply(221, "Bye")
+			return
+		default:
+			reply(502, "Command not implemented")
+		}
+	}
+}
+
+// deliver ingests a received message and writes the matching SMTP reply.
+func (s *SMTPServer) deliver(sender string, raw []byte, reply func(int, string) error) {
+	created, err := IngestNewsletter(s.app, raw)
+	switch {
+	case errors.Is(err, ErrUnknownSender):
+		log.Printf("SMTP: rejected newsletter from unknown sender %s", sender)
+		reply(550, "No newsletter resource for this sender")
+	case err != nil:
+		log.Printf("SMTP: failed to ingest newsletter from %s: %v", sender, err)
+		reply(554, "Message could not be processed")
+	default:
+		log.Printf("SMTP: ingested newsletter from %s (%d entries)", sender, created)
+		reply(250, fmt.Sprintf("OK, %d entries created", created))
+	}
+}
+
+// smtpPathArg extracts the address from a "FROM:<addr> PARAMS" style argument.
+func smtpPathArg(arg, prefix string) (string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", false
+	}
+	path := strings.TrimSpace(arg[len(prefix):])
+	if path, _, _ = strings.Cut(path, " "); !strings.HasPrefix(path, "<") || !strings.HasSuffix(path, ">") {
+		return "", false
+	}
+	return strings.Trim(path, "<>"), true
+}
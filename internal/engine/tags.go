@@ -0,0 +1,189 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// TagCount is the number of entries carrying a tag.
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// TagFrequencies counts the tags of entries discovered since the given time
+// (all entries when since is zero), most frequent first.
+func TagFrequencies(app core.App, since time.Time) ([]TagCount, error) {
+	filter := `tags ~ '"'`
+	params := map[string]any{}
+	if !since.IsZero() {
+		filter += " && discovered_at >= {:since}"
+		params["since"] = since.UTC().Format("2006-01-02 15:04:05.000Z")
+	}
+	entries, err := app.FindRecordsByFilter("entries", filter, "", 0, 0, params)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	for _, entry := range entries {
+		for _, tag := range ai.EntryTags(entry) {
+			counts[tag]++
+		}
+	}
+	result := make([]TagCount, 0, len(counts))
+	for name, count := range counts {
+		result = append(result, TagCount{Name: name, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// MergeTags folds one tag into another: the source name and its synonyms
+// become synonyms of the target (created when missing), every entry tagged
+// with the source is retagged, and the source tag is deleted. It returns the
+// number of entries updated.
+func MergeTags(app core.App, from, into string) (int, error) {
+	from, into = ai.NormalizeTag(from), ai.NormalizeTag(into)
+	if from == "" || into == "" {
+		return 0, errors.New("both tags are required")
+	}
+	if from == into {
+		return 0, errors.New("cannot merge a tag into itself")
+	}
+	collection, err := app.FindCollectionByNameOrId("tags")
+	if err != nil {
+		return 0, err
+	}
+
+	source, _ := app.FindFirstRecordByFilter("tags", "name = {:name}", map[string]any{"name": from})
+	target, err := app.FindFirstRecordByFilter("tags", "name = {:name}", map[string]any{"name": into})
+	if err != nil {
+		target = core.NewRecord(collection)
+		target.Set("name", into)
+	}
+
+	synonyms := ai.TagSynonyms(target)
+	synonyms = appendTag(synonyms, from)
+	if source != nil {
+		for _, synonym := range ai.TagSynonyms(source) {
+			synonyms = appendTag(synonyms, synonym)
+		}
+	}
+	synonyms = slices.DeleteFunc(synonyms, func(s string) bool { return ai.NormalizeTag(s) == into })
+	target.Set("synonyms", synonyms)
+
+	updated := 0
+	err = app.RunInTransaction(func(txApp core.App) error {
+		if err := txApp.Save(target); err != nil {
+			return fmt.Errorf("saving tag %q: %w", into, err)
+		}
+		entries, err := txApp.FindRecordsByFilter("entries", "tags ~ {:tag}", "", 0, 0, map[string]any{"tag": `"` + from + `"`})
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			tags := ai.EntryTags(entry)
+			if !slices.Contains(tags, from) {
+				continue
+			}
+			var retagged []string
+			for _, tag := range tags {
+				if tag == from {
+					tag = into
+				}
+				if !slices.Contains(retagged, tag) {
+					retagged = append(retagged, tag)
+				}
+			}
+			entry.Set("tags", retagged)
+			if err := txApp.Save(entry); err != nil {
+				return fmt.Errorf("retagging entry %s: %w", entry.Id, err)
+			}
+			updated++
+		}
+		if source != nil {
+			return txApp.Delete(source)
+		}
+		return nil
+	})
+	return updated, err
+}
+
+// tagBackfillRunning guards against concurrent backfills.
+var tagBackfillRunning atomic.Bool
+
+// ErrTagBackfillRunning is returned when a backfill is already in progress.
+var ErrTagBackfillRunning = errors.New("tag backfill already running")
+
+// UntaggedEntries returns processed entries that have no tags yet, oldest
+// first.
+func UntaggedEntries(app core.App) ([]*core.Record, error) {
+	return app.FindRecordsByFilter(
+		"entries",
+		"processing_status = 'done' && (tags = null || tags = '' || tags = '[]')",
+		"created",
+		0, 0,
+	)
+}
+
+// StartTagBackfill tags every processed entry without tags in the
+// background and returns the number of entries queued.
+func StartTagBackfill(app core.App) (int, error) {
+	if !tagBackfillRunning.CompareAndSwap(false, true) {
+		return 0, ErrTagBackfillRunning
+	}
+	entries, err := UntaggedEntries(app)
+	if err != nil {
+		tagBackfillRunning.Store(false)
+		return 0, err
+	}
+	go func() {
+		defer tagBackfillRunning.Store(false)
+		defer func() {
+			if r := recover(); r != nil {
+				PanicCount.Add(1)
+				log.Printf("PANIC in tag backfill: %v\n%s", r, debug.Stack())
+			}
+		}()
+		tagged := BackfillTags(app, entries)
+		log.Printf("Tag backfill finished: tagged %d of %d entries", tagged, len(entries))
+	}()
+	return len(entries), nil
+}
+
+// BackfillTags asks the AI for tags for each entry in turn and returns how
+// many were tagged. Entries are reloaded first so that changes made since
+// they were queued (stars, read state) are not overwritten. Failures are
+// logged and skipped.
+func BackfillTags(app core.App, entries []*core.Record) int {
+	tagged := 0
+	for _, queued := range entries {
+		entry, err := app.FindRecordById("entries", queued.Id)
+		if err != nil || len(ai.EntryTags(entry)) > 0 {
+			continue
+		}
+		if err := ai.TagEntry(app, entry); err != nil {
+			log.Printf("Tagging entry %s failed: %v", entry.Id, err)
+			continue
+		}
+		if len(ai.EntryTags(entry)) > 0 {
+			tagged++
+		}
+	}
+	return tagged
+}
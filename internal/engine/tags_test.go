@@ -0,0 +1,231 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func createTaggedEntry(t *testing.T, app core.App, resourceID, slug string, discovered time.Time, tags ...string) *core.Record {
+	t.Helper()
+	entry := testutil.CreateEntry(t, app, resourceID, slug, "https://example.com/"+slug, slug)
+	entry.Set("discovered_at", discovered)
+	if len(tags) > 0 {
+		entry.Set("tags", tags)
+	}
+	if err := app.Save(entry); err != nil {
+		t.Fatal(err)
+	}
+	return entry
+}
+
+func TestTagFrequencies(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	now := time.Now().UTC()
+	createTaggedEntry(t, app, res.Id, "a", now, "go", "databases")
+	createTaggedEntry(t, app, res.Id, "b", now.Add(-time.Hour), "go")
+	createTaggedEntry(t, app, res.Id, "c", now.AddDate(0, 0, -10), "security", "go")
+	createTaggedEntry(t, app, res.Id, "d", now)
+
+	all, err := TagFrequencies(app, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TagCount{{"go", 3}, {"databases", 1}, {"security", 1}}
+	if !reflect.DeepEqual(all, want) {
+		t.Errorf("all = %v, want %v", all, want)
+	}
+
+	recent, err := TagFrequencies(app, now.AddDate(0, 0, -7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []TagCount{{"go", 2}, {"databases", 1}}
+	if !reflect.DeepEqual(recent, want) {
+		t.Errorf("recent = %v, want %v", recent, want)
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	testutil.CreateTag(t, app, "golang", "go-lang")
+	testutil.CreateTag(t, app, "go")
+	both := createTaggedEntry(t, app, res.Id, "both", time.Now(), "go", "golang", "testing")
+	only := createTaggedEntry(t, app, res.Id, "only", time.Now(), "golang")
+	other := createTaggedEntry(t, app, res.Id, "other", time.Now(), "golang-jobs")
+
+	updated, err := MergeTags(app, "Golang", "go")
+	if err != nil {
+		t.Fatalf("MergeTags: %v", err)
+	}
+	if updated != 2 {
+		t.Errorf("expected 2 entries updated, got %d", updated)
+	}
+
+	check := func(entry *core.Record, want []string) {
+		t.Helper()
+		saved, _ := app.FindRecordById("entries", entry.Id)
+		if got := ai.EntryTags(saved); !reflect.DeepEqual(got, want) {
+			t.Errorf("entry %s tags = %v, want %v", entry.GetString("title"), got, want)
+		}
+	}
+	check(both, []string{"go", "testing"})
+	check(only, []string{"go"})
+	check(other, []string{"golang-jobs"})
+
+	if _, err := app.FindFirstRecordByFilter("tags", "name = 'golang'"); err == nil {
+		t.Error("expected merged tag deleted")
+	}
+	target, err := app.FindFirstRecordByFilter("tags", "name = 'go'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ai.TagSynonyms(target); !reflect.DeepEqual(got, []string{"golang", "go-lang"}) {
+		t.Errorf("synonyms = %v", got)
+	}
+	if got := ai.LoadTagTaxonomy(app).Resolve("GoLang"); got != "go" {
+		t.Errorf("expected golang to resolve to go after merge, got %q", got)
+	}
+}
+
+func TestMergeTags_CreatesTarget(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	if _, err := MergeTags(app, "k8s", "kubernetes"); err != nil {
+		t.Fatalf("MergeTags: %v", err)
+	}
+	target, err := app.FindFirstRecordByFilter("tags", "name = 'kubernetes'")
+	if err != nil {
+		t.Fatalf("expected target tag created: %v", err)
+	}
+	if got := ai.TagSynonyms(target); !reflect.DeepEqual(got, []string{"k8s"}) {
+		t.Errorf("synonyms = %v", got)
+	}
+}
+
+func TestMergeTags_Errors(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	if _, err := MergeTags(app, "", "go"); err == nil {
+		t.Error("expected error for a missing tag")
+	}
+	if _, err := MergeTags(app, "Go", "go"); err == nil {
+		t.Error("expected error merging a tag into itself")
+	}
+}
+
+func TestUntaggedEntries(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	unset := testutil.CreateEntryWithStars(t, app, res.Id, "Unset", "https://example.com/unset", 3, 0)
+	cleared := testutil.CreateEntryWithStars(t, app, res.Id, "Cleared", "https://example.com/cleared", 3, 0)
+	cleared.Set("tags", []string{})
+	if err := app.Save(cleared); err != nil {
+		t.Fatal(err)
+	}
+	tagged := testutil.CreateEntryWithStars(t, app, res.Id, "Tagged", "https://example.com/tagged", 3, 0)
+	tagged.Set("tags", []string{"go"})
+	if err := app.Save(tagged); err != nil {
+		t.Fatal(err)
+	}
+
+	queued, err := UntaggedEntries(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, e := range queued {
+		ids = append(ids, e.Id)
+	}
+	if !reflect.DeepEqual(ids, []string{unset.Id, cleared.Id}) {
+		t.Errorf("expected the unset and cleared entries, got %v", ids)
+	}
+}
+
+func TestBackfillTags(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	untagged := testutil.CreateEntryWithStars(t, app, res.Id, "Untagged", "https://example.com/untagged", 3, 0)
+	testutil.CreateEntryWithStars(t, app, res.Id, "Failing", "https://example.com/failing", 3, 0)
+	tagged := createTaggedEntry(t, app, res.Id, "tagged", time.Now(), "existing")
+	tagged.Set("processing_status", "done")
+	app.Save(tagged)
+	pending := testutil.CreateEntry(t, app, res.Id, "Pending", "https://example.com/pending", "pending")
+	pending.Set("processing_status", "pending")
+	app.Save(pending)
+
+	restore := ai.SetCompleteFunc(func(_, _ string, msgs []ai.Message) (string, error) {
+		if strings.Contains(msgs[len(msgs)-1].Content, "Failing") {
+			return "not json", nil
+		}
+		return `{"tags":["observability"]}`, nil
+	})
+	defer restore()
+
+	queued, err := UntaggedEntries(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != 2 {
+		t.Fatalf("expected 2 untagged processed entries, got %d", len(queued))
+	}
+
+	// The user rates the entry after it was queued; the backfill keeps it.
+	untagged.Set("user_stars", 5)
+	app.Save(untagged)
+
+	if n := BackfillTags(app, queued); n != 1 {
+		t.Errorf("expected 1 entry tagged, got %d", n)
+	}
+	saved, _ := app.FindRecordById("entries", untagged.Id)
+	if got := ai.EntryTags(saved); !reflect.DeepEqual(got, []string{"observability"}) {
+		t.Errorf("tags = %v", got)
+	}
+	if saved.GetInt("user_stars") != 5 {
+		t.Error("expected rating made after queueing to be kept")
+	}
+}
+
+func TestStartTagBackfill(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	testutil.CreateEntryWithStars(t, app, res.Id, "Untagged", "https://example.com/untagged", 3, 0)
+
+	block := make(chan struct{})
+	restore := ai.SetCompleteFunc(func(_, _ string, _ []ai.Message) (string, error) {
+		<-block
+		return `{"tags":["misc"]}`, nil
+	})
+	defer restore()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+
+	queued, err := StartTagBackfill(app)
+	if err != nil || queued != 1 {
+		t.Fatalf("StartTagBackfill = %d, %v", queued, err)
+	}
+	if _, err := StartTagBackfill(app); err != ErrTagBackfillRunning {
+		t.Errorf("expected ErrTagBackfillRunning, got %v", err)
+	}
+	close(block)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for tagBackfillRunning.Load() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if tagBackfillRunning.Load() {
+		t.Fatal("expected backfill to finish")
+	}
+}
@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+)
+
+func TestProcessEntry_DetectsLanguageAndTranslates(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	user := testutil.CreateSuperuser(t, app, "reader@example.com")
+	testutil.CreateUserSettings(t, app, user.Id, "en", false)
+
+	resource := testutil.CreateResource(t, app, "Heise", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, resource.Id, "Neue Version", "https://example.com/neu", "guid-neu")
+	entry.Set("raw_content", "<p>Die neue Version des Compilers ist schneller und es wird auch einfacher, die Ausgabe zu lesen.</p>")
+	// The page language readability extracted is kept next to the detected one.
+	entry.Set("language", "de-DE")
+	entry.Set("processing_status", "pending")
+	if err := app.Save(entry); err != nil {
+		t.Fatalf("save entry: %v", err)
+	}
+
+	restore := ai.SetCompleteFunc(func(_, _ string, msgs []ai.Message) (string, error) {
+		if strings.Contains(msgs[len(msgs)-1].Content, "SUMMARY_JSON") {
+			return `{"summary": "The new compiler is faster."}`, nil
+		}
+		return `{"summary": "Der neue Compiler ist schneller.", "stars": 3}`, nil
+	})
+	defer restore()
+
+	processEntry(app, entry)
+
+	updated, _ := app.FindRecordById("entries", entry.Id)
+	if updated.GetString("processing_status") != "done" || updated.GetString("detected_language") != "de" {
+		t.Fatalf("expected a done German entry, got %q in %q", updated.GetString("processing_status"), updated.GetString("detected_language"))
+	}
+	if updated.GetString("language") != "de-DE" {
+		t.Errorf("expected the extracted language to be kept, got %q", updated.GetString("language"))
+	}
+	if tr, ok := ai.TranslationFor(updated, "en"); !ok || tr.Summary != "The new compiler is faster." {
+		t.Errorf("expected an English translation, got %+v %v", tr, ok)
+	}
+}
+
+func TestProcessEntry_TranslationFailureKeepsEntry(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	user := testutil.CreateSuperuser(t, app, "reader@example.com")
+	testutil.CreateUserSettings(t, app, user.Id, "nl", false)
+
+	resource := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, resource.Id, "Release", "https://example.com/release", "guid-release")
+	entry.Set("raw_content", "<p>The new release of the compiler is faster and it is easier to read the output.</p>")
+	entry.Set("processing_status", "pending")
+	if err := app.Save(entry); err != nil {
+		t.Fatalf("save entry: %v", err)
+	}
+
+	restore := ai.SetCompleteFunc(func(_, _ string, msgs []ai.Message) (string, error) {
+		if strings.Contains(msgs[len(msgs)-1].Content, "SUMMARY_JSON") {
+			return "not json", nil
+		}
+		return `{"summary": "The compiler is faster.", "stars": 3}`, nil
+	})
+	defer restore()
+
+	processEntry(app, entry)
+
+	updated, _ := app.FindRecordById("entries", entry.Id)
+	if updated.GetString("processing_status") != "done" || updated.GetString("summary") != "The compiler is faster." {
+		t.Errorf("expected the entry to stay processed, got %q", updated.GetString("processing_status"))
+	}
+	if _, ok := ai.TranslationFor(updated, "nl"); ok {
+		t.Error("expected no translation to be stored")
+	}
+}
@@ -0,0 +1,264 @@
+package engine
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/jgordijn/knowledgehub/internal/pdf"
+)
+
+// MaxUploadSize caps files uploaded through Quick Add.
+const MaxUploadSize = 50 << 20
+
+// maxEPUBContent caps the decompressed size of the chapters read from an
+// EPUB, guarding against zip bombs.
+const maxEPUBContent = 64 << 20
+
+// ErrUnsupportedUpload is returned for files whose format cannot be extracted.
+var ErrUnsupportedUpload = errors.New("unsupported file type: upload Markdown, HTML, EPUB or PDF")
+
+// Upload formats, as stored in an entry's source_format.
+const (
+	UploadMarkdown = "markdown"
+	UploadHTML     = "html"
+	UploadEPUB     = "epub"
+	UploadPDF      = "pdf"
+)
+
+// DetectUploadFormat determines the format of an uploaded file from its
+// extension, falling back to its content (PDF and zip signatures, HTML tags)
+// for files without a known extension.
+func DetectUploadFormat(filename string, data []byte) string {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".md", ".markdown", ".mdown", ".txt":
+		return UploadMarkdown
+	case ".html", ".htm", ".xhtml":
+		return UploadHTML
+	case ".epub":
+		return UploadEPUB
+	case ".pdf":
+		return UploadPDF
+	}
+
+	switch {
+	case pdf.IsPDF(data):
+		return UploadPDF
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")) && bytes.Contains(data[:min(len(data), 200)], []byte("application/epub+zip")):
+		return UploadEPUB
+	}
+	head := strings.ToLower(string(data[:min(len(data), 1024)]))
+	if strings.Contains(head, "<html") || strings.Contains(head, "<!doctype html") || strings.Contains(head, "<body") {
+		return UploadHTML
+	}
+	return ""
+}
+
+// ExtractUpload extracts the text and metadata of an uploaded document. The
+// title falls back to the file name (without extension) when the document
+// has none.
+func ExtractUpload(filename string, data []byte) (ExtractedContent, string, error) {
+	format := DetectUploadFormat(filename, data)
+
+	var extracted ExtractedContent
+	var err error
+	switch format {
+	case UploadMarkdown:
+		extracted = extractMarkdown(string(data))
+	case UploadHTML:
+		extracted = ExtractContentFromHTML(string(data), "")
+	case UploadEPUB:
+		extracted, err = ExtractEPUBContent(data)
+	case UploadPDF:
+		extracted, err = ExtractPDFContent(data, filename)
+	default:
+		return ExtractedContent{}, "", ErrUnsupportedUpload
+	}
+	if err != nil {
+		return ExtractedContent{}, format, err
+	}
+
+	if strings.TrimSpace(extracted.Content) == "" {
+		return ExtractedContent{}, format, fmt.Errorf("no text found in %s", filename)
+	}
+	if extracted.Title == "" || extracted.Title == filename {
+		extracted.Title = strings.TrimSuffix(path.Base(filename), path.Ext(filename))
+	}
+	return extracted, format, nil
+}
+
+// extractMarkdown uses the document as both Markdown and plain text; the
+// title is its first level-one heading.
+func extractMarkdown(markdown string) ExtractedContent {
+	markdown = strings.TrimSpace(strings.TrimPrefix(markdown, "\uFEFF"))
+	extracted := ExtractedContent{
+		Content:   markdown,
+		Markdown:  markdown,
+		WordCount: len(strings.Fields(markdown)),
+	}
+	for _, line := range strings.Split(markdown, "\n") {
+		if heading, ok := strings.CutPrefix(strings.TrimSpace(line), "# "); ok {
+			extracted.Title = strings.TrimSpace(heading)
+			break
+		}
+	}
+	extracted.Quality = ExtractionQuality(extracted)
+	return extracted
+}
+
+// epubPackage is the part of an EPUB package document (OPF) we need.
+type epubPackage struct {
+	Metadata struct {
+		Title       []string `xml:"title"`
+		Creator     []string `xml:"creator"`
+		Language    []string `xml:"language"`
+		Description []string `xml:"description"`
+		Publisher   []string `xml:"publisher"`
+	} `xml:"metadata"`
+	Manifest []struct {
+		ID        string `xml:"id,attr"`
+		Href      string `xml:"href,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+// ExtractEPUBContent extracts an EPUB: metadata from the package document
+// and the chapters in reading (spine) order, each sanitized and joined into
+// one article.
+func ExtractEPUBContent(data []byte) (ExtractedContent, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return ExtractedContent{}, fmt.Errorf("reading EPUB: %w", err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	budget := int64(maxEPUBContent)
+	read := func(name string) ([]byte, error) {
+		f, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("EPUB is missing %s", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		b, err := io.ReadAll(io.LimitReader(rc, budget+1))
+		if err != nil {
+			return nil, err
+		}
+		budget -= int64(len(b))
+		if budget < 0 {
+			return nil, errors.New("EPUB content is too large")
+		}
+		return b, nil
+	}
+
+	container, err := read("META-INF/container.xml")
+	if err != nil {
+		return ExtractedContent{}, err
+	}
+	var c struct {
+		Rootfiles []struct {
+			FullPath string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	if err := xml.Unmarshal(container, &c); err != nil || len(c.Rootfiles) == 0 {
+		return ExtractedContent{}, errors.New("EPUB container has no package document")
+	}
+	opfPath := c.Rootfiles[0].FullPath
+	opfData, err := read(opfPath)
+	if err != nil {
+		return ExtractedContent{}, err
+	}
+	var pkg epubPackage
+	if err := xml.Unmarshal(opfData, &pkg); err != nil {
+		return ExtractedContent{}, fmt.Errorf("parsing EPUB package: %w", err)
+	}
+
+	hrefs := map[string]string{}
+	for _, item := range pkg.Manifest {
+		if item.MediaType == "application/xhtml+xml" || item.MediaType == "text/html" {
+			hrefs[item.ID] = item.Href
+		}
+	}
+
+	var body strings.Builder
+	for _, ref := range pkg.Spine {
+		href, ok := hrefs[ref.IDRef]
+		if !ok {
+			continue
+		}
+		name, err := url.PathUnescape(path.Join(path.Dir(opfPath), href))
+		if err != nil {
+			continue
+		}
+		chapter, err := read(name)
+		if err != nil {
+			if budget < 0 {
+				return ExtractedContent{}, err
+			}
+			continue
+		}
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(chapter))
+		if err != nil {
+			continue
+		}
+		if inner, err := doc.Find("body").Html(); err == nil && strings.TrimSpace(inner) != "" {
+			body.WriteString("<section>" + inner + "</section>\n")
+		}
+	}
+
+	extracted := ExtractedContent{
+		Title:    firstNonEmpty(pkg.Metadata.Title),
+		Byline:   strings.Join(trimAll(pkg.Metadata.Creator), ", "),
+		SiteName: firstNonEmpty(pkg.Metadata.Publisher),
+		Language: firstNonEmpty(pkg.Metadata.Language),
+	}
+	if desc := firstNonEmpty(pkg.Metadata.Description); desc != "" {
+		if doc, err := goquery.NewDocumentFromReader(strings.NewReader(desc)); err == nil {
+			extracted.Excerpt = strings.TrimSpace(doc.Text())
+		}
+	}
+	extracted.HTML = SanitizeArticleHTML(body.String(), nil)
+	extracted.Markdown = ai.HTMLToMarkdown(extracted.HTML)
+	if doc, err := goquery.NewDocumentFromReader(strings.NewReader(extracted.HTML)); err == nil {
+		extracted.Content = strings.TrimSpace(doc.Text())
+	}
+	extracted.WordCount = len(strings.Fields(extracted.Content))
+	extracted.Quality = ExtractionQuality(extracted)
+	return extracted, nil
+}
+
+func firstNonEmpty(values []string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func trimAll(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
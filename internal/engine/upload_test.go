@@ -0,0 +1,164 @@
+package engine
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+)
+
+// buildEPUB writes a minimal EPUB with the given chapters (XHTML bodies).
+func buildEPUB(t *testing.T, title, author string, chapters ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	write := func(name, content string) {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	write("mimetype", "application/epub+zip")
+	write("META-INF/container.xml", `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`)
+
+	var manifest, spine strings.Builder
+	for i, ch := range chapters {
+		id := string(rune('a' + i))
+		manifest.WriteString(`<item id="` + id + `" href="text/ch%20` + id + `.xhtml" media-type="application/xhtml+xml"/>`)
+		// Spine order is the reverse of manifest order to check reading order.
+		spine.WriteString(`<itemref idref="` + string(rune('a'+len(chapters)-1-i)) + `"/>`)
+		write("OEBPS/text/ch "+id+".xhtml", `<?xml version="1.0"?><html xmlns="http://www.w3.org/1999/xhtml"><head><title>x</title><script>alert(1)</script></head><body>`+ch+`</body></html>`)
+	}
+	write("OEBPS/content.opf", `<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:title>`+title+`</dc:title><dc:creator>`+author+`</dc:creator><dc:language>en</dc:language>
+    <dc:description>&lt;p&gt;A short book.&lt;/p&gt;</dc:description>
+  </metadata>
+  <manifest>`+manifest.String()+`<item id="css" href="style.css" media-type="text/css"/></manifest>
+  <spine>`+spine.String()+`</spine>
+</package>`)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetectUploadFormat(t *testing.T) {
+	epub := buildEPUB(t, "Book", "Author", "<p>Text</p>")
+	cases := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"notes.md", []byte("# Notes"), UploadMarkdown},
+		{"notes.TXT", []byte("plain"), UploadMarkdown},
+		{"page.htm", []byte("<p>x</p>"), UploadHTML},
+		{"book.epub", epub, UploadEPUB},
+		{"paper.pdf", []byte("%PDF-1.4"), UploadPDF},
+		{"download", []byte("%PDF-1.4"), UploadPDF},
+		{"download", epub, UploadEPUB},
+		{"saved", []byte("<!DOCTYPE html><html><body>x</body></html>"), UploadHTML},
+		{"image.png", []byte("\x89PNG\r\n"), ""},
+	}
+	for _, tc := range cases {
+		if got := DetectUploadFormat(tc.name, tc.data); got != tc.want {
+			t.Errorf("DetectUploadFormat(%q) = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestExtractUpload_Markdown(t *testing.T) {
+	md := "\uFEFFSome front matter\n\n# Design Notes\n\nWe chose **SQLite** for simplicity.\n"
+	extracted, format, err := ExtractUpload("design.md", []byte(md))
+	if err != nil {
+		t.Fatalf("ExtractUpload: %v", err)
+	}
+	if format != UploadMarkdown || extracted.Title != "Design Notes" {
+		t.Errorf("unexpected format %q / title %q", format, extracted.Title)
+	}
+	if !strings.Contains(extracted.Markdown, "**SQLite**") || strings.HasPrefix(extracted.Content, "\uFEFF") {
+		t.Errorf("unexpected markdown: %q", extracted.Markdown)
+	}
+
+	extracted, _, err = ExtractUpload("meeting-notes.md", []byte("just some notes without a heading"))
+	if err != nil {
+		t.Fatalf("ExtractUpload: %v", err)
+	}
+	if extracted.Title != "meeting-notes" {
+		t.Errorf("expected file name as title, got %q", extracted.Title)
+	}
+}
+
+func TestExtractUpload_HTML(t *testing.T) {
+	page := `<html><head><title>Saved Page</title></head><body><article>
+<p>` + strings.Repeat("This saved page discusses observability in depth. ", 20) + `</p>
+<script>alert(1)</script></article></body></html>`
+	extracted, format, err := ExtractUpload("saved.html", []byte(page))
+	if err != nil {
+		t.Fatalf("ExtractUpload: %v", err)
+	}
+	if format != UploadHTML || extracted.Title != "Saved Page" {
+		t.Errorf("unexpected format %q / title %q", format, extracted.Title)
+	}
+	if strings.Contains(extracted.HTML, "<script") || !strings.Contains(extracted.Content, "observability") {
+		t.Errorf("unexpected extraction: %q", extracted.HTML)
+	}
+}
+
+func TestExtractUpload_EPUB(t *testing.T) {
+	data := buildEPUB(t, "The Go Book", "Jane Doe",
+		"<h1>Chapter One</h1><p>Goroutines are cheap.</p>",
+		"<h1>Chapter Two</h1><p>Channels connect them.</p>")
+	extracted, format, err := ExtractUpload("go.epub", data)
+	if err != nil {
+		t.Fatalf("ExtractUpload: %v", err)
+	}
+	if format != UploadEPUB || extracted.Title != "The Go Book" || extracted.Byline != "Jane Doe" {
+		t.Errorf("unexpected metadata: %q %q %q", format, extracted.Title, extracted.Byline)
+	}
+	if extracted.Language != "en" || extracted.Excerpt != "A short book." {
+		t.Errorf("unexpected language/excerpt: %q %q", extracted.Language, extracted.Excerpt)
+	}
+	two := strings.Index(extracted.Content, "Channels connect them.")
+	one := strings.Index(extracted.Content, "Goroutines are cheap.")
+	if one < 0 || two < 0 || two > one {
+		t.Errorf("expected chapters in spine order, got %q", extracted.Content)
+	}
+	if strings.Contains(extracted.HTML, "script") {
+		t.Errorf("expected sanitized HTML, got %q", extracted.HTML)
+	}
+}
+
+func TestExtractUpload_PDF(t *testing.T) {
+	data := testutil.BuildPDF("", "", "Internal Write-up\n\nThe migration finished early.")
+	extracted, format, err := ExtractUpload("writeup.pdf", data)
+	if err != nil {
+		t.Fatalf("ExtractUpload: %v", err)
+	}
+	if format != UploadPDF || extracted.Title != "Internal Write-up" || len(extracted.Pages) != 1 {
+		t.Errorf("unexpected extraction: %q %q %d pages", format, extracted.Title, len(extracted.Pages))
+	}
+	if extracted.SiteName != "" {
+		t.Errorf("expected no site name for uploads, got %q", extracted.SiteName)
+	}
+}
+
+func TestExtractUpload_Errors(t *testing.T) {
+	if _, _, err := ExtractUpload("photo.png", []byte("\x89PNG")); !errors.Is(err, ErrUnsupportedUpload) {
+		t.Errorf("expected ErrUnsupportedUpload, got %v", err)
+	}
+	if _, _, err := ExtractUpload("empty.md", []byte("   \n")); err == nil {
+		t.Error("expected an error for a document without text")
+	}
+	if _, _, err := ExtractUpload("broken.epub", []byte("PK\x03\x04garbage")); err == nil {
+		t.Error("expected an error for a corrupt EPUB")
+	}
+}
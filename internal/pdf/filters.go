@@ -0,0 +1,195 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"fmt"
+	"io"
+)
+
+// maxDecodedStream caps the decoded size of a single stream.
+const maxDecodedStream = 64 << 20
+
+// decodeStream applies the stream's filters. Decoding is lenient: a corrupt
+// Flate stream yields whatever could be inflated before the error.
+func (d *document) decodeStream(s *stream) ([]byte, error) {
+	data := s.raw
+	filters := d.resolve(s.dict["Filter"])
+	params := d.resolve(s.dict["DecodeParms"])
+
+	var filterList []name
+	var paramList []any
+	switch f := filters.(type) {
+	case name:
+		filterList = []name{f}
+		paramList = []any{params}
+	case array:
+		for i, item := range f {
+			if n, ok := d.resolve(item).(name); ok {
+				filterList = append(filterList, n)
+				var p any
+				if pa, ok := params.(array); ok && i < len(pa) {
+					p = pa[i]
+				}
+				paramList = append(paramList, p)
+			}
+		}
+	}
+
+	for i, filter := range filterList {
+		var err error
+		switch filter {
+		case "FlateDecode", "Fl":
+			data, err = inflate(data)
+			if err == nil {
+				data, err = d.applyPredictor(data, paramList[i])
+			}
+		case "ASCIIHexDecode", "AHx":
+			data = asciiHexDecode(data)
+		case "ASCII85Decode", "A85":
+			data, err = ascii85Decode(data)
+		default:
+			return nil, fmt.Errorf("unsupported PDF filter %s", filter)
+		}
+		if err != nil {
+			return nil, err
+		}
+		d.spend(len(data))
+	}
+	return data, nil
+}
+
+func inflate(data []byte) ([]byte, error) {
+	var out bytes.Buffer
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		// Some producers omit the zlib header.
+		fr := flate.NewReader(bytes.NewReader(data))
+		defer fr.Close()
+		_, err = io.Copy(&out, io.LimitReader(fr, maxDecodedStream))
+		if out.Len() > 0 {
+			return out.Bytes(), nil
+		}
+		return nil, fmt.Errorf("inflating stream: %w", err)
+	}
+	defer r.Close()
+	_, err = io.Copy(&out, io.LimitReader(r, maxDecodedStream))
+	if err != nil && out.Len() == 0 {
+		return nil, fmt.Errorf("inflating stream: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// applyPredictor undoes PNG predictors (used by xref and object streams).
+func (d *document) applyPredictor(data []byte, params any) ([]byte, error) {
+	p, ok := d.resolve(params).(dict)
+	if !ok {
+		return data, nil
+	}
+	predictor := d.intValue(p["Predictor"], 1)
+	if predictor < 10 {
+		return data, nil
+	}
+	columns := d.intValue(p["Columns"], 1)
+	colors := d.intValue(p["Colors"], 1)
+	bpc := d.intValue(p["BitsPerComponent"], 8)
+	bpp := (colors*bpc + 7) / 8
+	rowLen := (columns*colors*bpc + 7) / 8
+	if rowLen <= 0 || bpp <= 0 {
+		return data, nil
+	}
+
+	var out bytes.Buffer
+	prev := make([]byte, rowLen)
+	for len(data) >= rowLen+1 {
+		filter := data[0]
+		row := append([]byte(nil), data[1:rowLen+1]...)
+		data = data[rowLen+1:]
+		for i := range row {
+			var left, up, upLeft byte
+			if i >= bpp {
+				left = row[i-bpp]
+				upLeft = prev[i-bpp]
+			}
+			up = prev[i]
+			switch filter {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			}
+		}
+		out.Write(row)
+		prev = row
+	}
+	return out.Bytes(), nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	if pa <= pb && pa <= pc {
+		return a
+	}
+	if pb <= pc {
+		return b
+	}
+	return c
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func asciiHexDecode(data []byte) []byte {
+	l := newLexer(append(append([]byte(nil), data...), '>'))
+	return l.hexString()
+}
+
+func ascii85Decode(data []byte) ([]byte, error) {
+	var out bytes.Buffer
+	var group [5]byte
+	n := 0
+	for _, c := range data {
+		switch {
+		case c == '~':
+			goto done
+		case c == 'z' && n == 0:
+			out.Write([]byte{0, 0, 0, 0})
+			continue
+		case c < '!' || c > 'u':
+			continue
+		}
+		group[n] = c - '!'
+		n++
+		if n == 5 {
+			v := uint32(0)
+			for _, g := range group {
+				v = v*85 + uint32(g)
+			}
+			out.Write([]byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)})
+			n = 0
+		}
+	}
+done:
+	if n > 1 {
+		for i := n; i < 5; i++ {
+			group[i] = 84
+		}
+		v := uint32(0)
+		for _, g := range group {
+			v = v*85 + uint32(g)
+		}
+		b := []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+		out.Write(b[:n-1])
+	}
+	return out.Bytes(), nil
+}
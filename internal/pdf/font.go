@@ -0,0 +1,419 @@
+package pdf
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// font decodes glyph codes of a font to text.
+type font struct {
+	codeBytes  int               // bytes per code: 1 for simple fonts, usually 2 for Type0
+	toUnicode  map[uint32]string // from the ToUnicode CMap
+	byteWidths []int             // codespace lengths from the ToUnicode CMap, if any
+	encoding   [256]rune         // simple font encoding (0 = unmapped)
+
+	// Glyph widths in 1/1000 text space units.
+	firstChar    int
+	widths       []float64
+	cidWidths    map[uint32]float64
+	defaultWidth float64
+}
+
+// glyph is one decoded character code.
+type glyph struct {
+	text  string
+	width float64 // in 1/1000 units of the font size
+	space bool    // single-byte code 32, which receives word spacing
+}
+
+func (d *document) loadFont(v any) *font {
+	ref, isRef := v.(objref)
+	if isRef {
+		if f, ok := d.fonts[ref]; ok {
+			return f
+		}
+	}
+	f := d.buildFont(d.resolve(v))
+	if isRef {
+		d.fonts[ref] = f
+	}
+	return f
+}
+
+func (d *document) buildFont(v any) *font {
+	f := &font{codeBytes: 1, encoding: standardEncoding, defaultWidth: 500}
+	fd, ok := v.(dict)
+	if !ok {
+		return f
+	}
+
+	if fd["Subtype"] == name("Type0") {
+		// Identity-H/V and most predefined CJK CMaps use 2-byte codes.
+		f.codeBytes = 2
+		f.defaultWidth = 1000
+		if descendants, ok := d.resolve(fd["DescendantFonts"]).(array); ok && len(descendants) > 0 {
+			if cid, ok := d.resolve(descendants[0]).(dict); ok {
+				d.loadCIDWidths(f, cid)
+			}
+		}
+	} else {
+		f.encoding = d.simpleEncoding(fd)
+		f.firstChar = d.intValue(fd["FirstChar"], 0)
+		if widths, ok := d.resolve(fd["Widths"]).(array); ok {
+			for _, w := range widths {
+				f.widths = append(f.widths, d.number(w))
+			}
+		}
+		if desc, ok := d.resolve(fd["FontDescriptor"]).(dict); ok {
+			if mw := d.number(desc["MissingWidth"]); mw > 0 {
+				f.defaultWidth = mw
+			}
+		}
+	}
+
+	if s, ok := d.resolve(fd["ToUnicode"]).(*stream); ok {
+		if data, err := d.decodeStream(s); err == nil {
+			f.toUnicode, f.byteWidths = d.parseCMap(data)
+		}
+	}
+	return f
+}
+
+func (d *document) simpleEncoding(fd dict) [256]rune {
+	enc := standardEncoding
+	if fd["Subtype"] == name("TrueType") {
+		enc = winAnsiEncoding
+	}
+	setBase := func(n name) {
+		switch n {
+		case "WinAnsiEncoding":
+			enc = winAnsiEncoding
+		case "MacRomanEncoding":
+			enc = macRomanEncoding
+		case "StandardEncoding":
+			enc = standardEncoding
+		}
+	}
+	switch e := d.resolve(fd["Encoding"]).(type) {
+	case name:
+		setBase(e)
+	case dict:
+		if base, ok := d.resolve(e["BaseEncoding"]).(name); ok {
+			setBase(base)
+		}
+		if diffs, ok := d.resolve(e["Differences"]).(array); ok {
+			code := 0
+			for _, item := range diffs {
+				switch it := d.resolve(item).(type) {
+				case int64:
+					code = int(it)
+				case float64:
+					code = int(it)
+				case name:
+					if code >= 0 && code < 256 {
+						enc[code] = glyphRune(string(it))
+					}
+					code++
+				}
+			}
+		}
+	}
+	return enc
+}
+
+func (d *document) loadCIDWidths(f *font, cid dict) {
+	if dw := d.number(cid["DW"]); dw > 0 {
+		f.defaultWidth = dw
+	}
+	w, ok := d.resolve(cid["W"]).(array)
+	if !ok {
+		return
+	}
+	f.cidWidths = map[uint32]float64{}
+	for i := 0; i < len(w); {
+		first := uint32(d.number(w[i]))
+		if i+1 >= len(w) {
+			break
+		}
+		if list, ok := d.resolve(w[i+1]).(array); ok {
+			d.spend(len(list))
+			for j, width := range list {
+				f.cidWidths[first+uint32(j)] = d.number(width)
+			}
+			i += 2
+			continue
+		}
+		if i+2 >= len(w) {
+			break
+		}
+		last := uint32(d.number(w[i+1]))
+		width := d.number(w[i+2])
+		if last >= first {
+			d.spend(int(min(last-first, 0xFFFF)))
+		}
+		for c := first; c <= last && c-first < 0xFFFF; c++ {
+			f.cidWidths[c] = width
+		}
+		i += 3
+	}
+}
+
+func (d *document) number(v any) float64 {
+	switch n := d.resolve(v).(type) {
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
+
+func (f *font) width(code uint32, n int) float64 {
+	if n == 1 && f.widths != nil {
+		if i := int(code) - f.firstChar; i >= 0 && i < len(f.widths) {
+			return f.widths[i]
+		}
+	}
+	if w, ok := f.cidWidths[code]; ok {
+		return w
+	}
+	return f.defaultWidth
+}
+
+// glyphs decodes a string operand of a text-showing operator.
+func (f *font) glyphs(s []byte) []glyph {
+	var out []glyph
+	for i := 0; i < len(s); {
+		n := f.codeLength(s[i:])
+		if i+n > len(s) {
+			n = len(s) - i
+		}
+		var code uint32
+		for _, b := range s[i : i+n] {
+			code = code<<8 | uint32(b)
+		}
+		i += n
+
+		g := glyph{width: f.width(code, n), space: n == 1 && code == 32}
+		if text, ok := f.toUnicode[code]; ok {
+			g.text = text
+		} else if n == 1 && f.encoding[code] != 0 {
+			g.text = string(f.encoding[code])
+		}
+		// Unmapped composite glyph codes keep their width but no text.
+		out = append(out, g)
+	}
+	return out
+}
+
+func (f *font) codeLength(s []byte) int {
+	if len(f.byteWidths) == 1 {
+		return f.byteWidths[0]
+	}
+	if len(f.byteWidths) > 1 && f.toUnicode != nil {
+		// Mixed-width codespaces: prefer the shortest code that is mapped.
+		for _, w := range f.byteWidths {
+			if w > len(s) {
+				continue
+			}
+			var code uint32
+			for _, b := range s[:w] {
+				code = code<<8 | uint32(b)
+			}
+			if _, ok := f.toUnicode[code]; ok {
+				return w
+			}
+		}
+	}
+	return f.codeBytes
+}
+
+// parseCMap reads bfchar/bfrange mappings and codespace widths of a ToUnicode CMap.
+func (d *document) parseCMap(data []byte) (map[uint32]string, []int) {
+	mapping := map[uint32]string{}
+	widthSet := map[int]bool{}
+	l := newLexer(data)
+	var operands []any
+	mode := ""
+	for {
+		tok, err := l.token()
+		if err != nil {
+			break
+		}
+		if kw, ok := tok.(keyword); ok && kw != "[" && kw != "<<" {
+			switch kw {
+			case "begincodespacerange", "beginbfchar", "beginbfrange":
+				mode = string(kw)
+			case "endcodespacerange":
+				for i := 0; i+1 < len(operands); i += 2 {
+					if lo, ok := operands[i].(str); ok && len(lo) > 0 {
+						widthSet[len(lo)] = true
+					}
+				}
+				mode = ""
+			case "endbfchar":
+				for i := 0; i+1 < len(operands); i += 2 {
+					src, ok1 := operands[i].(str)
+					dst, ok2 := operands[i+1].(str)
+					if ok1 && ok2 {
+						mapping[codeValue(src)] = decodeUTF16BE(dst)
+					}
+				}
+				mode = ""
+			case "endbfrange":
+				for i := 0; i+2 < len(operands); i += 3 {
+					lo, ok1 := operands[i].(str)
+					hi, ok2 := operands[i+1].(str)
+					if !ok1 || !ok2 {
+						continue
+					}
+					start, end := codeValue(lo), codeValue(hi)
+					if end < start || end-start > 0xFFFF {
+						continue
+					}
+					switch dst := operands[i+2].(type) {
+					case str:
+						base := []rune(decodeUTF16BE(dst))
+						if len(base) == 0 {
+							continue
+						}
+						d.spend(int(end-start) + 1)
+						for n := uint32(0); n <= end-start; n++ {
+							r := append([]rune(nil), base...)
+							r[len(r)-1] += rune(n)
+							mapping[start+n] = string(r)
+						}
+					case array:
+						for j, item := range dst {
+							if s, ok := item.(str); ok && start+uint32(j) <= end {
+								mapping[start+uint32(j)] = decodeUTF16BE(s)
+							}
+						}
+					}
+				}
+				mode = ""
+			}
+			operands = operands[:0]
+			continue
+		}
+		if mode == "" {
+			operands = operands[:0]
+			continue
+		}
+		v, err := l.finish(tok, 0)
+		if err != nil {
+			break
+		}
+		operands = append(operands, v)
+	}
+
+	var widths []int
+	for w := 1; w <= 4; w++ {
+		if widthSet[w] {
+			widths = append(widths, w)
+		}
+	}
+	return mapping, widths
+}
+
+func codeValue(b []byte) uint32 {
+	var v uint32
+	for _, c := range b {
+		v = v<<8 | uint32(c)
+	}
+	return v
+}
+
+// glyphRune maps an Adobe glyph name to a rune: uniXXXX/uXXXX forms,
+// single-character names and the common names in glyphNames.
+func glyphRune(glyph string) rune {
+	if r, ok := glyphNames[glyph]; ok {
+		return r
+	}
+	if base, _, found := strings.Cut(glyph, "."); found && base != "" {
+		return glyphRune(base)
+	}
+	if strings.HasPrefix(glyph, "uni") && len(glyph) >= 7 {
+		if v, err := strconv.ParseUint(glyph[3:7], 16, 32); err == nil {
+			return rune(v)
+		}
+	}
+	if strings.HasPrefix(glyph, "u") && len(glyph) >= 5 && len(glyph) <= 7 {
+		if v, err := strconv.ParseUint(glyph[1:], 16, 32); err == nil {
+			return rune(v)
+		}
+	}
+	if utf8.RuneCountInString(glyph) == 1 {
+		r, _ := utf8.DecodeRuneInString(glyph)
+		return r
+	}
+	return 0
+}
+
+var glyphNames = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#', "dollar": '$', "percent": '%',
+	"ampersand": '&', "quotesingle": '\'', "parenleft": '(', "parenright": ')', "asterisk": '*',
+	"plus": '+', "comma": ',', "hyphen": '-', "period": '.', "slash": '/', "zero": '0', "one": '1',
+	"two": '2', "three": '3', "four": '4', "five": '5', "six": '6', "seven": '7', "eight": '8',
+	"nine": '9', "colon": ':', "semicolon": ';', "less": '<', "equal": '=', "greater": '>',
+	"question": '?', "at": '@', "bracketleft": '[', "backslash": '\\', "bracketright": ']',
+	"asciicircum": '^', "underscore": '_', "grave": '`', "braceleft": '{', "bar": '|',
+	"braceright": '}', "asciitilde": '~', "quoteleft": '‘', "quoteright": '’', "quotedblleft": '“',
+	"quotedblright": '”', "quotesinglbase": '‚', "quotedblbase": '„', "endash": '–', "emdash": '—',
+	"bullet": '•', "ellipsis": '…', "dagger": '†', "daggerdbl": '‡', "fi": 'ﬁ', "fl": 'ﬂ',
+	"ff": 'ﬀ', "ffi": 'ﬃ', "ffl": 'ﬄ', "minus": '−', "multiply": '×', "divide": '÷',
+	"degree": '°', "copyright": '©', "registered": '®', "trademark": '™', "section": '§',
+	"paragraph": '¶', "periodcentered": '·', "guillemotleft": '«', "guillemotright": '»',
+	"guilsinglleft": '‹', "guilsinglright": '›', "Euro": '€', "sterling": '£', "yen": '¥',
+	"cent": '¢', "florin": 'ƒ', "perthousand": '‰', "exclamdown": '¡', "questiondown": '¿',
+	"nbspace": ' ', "sfthyphen": '­', "dotlessi": 'ı', "germandbls": 'ß',
+	"ae": 'æ', "AE": 'Æ', "oe": 'œ', "OE": 'Œ', "oslash": 'ø', "Oslash": 'Ø', "lslash": 'ł', "Lslash": 'Ł',
+	"aacute": 'á', "agrave": 'à', "acircumflex": 'â', "adieresis": 'ä', "atilde": 'ã', "aring": 'å',
+	"eacute": 'é', "egrave": 'è', "ecircumflex": 'ê', "edieresis": 'ë', "iacute": 'í', "igrave": 'ì',
+	"icircumflex": 'î', "idieresis": 'ï', "oacute": 'ó', "ograve": 'ò', "ocircumflex": 'ô',
+	"odieresis": 'ö', "otilde": 'õ', "uacute": 'ú', "ugrave": 'ù', "ucircumflex": 'û',
+	"udieresis": 'ü', "ccedilla": 'ç', "ntilde": 'ñ', "yacute": 'ý', "ydieresis": 'ÿ',
+	"Aacute": 'Á', "Agrave": 'À', "Acircumflex": 'Â', "Adieresis": 'Ä', "Atilde": 'Ã', "Aring": 'Å',
+	"Eacute": 'É', "Egrave": 'È', "Ecircumflex": 'Ê', "Edieresis": 'Ë', "Iacute": 'Í',
+	"Oacute": 'Ó', "Odieresis": 'Ö', "Uacute": 'Ú', "Udieresis": 'Ü', "Ccedilla": 'Ç', "Ntilde": 'Ñ',
+	"scaron": 'š', "Scaron": 'Š', "zcaron": 'ž', "Zcaron": 'Ž', "alpha": 'α', "beta": 'β',
+	"gamma": 'γ', "delta": 'δ', "epsilon": 'ε', "lambda": 'λ', "mu": 'μ', "pi": 'π', "sigma": 'σ',
+	"tau": 'τ', "theta": 'θ', "omega": 'ω', "Delta": 'Δ', "Sigma": 'Σ', "Omega": 'Ω',
+	"arrowright": '→', "arrowleft": '←', "lessequal": '≤', "greaterequal": '≥', "notequal": '≠',
+	"approxequal": '≈', "infinity": '∞', "plusminus": '±',
+}
+
+// Base encodings for simple fonts. Codes 32-126 are ASCII in all three
+// except where StandardEncoding uses curly quotes.
+var standardEncoding, winAnsiEncoding, macRomanEncoding = buildEncodings()
+
+func buildEncodings() (std, win, mac [256]rune) {
+	for c := 32; c < 127; c++ {
+		std[c], win[c], mac[c] = rune(c), rune(c), rune(c)
+	}
+	std['\''] = '’'
+	std['`'] = '‘'
+	for c, r := range map[int]rune{0xA1: '¡', 0xA2: '¢', 0xA3: '£', 0xA5: '¥', 0xA7: '§', 0xAA: '“',
+		0xAB: '«', 0xAE: 'ﬁ', 0xAF: 'ﬂ', 0xB1: '–', 0xB2: '†', 0xB3: '‡', 0xB4: '·', 0xB7: '•',
+		0xBA: '”', 0xBB: '»', 0xBC: '…', 0xD0: '—', 0xE1: 'Æ', 0xE9: 'Ø', 0xEA: 'Œ', 0xF1: 'æ',
+		0xF5: 'ı', 0xF8: 'ł', 0xF9: 'ø', 0xFA: 'œ', 0xFB: 'ß'} {
+		std[c] = r
+	}
+
+	for c := 0xA0; c < 256; c++ {
+		win[c] = rune(c)
+	}
+	for c, r := range map[int]rune{0x80: '€', 0x82: '‚', 0x83: 'ƒ', 0x84: '„', 0x85: '…', 0x86: '†',
+		0x87: '‡', 0x88: 'ˆ', 0x89: '‰', 0x8A: 'Š', 0x8B: '‹', 0x8C: 'Œ', 0x8E: 'Ž', 0x91: '‘',
+		0x92: '’', 0x93: '“', 0x94: '”', 0x95: '•', 0x96: '–', 0x97: '—', 0x98: '˜', 0x99: '™',
+		0x9A: 'š', 0x9B: '›', 0x9C: 'œ', 0x9E: 'ž', 0x9F: 'Ÿ'} {
+		win[c] = r
+	}
+
+	macHigh := []rune("ÄÅÇÉÑÖÜáàâäãåçéèêëíìîïñóòôöõúùûü†°¢£§•¶ß®©™´¨≠ÆØ∞±≤≥¥µ∂∑∏π∫ªºΩæø¿¡¬√ƒ≈∆«»…\u00a0ÀÃÕŒœ–—“”‘’÷◊ÿŸ⁄€‹›\ufb01\ufb02‡·‚„‰ÂÊÁËÈÍÎÏÌÓÔ\uf8ffÒÚÛÙıˆ˜¯˘˙˚¸˝˛ˇ")
+	for i, r := range macHigh {
+		mac[0x80+i] = r
+	}
+	return std, win, mac
+}
@@ -0,0 +1,365 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// PDF object model. Strings are kept as raw bytes because their encoding
+// depends on where they are used (text strings vs. font-encoded glyph codes).
+type (
+	name   string
+	str    []byte
+	dict   map[name]any
+	array  []any
+	objref struct{ num, gen int }
+	// keyword is a bare token in a content stream (an operator) or
+	// structural keyword in the file (obj, endobj, stream, R).
+	keyword string
+)
+
+type stream struct {
+	dict dict
+	raw  []byte
+}
+
+// lexer reads PDF tokens and objects from a byte slice.
+type lexer struct {
+	data []byte
+	pos  int
+}
+
+func newLexer(data []byte) *lexer { return &lexer{data: data} }
+
+func isWhitespace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isWhitespace(c) {
+			l.pos++
+			continue
+		}
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		return
+	}
+}
+
+// errEOF marks the end of input.
+var errEOF = fmt.Errorf("unexpected end of PDF data")
+
+// token returns the next primitive token: a value (number, string, name,
+// bool, nil), a keyword, or one of the delimiter markers "[", "]", "<<", ">>".
+func (l *lexer) token() (any, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, errEOF
+	}
+	c := l.data[l.pos]
+	switch {
+	case c == '(':
+		l.pos++
+		return l.literalString(), nil
+	case c == '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return keyword("<<"), nil
+		}
+		l.pos++
+		return l.hexString(), nil
+	case c == '>':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return keyword(">>"), nil
+		}
+		l.pos++
+		return l.token()
+	case c == '[' || c == ']' || c == '{' || c == '}':
+		l.pos++
+		return keyword(string(c)), nil
+	case c == '/':
+		l.pos++
+		return l.name(), nil
+	case c == ')':
+		l.pos++
+		return l.token()
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isWhitespace(l.data[l.pos]) && !isDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if word == "" {
+		l.pos++
+		return l.token()
+	}
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	if c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9') {
+		if i, err := strconv.ParseInt(word, 10, 64); err == nil {
+			return i, nil
+		}
+		if f, err := strconv.ParseFloat(word, 64); err == nil {
+			return f, nil
+		}
+		// Malformed numbers like "--5" or "1.2.3" are read leniently.
+		if f, err := strconv.ParseFloat(leadingNumber(word), 64); err == nil {
+			return f, nil
+		}
+	}
+	return keyword(word), nil
+}
+
+func leadingNumber(s string) string {
+	for len(s) > 1 && (s[0] == '-' || s[0] == '+') && (s[1] == '-' || s[1] == '+') {
+		s = s[1:]
+	}
+	end := 0
+	dot := false
+	for end < len(s) {
+		c := s[end]
+		if c == '.' && !dot {
+			dot = true
+		} else if !(c >= '0' && c <= '9') && !(end == 0 && (c == '-' || c == '+')) {
+			break
+		}
+		end++
+	}
+	return s[:end]
+}
+
+func (l *lexer) name() name {
+	var b bytes.Buffer
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isWhitespace(c) || isDelimiter(c) {
+			break
+		}
+		if c == '#' && l.pos+2 < len(l.data) {
+			if v, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+3]), 16, 8); err == nil {
+				b.WriteByte(byte(v))
+				l.pos += 3
+				continue
+			}
+		}
+		b.WriteByte(c)
+		l.pos++
+	}
+	return name(b.String())
+}
+
+func (l *lexer) literalString() str {
+	var b bytes.Buffer
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return str(b.Bytes())
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return str(b.Bytes())
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					b.WriteByte(byte(v))
+				} else {
+					b.WriteByte(e)
+				}
+			}
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return str(b.Bytes())
+}
+
+func (l *lexer) hexString() str {
+	var b bytes.Buffer
+	var hi byte
+	half := false
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		if c == '>' {
+			break
+		}
+		v, ok := hexValue(c)
+		if !ok {
+			continue
+		}
+		if half {
+			b.WriteByte(hi<<4 | v)
+		} else {
+			hi = v
+		}
+		half = !half
+	}
+	if half {
+		b.WriteByte(hi << 4)
+	}
+	return str(b.Bytes())
+}
+
+func hexValue(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// object reads a complete object, combining "N G R" into references and
+// building arrays and dictionaries. Streams are not handled here.
+func (l *lexer) object() (any, error) {
+	tok, err := l.token()
+	if err != nil {
+		return nil, err
+	}
+	return l.finish(tok, 0)
+}
+
+const maxNesting = 64
+
+func (l *lexer) finish(tok any, depth int) (any, error) {
+	if depth > maxNesting {
+		return nil, fmt.Errorf("PDF objects nested too deeply")
+	}
+	switch t := tok.(type) {
+	case keyword:
+		switch t {
+		case "[":
+			var arr array
+			for {
+				next, err := l.token()
+				if err != nil {
+					return arr, err
+				}
+				if next == keyword("]") {
+					return arr, nil
+				}
+				v, err := l.finish(next, depth+1)
+				if err != nil {
+					return arr, err
+				}
+				arr = append(arr, v)
+				arr = collapseRef(arr)
+			}
+		case "<<":
+			d := dict{}
+			for {
+				next, err := l.token()
+				if err != nil {
+					return d, err
+				}
+				if next == keyword(">>") {
+					return d, nil
+				}
+				key, ok := next.(name)
+				if !ok {
+					continue
+				}
+				valTok, err := l.token()
+				if err != nil {
+					return d, err
+				}
+				if valTok == keyword(">>") {
+					return d, nil
+				}
+				v, err := l.finish(valTok, depth+1)
+				if err != nil {
+					return d, err
+				}
+				d[key] = l.maybeRef(v)
+			}
+		}
+	}
+	return tok, nil
+}
+
+// maybeRef looks ahead for "G R" after an integer to form a reference.
+func (l *lexer) maybeRef(v any) any {
+	num, ok := v.(int64)
+	if !ok {
+		return v
+	}
+	save := l.pos
+	gen, err := l.token()
+	if g, ok := gen.(int64); ok && err == nil {
+		if r, err := l.token(); err == nil && r == keyword("R") {
+			return objref{int(num), int(g)}
+		}
+	}
+	l.pos = save
+	return v
+}
+
+// collapseRef turns a trailing "num gen R" triple in an array into a reference.
+func collapseRef(arr array) array {
+	n := len(arr)
+	if n < 3 || arr[n-1] != keyword("R") {
+		return arr
+	}
+	num, ok1 := arr[n-3].(int64)
+	gen, ok2 := arr[n-2].(int64)
+	if !ok1 || !ok2 {
+		return arr
+	}
+	return append(arr[:n-3], objref{int(num), int(gen)})
+}
@@ -0,0 +1,415 @@
+// Package pdf extracts text and document metadata from PDF files.
+//
+// It is a small, pure-Go reader aimed at text-heavy documents (papers,
+// whitepapers, slides): it locates objects by scanning the file (so damaged
+// cross-reference tables are tolerated), supports object streams, Flate and
+// ASCII filters, simple and composite fonts with ToUnicode maps, and
+// reconstructs lines and paragraphs from text positioning. Encrypted files
+// are not supported.
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+var (
+	// ErrNotPDF is returned when the data does not start with a PDF header.
+	ErrNotPDF = errors.New("not a PDF file")
+	// ErrEncrypted is returned for encrypted PDFs.
+	ErrEncrypted = errors.New("encrypted PDFs are not supported")
+	// ErrTooComplex is returned when extraction exceeds its work or time
+	// budget, as crafted files (self-referencing forms, huge CMap ranges) do.
+	ErrTooComplex = errors.New("PDF is too complex to extract")
+)
+
+// maxPages bounds the number of pages extracted from one document.
+const maxPages = 2000
+
+// Budget for one document: work counts decoded stream bytes, content
+// tokens, shown glyphs and font table entries. Variables so tests can
+// lower them.
+var (
+	maxWork        = 64 << 20
+	extractTimeout = 30 * time.Second
+)
+
+// Document is the extracted content of a PDF.
+type Document struct {
+	Title    string
+	Author   string
+	Subject  string
+	Keywords string
+	Pages    []string // plain text per page; paragraphs are separated by blank lines
+}
+
+// Text returns the text of all pages separated by blank lines.
+func (d *Document) Text() string {
+	var parts []string
+	for _, p := range d.Pages {
+		if strings.TrimSpace(p) != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// IsPDF reports whether data starts with a PDF header (allowing leading junk
+// within the first kilobyte, as readers do).
+func IsPDF(data []byte) bool {
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	return bytes.Contains(head, []byte("%PDF-"))
+}
+
+// Extract parses a PDF and returns its metadata and per-page text. Malformed
+// input yields an error, never a panic, and extraction gives up with
+// ErrTooComplex once it exceeds its work or time budget.
+func Extract(data []byte) (doc *Document, err error) {
+	if !IsPDF(data) {
+		return nil, ErrNotPDF
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			doc = nil
+			if r == errBudgetExhausted {
+				err = ErrTooComplex
+			} else {
+				err = fmt.Errorf("%w: %v", errMalformed, r)
+			}
+		}
+	}()
+
+	d := &document{
+		data:     data,
+		objects:  map[int]any{},
+		fonts:    map[objref]*font{},
+		deadline: time.Now().Add(extractTimeout),
+	}
+	d.scanObjects()
+	if d.trailer == nil {
+		return nil, fmt.Errorf("PDF has no trailer or catalog")
+	}
+	if d.trailer["Encrypt"] != nil {
+		return nil, ErrEncrypted
+	}
+
+	doc = &Document{}
+	if info, ok := d.resolve(d.trailer["Info"]).(dict); ok {
+		doc.Title = d.textString(info["Title"])
+		doc.Author = d.textString(info["Author"])
+		doc.Subject = d.textString(info["Subject"])
+		doc.Keywords = d.textString(info["Keywords"])
+	}
+
+	root, _ := d.resolve(d.trailer["Root"]).(dict)
+	if root == nil {
+		return nil, fmt.Errorf("PDF catalog not found")
+	}
+	for _, page := range d.pages(root["Pages"], nil, 0, map[objref]bool{}) {
+		if len(doc.Pages) >= maxPages {
+			break
+		}
+		doc.Pages = append(doc.Pages, d.pageText(page))
+	}
+	if len(doc.Pages) == 0 {
+		return nil, fmt.Errorf("PDF has no pages")
+	}
+	return doc, nil
+}
+
+// document holds the parsed object table.
+type document struct {
+	data    []byte
+	objects map[int]any
+	trailer dict
+	fonts   map[objref]*font
+
+	work      int
+	nextClock int
+	deadline  time.Time
+}
+
+var (
+	// errBudgetExhausted aborts extraction from deep inside the interpreter;
+	// it is recovered in Extract.
+	errBudgetExhausted = errors.New("PDF work budget exhausted")
+	// errMalformed wraps a panic recovered from the parser.
+	errMalformed = errors.New("malformed PDF")
+)
+
+// spend charges n units of work and aborts extraction when the budget or
+// the deadline is exceeded. The clock is read once per 64K units.
+func (d *document) spend(n int) {
+	d.work += n
+	if d.work > maxWork {
+		panic(errBudgetExhausted)
+	}
+	if d.work >= d.nextClock {
+		d.nextClock = d.work + 1<<16
+		if time.Now().After(d.deadline) {
+			panic(errBudgetExhausted)
+		}
+	}
+}
+
+var objHeaderRe = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// scanObjects finds every "N G obj" in the file. Later definitions win, as
+// with incremental updates. Objects in object streams are added afterwards
+// unless defined directly.
+func (d *document) scanObjects() {
+	var objStreams []*stream
+	for _, m := range objHeaderRe.FindAllSubmatchIndex(d.data, -1) {
+		if m[0] > 0 && !isWhitespace(d.data[m[0]-1]) && !isDelimiter(d.data[m[0]-1]) {
+			continue
+		}
+		num, _ := strconv.Atoi(string(d.data[m[2]:m[3]]))
+		l := &lexer{data: d.data, pos: m[1]}
+		obj, err := l.object()
+		if err != nil && obj == nil {
+			continue
+		}
+		if sd, ok := obj.(dict); ok {
+			if s := d.readStream(l, sd); s != nil {
+				obj = s
+				switch sd["Type"] {
+				case name("ObjStm"):
+					objStreams = append(objStreams, s)
+				case name("XRef"):
+					d.setTrailer(sd)
+				}
+			}
+		}
+		d.objects[num] = obj
+	}
+
+	for _, s := range objStreams {
+		d.expandObjectStream(s)
+	}
+
+	// Classic trailers; the last one in the file is the most recent.
+	for idx := 0; ; {
+		i := bytes.Index(d.data[idx:], []byte("trailer"))
+		if i < 0 {
+			break
+		}
+		l := &lexer{data: d.data, pos: idx + i + len("trailer")}
+		if t, err := l.object(); err == nil {
+			if td, ok := t.(dict); ok {
+				d.setTrailer(td)
+			}
+		}
+		idx += i + len("trailer")
+	}
+
+	if d.trailer == nil || d.trailer["Root"] == nil {
+		// No usable trailer: look for the catalog directly.
+		for num, obj := range d.objects {
+			if od, ok := obj.(dict); ok && od["Type"] == name("Catalog") {
+				if d.trailer == nil {
+					d.trailer = dict{}
+				}
+				d.trailer["Root"] = objref{num, 0}
+				break
+			}
+		}
+	}
+}
+
+// setTrailer merges a trailer dictionary; entries from later trailers win.
+func (d *document) setTrailer(t dict) {
+	if d.trailer == nil {
+		d.trailer = dict{}
+	}
+	for _, key := range []name{"Root", "Info", "Encrypt"} {
+		if v, ok := t[key]; ok {
+			d.trailer[key] = v
+		}
+	}
+}
+
+// readStream reads stream data following a dictionary, if present.
+func (d *document) readStream(l *lexer, sd dict) *stream {
+	save := l.pos
+	tok, err := l.token()
+	if err != nil || tok != keyword("stream") {
+		l.pos = save
+		return nil
+	}
+	start := l.pos
+	if start < len(d.data) && d.data[start] == '\r' {
+		start++
+	}
+	if start < len(d.data) && d.data[start] == '\n' {
+		start++
+	}
+
+	if length, ok := sd["Length"].(int64); ok && length >= 0 && start+int(length) <= len(d.data) {
+		end := start + int(length)
+		rest := bytes.TrimLeft(d.data[end:min(end+32, len(d.data))], " \r\n\t")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			return &stream{dict: sd, raw: d.data[start:end]}
+		}
+	}
+	end := bytes.Index(d.data[start:], []byte("endstream"))
+	if end < 0 {
+		return &stream{dict: sd, raw: d.data[start:]}
+	}
+	raw := d.data[start : start+end]
+	raw = bytes.TrimSuffix(raw, []byte("\n"))
+	raw = bytes.TrimSuffix(raw, []byte("\r"))
+	return &stream{dict: sd, raw: raw}
+}
+
+func (d *document) expandObjectStream(s *stream) {
+	data, err := d.decodeStream(s)
+	if err != nil {
+		return
+	}
+	n := d.intValue(s.dict["N"], 0)
+	first := d.intValue(s.dict["First"], 0)
+	if first <= 0 || first > len(data) {
+		return
+	}
+	header := newLexer(data[:first])
+	for i := 0; i < n; i++ {
+		numTok, err1 := header.token()
+		offTok, err2 := header.token()
+		num, ok1 := numTok.(int64)
+		off, ok2 := offTok.(int64)
+		if err1 != nil || err2 != nil || !ok1 || !ok2 {
+			return
+		}
+		if _, exists := d.objects[int(num)]; exists {
+			continue
+		}
+		pos := first + int(off)
+		if pos >= len(data) {
+			continue
+		}
+		l := &lexer{data: data, pos: pos}
+		if obj, err := l.object(); err == nil || obj != nil {
+			d.objects[int(num)] = obj
+		}
+	}
+}
+
+// resolve follows references (with a depth limit against cycles).
+func (d *document) resolve(v any) any {
+	for i := 0; i < 32; i++ {
+		r, ok := v.(objref)
+		if !ok {
+			return v
+		}
+		v = d.objects[r.num]
+	}
+	return nil
+}
+
+func (d *document) intValue(v any, def int) int {
+	switch n := d.resolve(v).(type) {
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return def
+}
+
+// pages flattens the page tree, passing inherited resources down.
+func (d *document) pages(node any, inherited any, depth int, seen map[objref]bool) []dict {
+	if depth > 64 {
+		return nil
+	}
+	if r, ok := node.(objref); ok {
+		if seen[r] {
+			return nil
+		}
+		seen[r] = true
+	}
+	n, ok := d.resolve(node).(dict)
+	if !ok {
+		return nil
+	}
+	if res, ok := n["Resources"]; ok {
+		inherited = res
+	}
+
+	kids, hasKids := d.resolve(n["Kids"]).(array)
+	if n["Type"] == name("Page") || (!hasKids && n["Contents"] != nil) {
+		page := dict{}
+		for k, v := range n {
+			page[k] = v
+		}
+		if page["Resources"] == nil && inherited != nil {
+			page["Resources"] = inherited
+		}
+		return []dict{page}
+	}
+
+	var out []dict
+	for _, kid := range kids {
+		out = append(out, d.pages(kid, inherited, depth+1, seen)...)
+		if len(out) >= maxPages {
+			break
+		}
+	}
+	return out
+}
+
+// textString decodes a PDF text string (UTF-16BE with BOM, UTF-8 with BOM,
+// or PDFDocEncoding).
+func (d *document) textString(v any) string {
+	s, ok := d.resolve(v).(str)
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(strings.ReplaceAll(decodeTextString(s), "\x00", ""))
+}
+
+func decodeTextString(b []byte) string {
+	switch {
+	case len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF:
+		return decodeUTF16BE(b[2:])
+	case len(b) >= 3 && b[0] == 0xEF && b[1] == 0xBB && b[2] == 0xBF:
+		return string(b[3:])
+	}
+	var sb strings.Builder
+	for _, c := range b {
+		sb.WriteRune(pdfDocRune(c))
+	}
+	return sb.String()
+}
+
+func decodeUTF16BE(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// pdfDocRune maps PDFDocEncoding; it matches Latin-1 except for 0x80-0x9F
+// (and a few rarely used control codes) which hold typographic characters.
+func pdfDocRune(c byte) rune {
+	if r, ok := pdfDocHigh[c]; ok {
+		return r
+	}
+	return rune(c)
+}
+
+var pdfDocHigh = map[byte]rune{
+	0x80: '•', 0x81: '†', 0x82: '‡', 0x83: '…', 0x84: '—', 0x85: '–', 0x86: 'ƒ', 0x87: '⁄',
+	0x88: '‹', 0x89: '›', 0x8A: '−', 0x8B: '‰', 0x8C: '„', 0x8D: '“', 0x8E: '”', 0x8F: '‘',
+	0x90: '’', 0x91: '‚', 0x92: '™', 0x93: 'ﬁ', 0x94: 'ﬂ', 0x95: 'Ł', 0x96: 'Œ', 0x97: 'Š',
+	0x98: 'Ÿ', 0x99: 'Ž', 0x9A: 'ı', 0x9B: 'ł', 0x9C: 'œ', 0x9D: 'š', 0x9E: 'ž', 0xA0: '€',
+}
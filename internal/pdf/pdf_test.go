@@ -0,0 +1,344 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// buildPDF assembles a PDF from object bodies (object i+1 is objects[i])
+// with a classic xref table and the given trailer entries.
+func buildPDF(objects []string, trailer string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d %s >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, trailer, xref)
+	return b.Bytes()
+}
+
+func streamObj(dict, data string) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func flateObj(dict, data string) string {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write([]byte(data))
+	w.Close()
+	return fmt.Sprintf("<< %s /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream", dict, buf.Len(), buf.String())
+}
+
+// simplePDF builds a document whose pages have the given content streams and
+// share a Helvetica font resource /F1.
+func simplePDF(info string, contents ...string) []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"", // page tree, filled below
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+	}
+	var kids []string
+	for _, c := range contents {
+		objects = append(objects, flateObj("", c))
+		contentNum := len(objects)
+		objects = append(objects, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents %d 0 R >>", contentNum))
+		kids = append(kids, fmt.Sprintf("%d 0 R", len(objects)))
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /Resources << /Font << /F1 3 0 R >> >> >>", strings.Join(kids, " "), len(kids))
+	trailer := "/Root 1 0 R"
+	if info != "" {
+		objects = append(objects, info)
+		trailer += fmt.Sprintf(" /Info %d 0 R", len(objects))
+	}
+	return buildPDF(objects, trailer)
+}
+
+func TestExtract_LinesAndParagraphs(t *testing.T) {
+	content := `BT /F1 12 Tf 72 700 Td
+(Knowledge management is a) Tj 0 -14 Td
+(continuous practice.) Tj 0 -40 Td
+(Second paragraph starts here.) Tj
+ET`
+	doc, err := Extract(simplePDF("", content))
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	want := "Knowledge management is a continuous practice.\n\nSecond paragraph starts here."
+	if len(doc.Pages) != 1 || doc.Pages[0] != want {
+		t.Fatalf("unexpected text: %q", doc.Pages)
+	}
+}
+
+func TestExtract_TJKerningAndWordGaps(t *testing.T) {
+	content := `BT /F1 10 Tf 72 700 Td
+[(Ke) 80 (rning) -600 (works)] TJ
+ET`
+	doc, err := Extract(simplePDF("", content))
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if doc.Pages[0] != "Kerning works" {
+		t.Fatalf("expected kerning to join and large gaps to split words, got %q", doc.Pages[0])
+	}
+}
+
+func TestExtract_Dehyphenation(t *testing.T) {
+	content := `BT /F1 12 Tf 72 700 Td
+(An extraordi-) Tj 0 -14 Td
+(nary result for state-of-the-) Tj 0 -14 Td
+(art tools.) Tj
+ET`
+	doc, err := Extract(simplePDF("", content))
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if doc.Pages[0] != "An extraordinary result for state-of-the-art tools." {
+		t.Fatalf("unexpected text: %q", doc.Pages[0])
+	}
+}
+
+func TestExtract_InfoDictionary(t *testing.T) {
+	// Title is UTF-16BE with a BOM: "Café Notes".
+	info := "<< /Title <FEFF00430061006600E90020004E006F007400650073> /Author (Jane Doe) /Subject (Testing) >>"
+	doc, err := Extract(simplePDF(info, "BT /F1 12 Tf 72 700 Td (Body) Tj ET"))
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if doc.Title != "Café Notes" || doc.Author != "Jane Doe" || doc.Subject != "Testing" {
+		t.Fatalf("unexpected metadata: %+v", doc)
+	}
+}
+
+func TestExtract_MultiplePages(t *testing.T) {
+	doc, err := Extract(simplePDF("",
+		"BT /F1 12 Tf 72 700 Td (Page one) Tj ET",
+		"BT /F1 12 Tf 72 700 Td (Page two) Tj ET",
+	))
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if len(doc.Pages) != 2 || doc.Pages[0] != "Page one" || doc.Pages[1] != "Page two" {
+		t.Fatalf("unexpected pages: %q", doc.Pages)
+	}
+	if doc.Text() != "Page one\n\nPage two" {
+		t.Fatalf("unexpected text: %q", doc.Text())
+	}
+}
+
+func TestExtract_Type0FontWithToUnicode(t *testing.T) {
+	cmap := `/CIDInit /ProcSet findresource begin
+12 dict begin begincmap
+1 begincodespacerange <0000> <FFFF> endcodespacerange
+2 beginbfchar
+<0001> <0048>
+<0002> <0069>
+endbfchar
+1 beginbfrange
+<0010> <0012> [<00E9> <0020> <2014>]
+endbfrange
+endcmap CMapName currentdict /CMap defineresource pop end end`
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F0 5 0 R >> >> /Contents 4 0 R >>",
+		streamObj("", "BT /F0 12 Tf 72 700 Td <0001000200100011001200010002> Tj ET"),
+		"<< /Type /Font /Subtype /Type0 /BaseFont /ABC /Encoding /Identity-H /DescendantFonts [7 0 R] /ToUnicode 6 0 R >>",
+		flateObj("", cmap),
+		"<< /Type /Font /Subtype /CIDFontType2 /BaseFont /ABC /DW 500 >>",
+	}
+	doc, err := Extract(buildPDF(objects, "/Root 1 0 R"))
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if doc.Pages[0] != "Hié —Hi" {
+		t.Fatalf("unexpected text: %q", doc.Pages[0])
+	}
+}
+
+func TestExtract_DifferencesEncodingAndLigatures(t *testing.T) {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 5 0 R >> >> /Contents 4 0 R >>",
+		streamObj("", `BT /F1 12 Tf 72 700 Td (\001nd the \002ow) Tj ET`),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Times-Roman /Encoding << /Type /Encoding /BaseEncoding /WinAnsiEncoding /Differences [1 /fi /fl] >> >>",
+	}
+	doc, err := Extract(buildPDF(objects, "/Root 1 0 R"))
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if doc.Pages[0] != "find the flow" {
+		t.Fatalf("unexpected text: %q", doc.Pages[0])
+	}
+}
+
+func TestExtract_ObjectStreamWithoutXrefTable(t *testing.T) {
+	// Objects 2 and 3 live in an object stream; there is no classic trailer,
+	// so the catalog must be found by scanning.
+	pages := "<< /Type /Pages /Kids [3 0 R] /Count 1 /Resources << /Font << /F1 5 0 R >> >> >> "
+	inner := pages + "<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>"
+	header := fmt.Sprintf("2 0 3 %d ", len(pages))
+	objStm := flateObj(fmt.Sprintf("/Type /ObjStm /N 2 /First %d", len(header)), header+inner)
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.5\n")
+	fmt.Fprintf(&b, "1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	fmt.Fprintf(&b, "4 0 obj\n%s\nendobj\n", streamObj("", "BT /F1 12 Tf 72 700 Td (Packed objects) Tj ET"))
+	fmt.Fprintf(&b, "5 0 obj\n<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>\nendobj\n")
+	fmt.Fprintf(&b, "6 0 obj\n%s\nendobj\n%%%%EOF\n", objStm)
+
+	doc, err := Extract(b.Bytes())
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if doc.Pages[0] != "Packed objects" {
+		t.Fatalf("unexpected text: %q", doc.Pages[0])
+	}
+}
+
+func TestExtract_FormXObjectAndInlineImage(t *testing.T) {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 5 0 R >> /XObject << /X1 6 0 R >> >> /Contents 4 0 R >>",
+		streamObj("", "BT /F1 12 Tf 72 700 Td (Before) Tj ET\nBI /W 2 /H 1 /BPC 8 /CS /G ID \x28\x29 EI\nq 1 0 0 1 0 -100 cm /X1 Do Q"),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		streamObj("/Type /XObject /Subtype /Form /BBox [0 0 612 792] /Resources << /Font << /F2 5 0 R >> >>",
+			"BT /F2 12 Tf 72 700 Td (Inside form) Tj ET"),
+	}
+	doc, err := Extract(buildPDF(objects, "/Root 1 0 R"))
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if doc.Pages[0] != "Before\n\nInside form" {
+		t.Fatalf("unexpected text: %q", doc.Pages[0])
+	}
+}
+
+func TestExtract_Errors(t *testing.T) {
+	if _, err := Extract([]byte("<html>not a pdf</html>")); !errors.Is(err, ErrNotPDF) {
+		t.Errorf("expected ErrNotPDF, got %v", err)
+	}
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [] /Count 0 >>",
+		"<< /Filter /Standard /V 2 /R 3 >>",
+	}
+	if _, err := Extract(buildPDF(objects, "/Root 1 0 R /Encrypt 3 0 R")); !errors.Is(err, ErrEncrypted) {
+		t.Errorf("expected ErrEncrypted, got %v", err)
+	}
+}
+
+func TestExtract_SelfReferencingFormExhaustsBudget(t *testing.T) {
+	// Every level of the form draws itself 50 times: 50^8 invocations.
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /XObject << /X1 5 0 R >> >> /Contents 4 0 R >>",
+		streamObj("", "/X1 Do"),
+		streamObj("/Type /XObject /Subtype /Form /Resources << /XObject << /X1 5 0 R >> >>",
+			strings.Repeat("/X1 Do\n", 50)+"BT (x) Tj ET"),
+	}
+	old := maxWork
+	defer func() { maxWork = old }()
+	maxWork = 1 << 20
+
+	start := time.Now()
+	_, err := Extract(buildPDF(objects, "/Root 1 0 R"))
+	if !errors.Is(err, ErrTooComplex) {
+		t.Fatalf("expected ErrTooComplex, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("extraction took %v, expected the work budget to stop it", elapsed)
+	}
+}
+
+func TestExtract_DeadlineExceeded(t *testing.T) {
+	old := extractTimeout
+	defer func() { extractTimeout = old }()
+	extractTimeout = 0
+
+	_, err := Extract(simplePDF("", "BT /F1 12 Tf 72 700 Td (Hello) Tj ET"))
+	if !errors.Is(err, ErrTooComplex) {
+		t.Fatalf("expected ErrTooComplex, got %v", err)
+	}
+}
+
+func TestExtract_CMapRangeAtCodeLimit(t *testing.T) {
+	// A bfrange ending at the largest code used to wrap around forever.
+	cmap := `begincmap
+1 begincodespacerange <00000000> <FFFFFFFF> endcodespacerange
+1 beginbfrange
+<FFFFFFF0> <FFFFFFFF> <0041>
+endbfrange
+1 beginbfchar
+<00000001> <0048>
+endbfchar
+endcmap`
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F0 5 0 R >> >> /Contents 4 0 R >>",
+		streamObj("", "BT /F0 12 Tf 72 700 Td <00000001FFFFFFFF> Tj ET"),
+		"<< /Type /Font /Subtype /Type0 /BaseFont /ABC /Encoding /Identity-H /DescendantFonts [7 0 R] /ToUnicode 6 0 R >>",
+		flateObj("", cmap),
+		"<< /Type /Font /Subtype /CIDFontType2 /BaseFont /ABC /DW 500 >>",
+	}
+	doc, err := Extract(buildPDF(objects, "/Root 1 0 R"))
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if doc.Pages[0] != "HP" {
+		t.Fatalf("unexpected text: %q", doc.Pages[0])
+	}
+}
+
+func FuzzExtract(f *testing.F) {
+	f.Add(simplePDF("/Title (Fuzz)", "BT /F1 12 Tf 72 700 Td (Hello) Tj ET", "BT /F1 12 Tf [(A) -200 (B)] TJ ET"))
+	f.Add(buildPDF([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /XObject << /X1 5 0 R >> >> /Contents 4 0 R >>",
+		streamObj("", "/X1 Do"),
+		streamObj("/Type /XObject /Subtype /Form /Resources << /XObject << /X1 5 0 R >> >>", "/X1 Do /X1 Do"),
+	}, "/Root 1 0 R"))
+	f.Add([]byte("%PDF-1.4\n1 0 obj << /Type /Catalog /Pages 1 0 R >> endobj\ntrailer << /Root 1 0 R >>"))
+
+	old := maxWork
+	defer func() { maxWork = old }()
+	maxWork = 1 << 20
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		doc, err := Extract(data)
+		if errors.Is(err, errMalformed) {
+			t.Fatalf("parser panicked: %v", err)
+		}
+		if err == nil && len(doc.Pages) == 0 {
+			t.Fatal("document without pages returned without error")
+		}
+	})
+}
+
+func TestIsPDF(t *testing.T) {
+	if !IsPDF([]byte("%PDF-1.4\n...")) {
+		t.Error("expected PDF header to be detected")
+	}
+	if !IsPDF(append(bytes.Repeat([]byte{' '}, 100), []byte("%PDF-1.4")...)) {
+		t.Error("expected PDF header after leading junk to be detected")
+	}
+	if IsPDF([]byte("<!doctype html>")) {
+		t.Error("expected HTML not to be detected as PDF")
+	}
+}
@@ -0,0 +1,442 @@
+package pdf
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// maxFormDepth bounds recursion into nested form XObjects.
+const maxFormDepth = 8
+
+// matrix is a PDF affine transform [a b c d e f].
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// mul returns m × n.
+func (m matrix) mul(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+func translate(x, y float64) matrix { return matrix{1, 0, 0, 1, x, y} }
+
+// textState is the graphics and text state relevant for extraction.
+type textState struct {
+	ctm       matrix
+	font      *font
+	fontSize  float64
+	charSpace float64
+	wordSpace float64
+	hScale    float64
+	leading   float64
+}
+
+// pageText interprets a page's content streams and returns its text.
+func (d *document) pageText(page dict) string {
+	var content []byte
+	switch c := d.resolve(page["Contents"]).(type) {
+	case *stream:
+		content, _ = d.decodeStream(c)
+	case array:
+		for _, part := range c {
+			if s, ok := d.resolve(part).(*stream); ok {
+				if data, err := d.decodeStream(s); err == nil {
+					content = append(content, data...)
+					content = append(content, '\n')
+				}
+			}
+		}
+	}
+
+	w := &textWriter{}
+	res, _ := d.resolve(page["Resources"]).(dict)
+	d.runContent(content, res, textState{ctm: identity, hScale: 1}, w, 0)
+	return w.String()
+}
+
+// runContent executes a content stream, writing shown text to w.
+func (d *document) runContent(content []byte, res dict, gs textState, w *textWriter, depth int) {
+	l := newLexer(content)
+	var stack []textState
+	var operands []any
+	var tm, tlm matrix
+
+	fonts, _ := d.resolve(res["Font"]).(dict)
+	xobjects, _ := d.resolve(res["XObject"]).(dict)
+
+	show := func(s []byte) {
+		if gs.font == nil {
+			gs.font = &font{codeBytes: 1, encoding: standardEncoding, defaultWidth: 500}
+		}
+		glyphs := gs.font.glyphs(s)
+		d.spend(len(glyphs))
+		for _, g := range glyphs {
+			trm := matrix{gs.fontSize * gs.hScale, 0, 0, gs.fontSize, 0, 0}.mul(tm).mul(gs.ctm)
+			size := math.Hypot(trm[2], trm[3])
+			w.place(trm[4], trm[5], size)
+			w.write(g.text)
+
+			tx := (g.width/1000*gs.fontSize + gs.charSpace) * gs.hScale
+			if g.space {
+				tx += gs.wordSpace * gs.hScale
+			}
+			tm = translate(tx, 0).mul(tm)
+			end := matrix{gs.fontSize * gs.hScale, 0, 0, gs.fontSize, 0, 0}.mul(tm).mul(gs.ctm)
+			w.lastX = end[4]
+		}
+	}
+	nextLine := func(tx, ty float64) {
+		tlm = translate(tx, ty).mul(tlm)
+		tm = tlm
+	}
+
+	for {
+		tok, err := l.token()
+		if err != nil {
+			return
+		}
+		d.spend(1)
+		op, isOp := tok.(keyword)
+		if !isOp || op == "[" || op == "<<" {
+			v, _ := l.finish(tok, 0)
+			operands = append(operands, v)
+			continue
+		}
+
+		switch op {
+		case "q":
+			stack = append(stack, gs)
+		case "Q":
+			if n := len(stack); n > 0 {
+				gs = stack[n-1]
+				stack = stack[:n-1]
+			}
+		case "cm":
+			if m, ok := matrixOperands(operands); ok {
+				gs.ctm = m.mul(gs.ctm)
+			}
+		case "BT":
+			tm, tlm = identity, identity
+		case "ET":
+		case "Tf":
+			if len(operands) >= 2 {
+				if fname, ok := operands[0].(name); ok {
+					gs.font = d.loadFont(fonts[fname])
+				}
+				gs.fontSize = toFloat(operands[1])
+			}
+		case "Tc":
+			if len(operands) >= 1 {
+				gs.charSpace = toFloat(operands[0])
+			}
+		case "Tw":
+			if len(operands) >= 1 {
+				gs.wordSpace = toFloat(operands[0])
+			}
+		case "Tz":
+			if len(operands) >= 1 {
+				gs.hScale = toFloat(operands[0]) / 100
+			}
+		case "TL":
+			if len(operands) >= 1 {
+				gs.leading = toFloat(operands[0])
+			}
+		case "Td":
+			if len(operands) >= 2 {
+				nextLine(toFloat(operands[0]), toFloat(operands[1]))
+			}
+		case "TD":
+			if len(operands) >= 2 {
+				gs.leading = -toFloat(operands[1])
+				nextLine(toFloat(operands[0]), toFloat(operands[1]))
+			}
+		case "Tm":
+			if m, ok := matrixOperands(operands); ok {
+				tm, tlm = m, m
+			}
+		case "T*":
+			nextLine(0, -gs.leading)
+		case "Tj":
+			if len(operands) >= 1 {
+				if s, ok := operands[len(operands)-1].(str); ok {
+					show(s)
+				}
+			}
+		case "'", "\"":
+			if op == "\"" && len(operands) >= 3 {
+				gs.wordSpace = toFloat(operands[0])
+				gs.charSpace = toFloat(operands[1])
+			}
+			nextLine(0, -gs.leading)
+			if len(operands) >= 1 {
+				if s, ok := operands[len(operands)-1].(str); ok {
+					show(s)
+				}
+			}
+		case "TJ":
+			if len(operands) >= 1 {
+				if arr, ok := operands[len(operands)-1].(array); ok {
+					for _, item := range arr {
+						switch v := item.(type) {
+						case str:
+							show(v)
+						case int64, float64:
+							tx := -toFloat(v) / 1000 * gs.fontSize * gs.hScale
+							tm = translate(tx, 0).mul(tm)
+						}
+					}
+				}
+			}
+		case "Do":
+			if len(operands) >= 1 && depth < maxFormDepth {
+				if xname, ok := operands[0].(name); ok {
+					d.runForm(xobjects[xname], res, gs, w, depth)
+				}
+			}
+		case "BI":
+			skipInlineImage(l)
+		}
+		operands = operands[:0]
+	}
+}
+
+// runForm executes a form XObject with its own resources and matrix.
+func (d *document) runForm(v any, parentRes dict, gs textState, w *textWriter, depth int) {
+	s, ok := d.resolve(v).(*stream)
+	if !ok || s.dict["Subtype"] != name("Form") {
+		return
+	}
+	data, err := d.decodeStream(s)
+	if err != nil {
+		return
+	}
+	res, ok := d.resolve(s.dict["Resources"]).(dict)
+	if !ok {
+		res = parentRes
+	}
+	if m, ok := d.resolve(s.dict["Matrix"]).(array); ok {
+		if fm, ok := matrixOperands(m); ok {
+			gs.ctm = fm.mul(gs.ctm)
+		}
+	}
+	d.runContent(data, res, gs, w, depth+1)
+}
+
+// skipInlineImage moves past inline image data (BI ... ID <data> EI).
+func skipInlineImage(l *lexer) {
+	idx := bytes.Index(l.data[l.pos:], []byte("ID"))
+	if idx < 0 {
+		l.pos = len(l.data)
+		return
+	}
+	l.pos += idx + 3
+	for l.pos < len(l.data) {
+		i := bytes.Index(l.data[l.pos:], []byte("EI"))
+		if i < 0 {
+			l.pos = len(l.data)
+			return
+		}
+		at := l.pos + i
+		before := at == 0 || isWhitespace(l.data[at-1])
+		after := at+2 >= len(l.data) || isWhitespace(l.data[at+2])
+		l.pos = at + 2
+		if before && after {
+			return
+		}
+	}
+}
+
+func matrixOperands(ops []any) (matrix, bool) {
+	if len(ops) < 6 {
+		return matrix{}, false
+	}
+	var m matrix
+	for i := 0; i < 6; i++ {
+		switch v := ops[len(ops)-6+i].(type) {
+		case int64:
+			m[i] = float64(v)
+		case float64:
+			m[i] = v
+		default:
+			return matrix{}, false
+		}
+	}
+	return m, true
+}
+
+func toFloat(v any) float64 {
+	switch n := v.(type) {
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
+
+// textWriter assembles shown glyphs into lines and paragraphs using their
+// positions on the page.
+type textWriter struct {
+	paragraphs [][]string
+	line       strings.Builder
+	started    bool
+	lastX      float64 // end of the previous glyph
+	lastY      float64 // baseline of the current line
+	lineSize   float64
+	lineGap    float64 // typical baseline distance within the current paragraph
+}
+
+// place positions the writer for a glyph at (x, y) of the given size,
+// starting new lines, paragraphs or words as needed.
+func (w *textWriter) place(x, y, size float64) {
+	if size <= 0 {
+		size = 1
+	}
+	if !w.started {
+		w.started = true
+		w.lastX, w.lastY, w.lineSize = x, y, size
+		return
+	}
+
+	dy := w.lastY - y
+	ref := math.Max(size, w.lineSize)
+	switch {
+	case math.Abs(dy) > ref*0.5:
+		// New line; a large or upward jump starts a new paragraph.
+		gap := math.Abs(dy)
+		newParagraph := dy < 0 || gap > ref*2.2 || (w.lineGap > 0 && gap > w.lineGap*1.4)
+		w.endLine(newParagraph)
+		if newParagraph {
+			w.lineGap = 0
+		} else {
+			w.lineGap = gap
+		}
+		w.lastY, w.lineSize = y, size
+	case x-w.lastX > size*0.15 || w.lastX-x > size*4:
+		// Horizontal gap (or a jump back) on the same line separates words.
+		w.space()
+	}
+	w.lastX = x
+}
+
+func (w *textWriter) write(s string) {
+	if s == "" {
+		return
+	}
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			w.space()
+			continue
+		}
+		w.line.WriteString(expandLigature(r))
+	}
+}
+
+func (w *textWriter) space() {
+	if w.line.Len() > 0 && !strings.HasSuffix(w.line.String(), " ") {
+		w.line.WriteByte(' ')
+	}
+}
+
+func (w *textWriter) endLine(newParagraph bool) {
+	text := strings.TrimSpace(w.line.String())
+	w.line.Reset()
+	if text != "" {
+		if len(w.paragraphs) == 0 {
+			w.paragraphs = append(w.paragraphs, nil)
+		}
+		last := len(w.paragraphs) - 1
+		w.paragraphs[last] = append(w.paragraphs[last], text)
+	}
+	if newParagraph && len(w.paragraphs) > 0 && len(w.paragraphs[len(w.paragraphs)-1]) > 0 {
+		w.paragraphs = append(w.paragraphs, nil)
+	}
+}
+
+// String returns the page text with paragraphs separated by blank lines.
+func (w *textWriter) String() string {
+	w.endLine(false)
+	var out []string
+	for _, lines := range w.paragraphs {
+		if para := joinLines(lines); para != "" {
+			out = append(out, para)
+		}
+	}
+	return strings.Join(out, "\n\n")
+}
+
+// joinLines joins the lines of a paragraph, removing end-of-line hyphenation
+// when the word continues in lower case on the next line.
+func joinLines(lines []string) string {
+	var sb strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			prev := lines[i-1]
+			first, _ := firstRune(line)
+			if strings.HasSuffix(prev, "-") && unicode.IsLower(first) && unicode.IsLetter(lastRune(prev[:len(prev)-1])) {
+				// A word that already contains a hyphen is a compound
+				// ("state-of-the-art") and keeps it.
+				if !strings.Contains(lastWord(prev[:len(prev)-1]), "-") {
+					s := sb.String()
+					sb.Reset()
+					sb.WriteString(s[:len(s)-1])
+				}
+			} else {
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteString(line)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+func firstRune(s string) (rune, bool) {
+	for _, r := range s {
+		return r, true
+	}
+	return 0, false
+}
+
+func lastRune(s string) rune {
+	r := []rune(s)
+	if len(r) == 0 {
+		return 0
+	}
+	return r[len(r)-1]
+}
+
+func lastWord(s string) string {
+	if i := strings.LastIndexByte(s, ' '); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
+
+func expandLigature(r rune) string {
+	switch r {
+	case 'ﬀ':
+		return "ff"
+	case 'ﬁ':
+		return "fi"
+	case 'ﬂ':
+		return "fl"
+	case 'ﬃ':
+		return "ffi"
+	case 'ﬄ':
+		return "ffl"
+	case 'ﬅ', 'ﬆ':
+		return "st"
+	case '­':
+		return ""
+	}
+	return string(r)
+}
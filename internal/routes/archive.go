@@ -0,0 +1,94 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jgordijn/knowledgehub/internal/engine"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// archiveCSP keeps archived pages inert when served from our origin: no
+// scripts, no network access, and a sandbox so the page cannot reach the
+// app's storage or cookies.
+const archiveCSP = "default-src 'none'; img-src data: https: http:; style-src 'unsafe-inline' data:; font-src data:; sandbox"
+
+// ArchiveDTO describes the snapshot of an entry.
+type ArchiveDTO struct {
+	ID           string `json:"id"`
+	URL          string `json:"url"`
+	Size         int    `json:"size"`
+	CapturedWith string `json:"captured_with"`
+	ArchivedAt   string `json:"archived_at"`
+}
+
+// RegisterArchiveRoutes adds the endpoints to serve and create entry snapshots.
+func RegisterArchiveRoutes(se *core.ServeEvent) {
+	// GET /api/entries/{id}/archive — serve the archived HTML snapshot
+	se.Router.GET("/api/entries/{id}/archive", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		status, snapshot, err := HandleGetArchiveDirect(re.App, re.Request.PathValue("id"))
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		re.Response.Header().Set("Content-Security-Policy", archiveCSP)
+		re.Response.Header().Set("X-Content-Type-Options", "nosniff")
+		return re.Blob(status, "text/html; charset=utf-8", snapshot)
+	})
+
+	// POST /api/entries/{id}/archive — snapshot the entry now
+	se.Router.POST("/api/entries/{id}/archive", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		status, dto, err := HandleCreateArchiveDirect(re.App, re.Request.PathValue("id"), engine.DefaultHTTPClient)
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, dto)
+	})
+}
+
+// HandleGetArchiveDirect is the testable core logic for serving a snapshot.
+func HandleGetArchiveDirect(app core.App, entryID string) (int, []byte, error) {
+	entry, err := app.FindRecordById("entries", entryID)
+	if err != nil {
+		return http.StatusNotFound, nil, errors.New("Entry not found.")
+	}
+	archiveID := entry.GetString("archive")
+	if archiveID == "" {
+		return http.StatusNotFound, nil, errors.New("Entry has not been archived.")
+	}
+	archive, err := app.FindRecordById("archives", archiveID)
+	if err != nil {
+		return http.StatusNotFound, nil, errors.New("Archive not found.")
+	}
+	snapshot, err := engine.ReadArchive(app, archive)
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("reading archive: %w", err)
+	}
+	return http.StatusOK, snapshot, nil
+}
+
+// HandleCreateArchiveDirect snapshots an entry on demand, regardless of
+// whether automatic archiving is enabled.
+func HandleCreateArchiveDirect(app core.App, entryID string, client *http.Client) (int, ArchiveDTO, error) {
+	entry, err := app.FindRecordById("entries", entryID)
+	if err != nil {
+		return http.StatusNotFound, ArchiveDTO{}, errors.New("Entry not found.")
+	}
+	archive, err := engine.ArchiveEntry(app, entry, client)
+	if err != nil {
+		return http.StatusBadGateway, ArchiveDTO{}, fmt.Errorf("archiving failed: %w", err)
+	}
+	return http.StatusOK, ArchiveDTO{
+		ID:           archive.Id,
+		URL:          archive.GetString("url"),
+		Size:         archive.GetInt("size"),
+		CapturedWith: archive.GetString("captured_with"),
+		ArchivedAt:   entry.GetString("archived_at"),
+	}, nil
+}
@@ -0,0 +1,97 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+)
+
+func TestArchiveRoutesRequireAuth(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	mux := buildMux(t, app)
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		req := httptest.NewRequest(method, "/api/entries/abc/archive", nil)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s status = %d, want 401", method, rec.Code)
+		}
+	}
+}
+
+func TestArchiveRoutes_CreateAndServeSnapshot(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	mux := buildMux(t, app)
+	token := createAuthToken(t, app)
+
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><script>alert(1)</script></head><body><h1>Keep me</h1></body></html>`)
+	}))
+	defer page.Close()
+
+	res := testutil.CreateResource(t, app, "Blog", page.URL, "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Post", page.URL+"/post", "g1")
+
+	// Not archived yet.
+	req := httptest.NewRequest(http.MethodGet, "/api/entries/"+entry.Id+"/archive", nil)
+	req.Header.Set("Authorization", token)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("GET before archiving = %d, want 404", rec.Code)
+	}
+
+	status, dto, err := HandleCreateArchiveDirect(app, entry.Id, page.Client())
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("HandleCreateArchiveDirect = %d, %v", status, err)
+	}
+	if dto.ID == "" || dto.CapturedWith != "http" || dto.Size == 0 {
+		t.Errorf("unexpected archive DTO: %+v", dto)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET archive = %d body=%s", rec.Code, rec.Body.String())
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("Content-Type = %q", rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Header().Get("Content-Security-Policy"), "sandbox") {
+		t.Errorf("expected sandboxing CSP, got %q", rec.Header().Get("Content-Security-Policy"))
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "<h1>Keep me</h1>") || strings.Contains(body, "alert(1)") {
+		t.Errorf("unexpected snapshot body: %s", body)
+	}
+}
+
+func TestHandleCreateArchiveDirect_Errors(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	if status, _, err := HandleCreateArchiveDirect(app, "missing", http.DefaultClient); err == nil || status != http.StatusNotFound {
+		t.Errorf("missing entry = %d, %v; want 404", status, err)
+	}
+
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer page.Close()
+	res := testutil.CreateResource(t, app, "Blog", page.URL, "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Gone", page.URL+"/gone", "g1")
+	if status, _, err := HandleCreateArchiveDirect(app, entry.Id, page.Client()); err == nil || status != http.StatusBadGateway {
+		t.Errorf("unreachable page = %d, %v; want 502", status, err)
+	}
+	if status, _, err := HandleGetArchiveDirect(app, "missing"); err == nil || status != http.StatusNotFound {
+		t.Errorf("missing entry = %d, %v; want 404", status, err)
+	}
+}
@@ -1,9 +1,12 @@
 package routes
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
 
 	"github.com/jgordijn/knowledgehub/internal/ai"
 	"github.com/pocketbase/pocketbase/core"
@@ -14,9 +17,17 @@ type ChatRequestBody struct {
 	EntryID      string       `json:"entry_id"`
 	Messages     []ai.Message `json:"messages"`
 	ExtraContext string       `json:"extra_context,omitempty"`
+	// Language is the user's summary language; a translated article body
+	// stored in it is used instead of the original. Signed-in users who
+	// translate articles get the body translated on their first chat.
+	Language string `json:"language,omitempty"`
+	// SessionID continues a saved chat session. Signed-in requests without
+	// one start a new session.
+	SessionID string `json:"session_id,omitempty"`
 }
 
-// RegisterChatRoute adds the POST /api/chat streaming endpoint.
+// RegisterChatRoute adds the POST /api/chat streaming endpoint. Signed-in
+// conversations are saved as chat sessions.
 func RegisterChatRoute(se *core.ServeEvent) {
 	se.Router.POST("/api/chat", func(re *core.RequestEvent) error {
 		return handleChat(re)
@@ -36,6 +47,13 @@ func handleChat(re *core.RequestEvent) error {
 	app := re.App
 	w := re.Response
 
+	if re.Auth != nil {
+		status, err := HandleChatSessionDirect(re.Request.Context(), app, w, re.Auth.Id, body, "")
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return nil
+	}
 	return HandleChatDirect(app, w, body, "")
 }
 
@@ -51,52 +69,122 @@ func HandleChatDirect(app core.App, w http.ResponseWriter, body ChatRequestBody,
 		return fmt.Errorf("entry not found: %w", err)
 	}
 
-	rawContent := entry.GetString("raw_content")
+	recordChatInteraction(app, entry)
+
+	client, messages, err := prepareChat(app, entry, body, "", baseURL)
+	if err != nil {
+		return err
+	}
+	streamChatReply(context.Background(), w, client, messages)
+	return nil
+}
+
+// prepareChat builds the client and the prompt for a conversation about an
+// entry. userID is empty for anonymous chats.
+func prepareChat(app core.App, entry *core.Record, body ChatRequestBody, userID, baseURL string) (*ai.Client, []ai.Message, error) {
+	rawContent := chatArticle(app, entry, userID, body.Language)
 	title := entry.GetString("title")
 
 	apiKey, err := ai.GetAPIKey(app)
 	if err != nil {
-		return fmt.Errorf("API key not configured: %w", err)
+		return nil, nil, fmt.Errorf("API key not configured: %w", err)
 	}
 	model := ai.GetModel(app)
 
+	pages := ai.EntryPDFPages(entry)
+	longArticle := false
+	if len(pages) > 0 {
+		rawContent = ai.SelectPDFContext(pages, lastUserMessage(body.Messages), pdfChatContextChars)
+	} else if len(rawContent) > chatContextChars {
+		// Long articles: send the parts most relevant to the question
+		// rather than only the beginning.
+		rawContent = ai.SelectChunkContext(rawContent, lastUserMessage(body.Messages), chatContextChars)
+		longArticle = true
+	}
+
 	messages := BuildChatMessages(title, rawContent, body.Messages, body.ExtraContext)
+	if len(pages) > 0 {
+		messages[0].Content += "\n\n" + pdfCitationInstruction
+	}
+	if longArticle {
+		messages[0].Content += "\n\n" + chunkContextInstruction
+	}
 	client := ai.NewClient(apiKey, model)
 	if baseURL != "" {
 		client.BaseURL = baseURL
 	}
+	return client, messages, nil
+}
+
+// chatArticle returns the article body to chat about: the stored
+// translation into language if there is one, or for users who translate
+// articles a translation made now and stored for the next chat. A failed
+// translation falls back to the original article.
+func chatArticle(app core.App, entry *core.Record, userID, language string) string {
+	if t, ok := ai.TranslationFor(entry, language); ok && t.Content != "" {
+		return t.Content
+	}
+	if userID != "" && language != "" {
+		pref := ai.UserLanguagePreference(app, userID)
+		if pref.Language == language && pref.TranslateArticles && ai.NeedsTranslation(entry, pref) {
+			content, err := ai.TranslateArticle(app, entry, language)
+			if err != nil {
+				log.Printf("Translating entry %s for chat failed: %v", entry.Id, err)
+			} else if content != "" {
+				return content
+			}
+		}
+	}
+	return ai.EntryMarkdown(entry)
+}
 
-	// Set SSE headers
+func recordChatInteraction(app core.App, entry *core.Record) {
+	if _, err := ai.RecordInteraction(app, entry.Id, ai.InteractionChatted, 0); err != nil {
+		log.Printf("Failed to record chat interaction for %s: %v", entry.Id, err)
+	}
+}
+
+// setSSEHeaders prepares w for a server-sent event stream.
+func setSSEHeaders(w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
+}
 
-	err = client.CompleteStream(messages, func(chunk string) error {
-		data, _ := json.Marshal(map[string]string{"content": chunk})
-		_, writeErr := fmt.Fprintf(w, "data: %s\n\n", data)
-		if writeErr != nil {
-			return writeErr
-		}
-		if f, ok := w.(http.Flusher); ok {
-			f.Flush()
+// writeSSE sends one data event and flushes it to the client.
+func writeSSE(w http.ResponseWriter, data string) error {
+	if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+		return err
+	}
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// streamChatReply streams the model's reply to w as server-sent events. It
+// returns the text streamed so far and the error that ended the stream early:
+// a failed request, a failed write or a cancelled ctx when the client went
+// away.
+func streamChatReply(ctx context.Context, w http.ResponseWriter, client *ai.Client, messages []ai.Message) (string, error) {
+	setSSEHeaders(w)
+
+	var reply strings.Builder
+	err := client.CompleteStream(messages, func(chunk string) error {
+		if err := ctx.Err(); err != nil {
+			return err
 		}
-		return nil
+		reply.WriteString(chunk)
+		data, _ := json.Marshal(map[string]string{"content": chunk})
+		return writeSSE(w, string(data))
 	})
 
 	if err != nil {
 		errData, _ := json.Marshal(map[string]string{"error": err.Error()})
-		fmt.Fprintf(w, "data: %s\n\n", errData)
-		if f, ok := w.(http.Flusher); ok {
-			f.Flush()
-		}
+		writeSSE(w, string(errData))
 	}
-
-	fmt.Fprintf(w, "data: [DONE]\n\n")
-	if f, ok := w.(http.Flusher); ok {
-		f.Flush()
-	}
-
-	return nil
+	writeSSE(w, "[DONE]")
+	return reply.String(), err
 }
 
 // NewChatHandler returns an http.Handler for testing the chat endpoint outside PocketBase.
@@ -117,6 +205,28 @@ func NewChatHandler(app core.App, baseURL string) http.Handler {
 	})
 }
 
+// pdfChatContextChars is the budget for PDF excerpts in the chat prompt
+// (matching the article truncation in buildChatSystemPrompt).
+const pdfChatContextChars = 8000
+
+// chatContextChars is the article budget in the chat prompt; longer articles
+// are reduced to the parts most relevant to the question.
+const chatContextChars = 8000
+
+const chunkContextInstruction = "The article is long; the excerpts above are the parts most relevant to the question, labelled with their part and section. If the answer may be in a part that is not included, say so."
+
+const pdfCitationInstruction = "The article is a PDF document; the excerpts above are the parts most relevant to the question, labelled with their pages. Cite the page numbers you rely on, e.g. (p. 3)."
+
+// lastUserMessage returns the content of the most recent user message.
+func lastUserMessage(messages []ai.Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
+
 // ValidateChatRequest checks required fields in the request body.
 func ValidateChatRequest(body ChatRequestBody) error {
 	if body.EntryID == "" {
@@ -145,13 +255,9 @@ func BuildChatMessages(title, rawContent string, userMessages []ai.Message, extr
 }
 
 func buildChatSystemPrompt(title, content, extraContext string) string {
-	if len(content) > 8000 {
-		content = content[:8000] + "..."
-	}
+	content = ai.TruncateUTF8(content, chatContextChars)
 	if extraContext != "" {
-		if len(extraContext) > 8000 {
-			extraContext = extraContext[:8000] + "..."
-		}
+		extraContext = ai.TruncateUTF8(extraContext, chatContextChars)
 		return fmt.Sprintf(
 			"Answer ONLY based on the articles below. If the answer is not in the articles, say so.\n\nMain Article: %s\n\n%s\n\nLinked Article:\n%s",
 			title, content, extraContext,
@@ -224,3 +224,109 @@ func TestChatHTTPHandler_InvalidJSON(t *testing.T) {
 		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
 	}
 }
+
+func TestHandleChat_PDFEntryUsesRelevantPages(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	var systemPrompt string
+	aiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []ai.Message `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Messages) > 0 {
+			systemPrompt = req.Messages[0].Content
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintln(w, `data: {"choices":[{"delta":{"content":"See p. 7"}}]}`)
+		fmt.Fprintln(w, "data: [DONE]")
+	}))
+	defer aiServer.Close()
+
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	testutil.CreateSetting(t, app, "openrouter_model", "test-model")
+
+	pages := make([]string, 12)
+	for i := range pages {
+		pages[i] = strings.Repeat(fmt.Sprintf("Filler text on page %d. ", i+1), 60)
+	}
+	pages[6] = "The evaluation uses the BLEU metric on newstest2014. " + pages[6]
+
+	resource := testutil.CreateResource(t, app, "test", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, resource.Id, "Paper", "https://example.com/paper.pdf", "g1")
+	entry.Set("raw_content", strings.Join(pages, "\n\n"))
+	entry.Set("pdf_pages", pages)
+	if err := app.Save(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	req := ChatRequestBody{
+		EntryID:  entry.Id,
+		Messages: []ai.Message{{Role: "user", Content: "Which BLEU metric does the evaluation use?"}},
+	}
+	if err := HandleChatDirect(app, httptest.NewRecorder(), req, aiServer.URL); err != nil {
+		t.Fatalf("HandleChatDirect error: %v", err)
+	}
+
+	if !strings.Contains(systemPrompt, "[Page 7]\nThe evaluation uses the BLEU metric") {
+		t.Errorf("expected the relevant page in the prompt, got: %.500s", systemPrompt)
+	}
+	if strings.Contains(systemPrompt, "[Page 12]") {
+		t.Error("expected irrelevant pages to be left out once the budget is used")
+	}
+	if !strings.Contains(systemPrompt, "Cite the page numbers") {
+		t.Error("expected the page citation instruction")
+	}
+}
+
+func TestHandleChat_LongArticleUsesRelevantParts(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	var systemPrompt string
+	aiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []ai.Message `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Messages) > 0 {
+			systemPrompt = req.Messages[0].Content
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintln(w, `data: {"choices":[{"delta":{"content":"Kubernetes"}}]}`)
+		fmt.Fprintln(w, "data: [DONE]")
+	}))
+	defer aiServer.Close()
+
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	testutil.CreateSetting(t, app, "openrouter_model", "test-model")
+
+	var sections []string
+	for i := 1; i <= 10; i++ {
+		sections = append(sections, fmt.Sprintf("## Section %d\n\n%s", i, strings.Repeat(fmt.Sprintf("Filler prose for section %d. ", i), 60)))
+	}
+	sections[8] = "## Deployment\n\nThe service is deployed on Kubernetes with three replicas.\n\n" + strings.Repeat("More filler. ", 60)
+
+	resource := testutil.CreateResource(t, app, "test", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, resource.Id, "Essay", "https://example.com/essay", "g1")
+	entry.Set("content_markdown", strings.Join(sections, "\n\n"))
+	if err := app.Save(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	req := ChatRequestBody{
+		EntryID:  entry.Id,
+		Messages: []ai.Message{{Role: "user", Content: "Where is the service deployed, which Kubernetes setup?"}},
+	}
+	if err := HandleChatDirect(app, httptest.NewRecorder(), req, aiServer.URL); err != nil {
+		t.Fatalf("HandleChatDirect error: %v", err)
+	}
+
+	if !strings.Contains(systemPrompt, "Deployment]\n## Deployment\n\nThe service is deployed on Kubernetes") {
+		t.Errorf("expected the relevant part in the prompt, got: %.600s", systemPrompt)
+	}
+	if !strings.Contains(systemPrompt, "the excerpts above are the parts most relevant") {
+		t.Error("expected the excerpt instruction")
+	}
+}
@@ -0,0 +1,402 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// maxChatSessionTitle is the maximum length of a chat session title in
+// characters.
+const maxChatSessionTitle = 200
+
+// defaultChatSessionTitleChars is the length of the title a new session gets
+// from its first question.
+const defaultChatSessionTitleChars = 80
+
+// ChatSessionDTO is a saved conversation about an entry.
+type ChatSessionDTO struct {
+	ID           string `json:"id"`
+	EntryID      string `json:"entry_id"`
+	EntryTitle   string `json:"entry_title"`
+	Title        string `json:"title"`
+	MessageCount int    `json:"message_count"`
+	Created      string `json:"created"`
+	Updated      string `json:"updated"`
+}
+
+// ChatSessionListDTO is the list of a user's chat sessions, most recently
+// continued first.
+type ChatSessionListDTO struct {
+	Sessions []ChatSessionDTO `json:"sessions"`
+}
+
+// ChatMessageDTO is one message of a chat session. Partial marks an answer
+// whose stream ended early.
+type ChatMessageDTO struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	Partial bool   `json:"partial"`
+	Created string `json:"created"`
+}
+
+// ChatSessionDetailDTO is a chat session with its messages, for resuming it.
+type ChatSessionDetailDTO struct {
+	ChatSessionDTO
+	Messages []ChatMessageDTO `json:"messages"`
+}
+
+// ChatSessionTitleInput renames a chat session.
+type ChatSessionTitleInput struct {
+	Title string `json:"title"`
+}
+
+// RegisterChatSessionRoutes adds the endpoints to list, resume, rename,
+// export and delete saved chat sessions.
+func RegisterChatSessionRoutes(se *core.ServeEvent) {
+	// GET /api/chat/sessions — the user's sessions, optionally for one entry
+	se.Router.GET("/api/chat/sessions", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		status, dto, err := HandleListChatSessionsDirect(re.App, re.Auth.Id, re.Request.URL.Query().Get("entry_id"))
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, dto)
+	})
+
+	// GET /api/chat/sessions/{id} — a session with its messages
+	se.Router.GET("/api/chat/sessions/{id}", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		status, dto, err := HandleGetChatSessionDirect(re.App, re.Auth.Id, re.Request.PathValue("id"))
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, dto)
+	})
+
+	// PATCH /api/chat/sessions/{id} — rename a session
+	se.Router.PATCH("/api/chat/sessions/{id}", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		var body ChatSessionTitleInput
+		if err := re.BindBody(&body); err != nil {
+			return re.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body."})
+		}
+		status, dto, err := HandleRenameChatSessionDirect(re.App, re.Auth.Id, re.Request.PathValue("id"), body)
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, dto)
+	})
+
+	// GET /api/chat/sessions/{id}/export — download a session as Markdown
+	se.Router.GET("/api/chat/sessions/{id}/export", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		status, filename, markdown, err := HandleExportChatSessionDirect(re.App, re.Auth.Id, re.Request.PathValue("id"))
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		re.Response.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+		return re.Blob(status, "text/markdown; charset=utf-8", []byte(markdown))
+	})
+
+	// DELETE /api/chat/sessions/{id} — delete a session and its messages
+	se.Router.DELETE("/api/chat/sessions/{id}", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		status, err := HandleDeleteChatSessionDirect(re.App, re.Auth.Id, re.Request.PathValue("id"))
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.NoContent(status)
+	})
+}
+
+// HandleChatSessionDirect is the testable core logic of a signed-in chat. It
+// continues body.SessionID, or starts a session when it is empty, and only
+// the last message of body.Messages is taken as the new question when
+// continuing. The session id is sent as the first event, before the answer is
+// streamed. The question is saved together with the answer when the stream
+// ends, also when it ended early because the model failed or the client
+// disconnected (ctx is cancelled); such an answer is marked partial, and is
+// empty when nothing was streamed, so questions and answers keep alternating.
+// Errors returned before the stream starts carry an HTTP status.
+func HandleChatSessionDirect(ctx context.Context, app core.App, w http.ResponseWriter, userID string, body ChatRequestBody, baseURL string) (int, error) {
+	if err := ValidateChatRequest(body); err != nil {
+		return http.StatusBadRequest, err
+	}
+	question := body.Messages[len(body.Messages)-1]
+	if question.Role != "user" || strings.TrimSpace(question.Content) == "" {
+		return http.StatusBadRequest, errors.New("The last message must be a question from the user.")
+	}
+	entry, err := app.FindRecordById("entries", body.EntryID)
+	if err != nil {
+		return http.StatusNotFound, errors.New("Entry not found.")
+	}
+
+	var session *core.Record
+	pending := body.Messages
+	history := body.Messages[:len(body.Messages)-1]
+	if body.SessionID != "" {
+		session, err = findChatSession(app, userID, body.SessionID)
+		if err != nil || session.GetString("entry") != entry.Id {
+			return http.StatusNotFound, errors.New("Chat session not found.")
+		}
+		stored, err := chatSessionMessages(app, session.Id)
+		if err != nil {
+			return http.StatusInternalServerError, fmt.Errorf("Failed to load the chat session: %v", err)
+		}
+		history = make([]ai.Message, 0, len(stored))
+		for _, m := range stored {
+			history = append(history, ai.Message{Role: m.GetString("role"), Content: m.GetString("content")})
+		}
+		pending = []ai.Message{question}
+	}
+	body.Messages = append(append([]ai.Message{}, history...), question)
+
+	recordChatInteraction(app, entry)
+
+	client, messages, err := prepareChat(app, entry, body, userID, baseURL)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	if session == nil {
+		session, err = createChatSession(app, userID, entry.Id, defaultChatSessionTitle(question.Content))
+		if err != nil {
+			return http.StatusInternalServerError, fmt.Errorf("Failed to save the chat session: %v", err)
+		}
+	}
+
+	setSSEHeaders(w)
+	data, _ := json.Marshal(map[string]string{"session_id": session.Id})
+	writeSSE(w, string(data))
+
+	reply, streamErr := streamChatReply(ctx, w, client, messages)
+	if err := saveChatTurn(app, session.Id, pending, reply, streamErr != nil || reply == ""); err != nil {
+		log.Printf("Failed to save chat turn for session %s: %v", session.Id, err)
+	}
+	// Saving bumps updated, which orders the session list.
+	if err := app.Save(session); err != nil {
+		log.Printf("Failed to update chat session %s: %v", session.Id, err)
+	}
+	return http.StatusOK, nil
+}
+
+// HandleListChatSessionsDirect returns the user's chat sessions, most
+// recently continued first. A non-empty entryID limits them to that entry.
+func HandleListChatSessionsDirect(app core.App, userID, entryID string) (int, ChatSessionListDTO, error) {
+	filter := "user = {:user}"
+	params := dbx.Params{"user": userID}
+	if entryID != "" {
+		filter += " && entry = {:entry}"
+		params["entry"] = entryID
+	}
+	records, err := app.FindRecordsByFilter("chat_sessions", filter, "-updated", 0, 0, params)
+	if err != nil {
+		return http.StatusInternalServerError, ChatSessionListDTO{}, fmt.Errorf("Failed to load chat sessions: %v", err)
+	}
+	dto := ChatSessionListDTO{Sessions: make([]ChatSessionDTO, 0, len(records))}
+	for _, record := range records {
+		dto.Sessions = append(dto.Sessions, chatSessionDTO(app, record))
+	}
+	return http.StatusOK, dto, nil
+}
+
+// HandleGetChatSessionDirect returns a chat session with its messages.
+func HandleGetChatSessionDirect(app core.App, userID, sessionID string) (int, ChatSessionDetailDTO, error) {
+	session, err := findChatSession(app, userID, sessionID)
+	if err != nil {
+		return http.StatusNotFound, ChatSessionDetailDTO{}, errors.New("Chat session not found.")
+	}
+	messages, err := chatSessionMessages(app, session.Id)
+	if err != nil {
+		return http.StatusInternalServerError, ChatSessionDetailDTO{}, fmt.Errorf("Failed to load the chat session: %v", err)
+	}
+	dto := ChatSessionDetailDTO{ChatSessionDTO: chatSessionDTO(app, session), Messages: make([]ChatMessageDTO, 0, len(messages))}
+	for _, m := range messages {
+		dto.Messages = append(dto.Messages, ChatMessageDTO{
+			Role:    m.GetString("role"),
+			Content: m.GetString("content"),
+			Partial: m.GetBool("partial"),
+			Created: m.GetDateTime("created").String(),
+		})
+	}
+	return http.StatusOK, dto, nil
+}
+
+// HandleRenameChatSessionDirect is the testable core logic for renaming a
+// chat session.
+func HandleRenameChatSessionDirect(app core.App, userID, sessionID string, input ChatSessionTitleInput) (int, ChatSessionDTO, error) {
+	title := strings.TrimSpace(input.Title)
+	if title == "" {
+		return http.StatusBadRequest, ChatSessionDTO{}, errors.New("Title is required.")
+	}
+	if utf8.RuneCountInString(title) > maxChatSessionTitle {
+		return http.StatusBadRequest, ChatSessionDTO{}, fmt.Errorf("Title must be at most %d characters.", maxChatSessionTitle)
+	}
+	session, err := findChatSession(app, userID, sessionID)
+	if err != nil {
+		return http.StatusNotFound, ChatSessionDTO{}, errors.New("Chat session not found.")
+	}
+	session.Set("title", title)
+	if err := app.Save(session); err != nil {
+		return http.StatusInternalServerError, ChatSessionDTO{}, fmt.Errorf("Failed to rename the chat session: %v", err)
+	}
+	return http.StatusOK, chatSessionDTO(app, session), nil
+}
+
+// HandleExportChatSessionDirect returns a chat session as a Markdown document
+// and the file name to download it as.
+func HandleExportChatSessionDirect(app core.App, userID, sessionID string) (int, string, string, error) {
+	status, dto, err := HandleGetChatSessionDirect(app, userID, sessionID)
+	if err != nil {
+		return status, "", "", err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", dto.Title)
+	if entry, err := app.FindRecordById("entries", dto.EntryID); err == nil {
+		fmt.Fprintf(&b, "Article: [%s](%s)\n", entry.GetString("title"), entry.GetString("url"))
+	}
+	for _, m := range dto.Messages {
+		speaker := "You"
+		if m.Role == "assistant" {
+			speaker = "Assistant"
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n%s\n", speaker, strings.TrimSpace(m.Content))
+		if m.Partial {
+			b.WriteString("\n_The answer was interrupted._\n")
+		}
+	}
+	return http.StatusOK, "chat-" + dto.ID + ".md", b.String(), nil
+}
+
+// HandleDeleteChatSessionDirect deletes a chat session and its messages.
+func HandleDeleteChatSessionDirect(app core.App, userID, sessionID string) (int, error) {
+	session, err := findChatSession(app, userID, sessionID)
+	if err != nil {
+		return http.StatusNotFound, errors.New("Chat session not found.")
+	}
+	if err := app.Delete(session); err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("Failed to delete the chat session: %v", err)
+	}
+	return http.StatusNoContent, nil
+}
+
+// findChatSession returns the session when it belongs to the user.
+func findChatSession(app core.App, userID, sessionID string) (*core.Record, error) {
+	session, err := app.FindRecordById("chat_sessions", sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.GetString("user") != userID {
+		return nil, errors.New("chat session belongs to another user")
+	}
+	return session, nil
+}
+
+func chatSessionMessages(app core.App, sessionID string) ([]*core.Record, error) {
+	return app.FindRecordsByFilter("chat_messages", "session = {:session}", "position", 0, 0, dbx.Params{"session": sessionID})
+}
+
+func createChatSession(app core.App, userID, entryID, title string) (*core.Record, error) {
+	col, err := app.FindCollectionByNameOrId("chat_sessions")
+	if err != nil {
+		return nil, err
+	}
+	session := core.NewRecord(col)
+	session.Set("user", userID)
+	session.Set("entry", entryID)
+	session.Set("title", title)
+	if err := app.Save(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// saveChatTurn appends the new messages and the answer to a session in one
+// transaction, numbering them after the last stored message so concurrent
+// requests on a session cannot take the same positions.
+func saveChatTurn(app core.App, sessionID string, pending []ai.Message, reply string, partial bool) error {
+	return app.RunInTransaction(func(txApp core.App) error {
+		var next struct {
+			Position int `db:"position"`
+		}
+		err := txApp.DB().Select("COALESCE(MAX(position) + 1, 0) AS position").
+			From("chat_messages").
+			Where(dbx.HashExp{"session": sessionID}).
+			One(&next)
+		if err != nil {
+			return err
+		}
+		for _, m := range pending {
+			if err := saveChatMessage(txApp, sessionID, next.Position, m.Role, m.Content, false); err != nil {
+				return err
+			}
+			next.Position++
+		}
+		return saveChatMessage(txApp, sessionID, next.Position, "assistant", reply, partial)
+	})
+}
+
+func saveChatMessage(app core.App, sessionID string, position int, role, content string, partial bool) error {
+	col, err := app.FindCollectionByNameOrId("chat_messages")
+	if err != nil {
+		return err
+	}
+	message := core.NewRecord(col)
+	message.Set("session", sessionID)
+	message.Set("position", position)
+	message.Set("role", role)
+	message.Set("content", content)
+	message.Set("partial", partial)
+	return app.Save(message)
+}
+
+// defaultChatSessionTitle names a new session after the first line of its
+// first question.
+func defaultChatSessionTitle(question string) string {
+	title := strings.TrimSpace(question)
+	if i := strings.IndexByte(title, '\n'); i >= 0 {
+		title = strings.TrimSpace(title[:i])
+	}
+	if utf8.RuneCountInString(title) > defaultChatSessionTitleChars {
+		title = strings.TrimSpace(string([]rune(title)[:defaultChatSessionTitleChars-1])) + "…"
+	}
+	return title
+}
+
+func chatSessionDTO(app core.App, session *core.Record) ChatSessionDTO {
+	dto := ChatSessionDTO{
+		ID:      session.Id,
+		EntryID: session.GetString("entry"),
+		Title:   session.GetString("title"),
+		Created: session.GetDateTime("created").String(),
+		Updated: session.GetDateTime("updated").String(),
+	}
+	if entry, err := app.FindRecordById("entries", dto.EntryID); err == nil {
+		dto.EntryTitle = entry.GetString("title")
+	}
+	if count, err := app.CountRecords("chat_messages", dbx.HashExp{"session": session.Id}); err == nil {
+		dto.MessageCount = int(count)
+	}
+	return dto
+}
@@ -0,0 +1,388 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// newChatAIServer streams chunks as one answer and records the messages of
+// every request.
+func newChatAIServer(t *testing.T, chunks ...string) (*httptest.Server, *[][]ai.Message) {
+	t.Helper()
+	var requests [][]ai.Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []ai.Message `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req.Messages)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range chunks {
+			data, _ := json.Marshal(map[string]any{"choices": []map[string]any{{"delta": map[string]string{"content": chunk}}}})
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		fmt.Fprintln(w, "data: [DONE]")
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func createChatEntry(t *testing.T, app core.App, guid string) *core.Record {
+	t.Helper()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	resource := testutil.CreateResource(t, app, "test-"+guid, "https://example.com/"+guid, "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, resource.Id, "Raft", "https://example.com/"+guid+"/raft", guid)
+	entry.Set("raw_content", "Raft keeps replicated logs consistent.")
+	if err := app.Save(entry); err != nil {
+		t.Fatal(err)
+	}
+	return entry
+}
+
+func sessionIDFromStream(t *testing.T, body string) string {
+	t.Helper()
+	first, _, _ := strings.Cut(body, "\n\n")
+	var event struct {
+		SessionID string `json:"session_id"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(first, "data: ")), &event); err != nil || event.SessionID == "" {
+		t.Fatalf("expected the session id as the first event, got %q", body)
+	}
+	return event.SessionID
+}
+
+func TestHandleChatSessionDirect_SavesAndResumes(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	user := testutil.CreateSuperuser(t, app, "reader@example.com")
+	entry := createChatEntry(t, app, "g1")
+	aiServer, requests := newChatAIServer(t, "Leaders ", "replicate logs.")
+
+	rec := httptest.NewRecorder()
+	body := ChatRequestBody{EntryID: entry.Id, Messages: []ai.Message{{Role: "user", Content: "How does Raft work?\nPlease be brief."}}}
+	if status, err := HandleChatSessionDirect(context.Background(), app, rec, user.Id, body, aiServer.URL); err != nil {
+		t.Fatalf("unexpected error: %d %v", status, err)
+	}
+	sessionID := sessionIDFromStream(t, rec.Body.String())
+	if !strings.Contains(rec.Body.String(), "replicate logs.") || !strings.HasSuffix(rec.Body.String(), "data: [DONE]\n\n") {
+		t.Errorf("expected the answer to be streamed, got %q", rec.Body.String())
+	}
+
+	_, detail, err := HandleGetChatSessionDirect(app, user.Id, sessionID)
+	if err != nil {
+		t.Fatalf("get session: %v", err)
+	}
+	if detail.Title != "How does Raft work?" || detail.EntryTitle != "Raft" || len(detail.Messages) != 2 {
+		t.Fatalf("unexpected session: %+v", detail)
+	}
+	if m := detail.Messages[1]; m.Role != "assistant" || m.Content != "Leaders replicate logs." || m.Partial {
+		t.Errorf("unexpected answer: %+v", m)
+	}
+
+	// Resuming takes the history from the session, not from the client.
+	body.SessionID = sessionID
+	body.Messages = []ai.Message{{Role: "user", Content: "Forgotten"}, {Role: "user", Content: "And elections?"}}
+	if status, err := HandleChatSessionDirect(context.Background(), app, httptest.NewRecorder(), user.Id, body, aiServer.URL); err != nil {
+		t.Fatalf("unexpected error: %d %v", status, err)
+	}
+	sent := (*requests)[1]
+	if len(sent) != 4 || sent[1].Content != "How does Raft work?\nPlease be brief." || sent[2].Role != "assistant" || sent[3].Content != "And elections?" {
+		t.Errorf("expected the stored history and the new question, got %+v", sent)
+	}
+	_, detail, _ = HandleGetChatSessionDirect(app, user.Id, sessionID)
+	if len(detail.Messages) != 4 || detail.Messages[2].Content != "And elections?" || detail.MessageCount != 4 {
+		t.Errorf("expected four messages in order, got %+v", detail.Messages)
+	}
+}
+
+// cancelWriter cancels the request context once the answer starts streaming,
+// as if the client went away.
+type cancelWriter struct {
+	*httptest.ResponseRecorder
+	cancel context.CancelFunc
+}
+
+func (w *cancelWriter) Write(data []byte) (int, error) {
+	if strings.Contains(string(data), `"content"`) {
+		w.cancel()
+	}
+	return w.ResponseRecorder.Write(data)
+}
+
+func TestHandleChatSessionDirect_SavesPartialAnswerOnDisconnect(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	user := testutil.CreateSuperuser(t, app, "reader@example.com")
+	entry := createChatEntry(t, app, "g1")
+	aiServer, _ := newChatAIServer(t, "Leaders ", "replicate logs.")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := &cancelWriter{ResponseRecorder: httptest.NewRecorder(), cancel: cancel}
+	body := ChatRequestBody{EntryID: entry.Id, Messages: []ai.Message{{Role: "user", Content: "How does Raft work?"}}}
+	if status, err := HandleChatSessionDirect(ctx, app, w, user.Id, body, aiServer.URL); err != nil {
+		t.Fatalf("unexpected error: %d %v", status, err)
+	}
+
+	_, detail, _ := HandleGetChatSessionDirect(app, user.Id, sessionIDFromStream(t, w.Body.String()))
+	if len(detail.Messages) != 2 {
+		t.Fatalf("expected the question and the partial answer, got %+v", detail.Messages)
+	}
+	if m := detail.Messages[1]; m.Content != "Leaders " || !m.Partial {
+		t.Errorf("expected a partial answer, got %+v", m)
+	}
+}
+
+func TestHandleChatSessionDirect_KeepsTurnsAlternatingWhenStreamFails(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	user := testutil.CreateSuperuser(t, app, "reader@example.com")
+	entry := createChatEntry(t, app, "g1")
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	rec := httptest.NewRecorder()
+	body := ChatRequestBody{EntryID: entry.Id, Messages: []ai.Message{{Role: "user", Content: "How does Raft work?"}}}
+	if status, err := HandleChatSessionDirect(context.Background(), app, rec, user.Id, body, failing.URL); err != nil {
+		t.Fatalf("unexpected error: %d %v", status, err)
+	}
+	sessionID := sessionIDFromStream(t, rec.Body.String())
+	_, detail, _ := HandleGetChatSessionDirect(app, user.Id, sessionID)
+	if len(detail.Messages) != 2 {
+		t.Fatalf("expected the question and an empty partial answer, got %+v", detail.Messages)
+	}
+	if m := detail.Messages[1]; m.Role != "assistant" || m.Content != "" || !m.Partial {
+		t.Errorf("expected an empty partial answer, got %+v", m)
+	}
+
+	aiServer, requests := newChatAIServer(t, "Leaders replicate logs.")
+	body.SessionID = sessionID
+	body.Messages = []ai.Message{{Role: "user", Content: "How does Raft work?"}}
+	if status, err := HandleChatSessionDirect(context.Background(), app, httptest.NewRecorder(), user.Id, body, aiServer.URL); err != nil {
+		t.Fatalf("unexpected error: %d %v", status, err)
+	}
+	sent := (*requests)[0]
+	for i := 1; i < len(sent); i++ {
+		if sent[i].Role == sent[i-1].Role {
+			t.Fatalf("expected alternating turns, got %+v", sent)
+		}
+	}
+}
+
+func TestSaveChatTurn_ConcurrentPositions(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	user := testutil.CreateSuperuser(t, app, "reader@example.com")
+	entry := createChatEntry(t, app, "g1")
+	session, err := createChatSession(app, user.Id, entry.Id, "Raft")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			question := []ai.Message{{Role: "user", Content: fmt.Sprintf("Question %d", i)}}
+			if err := saveChatTurn(app, session.Id, question, "Answer", false); err != nil {
+				t.Errorf("saving turn %d: %v", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	messages, _ := chatSessionMessages(app, session.Id)
+	if len(messages) != 10 {
+		t.Fatalf("expected 10 messages, got %d", len(messages))
+	}
+	for i, m := range messages {
+		if m.GetInt("position") != i {
+			t.Errorf("message %d has position %d", i, m.GetInt("position"))
+		}
+	}
+	if err := saveChatMessage(app, session.Id, 0, "user", "Duplicate", false); err == nil {
+		t.Error("expected a duplicate position to be rejected")
+	}
+}
+
+func TestHandleChatSessionDirect_Errors(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	user := testutil.CreateSuperuser(t, app, "reader@example.com")
+	other := testutil.CreateSuperuser(t, app, "other@example.com")
+	entry := createChatEntry(t, app, "g1")
+	otherEntry := createChatEntry(t, app, "g2")
+	session, err := createChatSession(app, user.Id, entry.Id, "Raft")
+	if err != nil {
+		t.Fatal(err)
+	}
+	question := []ai.Message{{Role: "user", Content: "Why?"}}
+
+	cases := []struct {
+		name   string
+		userID string
+		body   ChatRequestBody
+		want   int
+	}{
+		{"no messages", user.Id, ChatRequestBody{EntryID: entry.Id}, http.StatusBadRequest},
+		{"answer last", user.Id, ChatRequestBody{EntryID: entry.Id, Messages: []ai.Message{{Role: "assistant", Content: "Hi"}}}, http.StatusBadRequest},
+		{"missing entry", user.Id, ChatRequestBody{EntryID: "missing", Messages: question}, http.StatusNotFound},
+		{"other user", other.Id, ChatRequestBody{EntryID: entry.Id, Messages: question, SessionID: session.Id}, http.StatusNotFound},
+		{"other entry", user.Id, ChatRequestBody{EntryID: otherEntry.Id, Messages: question, SessionID: session.Id}, http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		if status, err := HandleChatSessionDirect(context.Background(), app, httptest.NewRecorder(), tc.userID, tc.body, "http://unused"); err == nil || status != tc.want {
+			t.Errorf("%s: expected %d, got %d %v", tc.name, tc.want, status, err)
+		}
+	}
+	if records, _ := app.FindAllRecords("chat_messages"); len(records) != 0 {
+		t.Errorf("expected nothing to be saved, got %d messages", len(records))
+	}
+}
+
+func TestChatSessionManagement(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	user := testutil.CreateSuperuser(t, app, "reader@example.com")
+	other := testutil.CreateSuperuser(t, app, "other@example.com")
+	entry := createChatEntry(t, app, "g1")
+	otherEntry := createChatEntry(t, app, "g2")
+
+	session, _ := createChatSession(app, user.Id, entry.Id, "Raft")
+	saveChatMessage(app, session.Id, 0, "user", "How does Raft work?", false)
+	saveChatMessage(app, session.Id, 1, "assistant", "Leaders", true)
+	second, _ := createChatSession(app, user.Id, otherEntry.Id, "Other")
+	createChatSession(app, other.Id, entry.Id, "Not mine")
+
+	_, list, err := HandleListChatSessionsDirect(app, user.Id, "")
+	if err != nil || len(list.Sessions) != 2 {
+		t.Fatalf("expected the user's two sessions, got %+v %v", list, err)
+	}
+	_, list, _ = HandleListChatSessionsDirect(app, user.Id, entry.Id)
+	if len(list.Sessions) != 1 || list.Sessions[0].ID != session.Id || list.Sessions[0].MessageCount != 2 {
+		t.Fatalf("expected the session for the entry, got %+v", list)
+	}
+
+	if status, _, err := HandleRenameChatSessionDirect(app, user.Id, session.Id, ChatSessionTitleInput{Title: "  "}); err == nil || status != http.StatusBadRequest {
+		t.Errorf("expected an empty title to be rejected, got %d %v", status, err)
+	}
+	if status, _, err := HandleRenameChatSessionDirect(app, other.Id, session.Id, ChatSessionTitleInput{Title: "Mine"}); err == nil || status != http.StatusNotFound {
+		t.Errorf("expected another user's session to be hidden, got %d %v", status, err)
+	}
+	_, renamed, err := HandleRenameChatSessionDirect(app, user.Id, session.Id, ChatSessionTitleInput{Title: " Raft basics "})
+	if err != nil || renamed.Title != "Raft basics" {
+		t.Errorf("unexpected rename: %+v %v", renamed, err)
+	}
+
+	_, filename, markdown, err := HandleExportChatSessionDirect(app, user.Id, session.Id)
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	for _, want := range []string{"# Raft basics", "Article: [Raft](https://example.com/g1/raft)", "## You\n\nHow does Raft work?", "## Assistant\n\nLeaders", "interrupted"} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("expected %q in the export:\n%s", want, markdown)
+		}
+	}
+	if filename != "chat-"+session.Id+".md" {
+		t.Errorf("unexpected file name %q", filename)
+	}
+
+	if status, err := HandleDeleteChatSessionDirect(app, other.Id, session.Id); err == nil || status != http.StatusNotFound {
+		t.Errorf("expected another user's delete to fail, got %d %v", status, err)
+	}
+	if status, err := HandleDeleteChatSessionDirect(app, user.Id, session.Id); err != nil || status != http.StatusNoContent {
+		t.Fatalf("unexpected delete: %d %v", status, err)
+	}
+	if records, _ := app.FindAllRecords("chat_messages"); len(records) != 0 {
+		t.Errorf("expected the messages to be deleted with the session, got %d", len(records))
+	}
+
+	if err := app.Delete(otherEntry); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.FindRecordById("chat_sessions", second.Id); err == nil {
+		t.Error("expected the entry's sessions to be deleted with it")
+	}
+}
+
+func TestDefaultChatSessionTitle(t *testing.T) {
+	long := strings.Repeat("é", 100)
+	if got := defaultChatSessionTitle(long); len([]rune(got)) != defaultChatSessionTitleChars || !strings.HasSuffix(got, "…") {
+		t.Errorf("expected a shortened title, got %q", got)
+	}
+	if got := defaultChatSessionTitle("  First line\nsecond line"); got != "First line" {
+		t.Errorf("expected the first line, got %q", got)
+	}
+}
+
+func TestChatSessionRoutes(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	mux := buildMux(t, app)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/chat/sessions", nil),
+		httptest.NewRequest(http.MethodGet, "/api/chat/sessions/x", nil),
+		httptest.NewRequest(http.MethodPatch, "/api/chat/sessions/x", strings.NewReader(`{"title":"t"}`)),
+		httptest.NewRequest(http.MethodGet, "/api/chat/sessions/x/export", nil),
+		httptest.NewRequest(http.MethodDelete, "/api/chat/sessions/x", nil),
+	} {
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s: expected 401 without auth, got %d", req.Method, req.URL.Path, rec.Code)
+		}
+	}
+
+	token := createAuthToken(t, app)
+	user, err := app.FindAuthRecordByEmail(core.CollectionNameSuperusers, "test@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := createChatEntry(t, app, "g1")
+	session, _ := createChatSession(app, user.Id, entry.Id, "Raft")
+	saveChatMessage(app, session.Id, 0, "user", "How does Raft work?", false)
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", token)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve(http.MethodGet, "/api/chat/sessions?entry_id="+entry.Id, ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"id":"`+session.Id+`"`) {
+		t.Errorf("unexpected list: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve(http.MethodGet, "/api/chat/sessions/"+session.Id, ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "How does Raft work?") {
+		t.Errorf("unexpected session: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve(http.MethodPatch, "/api/chat/sessions/"+session.Id, `{"title":"Renamed"}`); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"title":"Renamed"`) {
+		t.Errorf("unexpected rename: %d %s", rec.Code, rec.Body.String())
+	}
+	rec := serve(http.MethodGet, "/api/chat/sessions/"+session.Id+"/export", "")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/markdown") || !strings.Contains(rec.Header().Get("Content-Disposition"), "attachment") {
+		t.Errorf("unexpected export: %d %v", rec.Code, rec.Header())
+	}
+	if rec := serve(http.MethodDelete, "/api/chat/sessions/"+session.Id, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("unexpected delete: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve(http.MethodGet, "/api/chat/sessions/"+session.Id, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected the deleted session to be gone, got %d", rec.Code)
+	}
+}
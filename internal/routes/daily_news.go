@@ -9,6 +9,7 @@ import (
 	"unicode"
 	"unicode/utf8"
 
+	"github.com/jgordijn/knowledgehub/internal/ai"
 	"github.com/jgordijn/knowledgehub/internal/engine"
 	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase/core"
@@ -54,6 +55,7 @@ type DailyNewsSettingsDTO struct {
 	GenerationTime    string `json:"generation_time"`
 	Timezone          string `json:"timezone"`
 	ExtraInstructions string `json:"extra_instructions"`
+	RankBy            string `json:"rank_by"`
 }
 
 type DailyNewsSettingsInput struct {
@@ -61,6 +63,7 @@ type DailyNewsSettingsInput struct {
 	GenerationTime    string `json:"generation_time"`
 	Timezone          string `json:"timezone"`
 	ExtraInstructions string `json:"extra_instructions"`
+	RankBy            string `json:"rank_by"`
 }
 
 type DailyNewsEntryReferenceDTO struct {
@@ -193,6 +196,7 @@ func HandleDailyNewsSaveSettings(app core.App, userID string, input DailyNewsSet
 	settings.Set("generation_time", input.GenerationTime)
 	settings.Set("timezone", input.Timezone)
 	settings.Set("extra_instructions", input.ExtraInstructions)
+	settings.Set("rank_by", input.RankBy)
 	if err := app.Save(settings); err != nil {
 		return http.StatusInternalServerError, DailyNewsSettingsDTO{}, err
 	}
@@ -225,7 +229,7 @@ func HandleDailyNewsEntryReference(app core.App, userID, digestID, entryID strin
 	if err != nil {
 		return http.StatusOK, DailyNewsEntryReferenceDTO{Available: false, Message: "Referenced entry is no longer available."}, nil
 	}
-	return http.StatusOK, DailyNewsEntryReferenceDTO{Available: true, Entry: dailyNewsEntryCardDTO(app, entry)}, nil
+	return http.StatusOK, DailyNewsEntryReferenceDTO{Available: true, Entry: dailyNewsEntryCardDTO(app, entry, ai.UserLanguagePreference(app, userID).Language)}, nil
 }
 
 func dailyNewsDigestListRecency(record *core.Record) time.Time {
@@ -448,6 +452,7 @@ func dailyNewsSettingsDTO(record *core.Record) DailyNewsSettingsDTO {
 		GenerationTime:    record.GetString("generation_time"),
 		Timezone:          record.GetString("timezone"),
 		ExtraInstructions: record.GetString("extra_instructions"),
+		RankBy:            record.GetString("rank_by"),
 	}
 }
 
@@ -455,6 +460,9 @@ func validateDailyNewsSettingsInput(input DailyNewsSettingsInput) error {
 	if err := engine.ValidateDailyNewsScheduleSettings(engine.DailyNewsScheduleSettings{Enabled: input.Enabled, GenerationTime: input.GenerationTime, Timezone: input.Timezone}); err != nil {
 		return err
 	}
+	if !engine.ValidDailyNewsRankBy(input.RankBy) {
+		return errors.New("Rank by must be stars, relevance, depth, novelty or credibility.")
+	}
 	if utf8.RuneCountInString(input.ExtraInstructions) > 2000 {
 		return errors.New("Extra instructions must be 2000 characters or fewer.")
 	}
@@ -495,7 +503,7 @@ func dailyNewsDigestDTO(record *core.Record) DailyNewsDigestDTO {
 	}
 }
 
-func dailyNewsEntryCardDTO(app core.App, entry *core.Record) *DailyNewsEntryCardDTO {
+func dailyNewsEntryCardDTO(app core.App, entry *core.Record, language string) *DailyNewsEntryCardDTO {
 	effectiveStars := int(entry.GetFloat("ai_stars"))
 	if userStars := int(entry.GetFloat("user_stars")); userStars > 0 {
 		effectiveStars = userStars
@@ -510,6 +518,9 @@ func dailyNewsEntryCardDTO(app core.App, entry *core.Record) *DailyNewsEntryCard
 		PublishedAt:    entry.GetDateTime("published_at").String(),
 		DiscoveredAt:   entry.GetDateTime("discovered_at").String(),
 	}
+	if t, ok := ai.TranslationFor(entry, language); ok && t.Summary != "" {
+		dto.Summary, dto.Takeaways = t.Summary, t.Takeaways
+	}
 	if resourceID := entry.GetString("resource"); resourceID != "" {
 		if resource, err := app.FindRecordById("resources", resourceID); err == nil {
 			dto.SourceName = resource.GetString("name")
@@ -23,11 +23,11 @@ func TestHandleDailyNewsSettingsMaterializesAndSavesValidSettings(t *testing.T)
 	if dto.User != user.Id || !dto.Enabled || dto.GenerationTime != "08:00" || dto.Timezone != "Europe/Amsterdam" {
 		t.Fatalf("unexpected defaults: %+v", dto)
 	}
-	status, saved, err := HandleDailyNewsSaveSettings(app, user.Id, DailyNewsSettingsInput{Enabled: false, GenerationTime: "07:15", Timezone: "UTC", ExtraInstructions: "Prioritize AI releases\nUse bullets"})
+	status, saved, err := HandleDailyNewsSaveSettings(app, user.Id, DailyNewsSettingsInput{Enabled: false, GenerationTime: "07:15", Timezone: "UTC", ExtraInstructions: "Prioritize AI releases\nUse bullets", RankBy: "depth"})
 	if err != nil || status != http.StatusOK {
 		t.Fatalf("save settings failed: status=%d err=%v", status, err)
 	}
-	if saved.Enabled || saved.GenerationTime != "07:15" || saved.Timezone != "UTC" || saved.ExtraInstructions != "Prioritize AI releases\nUse bullets" {
+	if saved.Enabled || saved.GenerationTime != "07:15" || saved.Timezone != "UTC" || saved.ExtraInstructions != "Prioritize AI releases\nUse bullets" || saved.RankBy != "depth" {
 		t.Fatalf("unexpected saved settings: %+v", saved)
 	}
 }
@@ -42,6 +42,7 @@ func TestHandleDailyNewsSettingsRejectsInvalidValuesWithoutMutation(t *testing.T
 		{Enabled: true, GenerationTime: "24:00", Timezone: "Europe/Amsterdam"},
 		{Enabled: true, GenerationTime: "08:00", Timezone: "No/SuchZone"},
 		{Enabled: true, GenerationTime: "08:00", Timezone: "Europe/Amsterdam", ExtraInstructions: string(rune(0x202e))},
+		{Enabled: true, GenerationTime: "08:00", Timezone: "Europe/Amsterdam", RankBy: "popularity"},
 	}
 	for _, input := range cases {
 		status, _, err := HandleDailyNewsSaveSettings(app, user.Id, input)
@@ -0,0 +1,170 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/jgordijn/knowledgehub/internal/engine"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// maxImportFileSize caps uploaded bookmark exports.
+const maxImportFileSize = 20 << 20
+
+// BatchQuickAddRequest is the body of a batch Quick Add. Text is any blob of
+// text containing links; URL and Title carry the fields of a share target so
+// a shared page can be posted as is.
+type BatchQuickAddRequest struct {
+	Text  string `json:"text"`
+	URL   string `json:"url"`
+	Title string `json:"title"`
+}
+
+// ImportJobDTO reports the progress of an import job.
+type ImportJobDTO struct {
+	ID         string                 `json:"id"`
+	Source     string                 `json:"source"`
+	Status     string                 `json:"status"`
+	Total      int                    `json:"total"`
+	Processed  int                    `json:"processed"`
+	Added      int                    `json:"added"`
+	Skipped    int                    `json:"skipped"`
+	Failed     int                    `json:"failed"`
+	Error      string                 `json:"error,omitempty"`
+	FinishedAt string                 `json:"finished_at,omitempty"`
+	Items      []engine.ImportJobItem `json:"items,omitempty"`
+}
+
+// RegisterImportRoutes adds the batch Quick Add, bookmark import and import
+// progress endpoints.
+func RegisterImportRoutes(se *core.ServeEvent) {
+	// POST /api/quick-add/batch — queue every link found in a blob of text
+	se.Router.POST("/api/quick-add/batch", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		var body BatchQuickAddRequest
+		if err := re.BindBody(&body); err != nil {
+			return re.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body."})
+		}
+		status, dto, err := HandleBatchQuickAddDirect(re.App, body, engine.DefaultHTTPClient)
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, dto)
+	})
+
+	// POST /api/quick-add/import — multipart form with "source" and "file"
+	se.Router.POST("/api/quick-add/import", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		file, _, err := re.Request.FormFile("file")
+		if err != nil {
+			return re.JSON(http.StatusBadRequest, map[string]string{"error": "An export file is required."})
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(io.LimitReader(file, maxImportFileSize+1))
+		if err != nil {
+			return re.JSON(http.StatusBadRequest, map[string]string{"error": "Could not read the export file."})
+		}
+		status, dto, err := HandleImportDirect(re.App, re.Request.FormValue("source"), data, engine.DefaultHTTPClient)
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, dto)
+	}).Bind(apis.BodyLimit(maxImportFileSize + 1<<20))
+
+	// GET /api/quick-add/jobs/{id} — progress of a batch or import
+	se.Router.GET("/api/quick-add/jobs/{id}", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		status, dto, err := HandleGetImportJobDirect(re.App, re.Request.PathValue("id"))
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, dto)
+	})
+}
+
+// HandleBatchQuickAddDirect is the testable core logic for batch Quick Add:
+// it extracts every URL from the request and queues them as an import job.
+// When a share target posts a single link with a title, that title is kept.
+func HandleBatchQuickAddDirect(app core.App, body BatchQuickAddRequest, client *http.Client) (int, *ImportJobDTO, error) {
+	items := engine.ExtractURLs(strings.Join([]string{body.URL, body.Title, body.Text}, "\n"))
+	if len(items) == 0 {
+		return http.StatusBadRequest, nil, errors.New("No links found.")
+	}
+	if len(items) == 1 && strings.TrimSpace(body.URL) != "" {
+		items[0].Title = strings.TrimSpace(body.Title)
+	}
+	return startImport(app, engine.ImportText, items, client)
+}
+
+// HandleImportDirect is the testable core logic for importing a Pocket,
+// Instapaper, Raindrop or Netscape bookmark export.
+func HandleImportDirect(app core.App, source string, data []byte, client *http.Client) (int, *ImportJobDTO, error) {
+	if source == engine.ImportText || !slices.Contains(engine.ImportSources, source) {
+		return http.StatusBadRequest, nil, errors.New("Unknown import source. Use pocket, instapaper, raindrop or netscape.")
+	}
+	if len(data) > maxImportFileSize {
+		return http.StatusRequestEntityTooLarge, nil, fmt.Errorf("The export is larger than %d MB.", maxImportFileSize>>20)
+	}
+	items, err := engine.ParseImport(source, data)
+	if err != nil {
+		return http.StatusUnprocessableEntity, nil, fmt.Errorf("Failed to read export: %v", err)
+	}
+	return startImport(app, source, items, client)
+}
+
+func startImport(app core.App, source string, items []engine.ImportItem, client *http.Client) (int, *ImportJobDTO, error) {
+	if _, err := findQuickAddResource(app); err != nil {
+		return http.StatusInternalServerError, nil, errors.New("Quick Add resource not found. Restart the application.")
+	}
+	job, err := engine.CreateImportJob(app, source, items)
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("Failed to queue import: %v", err)
+	}
+	engine.StartImportJob(app, job.Id, client)
+
+	dto := importJobDTO(job)
+	dto.Items = nil
+	return http.StatusAccepted, dto, nil
+}
+
+// HandleGetImportJobDirect returns the progress of an import job, including
+// the result of every processed link.
+func HandleGetImportJobDirect(app core.App, jobID string) (int, *ImportJobDTO, error) {
+	job, err := app.FindRecordById("import_jobs", jobID)
+	if err != nil {
+		return http.StatusNotFound, nil, errors.New("Import not found.")
+	}
+	return http.StatusOK, importJobDTO(job), nil
+}
+
+func importJobDTO(job *core.Record) *ImportJobDTO {
+	dto := &ImportJobDTO{
+		ID:        job.Id,
+		Source:    job.GetString("source"),
+		Status:    job.GetString("status"),
+		Total:     job.GetInt("total"),
+		Processed: job.GetInt("processed"),
+		Added:     job.GetInt("added"),
+		Skipped:   job.GetInt("skipped"),
+		Failed:    job.GetInt("failed"),
+		Error:     job.GetString("error"),
+	}
+	if finished := job.GetDateTime("finished_at"); !finished.IsZero() {
+		dto.FinishedAt = finished.String()
+	}
+	_ = json.Unmarshal([]byte(job.GetString("items")), &dto.Items)
+	return dto
+}
@@ -0,0 +1,203 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jgordijn/knowledgehub/internal/engine"
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// waitForImportJob polls an import job until it is no longer queued or running.
+func waitForImportJob(t *testing.T, app core.App, jobID string) *ImportJobDTO {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, dto, err := HandleGetImportJobDirect(app, jobID)
+		if err != nil {
+			t.Fatalf("job not found: %v", err)
+		}
+		if dto.Status == engine.ImportJobDone || dto.Status == engine.ImportJobFailed || time.Now().After(deadline) {
+			return dto
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestHandleBatchQuickAddDirect(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	resource := testutil.CreateResource(t, app, "Quick Add", "https://quickadd.local", "quickadd", "healthy", 0, true)
+	testutil.CreateEntry(t, app, resource.Id, "Known", "https://known.example.com/post", "known")
+
+	// Links to unreachable hosts fail quickly; the known link is skipped.
+	status, dto, err := HandleBatchQuickAddDirect(app, BatchQuickAddRequest{
+		Text: "Read https://known.example.com/post and http://127.0.0.1:1/unreachable.",
+	}, http.DefaultClient)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status != http.StatusAccepted || dto.Source != engine.ImportText || dto.Total != 2 {
+		t.Fatalf("unexpected response: %d %+v", status, dto)
+	}
+
+	done := waitForImportJob(t, app, dto.ID)
+	if done.Status != engine.ImportJobDone || done.Processed != 2 || done.Skipped != 1 || done.Failed != 1 {
+		t.Errorf("unexpected progress: %+v", done)
+	}
+	if len(done.Items) != 2 || done.Items[0].Status != "skipped" || done.Items[1].Error == "" {
+		t.Errorf("unexpected item results: %+v", done.Items)
+	}
+}
+
+func TestHandleBatchQuickAddDirect_ShareTarget(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateResource(t, app, "Quick Add", "https://quickadd.local", "quickadd", "healthy", 0, true)
+
+	_, dto, err := HandleBatchQuickAddDirect(app, BatchQuickAddRequest{
+		URL:   "http://127.0.0.1:1/shared",
+		Title: "Shared page",
+	}, http.DefaultClient)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	done := waitForImportJob(t, app, dto.ID)
+	if len(done.Items) != 1 || done.Items[0].Title != "Shared page" {
+		t.Errorf("expected the shared title kept, got %+v", done.Items)
+	}
+}
+
+func TestHandleBatchQuickAddDirect_Errors(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	status, _, err := HandleBatchQuickAddDirect(app, BatchQuickAddRequest{Text: "nothing to see"}, http.DefaultClient)
+	if err == nil || status != http.StatusBadRequest {
+		t.Errorf("expected 400 without links, got %d %v", status, err)
+	}
+	status, _, err = HandleBatchQuickAddDirect(app, BatchQuickAddRequest{Text: "https://example.com"}, http.DefaultClient)
+	if err == nil || status != http.StatusInternalServerError {
+		t.Errorf("expected 500 without a Quick Add resource, got %d %v", status, err)
+	}
+}
+
+func TestHandleImportDirect_Errors(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateResource(t, app, "Quick Add", "https://quickadd.local", "quickadd", "healthy", 0, true)
+
+	for _, source := range []string{"", "text", "delicious"} {
+		if status, _, err := HandleImportDirect(app, source, []byte("x"), http.DefaultClient); err == nil || status != http.StatusBadRequest {
+			t.Errorf("source %q: expected 400, got %d %v", source, status, err)
+		}
+	}
+	status, _, err := HandleImportDirect(app, engine.ImportPocket, []byte("title,link\n"), http.DefaultClient)
+	if err == nil || status != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for an unreadable export, got %d %v", status, err)
+	}
+	status, _, err = HandleImportDirect(app, engine.ImportPocket, make([]byte, maxImportFileSize+1), http.DefaultClient)
+	if err == nil || status != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for an oversized export, got %d %v", status, err)
+	}
+}
+
+func TestHandleGetImportJobDirect_NotFound(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	if status, _, err := HandleGetImportJobDirect(app, "missing"); err == nil || status != http.StatusNotFound {
+		t.Errorf("expected 404, got %d %v", status, err)
+	}
+}
+
+func importRequest(t *testing.T, token, source string, data []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("source", source)
+	fw, err := mw.CreateFormFile("file", "export.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(data)
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/quick-add/import", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	return req
+}
+
+func TestImportRoutes(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	resource := testutil.CreateResource(t, app, "Quick Add", "https://quickadd.local", "quickadd", "healthy", 0, true)
+	testutil.CreateEntry(t, app, resource.Id, "Known", "https://known.example.com/a", "known-a")
+
+	mux := buildMux(t, app)
+	token := createAuthToken(t, app)
+	export := []byte(`<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<DL><p><DT><A HREF="https://known.example.com/a" ADD_DATE="1700000000" TAGS="go">A</A></DL>`)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/api/quick-add/batch", strings.NewReader(`{"text":"https://example.com"}`)),
+		importRequest(t, "", engine.ImportNetscape, export),
+		httptest.NewRequest(http.MethodGet, "/api/quick-add/jobs/abc", nil),
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s: expected 401 without auth, got %d", req.Method, req.URL.Path, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/quick-add/batch", strings.NewReader(`{"text":"no links"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for text without links, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, importRequest(t, token, engine.ImportNetscape, export))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var queued ImportJobDTO
+	if err := json.Unmarshal(rec.Body.Bytes(), &queued); err != nil {
+		t.Fatal(err)
+	}
+	if queued.Source != engine.ImportNetscape || queued.Total != 1 {
+		t.Errorf("unexpected queued job: %+v", queued)
+	}
+	waitForImportJob(t, app, queued.ID)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/quick-add/jobs/"+queued.ID, nil)
+	req.Header.Set("Authorization", token)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var progress ImportJobDTO
+	if err := json.Unmarshal(rec.Body.Bytes(), &progress); err != nil {
+		t.Fatal(err)
+	}
+	if progress.Status != engine.ImportJobDone || progress.Skipped != 1 || len(progress.Items) != 1 {
+		t.Errorf("unexpected progress: %+v", progress)
+	}
+	if progress.Items[0].SavedAt == nil || len(progress.Items[0].Tags) != 1 {
+		t.Errorf("expected saved date and tags on the item: %+v", progress.Items[0])
+	}
+}
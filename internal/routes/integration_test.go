@@ -34,10 +34,23 @@ func buildMux(t *testing.T, app core.App) http.Handler {
 
 	// Register our custom routes
 	RegisterChatRoute(se)
+	RegisterChatSessionRoutes(se)
 	RegisterLinkSummaryRoute(se)
 	RegisterTriggerRoutes(se)
 	RegisterDailyNewsRoutes(se)
 	RegisterQuickAddRoutes(se)
+	RegisterArchiveRoutes(se)
+	RegisterUploadRoutes(se)
+	RegisterImportRoutes(se)
+	RegisterTagRoutes(se)
+	RegisterScoringRoutes(se)
+	RegisterRelevanceRoutes(se)
+	RegisterPreferenceRoutes(se)
+	RegisterInteractionRoutes(se)
+	RegisterRescoreRoutes(se)
+	RegisterOnboardingRoutes(se)
+	RegisterPromptRoutes(se)
+	RegisterLanguageRoutes(se)
 
 	mux, err := pbRouter.BuildMux()
 	if err != nil {
@@ -0,0 +1,87 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// InteractionRequest is the expected JSON body for recording an interaction.
+type InteractionRequest struct {
+	EntryID         string `json:"entry_id"`
+	Type            string `json:"type"`
+	DurationSeconds int    `json:"duration_seconds"`
+}
+
+// InteractionDTO is a recorded interaction.
+type InteractionDTO struct {
+	ID              string `json:"id"`
+	EntryID         string `json:"entry_id"`
+	Type            string `json:"type"`
+	DurationSeconds int    `json:"duration_seconds"`
+	Created         string `json:"created"`
+}
+
+// RegisterInteractionRoutes adds the endpoints that log reading interactions
+// and show the implicit preference signals derived from them.
+func RegisterInteractionRoutes(se *core.ServeEvent) {
+	// POST /api/interactions — record an interaction with an entry
+	se.Router.POST("/api/interactions", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		var body InteractionRequest
+		if err := re.BindBody(&body); err != nil {
+			return re.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body."})
+		}
+		status, dto, err := HandleRecordInteractionDirect(re.App, body)
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, dto)
+	})
+
+	// GET /api/interactions/signals — the implicit signals used for preferences
+	se.Router.GET("/api/interactions/signals", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		signals := ai.ImplicitSignals(re.App, time.Now())
+		if signals == nil {
+			signals = []ai.ImplicitSignal{}
+		}
+		return re.JSON(http.StatusOK, map[string]any{"signals": signals})
+	})
+}
+
+// HandleRecordInteractionDirect is the testable core logic for recording an
+// interaction.
+func HandleRecordInteractionDirect(app core.App, body InteractionRequest) (int, InteractionDTO, error) {
+	if !ai.IsInteractionType(body.Type) {
+		return http.StatusBadRequest, InteractionDTO{}, errors.New("Unknown interaction type.")
+	}
+	if body.DurationSeconds < 0 || body.DurationSeconds > ai.MaxInteractionDuration {
+		return http.StatusBadRequest, InteractionDTO{}, fmt.Errorf("Duration must be between 0 and %d seconds.", ai.MaxInteractionDuration)
+	}
+	if body.EntryID == "" {
+		return http.StatusBadRequest, InteractionDTO{}, errors.New("Entry is required.")
+	}
+	if _, err := app.FindRecordById("entries", body.EntryID); err != nil {
+		return http.StatusNotFound, InteractionDTO{}, errors.New("Entry not found.")
+	}
+	record, err := ai.RecordInteraction(app, body.EntryID, body.Type, body.DurationSeconds)
+	if err != nil {
+		return http.StatusInternalServerError, InteractionDTO{}, fmt.Errorf("Failed to record interaction: %v", err)
+	}
+	return http.StatusCreated, InteractionDTO{
+		ID:              record.Id,
+		EntryID:         record.GetString("entry"),
+		Type:            record.GetString("type"),
+		DurationSeconds: record.GetInt("duration_seconds"),
+		Created:         record.GetString("created"),
+	}, nil
+}
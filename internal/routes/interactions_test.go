@@ -0,0 +1,110 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+)
+
+func TestHandleRecordInteractionDirect(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Post", "https://example.com/post", "post")
+
+	status, dto, err := HandleRecordInteractionDirect(app, InteractionRequest{EntryID: entry.Id, Type: "read", DurationSeconds: 90})
+	if err != nil || status != http.StatusCreated {
+		t.Fatalf("unexpected result: %d %v", status, err)
+	}
+	if dto.EntryID != entry.Id || dto.Type != "read" || dto.DurationSeconds != 90 || dto.Created == "" {
+		t.Errorf("unexpected dto: %+v", dto)
+	}
+
+	tests := []struct {
+		name   string
+		body   InteractionRequest
+		status int
+	}{
+		{"unknown type", InteractionRequest{EntryID: entry.Id, Type: "liked"}, http.StatusBadRequest},
+		{"negative duration", InteractionRequest{EntryID: entry.Id, Type: "read", DurationSeconds: -5}, http.StatusBadRequest},
+		{"too long", InteractionRequest{EntryID: entry.Id, Type: "read", DurationSeconds: ai.MaxInteractionDuration + 1}, http.StatusBadRequest},
+		{"missing entry id", InteractionRequest{Type: "opened"}, http.StatusBadRequest},
+		{"unknown entry", InteractionRequest{EntryID: "missing", Type: "opened"}, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, _, err := HandleRecordInteractionDirect(app, tt.body); err == nil || status != tt.status {
+				t.Errorf("expected %d, got %d %v", tt.status, status, err)
+			}
+		})
+	}
+}
+
+func TestInteractionRoutes(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	mux := buildMux(t, app)
+	token := createAuthToken(t, app)
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Raft consensus explained", "https://example.com/raft", "raft")
+
+	body := fmt.Sprintf(`{"entry_id":%q,"type":"bookmarked"}`, entry.Id)
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/api/interactions", strings.NewReader(body)),
+		httptest.NewRequest(http.MethodGet, "/api/interactions/signals", nil),
+	} {
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s: expected 401 without auth, got %d", req.Method, req.URL.Path, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/interactions", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/interactions/signals", nil)
+	req.Header.Set("Authorization", token)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"actions":["bookmarked"]`) {
+		t.Errorf("unexpected signals response: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleChatDirect_RecordsInteraction(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Post", "https://example.com/post", "post")
+
+	aiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintln(w, `data: {"choices":[{"delta":{"content":"Answer."}}]}`)
+		fmt.Fprintln(w, "data: [DONE]")
+	}))
+	defer aiServer.Close()
+
+	body := ChatRequestBody{EntryID: entry.Id, Messages: []ai.Message{{Role: "user", Content: "Why?"}}}
+	if err := HandleChatDirect(app, httptest.NewRecorder(), body, aiServer.URL); err != nil {
+		t.Fatalf("HandleChatDirect: %v", err)
+	}
+
+	records, err := app.FindRecordsByFilter("interactions", "entry = {:id}", "", 0, 0, map[string]any{"id": entry.Id})
+	if err != nil || len(records) != 1 || records[0].GetString("type") != ai.InteractionChatted {
+		t.Errorf("expected a chatted interaction, got %d %v", len(records), err)
+	}
+}
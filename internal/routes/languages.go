@@ -0,0 +1,151 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// LanguageDTO is a language summaries can be translated into.
+type LanguageDTO struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// UserSettingsDTO is the user's summary language setting.
+type UserSettingsDTO struct {
+	SummaryLanguage   string        `json:"summary_language"`
+	TranslateArticles bool          `json:"translate_articles"`
+	Languages         []LanguageDTO `json:"languages"`
+}
+
+// UserSettingsInput updates the user's summary language setting. An empty
+// language keeps summaries in the article's own language.
+type UserSettingsInput struct {
+	SummaryLanguage   string `json:"summary_language"`
+	TranslateArticles bool   `json:"translate_articles"`
+}
+
+// TranslationDTO is an entry's translation into the user's language.
+type TranslationDTO struct {
+	Language   string   `json:"language"`
+	Summary    string   `json:"summary"`
+	Takeaways  []string `json:"takeaways"`
+	HasContent bool     `json:"has_content"`
+}
+
+// RegisterLanguageRoutes adds the endpoints for the summary language setting
+// and on-demand translation of an entry.
+func RegisterLanguageRoutes(se *core.ServeEvent) {
+	// GET /api/user-settings — the summary language and available languages
+	se.Router.GET("/api/user-settings", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		status, dto, err := HandleGetUserSettingsDirect(re.App, re.Auth.Id)
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, dto)
+	})
+
+	// PUT /api/user-settings — change the summary language
+	se.Router.PUT("/api/user-settings", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		var body UserSettingsInput
+		if err := re.BindBody(&body); err != nil {
+			return re.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body."})
+		}
+		status, dto, err := HandleSaveUserSettingsDirect(re.App, re.Auth.Id, body)
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, dto)
+	})
+
+	// POST /api/entries/{id}/translate — translate an entry into the user's
+	// summary language now
+	se.Router.POST("/api/entries/{id}/translate", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		status, dto, err := HandleTranslateEntryDirect(re.App, re.Auth.Id, re.Request.PathValue("id"))
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, dto)
+	})
+}
+
+// HandleGetUserSettingsDirect returns the user's summary language setting.
+func HandleGetUserSettingsDirect(app core.App, userID string) (int, UserSettingsDTO, error) {
+	pref := ai.UserLanguagePreference(app, userID)
+	return http.StatusOK, userSettingsDTO(pref), nil
+}
+
+// HandleSaveUserSettingsDirect is the testable core logic for changing the
+// summary language.
+func HandleSaveUserSettingsDirect(app core.App, userID string, input UserSettingsInput) (int, UserSettingsDTO, error) {
+	if input.SummaryLanguage != "" && ai.LanguageName(input.SummaryLanguage) == "" {
+		return http.StatusBadRequest, UserSettingsDTO{}, errors.New("Unsupported summary language.")
+	}
+	record, err := app.FindFirstRecordByFilter("user_settings", "user = {:user}", dbx.Params{"user": userID})
+	if err != nil {
+		col, colErr := app.FindCollectionByNameOrId("user_settings")
+		if colErr != nil {
+			return http.StatusInternalServerError, UserSettingsDTO{}, fmt.Errorf("Failed to save settings: %v", colErr)
+		}
+		record = core.NewRecord(col)
+		record.Set("user", userID)
+	}
+	record.Set("summary_language", input.SummaryLanguage)
+	record.Set("translate_articles", input.TranslateArticles)
+	if err := app.Save(record); err != nil {
+		return http.StatusInternalServerError, UserSettingsDTO{}, fmt.Errorf("Failed to save settings: %v", err)
+	}
+	return http.StatusOK, userSettingsDTO(ai.UserLanguagePreference(app, userID)), nil
+}
+
+// HandleTranslateEntryDirect is the testable core logic for translating an
+// entry into the user's summary language on demand.
+func HandleTranslateEntryDirect(app core.App, userID, entryID string) (int, TranslationDTO, error) {
+	pref := ai.UserLanguagePreference(app, userID)
+	if pref.Language == "" {
+		return http.StatusBadRequest, TranslationDTO{}, errors.New("No summary language configured.")
+	}
+	entry, err := app.FindRecordById("entries", entryID)
+	if err != nil {
+		return http.StatusNotFound, TranslationDTO{}, errors.New("Entry not found.")
+	}
+	// The article body is translated when a chat needs it.
+	pref.TranslateArticles = false
+	if ai.NeedsTranslation(entry, pref) {
+		if err := ai.TranslateEntry(app, entry, pref.Language, false); err != nil {
+			return http.StatusBadGateway, TranslationDTO{}, fmt.Errorf("Translation failed: %v", err)
+		}
+	}
+	dto := TranslationDTO{Language: pref.Language, Summary: entry.GetString("summary"), Takeaways: entry.GetStringSlice("takeaways")}
+	if t, ok := ai.TranslationFor(entry, pref.Language); ok {
+		dto.Summary, dto.Takeaways, dto.HasContent = t.Summary, t.Takeaways, t.Content != ""
+	}
+	if dto.Takeaways == nil {
+		dto.Takeaways = []string{}
+	}
+	return http.StatusOK, dto, nil
+}
+
+func userSettingsDTO(pref ai.LanguagePreference) UserSettingsDTO {
+	languages := make([]LanguageDTO, 0, len(ai.SupportedLanguages))
+	for code, name := range ai.SupportedLanguages {
+		languages = append(languages, LanguageDTO{Code: code, Name: name})
+	}
+	sort.Slice(languages, func(i, j int) bool { return languages[i].Name < languages[j].Name })
+	return UserSettingsDTO{SummaryLanguage: pref.Language, TranslateArticles: pref.TranslateArticles, Languages: languages}
+}
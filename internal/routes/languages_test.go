@@ -0,0 +1,205 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+)
+
+func TestHandleUserSettings(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	user := testutil.CreateSuperuser(t, app, "reader@example.com")
+
+	status, dto, err := HandleGetUserSettingsDirect(app, user.Id)
+	if err != nil || status != http.StatusOK || dto.SummaryLanguage != "" || len(dto.Languages) != len(ai.SupportedLanguages) {
+		t.Fatalf("unexpected defaults: %d %+v %v", status, dto, err)
+	}
+
+	if status, _, err := HandleSaveUserSettingsDirect(app, user.Id, UserSettingsInput{SummaryLanguage: "xx"}); err == nil || status != http.StatusBadRequest {
+		t.Errorf("expected an unsupported language to be rejected, got %d %v", status, err)
+	}
+
+	status, dto, err = HandleSaveUserSettingsDirect(app, user.Id, UserSettingsInput{SummaryLanguage: "nl", TranslateArticles: true})
+	if err != nil || status != http.StatusOK || dto.SummaryLanguage != "nl" || !dto.TranslateArticles {
+		t.Fatalf("unexpected save: %d %+v %v", status, dto, err)
+	}
+	// Saving again updates the same record.
+	if _, _, err := HandleSaveUserSettingsDirect(app, user.Id, UserSettingsInput{SummaryLanguage: "de"}); err != nil {
+		t.Fatalf("second save: %v", err)
+	}
+	records, _ := app.FindAllRecords("user_settings")
+	if len(records) != 1 || ai.UserLanguagePreference(app, user.Id).Language != "de" {
+		t.Errorf("expected one record with de, got %d records", len(records))
+	}
+}
+
+func TestHandleTranslateEntry(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	user := testutil.CreateSuperuser(t, app, "reader@example.com")
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Raft", "https://example.com/raft", "raft")
+	entry.Set("summary", "Raft is a consensus algorithm.")
+	entry.Set("detected_language", "en")
+	if err := app.Save(entry); err != nil {
+		t.Fatalf("save entry: %v", err)
+	}
+
+	if status, _, err := HandleTranslateEntryDirect(app, user.Id, entry.Id); err == nil || status != http.StatusBadRequest {
+		t.Errorf("expected 400 without a language, got %d %v", status, err)
+	}
+	testutil.CreateUserSettings(t, app, user.Id, "nl", false)
+	if status, _, err := HandleTranslateEntryDirect(app, user.Id, "missing"); err == nil || status != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing entry, got %d %v", status, err)
+	}
+
+	var calls int
+	restore := ai.SetCompleteFunc(func(_, _ string, _ []ai.Message) (string, error) {
+		calls++
+		return `{"summary": "Raft is een consensusalgoritme."}`, nil
+	})
+	defer restore()
+
+	for i := 0; i < 2; i++ {
+		status, dto, err := HandleTranslateEntryDirect(app, user.Id, entry.Id)
+		if err != nil || status != http.StatusOK || dto.Summary != "Raft is een consensusalgoritme." || dto.Language != "nl" || dto.Takeaways == nil {
+			t.Fatalf("unexpected translation: %d %+v %v", status, dto, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected the stored translation to be reused, got %d calls", calls)
+	}
+}
+
+func TestLanguageRoutes(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	mux := buildMux(t, app)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/user-settings", nil),
+		httptest.NewRequest(http.MethodPut, "/api/user-settings", strings.NewReader(`{"summary_language":"nl"}`)),
+		httptest.NewRequest(http.MethodPost, "/api/entries/x/translate", nil),
+	} {
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s: expected 401 without auth, got %d", req.Method, req.URL.Path, rec.Code)
+		}
+	}
+
+	token := createAuthToken(t, app)
+	req := httptest.NewRequest(http.MethodPut, "/api/user-settings", strings.NewReader(`{"summary_language":"nl","translate_articles":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"summary_language":"nl"`) {
+		t.Fatalf("unexpected save response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/user-settings", nil)
+	req.Header.Set("Authorization", token)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"translate_articles":true`) || !strings.Contains(rec.Body.String(), `"name":"Dutch"`) {
+		t.Errorf("unexpected get response: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleChat_UsesTranslatedArticle(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	var systemPrompt string
+	aiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []ai.Message `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Messages) > 0 {
+			systemPrompt = req.Messages[0].Content
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintln(w, `data: {"choices":[{"delta":{"content":"Ja"}}]}`)
+		fmt.Fprintln(w, "data: [DONE]")
+	}))
+	defer aiServer.Close()
+
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	resource := testutil.CreateResource(t, app, "test", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, resource.Id, "Raft", "https://example.com/raft", "g1")
+	entry.Set("summary", "Raft is a consensus algorithm.")
+	entry.Set("content_markdown", "Raft keeps replicated logs consistent.")
+	entry.Set("translations", map[string]ai.EntryTranslation{
+		"nl": {Source: "Raft is a consensus algorithm.", Summary: "Raft is een consensusalgoritme.", Content: "Raft houdt gerepliceerde logs consistent."},
+	})
+	if err := app.Save(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := []ai.Message{{Role: "user", Content: "Wat doet Raft?"}}
+	if err := HandleChatDirect(app, httptest.NewRecorder(), ChatRequestBody{EntryID: entry.Id, Messages: messages, Language: "nl"}, aiServer.URL); err != nil {
+		t.Fatalf("HandleChatDirect error: %v", err)
+	}
+	if !strings.Contains(systemPrompt, "Raft houdt gerepliceerde logs consistent.") {
+		t.Errorf("expected the translated article, got: %s", systemPrompt)
+	}
+
+	if err := HandleChatDirect(app, httptest.NewRecorder(), ChatRequestBody{EntryID: entry.Id, Messages: messages}, aiServer.URL); err != nil {
+		t.Fatalf("HandleChatDirect error: %v", err)
+	}
+	if !strings.Contains(systemPrompt, "Raft keeps replicated logs consistent.") {
+		t.Errorf("expected the original article without a language, got: %s", systemPrompt)
+	}
+}
+
+func TestHandleChatSessionDirect_TranslatesArticleOnFirstChat(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	user := testutil.CreateSuperuser(t, app, "reader@example.com")
+	testutil.CreateUserSettings(t, app, user.Id, "nl", true)
+	entry := createChatEntry(t, app, "g1")
+	aiServer, requests := newChatAIServer(t, "Ja")
+
+	var calls int
+	restore := ai.SetCompleteFunc(func(_, _ string, _ []ai.Message) (string, error) {
+		calls++
+		return "Raft houdt gerepliceerde logs consistent.", nil
+	})
+	defer restore()
+
+	body := ChatRequestBody{EntryID: entry.Id, Messages: []ai.Message{{Role: "user", Content: "Wat doet Raft?"}}, Language: "nl"}
+	for i := 0; i < 2; i++ {
+		if status, err := HandleChatSessionDirect(context.Background(), app, httptest.NewRecorder(), user.Id, body, aiServer.URL); err != nil {
+			t.Fatalf("unexpected error: %d %v", status, err)
+		}
+		if system := (*requests)[i][0].Content; !strings.Contains(system, "Raft houdt gerepliceerde logs consistent.") {
+			t.Errorf("chat %d: expected the translated article, got: %s", i+1, system)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected the article to be translated once and stored, got %d calls", calls)
+	}
+
+	// Users who do not translate articles chat about the original.
+	other := testutil.CreateSuperuser(t, app, "other@example.com")
+	testutil.CreateUserSettings(t, app, other.Id, "de", false)
+	body.Language = "de"
+	if status, err := HandleChatSessionDirect(context.Background(), app, httptest.NewRecorder(), other.Id, body, aiServer.URL); err != nil {
+		t.Fatalf("unexpected error: %d %v", status, err)
+	}
+	if system := (*requests)[2][0].Content; !strings.Contains(system, "Raft keeps replicated logs consistent.") || calls != 1 {
+		t.Errorf("expected the original article without translating, got %d calls: %s", calls, system)
+	}
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 
 	"github.com/jgordijn/knowledgehub/internal/ai"
@@ -13,6 +14,9 @@ import (
 // LinkSummaryRequest is the expected JSON body for the link-summary endpoint.
 type LinkSummaryRequest struct {
 	URL string `json:"url"`
+	// EntryID is the entry the link was found in, if any; summarizing it
+	// counts as interest in that entry.
+	EntryID string `json:"entry_id,omitempty"`
 }
 
 // RegisterLinkSummaryRoute adds the POST /api/link-summary streaming endpoint.
@@ -51,6 +55,12 @@ func HandleLinkSummaryDirect(app core.App, w http.ResponseWriter, body LinkSumma
 		return writeSSEError(w, "could not extract content from the linked article")
 	}
 
+	if body.EntryID != "" {
+		if _, err := ai.RecordInteraction(app, body.EntryID, ai.InteractionLinkSummarized, 0); err != nil {
+			log.Printf("Failed to record link summary interaction for %s: %v", body.EntryID, err)
+		}
+	}
+
 	apiKey, err := ai.GetAPIKey(app)
 	if err != nil {
 		return writeSSEError(w, "API key not configured")
@@ -114,9 +124,7 @@ func HandleLinkSummaryDirect(app core.App, w http.ResponseWriter, body LinkSumma
 }
 
 func buildLinkSummaryMessages(title, content string) []ai.Message {
-	if len(content) > 8000 {
-		content = content[:8000] + "..."
-	}
+	content = ai.TruncateUTF8(content, 8000)
 	prompt := fmt.Sprintf(
 		"Summarize the following article in 3-5 concise sentences.\n\nArticle: %s\n\n%s",
 		title, content,
@@ -0,0 +1,117 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const onboardingSampleCount = 8
+
+// OnboardingSampleDTO is an article offered for rating during onboarding.
+type OnboardingSampleDTO struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	URL      string `json:"url"`
+	Summary  string `json:"summary"`
+	Resource string `json:"resource"`
+}
+
+// OnboardingStatusDTO tells the UI whether to offer onboarding.
+type OnboardingStatusDTO struct {
+	Needed  bool                  `json:"needed"`
+	Samples []OnboardingSampleDTO `json:"samples"`
+}
+
+// RegisterOnboardingRoutes adds the endpoints that set up a first preference
+// profile on a fresh install.
+func RegisterOnboardingRoutes(se *core.ServeEvent) {
+	// GET /api/onboarding — whether a profile exists, and articles to rate
+	se.Router.GET("/api/onboarding", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		status, dto, err := HandleOnboardingStatusDirect(re.App)
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, dto)
+	})
+
+	// POST /api/onboarding — build the first profile from interests, ratings
+	// and/or an imported profile
+	se.Router.POST("/api/onboarding", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		var body ai.OnboardingInput
+		if err := re.BindBody(&body); err != nil {
+			return re.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body."})
+		}
+		status, dto, err := HandleCompleteOnboardingDirect(re.App, body)
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, dto)
+	})
+}
+
+// HandleOnboardingStatusDirect is the testable core logic for the onboarding
+// status.
+func HandleOnboardingStatusDirect(app core.App) (int, OnboardingStatusDTO, error) {
+	dto := OnboardingStatusDTO{Needed: ai.NeedsOnboarding(app), Samples: []OnboardingSampleDTO{}}
+	if !dto.Needed {
+		return http.StatusOK, dto, nil
+	}
+	samples, err := ai.OnboardingSamples(app, onboardingSampleCount)
+	if err != nil {
+		return http.StatusInternalServerError, OnboardingStatusDTO{}, fmt.Errorf("Failed to load sample articles: %v", err)
+	}
+	for _, s := range samples {
+		sample := OnboardingSampleDTO{
+			ID:      s.Id,
+			Title:   s.GetString("title"),
+			URL:     s.GetString("url"),
+			Summary: s.GetString("summary"),
+		}
+		if res, err := app.FindRecordById("resources", s.GetString("resource")); err == nil {
+			sample.Resource = res.GetString("name")
+		}
+		dto.Samples = append(dto.Samples, sample)
+	}
+	return http.StatusOK, dto, nil
+}
+
+// HandleCompleteOnboardingDirect is the testable core logic for building the
+// first preference profile.
+func HandleCompleteOnboardingDirect(app core.App, body ai.OnboardingInput) (int, PreferenceVersionDTO, error) {
+	if len(body.Interests) > ai.MaxOnboardingInterestsLen {
+		return http.StatusBadRequest, PreferenceVersionDTO{}, fmt.Errorf("Interests must be at most %d characters.", ai.MaxOnboardingInterestsLen)
+	}
+	if len(body.ImportedProfile) > ai.MaxOnboardingProfileLen {
+		return http.StatusBadRequest, PreferenceVersionDTO{}, fmt.Errorf("The imported profile must be at most %d characters.", ai.MaxOnboardingProfileLen)
+	}
+	if len(body.Ratings) > ai.MaxOnboardingRatings {
+		return http.StatusBadRequest, PreferenceVersionDTO{}, fmt.Errorf("Rate at most %d sample articles.", ai.MaxOnboardingRatings)
+	}
+	for _, rating := range body.Ratings {
+		if rating.Stars < 1 || rating.Stars > 5 {
+			return http.StatusBadRequest, PreferenceVersionDTO{}, errors.New("Ratings must be between 1 and 5 stars.")
+		}
+		if _, err := app.FindRecordById("entries", rating.EntryID); err != nil {
+			return http.StatusNotFound, PreferenceVersionDTO{}, errors.New("Sample article not found.")
+		}
+	}
+
+	record, err := ai.CompleteOnboarding(app, body)
+	if errors.Is(err, ai.ErrEmptyOnboarding) {
+		return http.StatusBadRequest, PreferenceVersionDTO{}, errors.New("Describe your interests, rate a sample article or import a profile.")
+	}
+	if err != nil {
+		return http.StatusInternalServerError, PreferenceVersionDTO{}, fmt.Errorf("Failed to create profile: %v", err)
+	}
+	return http.StatusCreated, preferenceVersionDTO(record), nil
+}
@@ -0,0 +1,105 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+)
+
+func TestHandleOnboardingStatusDirect(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	testutil.CreateEntry(t, app, res.Id, "Post", "https://example.com/post", "post")
+
+	status, dto, err := HandleOnboardingStatusDirect(app)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("unexpected result: %d %v", status, err)
+	}
+	if !dto.Needed || len(dto.Samples) != 1 || dto.Samples[0].Resource != "Blog" {
+		t.Errorf("unexpected status: %+v", dto)
+	}
+
+	if _, _, err := HandleCompleteOnboardingDirect(app, ai.OnboardingInput{ImportedProfile: "Likes Go."}); err != nil {
+		t.Fatal(err)
+	}
+	if _, dto, _ := HandleOnboardingStatusDirect(app); dto.Needed || len(dto.Samples) != 0 {
+		t.Errorf("expected no onboarding once a profile exists, got %+v", dto)
+	}
+}
+
+func TestHandleCompleteOnboardingDirect_Validation(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Post", "https://example.com/post", "post")
+
+	tests := []struct {
+		name   string
+		body   ai.OnboardingInput
+		status int
+	}{
+		{"empty", ai.OnboardingInput{}, http.StatusBadRequest},
+		{"long interests", ai.OnboardingInput{Interests: strings.Repeat("x", ai.MaxOnboardingInterestsLen+1)}, http.StatusBadRequest},
+		{"long profile", ai.OnboardingInput{ImportedProfile: strings.Repeat("x", ai.MaxOnboardingProfileLen+1)}, http.StatusBadRequest},
+		{"bad stars", ai.OnboardingInput{Ratings: []ai.OnboardingRating{{EntryID: entry.Id, Stars: 6}}}, http.StatusBadRequest},
+		{"unknown entry", ai.OnboardingInput{Ratings: []ai.OnboardingRating{{EntryID: "missing", Stars: 4}}}, http.StatusNotFound},
+		{"no API key", ai.OnboardingInput{Interests: "Go"}, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, _, err := HandleCompleteOnboardingDirect(app, tt.body); err == nil || status != tt.status {
+				t.Errorf("expected %d, got %d %v", tt.status, status, err)
+			}
+		})
+	}
+}
+
+func TestOnboardingRoutes(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateSetting(t, app, "openrouter_api_key", "test-key")
+	mux := buildMux(t, app)
+	token := createAuthToken(t, app)
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Raft consensus", "https://example.com/raft", "raft")
+
+	restore := ai.SetCompleteFunc(func(_, _ string, _ []ai.Message) (string, error) {
+		return "Likes distributed systems.", nil
+	})
+	defer restore()
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/onboarding", nil),
+		httptest.NewRequest(http.MethodPost, "/api/onboarding", strings.NewReader(`{"interests":"Go"}`)),
+	} {
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s: expected 401 without auth, got %d", req.Method, req.URL.Path, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/onboarding", nil)
+	req.Header.Set("Authorization", token)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"needed":true`) || !strings.Contains(rec.Body.String(), entry.Id) {
+		t.Fatalf("unexpected status response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	body := `{"interests":"Distributed systems","ratings":[{"entry_id":"` + entry.Id + `","stars":5}]}`
+	req = httptest.NewRequest(http.MethodPost, "/api/onboarding", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), `"source":"onboarding"`) {
+		t.Errorf("unexpected onboarding response: %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,141 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/jgordijn/knowledgehub/internal/engine"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// PreferenceVersionDTO is one version of the preference profile.
+type PreferenceVersionDTO struct {
+	ID           string                    `json:"id"`
+	Version      int                       `json:"version"`
+	GeneratedAt  string                    `json:"generated_at"`
+	Model        string                    `json:"model"`
+	Source       string                    `json:"source"`
+	RestoredFrom string                    `json:"restored_from,omitempty"`
+	ProfileText  string                    `json:"profile_text"`
+	Corrections  []ai.PreferenceCorrection `json:"source_corrections"`
+	// Diff shows what changed from the previous version, in the same
+	// "- "/"+ " line format as page change entries.
+	Diff string `json:"diff"`
+}
+
+// PreferenceCompareDTO is the difference between two profile versions.
+type PreferenceCompareDTO struct {
+	From PreferenceVersionDTO `json:"from"`
+	To   PreferenceVersionDTO `json:"to"`
+	Diff string               `json:"diff"`
+}
+
+// RegisterPreferenceRoutes adds the preference profile history, compare and
+// rollback endpoints. Pinned rules are edited through the preference_rules
+// collection API.
+func RegisterPreferenceRoutes(se *core.ServeEvent) {
+	// GET /api/preferences/versions — every profile version, newest first
+	se.Router.GET("/api/preferences/versions", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		status, versions, err := HandlePreferenceVersionsDirect(re.App)
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, map[string]any{"versions": versions})
+	})
+
+	// GET /api/preferences/versions/compare?from=ID&to=ID — diff two versions
+	se.Router.GET("/api/preferences/versions/compare", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		query := re.Request.URL.Query()
+		status, dto, err := HandleComparePreferenceVersionsDirect(re.App, query.Get("from"), query.Get("to"))
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, dto)
+	})
+
+	// POST /api/preferences/versions/{id}/rollback — make an older version current
+	se.Router.POST("/api/preferences/versions/{id}/rollback", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		status, dto, err := HandleRollbackPreferenceDirect(re.App, re.Request.PathValue("id"))
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, dto)
+	})
+}
+
+// HandlePreferenceVersionsDirect is the testable core logic for listing
+// profile versions.
+func HandlePreferenceVersionsDirect(app core.App) (int, []PreferenceVersionDTO, error) {
+	records, err := ai.PreferenceVersions(app)
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("Failed to load profile versions: %v", err)
+	}
+	versions := make([]PreferenceVersionDTO, len(records))
+	for i, r := range records {
+		previous := ""
+		if i+1 < len(records) {
+			previous = records[i+1].GetString("profile_text")
+		}
+		versions[i] = preferenceVersionDTO(r)
+		versions[i].Diff = engine.DiffLines(previous, r.GetString("profile_text"))
+	}
+	return http.StatusOK, versions, nil
+}
+
+// HandleComparePreferenceVersionsDirect is the testable core logic for
+// comparing two profile versions.
+func HandleComparePreferenceVersionsDirect(app core.App, fromID, toID string) (int, PreferenceCompareDTO, error) {
+	if fromID == "" || toID == "" {
+		return http.StatusBadRequest, PreferenceCompareDTO{}, errors.New("Both from and to versions are required.")
+	}
+	from, err := app.FindRecordById("preferences", fromID)
+	if err != nil {
+		return http.StatusNotFound, PreferenceCompareDTO{}, errors.New("Profile version not found.")
+	}
+	to, err := app.FindRecordById("preferences", toID)
+	if err != nil {
+		return http.StatusNotFound, PreferenceCompareDTO{}, errors.New("Profile version not found.")
+	}
+	return http.StatusOK, PreferenceCompareDTO{
+		From: preferenceVersionDTO(from),
+		To:   preferenceVersionDTO(to),
+		Diff: engine.DiffLines(from.GetString("profile_text"), to.GetString("profile_text")),
+	}, nil
+}
+
+// HandleRollbackPreferenceDirect is the testable core logic for rolling the
+// profile back to an earlier version.
+func HandleRollbackPreferenceDirect(app core.App, versionID string) (int, PreferenceVersionDTO, error) {
+	if _, err := app.FindRecordById("preferences", versionID); err != nil {
+		return http.StatusNotFound, PreferenceVersionDTO{}, errors.New("Profile version not found.")
+	}
+	record, err := ai.RollbackPreferenceProfile(app, versionID)
+	if err != nil {
+		return http.StatusInternalServerError, PreferenceVersionDTO{}, fmt.Errorf("Failed to roll back profile: %v", err)
+	}
+	return http.StatusOK, preferenceVersionDTO(record), nil
+}
+
+func preferenceVersionDTO(record *core.Record) PreferenceVersionDTO {
+	return PreferenceVersionDTO{
+		ID:           record.Id,
+		Version:      record.GetInt("version"),
+		GeneratedAt:  record.GetString("generated_at"),
+		Model:        record.GetString("model"),
+		Source:       record.GetString("source"),
+		RestoredFrom: record.GetString("restored_from"),
+		ProfileText:  record.GetString("profile_text"),
+		Corrections:  ai.PreferenceVersionCorrections(record),
+	}
+}
@@ -0,0 +1,94 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+)
+
+func TestHandlePreferenceVersionsDirect(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreatePreference(t, app, "Likes Go.\nDislikes Java.", "2026-01-01 08:00:00.000Z")
+	testutil.CreatePreference(t, app, "Likes Go.\nDislikes crypto.", "2026-01-02 08:00:00.000Z")
+
+	status, versions, err := HandlePreferenceVersionsDirect(app)
+	if err != nil || status != http.StatusOK || len(versions) != 2 {
+		t.Fatalf("unexpected result: %d %v %v", status, versions, err)
+	}
+	if versions[0].Diff != "  Likes Go.\n- Dislikes Java.\n+ Dislikes crypto." {
+		t.Errorf("unexpected diff from previous version:\n%s", versions[0].Diff)
+	}
+	if versions[1].Diff != "+ Likes Go.\n+ Dislikes Java." {
+		t.Errorf("unexpected diff for the first version:\n%s", versions[1].Diff)
+	}
+	if versions[0].Corrections == nil {
+		t.Error("expected an empty corrections list, not null")
+	}
+}
+
+func TestHandleComparePreferenceVersionsDirect(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	a := testutil.CreatePreference(t, app, "Old", "2026-01-01 08:00:00.000Z")
+	b := testutil.CreatePreference(t, app, "New", "2026-01-02 08:00:00.000Z")
+
+	status, dto, err := HandleComparePreferenceVersionsDirect(app, a.Id, b.Id)
+	if err != nil || status != http.StatusOK || dto.Diff != "- Old\n+ New" || dto.From.ID != a.Id {
+		t.Fatalf("unexpected result: %d %+v %v", status, dto, err)
+	}
+	if status, _, err := HandleComparePreferenceVersionsDirect(app, a.Id, ""); err == nil || status != http.StatusBadRequest {
+		t.Errorf("expected 400 without to, got %d", status)
+	}
+	if status, _, err := HandleComparePreferenceVersionsDirect(app, a.Id, "missing"); err == nil || status != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown version, got %d", status)
+	}
+}
+
+func TestPreferenceRoutes(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	old := testutil.CreatePreference(t, app, "Good profile", "2026-01-01 08:00:00.000Z")
+	testutil.CreatePreference(t, app, "Bad profile", "2026-01-02 08:00:00.000Z")
+	mux := buildMux(t, app)
+	token := createAuthToken(t, app)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/preferences/versions", nil),
+		httptest.NewRequest(http.MethodGet, "/api/preferences/versions/compare?from=a&to=b", nil),
+		httptest.NewRequest(http.MethodPost, "/api/preferences/versions/"+old.Id+"/rollback", nil),
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s: expected 401 without auth, got %d", req.Method, req.URL.Path, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/preferences/versions/missing/rollback", nil)
+	req.Header.Set("Authorization", token)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown version, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/preferences/versions/"+old.Id+"/rollback", nil)
+	req.Header.Set("Authorization", token)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"source":"rollback"`) {
+		t.Fatalf("unexpected rollback response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/preferences/versions", nil)
+	req.Header.Set("Authorization", token)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"diff":"- Bad profile\n+ Good profile"`) {
+		t.Errorf("unexpected versions response: %d %s", rec.Code, rec.Body.String())
+	}
+}
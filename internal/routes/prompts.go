@@ -0,0 +1,117 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// PromptDTO lists the versions of one prompt template.
+type PromptDTO struct {
+	Name     string              `json:"name"`
+	Versions []ai.PromptTemplate `json:"versions"`
+}
+
+// CreatePromptVersionRequest is the body of a new prompt version.
+type CreatePromptVersionRequest struct {
+	Template string `json:"template"`
+	Notes    string `json:"notes"`
+}
+
+// ActivatePromptRequest selects the prompt version used in processing.
+type ActivatePromptRequest struct {
+	Version int `json:"version"`
+}
+
+// RegisterPromptRoutes adds the endpoints to list, add and activate versions
+// of the prompt templates.
+func RegisterPromptRoutes(se *core.ServeEvent) {
+	// GET /api/prompts — every prompt with its versions
+	se.Router.GET("/api/prompts", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		status, prompts, err := HandleListPromptsDirect(re.App)
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, map[string]any{"prompts": prompts})
+	})
+
+	// POST /api/prompts/{name} — store a new, inactive version
+	se.Router.POST("/api/prompts/{name}", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		var body CreatePromptVersionRequest
+		if err := re.BindBody(&body); err != nil {
+			return re.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body."})
+		}
+		status, dto, err := HandleCreatePromptVersionDirect(re.App, re.Request.PathValue("name"), body)
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, dto)
+	})
+
+	// POST /api/prompts/{name}/activate — use a version in processing
+	se.Router.POST("/api/prompts/{name}/activate", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		var body ActivatePromptRequest
+		if err := re.BindBody(&body); err != nil {
+			return re.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body."})
+		}
+		status, dto, err := HandleActivatePromptDirect(re.App, re.Request.PathValue("name"), body.Version)
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, dto)
+	})
+}
+
+// HandleListPromptsDirect returns every prompt template with its versions.
+func HandleListPromptsDirect(app core.App) (int, []PromptDTO, error) {
+	prompts := make([]PromptDTO, 0, len(ai.PromptNames))
+	for _, name := range ai.PromptNames {
+		versions, err := ai.PromptVersions(app, name)
+		if err != nil {
+			return http.StatusInternalServerError, nil, fmt.Errorf("Failed to load prompts: %v", err)
+		}
+		prompts = append(prompts, PromptDTO{Name: name, Versions: versions})
+	}
+	return http.StatusOK, prompts, nil
+}
+
+// HandleCreatePromptVersionDirect is the testable core logic for adding a
+// prompt version.
+func HandleCreatePromptVersionDirect(app core.App, name string, body CreatePromptVersionRequest) (int, ai.PromptTemplate, error) {
+	if !ai.IsPromptName(name) {
+		return http.StatusNotFound, ai.PromptTemplate{}, errors.New("Prompt not found.")
+	}
+	if len(body.Template) > ai.MaxPromptTemplateLen {
+		return http.StatusBadRequest, ai.PromptTemplate{}, fmt.Errorf("Template must be at most %d characters.", ai.MaxPromptTemplateLen)
+	}
+	p, err := ai.SavePromptVersion(app, name, body.Template, body.Notes)
+	if err != nil {
+		return http.StatusBadRequest, ai.PromptTemplate{}, fmt.Errorf("Invalid template: %v", err)
+	}
+	return http.StatusCreated, p, nil
+}
+
+// HandleActivatePromptDirect is the testable core logic for switching the
+// prompt version used in processing.
+func HandleActivatePromptDirect(app core.App, name string, version int) (int, ai.PromptTemplate, error) {
+	p, err := ai.ActivatePromptVersion(app, name, version)
+	if errors.Is(err, ai.ErrPromptNotFound) {
+		return http.StatusNotFound, ai.PromptTemplate{}, errors.New("Prompt version not found.")
+	}
+	if err != nil {
+		return http.StatusInternalServerError, ai.PromptTemplate{}, fmt.Errorf("Failed to activate prompt: %v", err)
+	}
+	return http.StatusOK, p, nil
+}
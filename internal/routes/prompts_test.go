@@ -0,0 +1,90 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+)
+
+func TestHandlePromptsDirect(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	status, prompts, err := HandleListPromptsDirect(app)
+	if err != nil || status != http.StatusOK || len(prompts) != len(ai.PromptNames) {
+		t.Fatalf("unexpected list: %d %v %v", status, prompts, err)
+	}
+
+	if status, _, err := HandleCreatePromptVersionDirect(app, "unknown", CreatePromptVersionRequest{Template: "x"}); status != http.StatusNotFound || err == nil {
+		t.Errorf("expected 404 for an unknown prompt, got %d %v", status, err)
+	}
+	if status, _, err := HandleCreatePromptVersionDirect(app, ai.PromptSummary, CreatePromptVersionRequest{Template: "{{.Nope}}"}); status != http.StatusBadRequest || err == nil {
+		t.Errorf("expected 400 for an invalid template, got %d %v", status, err)
+	}
+	long := CreatePromptVersionRequest{Template: strings.Repeat("x", ai.MaxPromptTemplateLen+1)}
+	if status, _, err := HandleCreatePromptVersionDirect(app, ai.PromptSummary, long); status != http.StatusBadRequest || err == nil {
+		t.Errorf("expected 400 for a long template, got %d %v", status, err)
+	}
+
+	status, p, err := HandleCreatePromptVersionDirect(app, ai.PromptSummary, CreatePromptVersionRequest{Template: "Summarize {{.Title}}"})
+	if err != nil || status != http.StatusCreated || p.Version != 2 {
+		t.Fatalf("unexpected create: %d %+v %v", status, p, err)
+	}
+	if status, _, err := HandleActivatePromptDirect(app, ai.PromptSummary, 9); status != http.StatusNotFound || err == nil {
+		t.Errorf("expected 404 for an unknown version, got %d %v", status, err)
+	}
+	status, p, err = HandleActivatePromptDirect(app, ai.PromptSummary, 2)
+	if err != nil || status != http.StatusOK || !p.Active {
+		t.Errorf("unexpected activate: %d %+v %v", status, p, err)
+	}
+}
+
+func TestPromptRoutes(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	mux := buildMux(t, app)
+	token := createAuthToken(t, app)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/prompts", nil),
+		httptest.NewRequest(http.MethodPost, "/api/prompts/summary", strings.NewReader(`{"template":"x"}`)),
+		httptest.NewRequest(http.MethodPost, "/api/prompts/summary/activate", strings.NewReader(`{"version":1}`)),
+	} {
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s: expected 401 without auth, got %d", req.Method, req.URL.Path, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/prompts/score_only", strings.NewReader(`{"template":"Rate {{.Title}}","notes":"terse"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), `"version":2`) {
+		t.Fatalf("unexpected create response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/prompts/score_only/activate", strings.NewReader(`{"version":2}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"active":true`) {
+		t.Fatalf("unexpected activate response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/prompts", nil)
+	req.Header.Set("Authorization", token)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"notes":"terse"`) {
+		t.Errorf("unexpected list response: %d %s", rec.Code, rec.Body.String())
+	}
+}
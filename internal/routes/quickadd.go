@@ -121,26 +121,13 @@ func HandleQuickAddDirect(app core.App, body QuickAddRequest, client *http.Clien
 	}
 
 	// Create entry
-	entry, err := createQuickAddEntry(app, quickAddResource.Id, title, body.URL, extracted.Content)
+	entry, err := createQuickAddEntry(app, quickAddResource.Id, title, body.URL, extracted)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to create entry: %v", err)
 	}
 
 	// Trigger AI processing in background
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Printf("AI processing panicked for quick-add entry %s: %v", entry.Id, r)
-			}
-		}()
-		if aiErr := ai.SummarizeAndScore(app, entry); aiErr != nil {
-			log.Printf("AI processing failed for quick-add entry %s: %v", entry.Id, aiErr)
-			entry.Set("processing_status", "failed")
-			app.Save(entry)
-			return
-		}
-		ai.CheckAndRegeneratePreferences(app)
-	}()
+	go processQuickAddEntry(app, entry)
 
 	response := &QuickAddResponse{
 		Entry: QuickAddEntryInfo{
@@ -151,7 +138,10 @@ func HandleQuickAddDirect(app core.App, body QuickAddRequest, client *http.Clien
 		Message: fmt.Sprintf("Added: %s", title),
 	}
 
-	// Discover RSS feeds
+	// Discover RSS feeds (PDF documents have no feed links)
+	if len(extracted.Pages) > 0 {
+		return response, nil
+	}
 	feeds, err := engine.DiscoverFeeds(body.URL, client)
 	if err == nil && len(feeds) > 0 {
 		feedURL := feeds[0].URL
@@ -225,6 +215,23 @@ func HandleSubscribeDirect(app core.App, body SubscribeRequest) (map[string]stri
 	}, nil
 }
 
+// processQuickAddEntry summarizes and scores a Quick Add entry, then lets
+// the preference profile catch up. Run it in a goroutine.
+func processQuickAddEntry(app core.App, entry *core.Record) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("AI processing panicked for quick-add entry %s: %v", entry.Id, r)
+		}
+	}()
+	if aiErr := ai.SummarizeAndScore(app, entry); aiErr != nil {
+		log.Printf("AI processing failed for quick-add entry %s: %v", entry.Id, aiErr)
+		entry.Set("processing_status", "failed")
+		app.Save(entry)
+		return
+	}
+	ai.CheckAndRegeneratePreferences(app)
+}
+
 // findQuickAddResource finds the system Quick Add resource.
 func findQuickAddResource(app core.App) (*core.Record, error) {
 	records, err := app.FindRecordsByFilter("resources", "type = 'quickadd'", "", 1, 0)
@@ -235,7 +242,7 @@ func findQuickAddResource(app core.App) (*core.Record, error) {
 }
 
 // createQuickAddEntry creates an entry under the Quick Add resource.
-func createQuickAddEntry(app core.App, resourceID, title, entryURL, content string) (*core.Record, error) {
+func createQuickAddEntry(app core.App, resourceID, title, entryURL string, extracted engine.ExtractedContent) (*core.Record, error) {
 	collection, err := app.FindCollectionByNameOrId("entries")
 	if err != nil {
 		return nil, err
@@ -246,11 +253,12 @@ func createQuickAddEntry(app core.App, resourceID, title, entryURL, content stri
 	record.Set("title", title)
 	record.Set("url", entryURL)
 	record.Set("guid", entryURL) // use URL as GUID for one-off articles
-	record.Set("raw_content", content)
+	record.Set("raw_content", extracted.Content)
 	record.Set("discovered_at", time.Now().UTC().Format(time.RFC3339))
 	record.Set("published_at", time.Now().UTC().Format(time.RFC3339))
 	record.Set("processing_status", "pending")
 	record.Set("is_read", false)
+	engine.SetExtractedFields(record, extracted)
 
 	if err := app.Save(record); err != nil {
 		return nil, err
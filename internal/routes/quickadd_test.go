@@ -94,6 +94,12 @@ func TestHandleQuickAddDirect_Success(t *testing.T) {
 	if status != "pending" && status != "done" && status != "failed" {
 		t.Errorf("unexpected processing_status: %s", status)
 	}
+	if !strings.Contains(entry.GetString("content_html"), "<p>This is a great article") {
+		t.Errorf("expected structured HTML to be stored, got %q", entry.GetString("content_html"))
+	}
+	if entry.GetInt("word_count") == 0 {
+		t.Error("expected word_count to be stored")
+	}
 }
 
 func TestHandleQuickAddDirect_DuplicateURL(t *testing.T) {
@@ -256,3 +262,53 @@ func TestHandleQuickAddDirect_FetchFailure(t *testing.T) {
 		t.Error("expected error for fetch failure")
 	}
 }
+
+func TestHandleQuickAddDirect_PDF(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	testutil.CreateResource(t, app, "Quick Add", "https://quickadd.local", "quickadd", "healthy", 0, true)
+
+	restore := ai.SetCompleteFunc(func(_, _ string, _ []ai.Message) (string, error) {
+		return `{"summary":"Test summary","stars":4,"takeaways":["point 1"]}`, nil
+	})
+	defer restore()
+
+	feedLookups := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/whitepaper.pdf" {
+			feedLookups++
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(testutil.BuildPDF("Bitcoin: A Peer-to-Peer Electronic Cash System", "Satoshi Nakamoto",
+			"Abstract\n\nA purely peer-to-peer version of electronic cash.", "1. Introduction\n\nCommerce on the Internet."))
+	}))
+	defer srv.Close()
+
+	resp, err := HandleQuickAddDirect(app, QuickAddRequest{URL: srv.URL + "/whitepaper.pdf"}, &http.Client{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Entry.Title != "Bitcoin: A Peer-to-Peer Electronic Cash System" {
+		t.Errorf("expected title from the PDF info dictionary, got %q", resp.Entry.Title)
+	}
+	if resp.RSS != nil || feedLookups > 0 {
+		t.Error("expected no feed discovery for a PDF")
+	}
+
+	entry, err := app.FindRecordById("entries", resp.Entry.ID)
+	if err != nil {
+		t.Fatalf("entry not found: %v", err)
+	}
+	if entry.GetString("byline") != "Satoshi Nakamoto" {
+		t.Errorf("expected byline from the PDF author, got %q", entry.GetString("byline"))
+	}
+	if !strings.Contains(entry.GetString("raw_content"), "A purely peer-to-peer version of electronic cash.") {
+		t.Errorf("expected PDF text in raw_content, got %q", entry.GetString("raw_content"))
+	}
+	if pages := ai.EntryPDFPages(entry); len(pages) != 2 {
+		t.Errorf("expected 2 stored pages, got %d", len(pages))
+	}
+}
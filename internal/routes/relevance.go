@@ -0,0 +1,73 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jgordijn/knowledgehub/internal/engine"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const defaultRelevanceReportDays = 90
+
+// RegisterRelevanceRoutes adds the endpoints to train and evaluate the local
+// relevance model.
+func RegisterRelevanceRoutes(se *core.ServeEvent) {
+	// GET /api/relevance/report?days=N — local predictions vs AI and user ratings per week
+	se.Router.GET("/api/relevance/report", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		status, report, err := HandleRelevanceReportDirect(re.App, re.Request.URL.Query().Get("days"), time.Now())
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, report)
+	})
+
+	// POST /api/relevance/train — retrain the local model now
+	se.Router.POST("/api/relevance/train", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		status, training, err := HandleTrainRelevanceDirect(re.App, time.Now())
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, training)
+	})
+}
+
+// HandleRelevanceReportDirect is the testable core logic for the evaluation
+// report. An empty days covers the last 90 days.
+func HandleRelevanceReportDirect(app core.App, days string, now time.Time) (int, engine.RelevanceReport, error) {
+	n := defaultRelevanceReportDays
+	if days != "" {
+		parsed, err := strconv.Atoi(days)
+		if err != nil || parsed < 1 {
+			return http.StatusBadRequest, engine.RelevanceReport{}, errors.New("days must be a positive number.")
+		}
+		n = parsed
+	}
+	report, err := engine.RelevanceEvaluation(app, now.AddDate(0, 0, -n))
+	if err != nil {
+		return http.StatusInternalServerError, engine.RelevanceReport{}, fmt.Errorf("Failed to build report: %v", err)
+	}
+	return http.StatusOK, report, nil
+}
+
+// HandleTrainRelevanceDirect is the testable core logic for training the
+// local relevance model on demand.
+func HandleTrainRelevanceDirect(app core.App, now time.Time) (int, engine.RelevanceTraining, error) {
+	record, err := engine.TrainAndSaveRelevanceModel(app, now)
+	if err != nil {
+		return http.StatusInternalServerError, engine.RelevanceTraining{}, fmt.Errorf("Failed to train model: %v", err)
+	}
+	if record == nil {
+		return http.StatusUnprocessableEntity, engine.RelevanceTraining{}, fmt.Errorf("Rate at least %d entries before training the relevance model.", engine.MinRelevanceSamples)
+	}
+	return http.StatusOK, engine.RelevanceTrainingFromRecord(record), nil
+}
@@ -0,0 +1,88 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+)
+
+func TestHandleRelevanceReportDirect(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntryWithStars(t, app, res.Id, "Post", "https://example.com/post", 4, 4)
+	entry.Set("local_stars", 4)
+	app.Save(entry)
+
+	status, report, err := HandleRelevanceReportDirect(app, "", time.Now())
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("unexpected result: %d %v", status, err)
+	}
+	if len(report.Weeks) != 1 || report.Weeks[0].AgreementUser != 1 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	for _, days := range []string{"0", "soon"} {
+		if status, _, err := HandleRelevanceReportDirect(app, days, time.Now()); err == nil || status != http.StatusBadRequest {
+			t.Errorf("days=%q: expected 400, got %d", days, status)
+		}
+	}
+}
+
+func TestHandleTrainRelevanceDirect(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	if status, _, err := HandleTrainRelevanceDirect(app, time.Now()); err == nil || status != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 without ratings, got %d %v", status, err)
+	}
+
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	for i := 0; i < 20; i++ {
+		stars := 1 + 4*(i%2)
+		testutil.CreateEntryWithStars(t, app, res.Id, fmt.Sprintf("Post %d", i), fmt.Sprintf("https://example.com/%d", i), stars, stars)
+	}
+	status, training, err := HandleTrainRelevanceDirect(app, time.Now())
+	if err != nil || status != http.StatusOK || training.Samples != 20 || training.Holdout != 4 {
+		t.Fatalf("unexpected result: %d %+v %v", status, training, err)
+	}
+}
+
+func TestRelevanceRoutes(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	mux := buildMux(t, app)
+	token := createAuthToken(t, app)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/relevance/report", nil),
+		httptest.NewRequest(http.MethodPost, "/api/relevance/train", nil),
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s: expected 401 without auth, got %d", req.Method, req.URL.Path, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/relevance/report?days=30", nil)
+	req.Header.Set("Authorization", token)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"weeks":[]`) {
+		t.Errorf("unexpected report response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/relevance/train", nil)
+	req.Header.Set("Authorization", token)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 without ratings, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,196 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/jgordijn/knowledgehub/internal/engine"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const rescoreJobsListLimit = 20
+
+// RescoreRequest is the body of an on-demand rescore. Zero values use the
+// configured rescore_days and rescore_max_entries settings.
+type RescoreRequest struct {
+	Days       int `json:"days"`
+	MaxEntries int `json:"max_entries"`
+}
+
+// RescoreJobDTO reports the progress of a rescore job and how far the
+// profile change moved the scores.
+type RescoreJobDTO struct {
+	ID              string `json:"id"`
+	Trigger         string `json:"trigger"`
+	Status          string `json:"status"`
+	Days            int    `json:"days"`
+	MaxEntries      int    `json:"max_entries"`
+	MaxTokens       int    `json:"max_tokens"`
+	ProfileVersion  int    `json:"profile_version,omitempty"`
+	Total           int    `json:"total"`
+	Processed       int    `json:"processed"`
+	Changed         int    `json:"changed"`
+	Unchanged       int    `json:"unchanged"`
+	Failed          int    `json:"failed"`
+	Skipped         int    `json:"skipped"`
+	EstimatedTokens int    `json:"estimated_tokens"`
+	// AverageShift is the mean absolute star change over rescored entries;
+	// Raised and Lowered count entries that moved up or down.
+	AverageShift float64                 `json:"average_shift"`
+	Raised       int                     `json:"raised"`
+	Lowered      int                     `json:"lowered"`
+	Error        string                  `json:"error,omitempty"`
+	Created      string                  `json:"created"`
+	FinishedAt   string                  `json:"finished_at,omitempty"`
+	Items        []engine.RescoreJobItem `json:"items,omitempty"`
+}
+
+// RegisterRescoreRoutes adds the endpoints to rescore recent unread entries
+// and follow the progress of rescore jobs.
+func RegisterRescoreRoutes(se *core.ServeEvent) {
+	// POST /api/rescore — queue a rescore of recent unread entries
+	se.Router.POST("/api/rescore", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		var body RescoreRequest
+		if re.Request.ContentLength != 0 {
+			if err := re.BindBody(&body); err != nil {
+				return re.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body."})
+			}
+		}
+		status, dto, err := HandleStartRescoreDirect(re.App, body, time.Now())
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, dto)
+	})
+
+	// GET /api/rescore/jobs — the most recent rescore jobs, without their entries
+	se.Router.GET("/api/rescore/jobs", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		status, jobs, err := HandleListRescoreJobsDirect(re.App)
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, map[string]any{"jobs": jobs})
+	})
+
+	// GET /api/rescore/jobs/{id} — progress with every entry's old and new score
+	se.Router.GET("/api/rescore/jobs/{id}", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		status, dto, err := HandleGetRescoreJobDirect(re.App, re.Request.PathValue("id"))
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, dto)
+	})
+}
+
+// HandleStartRescoreDirect is the testable core logic for an on-demand
+// rescore. The configured token budget always applies.
+func HandleStartRescoreDirect(app core.App, body RescoreRequest, now time.Time) (int, *RescoreJobDTO, error) {
+	if body.Days < 0 || body.Days > engine.MaxRescoreDays {
+		return http.StatusBadRequest, nil, fmt.Errorf("Days must be between 1 and %d.", engine.MaxRescoreDays)
+	}
+	if body.MaxEntries < 0 || body.MaxEntries > engine.MaxRescoreEntries {
+		return http.StatusBadRequest, nil, fmt.Errorf("Max entries must be between 1 and %d.", engine.MaxRescoreEntries)
+	}
+	if active, err := app.FindFirstRecordByFilter("rescore_jobs", "status = 'pending' || status = 'running'", nil); err == nil && active != nil {
+		return http.StatusConflict, nil, errors.New("A rescore is already running.")
+	}
+
+	opts := engine.DefaultRescoreOptions(app, engine.RescoreTriggerManual)
+	if body.Days > 0 {
+		opts.Days = body.Days
+	}
+	if body.MaxEntries > 0 {
+		opts.MaxEntries = body.MaxEntries
+	}
+	job, err := engine.CreateRescoreJob(app, opts, now)
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("Failed to queue rescore: %v", err)
+	}
+	engine.StartRescoreJob(app, job.Id)
+
+	dto := rescoreJobDTO(job)
+	dto.Items = nil
+	return http.StatusAccepted, dto, nil
+}
+
+// HandleListRescoreJobsDirect returns the most recent rescore jobs.
+func HandleListRescoreJobsDirect(app core.App) (int, []*RescoreJobDTO, error) {
+	records, err := app.FindRecordsByFilter("rescore_jobs", "", "-created", rescoreJobsListLimit, 0)
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("Failed to load rescore jobs: %v", err)
+	}
+	jobs := make([]*RescoreJobDTO, len(records))
+	for i, r := range records {
+		jobs[i] = rescoreJobDTO(r)
+		jobs[i].Items = nil
+	}
+	return http.StatusOK, jobs, nil
+}
+
+// HandleGetRescoreJobDirect returns the progress of a rescore job, including
+// the old and new score of every entry.
+func HandleGetRescoreJobDirect(app core.App, jobID string) (int, *RescoreJobDTO, error) {
+	job, err := app.FindRecordById("rescore_jobs", jobID)
+	if err != nil {
+		return http.StatusNotFound, nil, errors.New("Rescore job not found.")
+	}
+	return http.StatusOK, rescoreJobDTO(job), nil
+}
+
+func rescoreJobDTO(job *core.Record) *RescoreJobDTO {
+	dto := &RescoreJobDTO{
+		ID:              job.Id,
+		Trigger:         job.GetString("trigger"),
+		Status:          job.GetString("status"),
+		Days:            job.GetInt("days"),
+		MaxEntries:      job.GetInt("max_entries"),
+		MaxTokens:       job.GetInt("max_tokens"),
+		ProfileVersion:  job.GetInt("profile_version"),
+		Total:           job.GetInt("total"),
+		Processed:       job.GetInt("processed"),
+		Changed:         job.GetInt("changed"),
+		Unchanged:       job.GetInt("unchanged"),
+		Failed:          job.GetInt("failed"),
+		Skipped:         job.GetInt("skipped"),
+		EstimatedTokens: job.GetInt("estimated_tokens"),
+		Error:           job.GetString("error"),
+		Created:         job.GetString("created"),
+	}
+	if finished := job.GetDateTime("finished_at"); !finished.IsZero() {
+		dto.FinishedAt = finished.String()
+	}
+	_ = json.Unmarshal([]byte(job.GetString("items")), &dto.Items)
+
+	rescored, shift := 0, 0
+	for _, item := range dto.Items {
+		if item.Status == "" || item.Status == "failed" {
+			continue
+		}
+		rescored++
+		diff := item.NewStars - item.OldStars
+		shift += int(math.Abs(float64(diff)))
+		switch {
+		case diff > 0:
+			dto.Raised++
+		case diff < 0:
+			dto.Lowered++
+		}
+	}
+	if rescored > 0 {
+		dto.AverageShift = math.Round(float64(shift)/float64(rescored)*100) / 100
+	}
+	return dto
+}
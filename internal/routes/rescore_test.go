@@ -0,0 +1,145 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jgordijn/knowledgehub/internal/engine"
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// waitForRescoreJob waits until a background rescore job stops running.
+func waitForRescoreJob(t *testing.T, app core.App, jobID string) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		job, err := app.FindRecordById("rescore_jobs", jobID)
+		if err == nil && job.GetString("status") != engine.ImportJobPending && job.GetString("status") != engine.ImportJobRunning {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("rescore job did not finish")
+}
+
+func TestHandleStartRescoreDirect(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	testutil.CreateEntryWithStars(t, app, res.Id, "One", "https://example.com/1", 3, 0)
+	testutil.CreateEntryWithStars(t, app, res.Id, "Two", "https://example.com/2", 3, 0)
+
+	for _, body := range []RescoreRequest{{Days: -1}, {Days: engine.MaxRescoreDays + 1}, {MaxEntries: engine.MaxRescoreEntries + 1}} {
+		if status, _, err := HandleStartRescoreDirect(app, body, time.Now()); err == nil || status != http.StatusBadRequest {
+			t.Errorf("%+v: expected 400, got %d", body, status)
+		}
+	}
+
+	status, dto, err := HandleStartRescoreDirect(app, RescoreRequest{Days: 2, MaxEntries: 1}, time.Now())
+	if err != nil || status != http.StatusAccepted {
+		t.Fatalf("unexpected result: %d %v", status, err)
+	}
+	if dto.Trigger != engine.RescoreTriggerManual || dto.Days != 2 || dto.Total != 1 || dto.Skipped != 1 || dto.Items != nil {
+		t.Errorf("unexpected dto: %+v", dto)
+	}
+	waitForRescoreJob(t, app, dto.ID)
+
+	// Without an API key the job fails, so another one can be started.
+	if status, _, err := HandleStartRescoreDirect(app, RescoreRequest{}, time.Now()); err != nil || status != http.StatusAccepted {
+		t.Fatalf("unexpected result: %d %v", status, err)
+	}
+}
+
+func TestHandleStartRescoreDirect_Conflict(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	collection, _ := app.FindCollectionByNameOrId("rescore_jobs")
+	job := core.NewRecord(collection)
+	job.Set("trigger", engine.RescoreTriggerProfile)
+	job.Set("status", engine.ImportJobRunning)
+	if err := app.Save(job); err != nil {
+		t.Fatal(err)
+	}
+	if status, _, err := HandleStartRescoreDirect(app, RescoreRequest{}, time.Now()); err == nil || status != http.StatusConflict {
+		t.Errorf("expected 409, got %d %v", status, err)
+	}
+}
+
+func TestHandleGetRescoreJobDirect(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	collection, _ := app.FindCollectionByNameOrId("rescore_jobs")
+	job := core.NewRecord(collection)
+	job.Set("trigger", engine.RescoreTriggerProfile)
+	job.Set("status", engine.ImportJobDone)
+	job.Set("items", []engine.RescoreJobItem{
+		{Entry: "a", OldStars: 2, NewStars: 5, Status: "changed"},
+		{Entry: "b", OldStars: 4, NewStars: 3, Status: "changed"},
+		{Entry: "c", OldStars: 3, NewStars: 3, Status: "unchanged"},
+		{Entry: "d", OldStars: 3, Status: "failed"},
+	})
+	if err := app.Save(job); err != nil {
+		t.Fatal(err)
+	}
+
+	status, dto, err := HandleGetRescoreJobDirect(app, job.Id)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("unexpected result: %d %v", status, err)
+	}
+	if len(dto.Items) != 4 || dto.Raised != 1 || dto.Lowered != 1 || dto.AverageShift != 1.33 {
+		t.Errorf("unexpected dto: %+v", dto)
+	}
+
+	if status, _, _ := HandleGetRescoreJobDirect(app, "missing"); status != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", status)
+	}
+
+	status, jobs, err := HandleListRescoreJobsDirect(app)
+	if err != nil || status != http.StatusOK || len(jobs) != 1 || jobs[0].Items != nil || jobs[0].Raised != 1 {
+		t.Errorf("unexpected list: %d %+v %v", status, jobs, err)
+	}
+}
+
+func TestRescoreRoutes(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	mux := buildMux(t, app)
+	token := createAuthToken(t, app)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/api/rescore", nil),
+		httptest.NewRequest(http.MethodGet, "/api/rescore/jobs", nil),
+		httptest.NewRequest(http.MethodGet, "/api/rescore/jobs/abc", nil),
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s: expected 401 without auth, got %d", req.Method, req.URL.Path, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/rescore", strings.NewReader(`{"days":3}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted || !strings.Contains(rec.Body.String(), `"days":3`) {
+		t.Fatalf("unexpected response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/rescore/jobs", nil)
+	req.Header.Set("Authorization", token)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"trigger":"manual"`) {
+		t.Errorf("unexpected list response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	jobs, _ := app.FindRecordsByFilter("rescore_jobs", "", "", 0, 0)
+	for _, job := range jobs {
+		waitForRescoreJob(t, app, job.Id)
+	}
+}
@@ -0,0 +1,86 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// ScoreWeightsDTO is the response for the score weight endpoints.
+type ScoreWeightsDTO struct {
+	Weights    ai.ScoreWeights `json:"weights"`
+	Dimensions []string        `json:"dimensions"`
+	// Samples is the number of rated entries the weights were learned from.
+	Samples int `json:"samples,omitempty"`
+}
+
+// RegisterScoringRoutes adds the endpoints for the weights that combine the
+// score dimensions into ai_stars.
+func RegisterScoringRoutes(se *core.ServeEvent) {
+	// GET /api/scoring/weights — current dimension weights
+	se.Router.GET("/api/scoring/weights", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		return re.JSON(http.StatusOK, scoreWeightsDTO(ai.GetScoreWeights(re.App), 0))
+	})
+
+	// PUT /api/scoring/weights — set the dimension weights by hand
+	se.Router.PUT("/api/scoring/weights", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		var body ScoreWeightsDTO
+		if err := re.BindBody(&body); err != nil {
+			return re.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body."})
+		}
+		status, dto, err := HandleSaveScoreWeightsDirect(re.App, body.Weights)
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, dto)
+	})
+
+	// POST /api/scoring/weights/learn — fit the weights to the user's ratings
+	se.Router.POST("/api/scoring/weights/learn", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		status, dto, err := HandleLearnScoreWeightsDirect(re.App)
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, dto)
+	})
+}
+
+// HandleSaveScoreWeightsDirect is the testable core logic for saving weights.
+func HandleSaveScoreWeightsDirect(app core.App, weights ai.ScoreWeights) (int, ScoreWeightsDTO, error) {
+	if err := weights.Validate(); err != nil {
+		return http.StatusBadRequest, ScoreWeightsDTO{}, fmt.Errorf("Invalid weights: %v.", err)
+	}
+	if err := ai.SaveScoreWeights(app, weights); err != nil {
+		return http.StatusInternalServerError, ScoreWeightsDTO{}, fmt.Errorf("Failed to save weights: %v", err)
+	}
+	return http.StatusOK, scoreWeightsDTO(weights, 0), nil
+}
+
+// HandleLearnScoreWeightsDirect is the testable core logic for learning
+// weights from rating corrections.
+func HandleLearnScoreWeightsDirect(app core.App) (int, ScoreWeightsDTO, error) {
+	weights, samples, err := ai.LearnScoreWeights(app)
+	if errors.Is(err, ai.ErrTooFewRatings) {
+		return http.StatusUnprocessableEntity, ScoreWeightsDTO{}, fmt.Errorf("Rate more entries before learning weights (%d of 10 rated entries have dimension scores).", samples)
+	}
+	if err != nil {
+		return http.StatusInternalServerError, ScoreWeightsDTO{}, fmt.Errorf("Failed to learn weights: %v", err)
+	}
+	return http.StatusOK, scoreWeightsDTO(weights, samples), nil
+}
+
+func scoreWeightsDTO(weights ai.ScoreWeights, samples int) ScoreWeightsDTO {
+	return ScoreWeightsDTO{Weights: weights, Dimensions: ai.ScoreDimensions, Samples: samples}
+}
@@ -0,0 +1,99 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+)
+
+func TestHandleSaveScoreWeightsDirect(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	status, dto, err := HandleSaveScoreWeightsDirect(app, ai.ScoreWeights{"relevance": 1, "depth": 1})
+	if err != nil || status != http.StatusOK || dto.Weights["depth"] != 1 {
+		t.Fatalf("unexpected result: %d %+v %v", status, dto, err)
+	}
+	if got := ai.GetScoreWeights(app); got["relevance"] != 1 {
+		t.Errorf("weights not saved: %v", got)
+	}
+
+	for _, weights := range []ai.ScoreWeights{nil, {"relevance": -1}, {"popularity": 1}} {
+		if status, _, err := HandleSaveScoreWeightsDirect(app, weights); err == nil || status != http.StatusBadRequest {
+			t.Errorf("%v: expected 400, got %d", weights, status)
+		}
+	}
+}
+
+func TestHandleLearnScoreWeightsDirect(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	status, _, err := HandleLearnScoreWeightsDirect(app)
+	if err == nil || status != http.StatusUnprocessableEntity || !strings.Contains(err.Error(), "0 of 10") {
+		t.Fatalf("expected 422 without ratings, got %d %v", status, err)
+	}
+
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	for i := 0; i < 10; i++ {
+		stars := 1 + i%5
+		entry := testutil.CreateEntryWithStars(t, app, res.Id, fmt.Sprintf("Post %d", i), fmt.Sprintf("https://example.com/%d", i), 3, stars)
+		for _, dim := range ai.ScoreDimensions {
+			entry.Set("score_"+dim, stars)
+		}
+		app.Save(entry)
+	}
+	status, dto, err := HandleLearnScoreWeightsDirect(app)
+	if err != nil || status != http.StatusOK || dto.Samples != 10 {
+		t.Fatalf("unexpected result: %d %+v %v", status, dto, err)
+	}
+}
+
+func TestScoringRoutes(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	mux := buildMux(t, app)
+	token := createAuthToken(t, app)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/scoring/weights", nil),
+		httptest.NewRequest(http.MethodPut, "/api/scoring/weights", strings.NewReader(`{}`)),
+		httptest.NewRequest(http.MethodPost, "/api/scoring/weights/learn", nil),
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s: expected 401 without auth, got %d", req.Method, req.URL.Path, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/scoring/weights", nil)
+	req.Header.Set("Authorization", token)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"relevance":0.6`) {
+		t.Fatalf("unexpected weights response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/api/scoring/weights", strings.NewReader(`{"weights":{"novelty":1}}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"novelty":1`) {
+		t.Fatalf("unexpected save response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/scoring/weights/learn", nil)
+	req.Header.Set("Authorization", token)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 without ratings, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
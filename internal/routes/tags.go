@@ -0,0 +1,92 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jgordijn/knowledgehub/internal/engine"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// TagMergeRequest names the tag to fold into another.
+type TagMergeRequest struct {
+	From string `json:"from"`
+	Into string `json:"into"`
+}
+
+// RegisterTagRoutes adds the tag frequency, merge and backfill endpoints.
+// Tags and their synonyms are edited through the tags collection API.
+func RegisterTagRoutes(se *core.ServeEvent) {
+	// GET /api/tags/frequencies?days=N — tag counts, optionally for recent entries only
+	se.Router.GET("/api/tags/frequencies", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		status, counts, err := HandleTagFrequenciesDirect(re.App, re.Request.URL.Query().Get("days"), time.Now())
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, map[string]any{"tags": counts})
+	})
+
+	// POST /api/tags/merge — fold one tag into another
+	se.Router.POST("/api/tags/merge", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		var body TagMergeRequest
+		if err := re.BindBody(&body); err != nil {
+			return re.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body."})
+		}
+		status, updated, err := HandleMergeTagsDirect(re.App, body)
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, map[string]any{"updated": updated})
+	})
+
+	// POST /api/tags/backfill — tag processed entries that have no tags yet
+	se.Router.POST("/api/tags/backfill", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		queued, err := engine.StartTagBackfill(re.App)
+		if errors.Is(err, engine.ErrTagBackfillRunning) {
+			return re.JSON(http.StatusConflict, map[string]string{"error": "A tag backfill is already running."})
+		}
+		if err != nil {
+			return re.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(http.StatusAccepted, map[string]any{"queued": queued})
+	})
+}
+
+// HandleTagFrequenciesDirect is the testable core logic for tag counts. An
+// empty days counts all entries.
+func HandleTagFrequenciesDirect(app core.App, days string, now time.Time) (int, []engine.TagCount, error) {
+	var since time.Time
+	if days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 1 {
+			return http.StatusBadRequest, nil, errors.New("days must be a positive number.")
+		}
+		since = now.AddDate(0, 0, -n)
+	}
+	counts, err := engine.TagFrequencies(app, since)
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("Failed to count tags: %v", err)
+	}
+	return http.StatusOK, counts, nil
+}
+
+// HandleMergeTagsDirect is the testable core logic for merging tags.
+func HandleMergeTagsDirect(app core.App, body TagMergeRequest) (int, int, error) {
+	updated, err := engine.MergeTags(app, body.From, body.Into)
+	if err != nil {
+		return http.StatusBadRequest, 0, fmt.Errorf("Failed to merge tags: %v", err)
+	}
+	return http.StatusOK, updated, nil
+}
@@ -0,0 +1,122 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jgordijn/knowledgehub/internal/engine"
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+)
+
+func TestHandleTagFrequenciesDirect(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	now := time.Now().UTC()
+	for i, tags := range [][]string{{"go"}, {"go", "rust"}} {
+		entry := testutil.CreateEntry(t, app, res.Id, "Post", "https://example.com/"+tags[len(tags)-1], string(rune('a'+i)))
+		entry.Set("tags", tags)
+		entry.Set("discovered_at", now.AddDate(0, 0, -3*i))
+		app.Save(entry)
+	}
+
+	status, counts, err := HandleTagFrequenciesDirect(app, "", now)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("unexpected result: %d %v", status, err)
+	}
+	if len(counts) != 2 || counts[0] != (engine.TagCount{Name: "go", Count: 2}) {
+		t.Errorf("unexpected counts: %v", counts)
+	}
+
+	_, counts, _ = HandleTagFrequenciesDirect(app, "1", now)
+	if len(counts) != 1 || counts[0] != (engine.TagCount{Name: "go", Count: 1}) {
+		t.Errorf("unexpected counts for the last day: %v", counts)
+	}
+
+	for _, days := range []string{"0", "-1", "week"} {
+		if status, _, err := HandleTagFrequenciesDirect(app, days, now); err == nil || status != http.StatusBadRequest {
+			t.Errorf("days=%q: expected 400, got %d", days, status)
+		}
+	}
+}
+
+func TestHandleMergeTagsDirect(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Post", "https://example.com/post", "post")
+	entry.Set("tags", []string{"golang"})
+	app.Save(entry)
+
+	status, updated, err := HandleMergeTagsDirect(app, TagMergeRequest{From: "golang", Into: "go"})
+	if err != nil || status != http.StatusOK || updated != 1 {
+		t.Fatalf("unexpected result: %d %d %v", status, updated, err)
+	}
+	if status, _, err := HandleMergeTagsDirect(app, TagMergeRequest{From: "go"}); err == nil || status != http.StatusBadRequest {
+		t.Errorf("expected 400 without a target, got %d", status)
+	}
+}
+
+func TestTagRoutes(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	res := testutil.CreateResource(t, app, "Blog", "https://example.com", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Post", "https://example.com/post", "post")
+	entry.Set("tags", []string{"k8s"})
+	entry.Set("discovered_at", time.Now().UTC())
+	app.Save(entry)
+
+	mux := buildMux(t, app)
+	token := createAuthToken(t, app)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/tags/frequencies", nil),
+		httptest.NewRequest(http.MethodPost, "/api/tags/merge", strings.NewReader(`{}`)),
+		httptest.NewRequest(http.MethodPost, "/api/tags/backfill", nil),
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s: expected 401 without auth, got %d", req.Method, req.URL.Path, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/tags/merge", strings.NewReader(`{"from":"k8s","into":"kubernetes"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"updated":1`) {
+		t.Fatalf("unexpected merge response: %d %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/tags/frequencies?days=7", nil)
+	req.Header.Set("Authorization", token)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Tags []engine.TagCount `json:"tags"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Tags) != 1 || body.Tags[0].Name != "kubernetes" {
+		t.Errorf("unexpected frequencies: %v", body.Tags)
+	}
+
+	// Every processed entry is tagged, so the backfill has nothing to do.
+	req = httptest.NewRequest(http.MethodPost, "/api/tags/backfill", nil)
+	req.Header.Set("Authorization", token)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted || !strings.Contains(rec.Body.String(), `"queued":0`) {
+		t.Errorf("unexpected backfill response: %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,195 @@
+package routes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jgordijn/knowledgehub/internal/engine"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+)
+
+// sourceContentTypes are the Content-Types used when serving an entry's
+// original file, by upload format.
+var sourceContentTypes = map[string]string{
+	engine.UploadMarkdown: "text/markdown; charset=utf-8",
+	engine.UploadHTML:     "text/plain; charset=utf-8", // never render uploaded HTML on our origin
+	engine.UploadEPUB:     "application/epub+zip",
+	engine.UploadPDF:      "application/pdf",
+}
+
+// RegisterUploadRoutes adds the Quick Add file upload endpoint and the
+// endpoint serving an uploaded entry's original file.
+func RegisterUploadRoutes(se *core.ServeEvent) {
+	// POST /api/quick-add/upload — multipart form with "file" (and optional "title")
+	se.Router.POST("/api/quick-add/upload", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		file, header, err := re.Request.FormFile("file")
+		if err != nil {
+			return re.JSON(http.StatusBadRequest, map[string]string{"error": "A file is required."})
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(io.LimitReader(file, engine.MaxUploadSize+1))
+		if err != nil {
+			return re.JSON(http.StatusBadRequest, map[string]string{"error": "Could not read the uploaded file."})
+		}
+		status, resp, err := HandleQuickAddUploadDirect(re.App, header.Filename, data, re.Request.FormValue("title"))
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		return re.JSON(status, resp)
+	}).Bind(apis.BodyLimit(engine.MaxUploadSize + 1<<20))
+
+	// GET /api/entries/{id}/source — download the original uploaded file
+	se.Router.GET("/api/entries/{id}/source", func(re *core.RequestEvent) error {
+		if re.Auth == nil {
+			return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication required."})
+		}
+		status, source, err := HandleGetSourceFileDirect(re.App, re.Request.PathValue("id"))
+		if err != nil {
+			return re.JSON(status, map[string]string{"error": err.Error()})
+		}
+		contentType := sourceContentTypes[source.Format]
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		re.Response.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": source.Name}))
+		re.Response.Header().Set("X-Content-Type-Options", "nosniff")
+		return re.Blob(status, contentType, source.Data)
+	})
+}
+
+// HandleQuickAddUploadDirect is the testable core logic for file uploads: it
+// extracts the document, rejects files already uploaded (by SHA-256 of the
+// content), stores the original file and creates an entry under the Quick
+// Add resource, which is then summarized and scored like any other entry.
+func HandleQuickAddUploadDirect(app core.App, filename string, data []byte, title string) (int, *QuickAddResponse, error) {
+	filename = path.Base(strings.ReplaceAll(filename, `\`, "/"))
+	if len(data) == 0 {
+		return http.StatusBadRequest, nil, errors.New("The uploaded file is empty.")
+	}
+	if len(data) > engine.MaxUploadSize {
+		return http.StatusRequestEntityTooLarge, nil, fmt.Errorf("The file is larger than %d MB.", engine.MaxUploadSize>>20)
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	guid := "upload:" + hash
+	if existing, err := app.FindFirstRecordByFilter("entries", "guid = {:guid}", map[string]any{"guid": guid}); err == nil {
+		return http.StatusConflict, nil, fmt.Errorf("File already exists: %s", existing.GetString("title"))
+	}
+
+	quickAddResource, err := findQuickAddResource(app)
+	if err != nil {
+		return http.StatusInternalServerError, nil, errors.New("Quick Add resource not found. Restart the application.")
+	}
+
+	extracted, format, err := engine.ExtractUpload(filename, data)
+	if errors.Is(err, engine.ErrUnsupportedUpload) {
+		return http.StatusUnsupportedMediaType, nil, errors.New("Unsupported file type. Upload Markdown, HTML, EPUB or PDF.")
+	}
+	if err != nil {
+		return http.StatusUnprocessableEntity, nil, fmt.Errorf("Failed to read file: %v", err)
+	}
+
+	if title = strings.TrimSpace(title); title == "" {
+		title = extracted.Title
+	}
+	entryURL := strings.TrimRight(quickAddResource.GetString("url"), "#") + "#" + hash[:12]
+
+	entry, err := createUploadEntry(app, quickAddResource.Id, title, entryURL, guid, filename, format, data, extracted)
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("Failed to create entry: %v", err)
+	}
+
+	go processQuickAddEntry(app, entry)
+
+	return http.StatusOK, &QuickAddResponse{
+		Entry: QuickAddEntryInfo{
+			ID:    entry.Id,
+			Title: title,
+			URL:   entryURL,
+		},
+		Message: fmt.Sprintf("Added: %s", title),
+	}, nil
+}
+
+// createUploadEntry creates a Quick Add entry for an uploaded file, keeping
+// the original file on the entry.
+func createUploadEntry(app core.App, resourceID, title, entryURL, guid, filename, format string, data []byte, extracted engine.ExtractedContent) (*core.Record, error) {
+	collection, err := app.FindCollectionByNameOrId("entries")
+	if err != nil {
+		return nil, err
+	}
+	file, err := filesystem.NewFileFromBytes(data, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	record := core.NewRecord(collection)
+	record.Set("resource", resourceID)
+	record.Set("title", title)
+	record.Set("url", entryURL)
+	record.Set("guid", guid)
+	record.Set("raw_content", extracted.Content)
+	record.Set("discovered_at", now)
+	record.Set("published_at", now)
+	record.Set("processing_status", "pending")
+	record.Set("is_read", false)
+	record.Set("source_file", file)
+	record.Set("source_format", format)
+	engine.SetExtractedFields(record, extracted)
+
+	if err := app.Save(record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// SourceFile is the original file of an uploaded entry.
+type SourceFile struct {
+	Name   string
+	Format string
+	Data   []byte
+}
+
+// HandleGetSourceFileDirect returns the original file of an uploaded entry.
+func HandleGetSourceFileDirect(app core.App, entryID string) (int, *SourceFile, error) {
+	entry, err := app.FindRecordById("entries", entryID)
+	if err != nil {
+		return http.StatusNotFound, nil, errors.New("Entry not found.")
+	}
+	stored := entry.GetString("source_file")
+	if stored == "" {
+		return http.StatusNotFound, nil, errors.New("Entry has no uploaded file.")
+	}
+
+	fsys, err := app.NewFilesystem()
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+	defer fsys.Close()
+	reader, err := fsys.GetReader(entry.BaseFilesPath() + "/" + stored)
+	if err != nil {
+		return http.StatusNotFound, nil, errors.New("Uploaded file not found.")
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("reading uploaded file: %w", err)
+	}
+	return http.StatusOK, &SourceFile{Name: stored, Format: entry.GetString("source_format"), Data: data}, nil
+}
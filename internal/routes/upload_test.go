@@ -0,0 +1,172 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jgordijn/knowledgehub/internal/ai"
+	"github.com/jgordijn/knowledgehub/internal/testutil"
+)
+
+func uploadRequest(t *testing.T, token, filename string, data []byte, title string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if filename != "" {
+		fw, err := mw.CreateFormFile("file", filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(data)
+	}
+	if title != "" {
+		mw.WriteField("title", title)
+	}
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/quick-add/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	return req
+}
+
+func TestQuickAddUploadRoute(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	testutil.CreateResource(t, app, "Quick Add", "https://quickadd.local", "quickadd", "healthy", 0, true)
+	restore := ai.SetCompleteFunc(func(_, _ string, _ []ai.Message) (string, error) {
+		return `{"summary":"Test summary","stars":4,"takeaways":["point 1"]}`, nil
+	})
+	defer restore()
+
+	mux := buildMux(t, app)
+	token := createAuthToken(t, app)
+	doc := []byte("# Incident Review\n\nThe outage was caused by an expired certificate.")
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, uploadRequest(t, "", "review.md", doc, ""))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without auth, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, uploadRequest(t, token, "", nil, ""))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a file, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, uploadRequest(t, token, "review.md", doc, ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp QuickAddResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Entry.Title != "Incident Review" || !strings.HasPrefix(resp.Entry.URL, "https://quickadd.local#") {
+		t.Errorf("unexpected entry info: %+v", resp.Entry)
+	}
+
+	entry, err := app.FindRecordById("entries", resp.Entry.ID)
+	if err != nil {
+		t.Fatalf("entry not found: %v", err)
+	}
+	if entry.GetString("source_format") != "markdown" || entry.GetString("source_file") == "" {
+		t.Errorf("expected the original file to be stored, got format=%q file=%q",
+			entry.GetString("source_format"), entry.GetString("source_file"))
+	}
+	if !strings.Contains(entry.GetString("raw_content"), "expired certificate") {
+		t.Errorf("unexpected raw_content: %q", entry.GetString("raw_content"))
+	}
+
+	// Same content under another name is a duplicate.
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, uploadRequest(t, token, "copy.md", doc, ""))
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "Incident Review") {
+		t.Fatalf("expected 409 for duplicate content, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// The original file can be downloaded.
+	req := httptest.NewRequest(http.MethodGet, "/api/entries/"+entry.Id+"/source", nil)
+	req.Header.Set("Authorization", token)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), doc) {
+		t.Fatalf("expected original file, got %d: %q", rec.Code, rec.Body.String())
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Disposition"), "attachment") || rec.Header().Get("Content-Type") != "text/markdown; charset=utf-8" {
+		t.Errorf("unexpected headers: %v", rec.Header())
+	}
+}
+
+func TestHandleQuickAddUploadDirect(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+	restore := ai.SetCompleteFunc(func(_, _ string, _ []ai.Message) (string, error) {
+		return `{"summary":"Test summary","stars":3,"takeaways":[]}`, nil
+	})
+	defer restore()
+
+	pdfData := testutil.BuildPDF("Architecture Decision Record", "Platform Team", "We adopt event sourcing.")
+	if status, _, err := HandleQuickAddUploadDirect(app, "adr.pdf", pdfData, ""); status != http.StatusInternalServerError || err == nil {
+		t.Errorf("expected 500 without a Quick Add resource, got %d (%v)", status, err)
+	}
+
+	testutil.CreateResource(t, app, "Quick Add", "https://quickadd.local", "quickadd", "healthy", 0, true)
+
+	status, resp, err := HandleQuickAddUploadDirect(app, `C:\docs\adr.pdf`, pdfData, "  Custom Title ")
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("unexpected result %d: %v", status, err)
+	}
+	if resp.Entry.Title != "Custom Title" {
+		t.Errorf("expected the given title, got %q", resp.Entry.Title)
+	}
+	entry, _ := app.FindRecordById("entries", resp.Entry.ID)
+	if entry.GetString("byline") != "Platform Team" || len(ai.EntryPDFPages(entry)) != 1 {
+		t.Errorf("expected PDF metadata and pages on the entry")
+	}
+	if !strings.HasPrefix(entry.GetString("source_file"), "adr") {
+		t.Errorf("expected the client path to be stripped, got %q", entry.GetString("source_file"))
+	}
+
+	cases := []struct {
+		name     string
+		filename string
+		data     []byte
+		want     int
+	}{
+		{"empty", "a.md", nil, http.StatusBadRequest},
+		{"unsupported", "photo.png", []byte("\x89PNG\r\n"), http.StatusUnsupportedMediaType},
+		{"unreadable", "book.epub", []byte("not a zip"), http.StatusUnprocessableEntity},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, _, err := HandleQuickAddUploadDirect(app, tc.filename, tc.data, "")
+			if err == nil || status != tc.want {
+				t.Errorf("status = %d (%v), want %d", status, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestHandleGetSourceFileDirect_NotFound(t *testing.T) {
+	app, cleanup := testutil.NewTestApp(t)
+	defer cleanup()
+
+	if status, _, err := HandleGetSourceFileDirect(app, "missing"); status != http.StatusNotFound || err == nil {
+		t.Errorf("expected 404 for a missing entry, got %d", status)
+	}
+	res := testutil.CreateResource(t, app, "Feed", "https://example.com/feed", "rss", "healthy", 0, true)
+	entry := testutil.CreateEntry(t, app, res.Id, "Article", "https://example.com/a", "g1")
+	if status, _, err := HandleGetSourceFileDirect(app, entry.Id); status != http.StatusNotFound || err == nil {
+		t.Errorf("expected 404 for an entry without upload, got %d", status)
+	}
+}
@@ -0,0 +1,71 @@
+package testutil
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// BuildPDF returns a minimal PDF with the given info dictionary title and
+// author (omitted when empty) and one page per text. Lines of a page are
+// separated by "\n", paragraphs by "\n\n". Text must be ASCII.
+func BuildPDF(title, author string, pages ...string) []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"", // page tree, filled in below
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+	}
+	var kids []string
+	for _, page := range pages {
+		var content strings.Builder
+		content.WriteString("BT /F1 12 Tf 72 720 Td\n")
+		for i, para := range strings.Split(page, "\n\n") {
+			if i > 0 {
+				content.WriteString("0 -40 Td\n")
+			}
+			for j, line := range strings.Split(para, "\n") {
+				if j > 0 {
+					content.WriteString("0 -14 Td\n")
+				}
+				fmt.Fprintf(&content, "(%s) Tj\n", pdfEscape(line))
+			}
+		}
+		content.WriteString("ET")
+		objects = append(objects, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+		objects = append(objects, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents %d 0 R >>", len(objects)))
+		kids = append(kids, fmt.Sprintf("%d 0 R", len(objects)))
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /Resources << /Font << /F1 3 0 R >> >> >>", strings.Join(kids, " "), len(kids))
+
+	trailer := "/Root 1 0 R"
+	if title != "" || author != "" {
+		info := "<<"
+		if title != "" {
+			info += fmt.Sprintf(" /Title (%s)", pdfEscape(title))
+		}
+		if author != "" {
+			info += fmt.Sprintf(" /Author (%s)", pdfEscape(author))
+		}
+		objects = append(objects, info+" >>")
+		trailer += fmt.Sprintf(" /Info %d 0 R", len(objects))
+	}
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d %s >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, trailer, xref)
+	return b.Bytes()
+}
+
+func pdfEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s)
+}
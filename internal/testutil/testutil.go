@@ -51,7 +51,7 @@ func registerCollections(t *testing.T, app core.App) {
 	addAutodateFields(resources)
 	resources.Fields.Add(&core.TextField{Name: "name", Required: true, Max: 200})
 	resources.Fields.Add(&core.URLField{Name: "url", Required: true})
-	resources.Fields.Add(&core.SelectField{Name: "type", Required: true, Values: []string{"rss", "watchlist", "quickadd"}, MaxSelect: 1})
+	resources.Fields.Add(&core.SelectField{Name: "type", Required: true, Values: []string{"rss", "watchlist", "quickadd", "sitemap", "pagewatch", "newsletter", "json"}, MaxSelect: 1})
 	resources.Fields.Add(&core.TextField{Name: "article_selector"})
 	resources.Fields.Add(&core.TextField{Name: "content_selector"})
 	resources.Fields.Add(&core.SelectField{Name: "status", Required: true, Values: []string{"healthy", "failing", "quarantined"}, MaxSelect: 1})
@@ -66,6 +66,18 @@ func registerCollections(t *testing.T, app core.App) {
 	resources.Fields.Add(&core.TextField{Name: "fragment_hashes"})
 	resources.Fields.Add(&core.SelectField{Name: "fragment_mode", Values: []string{"auto", "separated"}, MaxSelect: 1})
 	resources.Fields.Add(&core.TextField{Name: "fragment_separator"})
+	resources.Fields.Add(&core.TextField{Name: "url_pattern"})
+	resources.Fields.Add(&core.DateField{Name: "sitemap_since"})
+	resources.Fields.Add(&core.EditorField{Name: "page_snapshot"})
+	resources.Fields.Add(&core.TextField{Name: "page_snapshot_hash"})
+	resources.Fields.Add(&core.TextField{Name: "newsletter_senders"})
+	resources.Fields.Add(&core.TextField{Name: "json_items_path"})
+	resources.Fields.Add(&core.JSONField{Name: "json_mapping", MaxSize: 5000})
+	resources.Fields.Add(&core.JSONField{Name: "json_headers", MaxSize: 5000})
+	resources.Fields.Add(&core.SelectField{Name: "summary_length", Values: []string{"short", "medium", "long"}, MaxSelect: 1})
+	resources.Fields.Add(&core.SelectField{Name: "summary_format", Values: []string{"sentences", "bullets", "tldr", "key_numbers"}, MaxSelect: 1})
+	resources.Fields.Add(&core.TextField{Name: "summary_language", Max: 50})
+	resources.Fields.Add(&core.TextField{Name: "summary_instructions", Max: 2000})
 	resources.ListRule = types.Pointer("")
 	resources.ViewRule = types.Pointer("")
 	resources.CreateRule = types.Pointer("")
@@ -75,6 +87,24 @@ func registerCollections(t *testing.T, app core.App) {
 		t.Fatalf("failed to create resources collection: %v", err)
 	}
 
+	// archives
+	archives := core.NewBaseCollection("archives")
+	addAutodateFields(archives)
+	archives.Fields.Add(&core.TextField{Name: "hash", Required: true, Max: 64})
+	archives.Fields.Add(&core.URLField{Name: "url"})
+	archives.Fields.Add(&core.FileField{Name: "file", MaxSelect: 1, MaxSize: 50 << 20, Protected: true})
+	archives.Fields.Add(&core.NumberField{Name: "size"})
+	archives.Fields.Add(&core.SelectField{Name: "captured_with", Values: []string{"http", "browser"}, MaxSelect: 1})
+	archives.Indexes = append(archives.Indexes,
+		"CREATE UNIQUE INDEX idx_archives_hash ON archives (hash)",
+	)
+	archives.ListRule = types.Pointer("")
+	archives.ViewRule = types.Pointer("")
+	archives.DeleteRule = types.Pointer("")
+	if err := app.Save(archives); err != nil {
+		t.Fatalf("failed to create archives collection: %v", err)
+	}
+
 	// entries
 	entries := core.NewBaseCollection("entries")
 	addAutodateFields(entries)
@@ -93,6 +123,35 @@ func registerCollections(t *testing.T, app core.App) {
 	entries.Fields.Add(&core.SelectField{Name: "processing_status", Values: []string{"pending", "done", "failed"}, MaxSelect: 1})
 	entries.Fields.Add(&core.BoolField{Name: "is_fragment"})
 	entries.Fields.Add(&core.JSONField{Name: "takeaways", MaxSize: 5000})
+	entries.Fields.Add(&core.EditorField{Name: "change_diff"})
+	entries.Fields.Add(&core.JSONField{Name: "media", MaxSize: 5000})
+	entries.Fields.Add(&core.EditorField{Name: "content_html"})
+	entries.Fields.Add(&core.EditorField{Name: "content_markdown"})
+	entries.Fields.Add(&core.TextField{Name: "byline"})
+	entries.Fields.Add(&core.TextField{Name: "site_name"})
+	entries.Fields.Add(&core.TextField{Name: "excerpt"})
+	entries.Fields.Add(&core.TextField{Name: "lead_image"})
+	entries.Fields.Add(&core.TextField{Name: "language"})
+	entries.Fields.Add(&core.NumberField{Name: "word_count"})
+	entries.Fields.Add(&core.BoolField{Name: "bookmarked"})
+	entries.Fields.Add(&core.RelationField{Name: "archive", CollectionId: archives.Id, MaxSelect: 1})
+	entries.Fields.Add(&core.DateField{Name: "archived_at"})
+	entries.Fields.Add(&core.JSONField{Name: "pdf_pages", MaxSize: 20 << 20})
+	entries.Fields.Add(&core.FileField{Name: "source_file", MaxSelect: 1, MaxSize: 50 << 20, Protected: true})
+	entries.Fields.Add(&core.TextField{Name: "source_format"})
+	entries.Fields.Add(&core.JSONField{Name: "tags", MaxSize: 5000})
+	entries.Fields.Add(&core.TextField{Name: "score_reason", Max: 1000})
+	entries.Fields.Add(&core.JSONField{Name: "score_influences", MaxSize: 5000})
+	entries.Fields.Add(&core.NumberField{Name: "score_relevance", Min: fp(0), Max: fp(5)})
+	entries.Fields.Add(&core.NumberField{Name: "score_depth", Min: fp(0), Max: fp(5)})
+	entries.Fields.Add(&core.NumberField{Name: "score_novelty", Min: fp(0), Max: fp(5)})
+	entries.Fields.Add(&core.NumberField{Name: "score_credibility", Min: fp(0), Max: fp(5)})
+	entries.Fields.Add(&core.NumberField{Name: "local_stars", Min: fp(0), Max: fp(5)})
+	entries.Fields.Add(&core.NumberField{Name: "local_confidence", Min: fp(0), Max: fp(1)})
+	entries.Fields.Add(&core.TextField{Name: "score_source", Max: 20})
+	entries.Fields.Add(&core.TextField{Name: "detected_language", Max: 10})
+	entries.Fields.Add(&core.JSONField{Name: "translations", MaxSize: 5 << 20})
+	entries.Fields.Add(&core.DateField{Name: "unarchivable_since"})
 	entries.ListRule = types.Pointer("")
 	entries.ViewRule = types.Pointer("")
 	entries.CreateRule = types.Pointer("")
@@ -102,11 +161,125 @@ func registerCollections(t *testing.T, app core.App) {
 		t.Fatalf("failed to create entries collection: %v", err)
 	}
 
+	// import_jobs
+	importJobs := core.NewBaseCollection("import_jobs")
+	addAutodateFields(importJobs)
+	importJobs.Fields.Add(&core.SelectField{Name: "source", Required: true, Values: []string{"text", "pocket", "instapaper", "raindrop", "netscape"}, MaxSelect: 1})
+	importJobs.Fields.Add(&core.SelectField{Name: "status", Required: true, Values: []string{"pending", "running", "done", "failed"}, MaxSelect: 1})
+	importJobs.Fields.Add(&core.JSONField{Name: "items", MaxSize: 20 << 20})
+	importJobs.Fields.Add(&core.NumberField{Name: "total"})
+	importJobs.Fields.Add(&core.NumberField{Name: "processed"})
+	importJobs.Fields.Add(&core.NumberField{Name: "added"})
+	importJobs.Fields.Add(&core.NumberField{Name: "skipped"})
+	importJobs.Fields.Add(&core.NumberField{Name: "failed"})
+	importJobs.Fields.Add(&core.TextField{Name: "error"})
+	importJobs.Fields.Add(&core.DateField{Name: "finished_at"})
+	importJobs.ListRule = types.Pointer("")
+	importJobs.ViewRule = types.Pointer("")
+	importJobs.DeleteRule = types.Pointer("")
+	if err := app.Save(importJobs); err != nil {
+		t.Fatalf("failed to create import_jobs collection: %v", err)
+	}
+
+	// tags
+	tags := core.NewBaseCollection("tags")
+	addAutodateFields(tags)
+	tags.Fields.Add(&core.TextField{Name: "name", Required: true, Max: 50})
+	tags.Fields.Add(&core.JSONField{Name: "synonyms", MaxSize: 5000})
+	tags.Fields.Add(&core.SelectField{Name: "summary_length", Values: []string{"short", "medium", "long"}, MaxSelect: 1})
+	tags.Fields.Add(&core.SelectField{Name: "summary_format", Values: []string{"sentences", "bullets", "tldr", "key_numbers"}, MaxSelect: 1})
+	tags.Fields.Add(&core.TextField{Name: "summary_language", Max: 50})
+	tags.Fields.Add(&core.TextField{Name: "summary_instructions", Max: 2000})
+	tags.ListRule = types.Pointer("")
+	tags.ViewRule = types.Pointer("")
+	tags.CreateRule = types.Pointer("")
+	tags.UpdateRule = types.Pointer("")
+	tags.DeleteRule = types.Pointer("")
+	tags.Indexes = append(tags.Indexes, "CREATE UNIQUE INDEX idx_tags_name ON tags (name)")
+	if err := app.Save(tags); err != nil {
+		t.Fatalf("failed to create tags collection: %v", err)
+	}
+
+	// relevance_models
+	relevanceModels := core.NewBaseCollection("relevance_models")
+	addAutodateFields(relevanceModels)
+	relevanceModels.Fields.Add(&core.DateField{Name: "trained_at", Required: true})
+	relevanceModels.Fields.Add(&core.NumberField{Name: "samples"})
+	relevanceModels.Fields.Add(&core.NumberField{Name: "holdout"})
+	relevanceModels.Fields.Add(&core.NumberField{Name: "accuracy"})
+	relevanceModels.Fields.Add(&core.NumberField{Name: "mae"})
+	relevanceModels.Fields.Add(&core.JSONField{Name: "model", MaxSize: 20 << 20})
+	relevanceModels.ListRule = types.Pointer("")
+	relevanceModels.ViewRule = types.Pointer("")
+	if err := app.Save(relevanceModels); err != nil {
+		t.Fatalf("failed to create relevance_models collection: %v", err)
+	}
+
+	// rescore_jobs
+	rescoreJobs := core.NewBaseCollection("rescore_jobs")
+	addAutodateFields(rescoreJobs)
+	rescoreJobs.Fields.Add(&core.SelectField{Name: "trigger", Required: true, Values: []string{"profile_change", "manual"}, MaxSelect: 1})
+	rescoreJobs.Fields.Add(&core.SelectField{Name: "status", Required: true, Values: []string{"pending", "running", "done", "failed"}, MaxSelect: 1})
+	rescoreJobs.Fields.Add(&core.NumberField{Name: "days"})
+	rescoreJobs.Fields.Add(&core.NumberField{Name: "max_entries"})
+	rescoreJobs.Fields.Add(&core.NumberField{Name: "max_tokens"})
+	rescoreJobs.Fields.Add(&core.NumberField{Name: "profile_version"})
+	rescoreJobs.Fields.Add(&core.JSONField{Name: "items", MaxSize: 20 << 20})
+	rescoreJobs.Fields.Add(&core.NumberField{Name: "total"})
+	rescoreJobs.Fields.Add(&core.NumberField{Name: "processed"})
+	rescoreJobs.Fields.Add(&core.NumberField{Name: "changed"})
+	rescoreJobs.Fields.Add(&core.NumberField{Name: "unchanged"})
+	rescoreJobs.Fields.Add(&core.NumberField{Name: "failed"})
+	rescoreJobs.Fields.Add(&core.NumberField{Name: "skipped"})
+	rescoreJobs.Fields.Add(&core.NumberField{Name: "estimated_tokens"})
+	rescoreJobs.Fields.Add(&core.TextField{Name: "error"})
+	rescoreJobs.Fields.Add(&core.DateField{Name: "finished_at"})
+	rescoreJobs.ListRule = types.Pointer("")
+	rescoreJobs.ViewRule = types.Pointer("")
+	rescoreJobs.DeleteRule = types.Pointer("")
+	if err := app.Save(rescoreJobs); err != nil {
+		t.Fatalf("failed to create rescore_jobs collection: %v", err)
+	}
+
+	// interactions
+	interactions := core.NewBaseCollection("interactions")
+	addAutodateFields(interactions)
+	interactions.Fields.Add(&core.RelationField{Name: "entry", CollectionId: entries.Id, Required: true, MaxSelect: 1, CascadeDelete: true})
+	interactions.Fields.Add(&core.SelectField{Name: "type", Required: true, Values: []string{"opened", "read", "bookmarked", "chatted", "link_summarized", "dismissed"}, MaxSelect: 1})
+	interactions.Fields.Add(&core.NumberField{Name: "duration_seconds", Min: fp(0)})
+	interactions.ListRule = types.Pointer("")
+	interactions.ViewRule = types.Pointer("")
+	interactions.DeleteRule = types.Pointer("")
+	if err := app.Save(interactions); err != nil {
+		t.Fatalf("failed to create interactions collection: %v", err)
+	}
+
+	// prompt_templates
+	promptTemplates := core.NewBaseCollection("prompt_templates")
+	addAutodateFields(promptTemplates)
+	promptTemplates.Fields.Add(&core.TextField{Name: "name", Required: true, Max: 50})
+	promptTemplates.Fields.Add(&core.NumberField{Name: "version", Required: true, Min: fp(2)})
+	promptTemplates.Fields.Add(&core.TextField{Name: "template", Required: true, Max: 20000})
+	promptTemplates.Fields.Add(&core.TextField{Name: "notes", Max: 500})
+	promptTemplates.Fields.Add(&core.BoolField{Name: "active"})
+	promptTemplates.Indexes = append(promptTemplates.Indexes, "CREATE UNIQUE INDEX idx_prompt_templates_name_version ON prompt_templates (name, version)")
+	promptTemplates.ListRule = types.Pointer("")
+	promptTemplates.ViewRule = types.Pointer("")
+	if err := app.Save(promptTemplates); err != nil {
+		t.Fatalf("failed to create prompt_templates collection: %v", err)
+	}
+
 	// preferences
 	prefs := core.NewBaseCollection("preferences")
 	addAutodateFields(prefs)
 	prefs.Fields.Add(&core.EditorField{Name: "profile_text"})
 	prefs.Fields.Add(&core.DateField{Name: "generated_at"})
+	prefs.Fields.Add(&core.NumberField{Name: "version"})
+	prefs.Fields.Add(&core.TextField{Name: "model"})
+	prefs.Fields.Add(&core.TextField{Name: "source", Max: 20})
+	prefs.Fields.Add(&core.TextField{Name: "restored_from"})
+	prefs.Fields.Add(&core.JSONField{Name: "source_corrections", MaxSize: 200000})
+	prefs.Fields.Add(&core.JSONField{Name: "implicit_signals", MaxSize: 50000})
 	prefs.ListRule = types.Pointer("")
 	prefs.ViewRule = types.Pointer("")
 	prefs.CreateRule = types.Pointer("")
@@ -116,6 +289,19 @@ func registerCollections(t *testing.T, app core.App) {
 		t.Fatalf("failed to create preferences collection: %v", err)
 	}
 
+	// preference_rules
+	prefRules := core.NewBaseCollection("preference_rules")
+	addAutodateFields(prefRules)
+	prefRules.Fields.Add(&core.TextField{Name: "rule", Required: true, Max: 500})
+	prefRules.ListRule = types.Pointer("")
+	prefRules.ViewRule = types.Pointer("")
+	prefRules.CreateRule = types.Pointer("")
+	prefRules.UpdateRule = types.Pointer("")
+	prefRules.DeleteRule = types.Pointer("")
+	if err := app.Save(prefRules); err != nil {
+		t.Fatalf("failed to create preference_rules collection: %v", err)
+	}
+
 	// daily_news_settings
 	dailySettings := core.NewBaseCollection("daily_news_settings")
 	addAutodateFields(dailySettings)
@@ -124,6 +310,7 @@ func registerCollections(t *testing.T, app core.App) {
 	dailySettings.Fields.Add(&core.TextField{Name: "generation_time", Required: true, Max: 5})
 	dailySettings.Fields.Add(&core.TextField{Name: "timezone", Required: true, Max: 100})
 	dailySettings.Fields.Add(&core.TextField{Name: "extra_instructions", Max: 8000})
+	dailySettings.Fields.Add(&core.TextField{Name: "rank_by", Max: 20})
 	dailySettings.ListRule = types.Pointer("user = @request.auth.id")
 	dailySettings.ViewRule = types.Pointer("user = @request.auth.id")
 	dailySettings.CreateRule = nil
@@ -134,6 +321,46 @@ func registerCollections(t *testing.T, app core.App) {
 		t.Fatalf("failed to create daily_news_settings collection: %v", err)
 	}
 
+	// user_settings
+	userSettings := core.NewBaseCollection("user_settings")
+	addAutodateFields(userSettings)
+	userSettings.Fields.Add(&core.RelationField{Name: "user", CollectionId: superusers.Id, Required: true, MaxSelect: 1})
+	userSettings.Fields.Add(&core.TextField{Name: "summary_language", Max: 10})
+	userSettings.Fields.Add(&core.BoolField{Name: "translate_articles"})
+	userSettings.ListRule = types.Pointer("user = @request.auth.id")
+	userSettings.ViewRule = types.Pointer("user = @request.auth.id")
+	userSettings.Indexes = append(userSettings.Indexes, "CREATE UNIQUE INDEX idx_user_settings_user ON user_settings (user)")
+	if err := app.Save(userSettings); err != nil {
+		t.Fatalf("failed to create user_settings collection: %v", err)
+	}
+
+	// chat_sessions
+	chatSessions := core.NewBaseCollection("chat_sessions")
+	addAutodateFields(chatSessions)
+	chatSessions.Fields.Add(&core.RelationField{Name: "user", CollectionId: superusers.Id, Required: true, MaxSelect: 1})
+	chatSessions.Fields.Add(&core.RelationField{Name: "entry", CollectionId: entries.Id, Required: true, MaxSelect: 1, CascadeDelete: true})
+	chatSessions.Fields.Add(&core.TextField{Name: "title", Max: 200})
+	chatSessions.ListRule = types.Pointer("user = @request.auth.id")
+	chatSessions.ViewRule = types.Pointer("user = @request.auth.id")
+	if err := app.Save(chatSessions); err != nil {
+		t.Fatalf("failed to create chat_sessions collection: %v", err)
+	}
+
+	// chat_messages
+	chatMessages := core.NewBaseCollection("chat_messages")
+	addAutodateFields(chatMessages)
+	chatMessages.Fields.Add(&core.RelationField{Name: "session", CollectionId: chatSessions.Id, Required: true, MaxSelect: 1, CascadeDelete: true})
+	chatMessages.Fields.Add(&core.NumberField{Name: "position", OnlyInt: true})
+	chatMessages.Fields.Add(&core.SelectField{Name: "role", Required: true, Values: []string{"user", "assistant"}, MaxSelect: 1})
+	chatMessages.Fields.Add(&core.EditorField{Name: "content"})
+	chatMessages.Fields.Add(&core.BoolField{Name: "partial"})
+	chatMessages.ListRule = types.Pointer("session.user = @request.auth.id")
+	chatMessages.ViewRule = types.Pointer("session.user = @request.auth.id")
+	chatMessages.Indexes = append(chatMessages.Indexes, "CREATE UNIQUE INDEX idx_chat_messages_session ON chat_messages (session, position)")
+	if err := app.Save(chatMessages); err != nil {
+		t.Fatalf("failed to create chat_messages collection: %v", err)
+	}
+
 	// daily_digests
 	dailyDigests := core.NewBaseCollection("daily_digests")
 	addAutodateFields(dailyDigests)
@@ -175,6 +402,24 @@ func registerCollections(t *testing.T, app core.App) {
 		t.Fatalf("failed to create daily_digests collection: %v", err)
 	}
 
+	// site_rules
+	siteRules := core.NewBaseCollection("site_rules")
+	addAutodateFields(siteRules)
+	siteRules.Fields.Add(&core.TextField{Name: "domain", Required: true, Max: 253})
+	siteRules.Fields.Add(&core.TextField{Name: "rules", Max: 50000})
+	siteRules.Fields.Add(&core.TextField{Name: "imported_from", Max: 1000})
+	siteRules.Indexes = append(siteRules.Indexes,
+		"CREATE UNIQUE INDEX idx_site_rules_domain ON site_rules (domain)",
+	)
+	siteRules.ListRule = types.Pointer("")
+	siteRules.ViewRule = types.Pointer("")
+	siteRules.CreateRule = types.Pointer("")
+	siteRules.UpdateRule = types.Pointer("")
+	siteRules.DeleteRule = types.Pointer("")
+	if err := app.Save(siteRules); err != nil {
+		t.Fatalf("failed to create site_rules collection: %v", err)
+	}
+
 	// app_settings
 	settings := core.NewBaseCollection("app_settings")
 	addAutodateFields(settings)
@@ -304,6 +549,23 @@ func CreateDailyNewsSettings(t *testing.T, app core.App, userID string, enabled
 	return r
 }
 
+// CreateUserSettings is a test helper to set a user's summary language.
+func CreateUserSettings(t *testing.T, app core.App, userID, summaryLanguage string, translateArticles bool) *core.Record {
+	t.Helper()
+	col, err := app.FindCollectionByNameOrId("user_settings")
+	if err != nil {
+		t.Fatalf("user_settings collection not found: %v", err)
+	}
+	r := core.NewRecord(col)
+	r.Set("user", userID)
+	r.Set("summary_language", summaryLanguage)
+	r.Set("translate_articles", translateArticles)
+	if err := app.Save(r); err != nil {
+		t.Fatalf("failed to create user settings: %v", err)
+	}
+	return r
+}
+
 // CreateDailyDigest is a test helper to create a Daily News digest record.
 func CreateDailyDigest(t *testing.T, app core.App, userID, localDate, status, trigger string) *core.Record {
 	t.Helper()
@@ -343,3 +605,37 @@ func CreateEntryWithStars(t *testing.T, app core.App, resourceID, title, url str
 	}
 	return r
 }
+
+// CreateTag is a test helper to create a tags record with optional synonyms.
+func CreateTag(t *testing.T, app core.App, name string, synonyms ...string) *core.Record {
+	t.Helper()
+	col, err := app.FindCollectionByNameOrId("tags")
+	if err != nil {
+		t.Fatalf("tags collection not found: %v", err)
+	}
+	r := core.NewRecord(col)
+	r.Set("name", name)
+	if len(synonyms) > 0 {
+		r.Set("synonyms", synonyms)
+	}
+	if err := app.Save(r); err != nil {
+		t.Fatalf("failed to create tag: %v", err)
+	}
+	return r
+}
+
+// DeleteEntriesCollection deletes the entries collection to make entry
+// queries fail. The collections relating to entries go first, as a
+// collection cannot be deleted while others reference it.
+func DeleteEntriesCollection(t *testing.T, app core.App) {
+	t.Helper()
+	for _, name := range []string{"chat_messages", "chat_sessions", "interactions", "entries"} {
+		col, err := app.FindCollectionByNameOrId(name)
+		if err != nil {
+			t.Fatalf("finding %s collection: %v", name, err)
+		}
+		if err := app.Delete(col); err != nil {
+			t.Fatalf("deleting %s collection: %v", name, err)
+		}
+	}
+}
@@ -31,6 +31,12 @@ describe('StarRating', () => {
 		unmount(component);
 	});
 
+	it('shows the score reason as a tooltip', () => {
+		const component = mount(StarRating, { target, props: { aiStars: 2, reason: 'Mostly product news.' } });
+		expect(target.querySelector('[role="group"]')?.getAttribute('title')).toBe('Mostly product news.');
+		unmount(component);
+	});
+
 	it('calls onRate when a star is clicked', async () => {
 		const onRate = vi.fn();
 		const component = mount(StarRating, { target, props: { aiStars: 3, onRate } });
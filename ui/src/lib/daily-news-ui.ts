@@ -30,6 +30,7 @@ export type DailyNewsSettingsDTO = {
 	generation_time: string;
 	timezone: string;
 	extra_instructions: string;
+	rank_by?: string;
 };
 
 export type DailyNewsEntryReferenceDTO = {
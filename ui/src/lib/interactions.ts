@@ -0,0 +1,57 @@
+import pb from './pb';
+
+export type InteractionType = 'opened' | 'read' | 'bookmarked' | 'chatted' | 'link_summarized' | 'dismissed';
+
+// Reads shorter than this are bounces, not reading.
+const MIN_READ_SECONDS = 10;
+const MAX_READ_SECONDS = 4 * 60 * 60;
+
+/** Logs an interaction with an entry; failures are ignored. */
+export async function recordInteraction(entryId: string, type: InteractionType, durationSeconds = 0): Promise<void> {
+	try {
+		await pb.send('/api/interactions', {
+			method: 'POST',
+			body: { entry_id: entryId, type, duration_seconds: durationSeconds }
+		});
+	} catch {
+		// Implicit signals are best effort
+	}
+}
+
+/**
+ * Logs that an entry was marked read without being looked at; entries the
+ * user opened or chatted about before are not dismissals.
+ */
+export async function recordDismissal(entryId: string): Promise<void> {
+	try {
+		const engaged = await pb.collection('interactions').getList(1, 1, {
+			filter: pb.filter("entry = {:entry} && (type = 'opened' || type = 'chatted')", { entry: entryId }),
+			fields: 'id',
+			skipTotal: false,
+			requestKey: null
+		});
+		if (engaged.totalItems > 0) return;
+	} catch {
+		// Without the history, a dismissal cannot be told apart
+		return;
+	}
+	await recordInteraction(entryId, 'dismissed');
+}
+
+/**
+ * Measures how long the user stays away after opening an article in a new
+ * tab and records it as read time once they come back.
+ */
+export function trackReadTime(entryId: string): void {
+	if (typeof document === 'undefined') return;
+	const openedAt = Date.now();
+	const onVisible = () => {
+		if (document.visibilityState !== 'visible') return;
+		document.removeEventListener('visibilitychange', onVisible);
+		const seconds = Math.min(Math.round((Date.now() - openedAt) / 1000), MAX_READ_SECONDS);
+		if (seconds >= MIN_READ_SECONDS) {
+			recordInteraction(entryId, 'read', seconds);
+		}
+	};
+	document.addEventListener('visibilitychange', onVisible);
+}
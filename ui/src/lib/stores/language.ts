@@ -0,0 +1,18 @@
+import { writable } from 'svelte/store';
+import pb from '$lib/pb';
+
+// The user's summary language code; '' keeps summaries in the article's language.
+export const summaryLanguage = writable('');
+
+let loaded = false;
+
+export async function loadSummaryLanguage(): Promise<void> {
+	if (loaded) return;
+	loaded = true;
+	try {
+		const settings = (await pb.send('/api/user-settings', { method: 'GET' })) as { summary_language?: string };
+		summaryLanguage.set(settings.summary_language ?? '');
+	} catch {
+		loaded = false;
+	}
+}